@@ -1,17 +1,26 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 
 	"github.com/joho/godotenv"
 
 	"languagepapi/internal/db"
+	"languagepapi/internal/models"
 	"languagepapi/internal/service"
 )
 
 func main() {
+	enqueueOnly := flag.Bool("enqueue-only", false, "queue the jobs for a running server instead of processing them here")
+	limit := flag.Int("limit", 1000, "maximum number of songs")
+	flag.Parse()
+
 	// Load .env file
 	_ = godotenv.Load()
 
@@ -21,22 +30,51 @@ func main() {
 		dbPath = "languagepapi.db"
 	}
 
+	if songsPath := os.Getenv("SONGS_PATH"); songsPath != "" {
+		service.SongsPath = songsPath
+	}
+
 	// Initialize database
 	if err := db.Init(dbPath); err != nil {
 		log.Fatal(err)
 	}
 	defer db.Close()
 
-	fmt.Println("Fetching lyrics for all songs from lrclib.net...")
-	fmt.Println("This will also translate lyrics to English using Gemini (if GEMINI_API_KEY is set)")
-	fmt.Println()
+	// Keep records of AI calls in the database
+	service.InitAI()
 
-	lyricsService := service.NewLyricsService()
-	success, failed, err := lyricsService.FetchLyricsForAllSongs()
+	jobService := service.NewJobService()
+	batch, err := jobService.StartBatch(0, "lyrics", *limit)
+	if errors.Is(err, service.ErrNothingToDo) {
+		fmt.Println("All songs already have lyrics")
+		return
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	fmt.Printf("Queued %s\n", batch.Name)
+	fmt.Println("Lyrics come from .lrc files and tags in SONGS_PATH, then lrclib.net unless LYRICS_OFFLINE is set")
+	fmt.Println("They are translated and their vocabulary extracted with the configured LLM (Gemini if GEMINI_API_KEY is set)")
+	if *enqueueOnly {
+		fmt.Println("The server's workers will process them; follow progress on /jobs")
+		return
+	}
+	fmt.Println()
+
+	// Ctrl-C stops the workers and puts their jobs back in the queue
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err = jobService.RunBatch(ctx, batch.ID, func(b *models.JobBatch) {
+		fmt.Printf("\r%d/%d jobs done, %d failed, %d running, %d queued ", b.Done, b.Total, b.Failed, b.Running, b.Pending)
+	})
 	fmt.Println()
-	fmt.Printf("Done! Fetched lyrics for %d songs, %d failed\n", success, failed)
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("Stopped; the batch's remaining jobs are queued for the server")
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Done! Failed jobs and their errors are listed on /jobs")
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"io/fs"
 	"log"
@@ -11,6 +12,7 @@ import (
 
 	"languagepapi/internal/db"
 	"languagepapi/internal/handlers"
+	"languagepapi/internal/service"
 )
 
 //go:embed static
@@ -31,22 +33,34 @@ func main() {
 	}
 	defer db.Close()
 
+	// Keep records of AI calls in the database
+	service.InitAI()
+
 	// Create a new ServeMux to avoid conflicts with default mux
 	mux := http.NewServeMux()
 
 	// Routes
 	mux.HandleFunc("GET /", handlers.HandleHome)
 
+	// Accounts (public - see handlers.RequireAuth)
+	mux.HandleFunc("GET /login", handlers.HandleLoginPage)
+	mux.HandleFunc("POST /login", handlers.HandleLogin)
+	mux.HandleFunc("GET /register", handlers.HandleRegisterPage)
+	mux.HandleFunc("POST /register", handlers.HandleRegister)
+	mux.HandleFunc("POST /logout", handlers.HandleLogout)
+
 	// Practice routes (legacy - kept for "extra practice")
 	mux.HandleFunc("GET /practice", handlers.HandlePractice)
 	mux.HandleFunc("GET /practice/card", handlers.HandlePracticeCard)
 	mux.HandleFunc("POST /practice/review", handlers.HandleReview)
+	mux.HandleFunc("POST /practice/check", handlers.HandleCheckAnswer)
 	mux.HandleFunc("POST /practice/skip", handlers.HandleSkip)
 	mux.HandleFunc("GET /practice/stats", handlers.HandlePracticeStats)
 
 	// Daily lesson routes (new journey mode)
 	mux.HandleFunc("GET /lesson/start", handlers.HandleLessonStart)
 	mux.HandleFunc("POST /lesson/review", handlers.HandleLessonReview)
+	mux.HandleFunc("POST /lesson/check", handlers.HandleLessonCheckAnswer)
 	mux.HandleFunc("POST /lesson/skip", handlers.HandleLessonSkip)
 
 	// Words management
@@ -57,8 +71,19 @@ func main() {
 	mux.HandleFunc("PUT /words/{id}", handlers.HandleUpdateCard)
 	mux.HandleFunc("DELETE /words/{id}", handlers.HandleDeleteCard)
 
+	// Bulk CSV/TSV import and export
+	mux.HandleFunc("GET /words/import", handlers.HandleWordsImportPage)
+	mux.HandleFunc("POST /words/import", handlers.HandleWordsImport)
+	mux.HandleFunc("GET /words/export.csv", handlers.HandleWordsExport)
+
+	// Anki deck export/import
+	mux.HandleFunc("GET /words/anki", handlers.HandleAnki)
+	mux.HandleFunc("GET /words/export.apkg", handlers.HandleAnkiExport)
+	mux.HandleFunc("POST /words/import-anki", handlers.HandleAnkiImport)
+
 	// AI generation routes
 	mux.HandleFunc("POST /words/{id}/generate-bridges", handlers.HandleGenerateBridges)
+	mux.HandleFunc("GET /words/{id}/bridges", handlers.HandleCardBridges)
 	mux.HandleFunc("POST /words/{id}/generate-example", handlers.HandleGenerateExample)
 
 	// Calendar / Stats
@@ -70,6 +95,12 @@ func main() {
 	// Settings
 	mux.HandleFunc("GET /settings", handlers.HandleSettings)
 	mux.HandleFunc("POST /settings", handlers.HandleSaveSettings)
+	mux.HandleFunc("POST /settings/optimize-fsrs", handlers.HandleOptimizeFSRS)
+
+	// Curricula
+	mux.HandleFunc("GET /curricula", handlers.HandleCurricula)
+	mux.HandleFunc("POST /curricula", handlers.HandleCreateCurriculum)
+	mux.HandleFunc("POST /curricula/{id}/start", handlers.HandleStartCurriculum)
 
 	// Grammar
 	mux.HandleFunc("GET /grammar", handlers.HandleGrammar)
@@ -85,8 +116,33 @@ func main() {
 	mux.HandleFunc("POST /songs/{id}/next-line", handlers.HandleSongNextLine)
 	mux.HandleFunc("POST /songs/{id}/skip-line", handlers.HandleSongSkipLine)
 	mux.HandleFunc("POST /songs/{id}/submit-blank", handlers.HandleSongBlankSubmit)
+	mux.HandleFunc("POST /songs/{id}/submit-dictation", handlers.HandleSongDictationSubmit)
+	mux.HandleFunc("POST /songs/{id}/next-dictation", handlers.HandleSongNextDictation)
 	mux.HandleFunc("POST /songs/{id}/complete", handlers.HandleSongComplete)
 	mux.HandleFunc("POST /songs/{id}/fetch-lyrics", handlers.HandleFetchLyrics)
+	mux.HandleFunc("GET /songs/{id}/lyrics.lrc", handlers.HandleSongLyricsExport)
+	mux.HandleFunc("GET /songs/{id}/lyrics.srt", handlers.HandleSongLyricsExport)
+	mux.HandleFunc("GET /songs/{id}/lyrics.vtt", handlers.HandleSongLyricsExport)
+	mux.HandleFunc("GET /songs/{id}/edit-lyrics", handlers.HandleLyricsEditor)
+	mux.HandleFunc("POST /songs/{id}/edit-lyrics", handlers.HandleSaveLyricsEdits)
+	mux.HandleFunc("POST /songs/{id}/edit-lyrics/undo", handlers.HandleUndoLyricsEdit)
+
+	// Background jobs
+	mux.HandleFunc("GET /jobs", handlers.HandleJobs)
+	mux.HandleFunc("GET /jobs/list", handlers.HandleJobsList)
+	mux.HandleFunc("POST /jobs/batches", handlers.HandleStartJobBatch)
+	mux.HandleFunc("POST /jobs/batches/{id}/cancel", handlers.HandleCancelJobBatch)
+	mux.HandleFunc("POST /jobs/batches/{id}/retry", handlers.HandleRetryJobBatch)
+	mux.HandleFunc("POST /jobs/{id}/cancel", handlers.HandleCancelJob)
+
+	// Conjugation drills
+	mux.HandleFunc("GET /conjugations", handlers.HandleConjugations)
+	mux.HandleFunc("GET /conjugations/next", handlers.HandleConjugationNext)
+	mux.HandleFunc("POST /conjugations/check", handlers.HandleConjugationCheck)
+
+	// AI usage and cost
+	mux.HandleFunc("GET /usage", handlers.HandleUsage)
+	mux.HandleFunc("POST /usage/cache/clear", handlers.HandleClearAICache)
 
 	// Static files (embedded in binary)
 	staticFS, _ := fs.Sub(staticFiles, "static")
@@ -97,10 +153,17 @@ func main() {
 
 	// Album art extracted from audio file metadata
 	handlers.SongsPath = songsPath
+	service.SongsPath = songsPath
 	mux.HandleFunc("GET /audio/cover/{filename}", handlers.HandleAlbumArt)
 
+	// JSON API and its OpenAPI document
+	handlers.RegisterAPI(mux)
+
+	// Run queued AI and lyrics work in the background
+	handlers.StartJobs(context.Background())
+
 	log.Printf("Server running on http://localhost:%s", port)
-	log.Fatal(http.ListenAndServe(":"+port, mux))
+	log.Fatal(http.ListenAndServe(":"+port, handlers.RequireAuth(mux)))
 }
 
 // getEnv returns environment variable or default value
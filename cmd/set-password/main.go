@@ -0,0 +1,51 @@
+// Command set-password sets the password of an existing account, such as
+// the seeded user, which has none and so cannot log in until it is set.
+// Accounts are never claimed through /register.
+//
+//	set-password [-user NAME] < password.txt
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+
+	"languagepapi/internal/db"
+	"languagepapi/internal/service"
+)
+
+func main() {
+	username := flag.String("user", "sangam", "account whose password to set")
+	flag.Parse()
+
+	// Load .env file
+	_ = godotenv.Load()
+
+	dbPath := os.Getenv("DB_PATH")
+	if dbPath == "" {
+		dbPath = "languagepapi.db"
+	}
+
+	fmt.Fprintf(os.Stderr, "New password for %s: ", *username)
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		log.Fatal("no password given on stdin")
+	}
+	password = strings.TrimRight(password, "\r\n")
+
+	if err := db.Init(dbPath); err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	user, err := service.NewAuthService().SetPassword(*username, password)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Password set for %s (user %d)\n", user.Username, user.ID)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+
+	"languagepapi/internal/db"
+	"languagepapi/internal/repository"
+	"languagepapi/internal/service"
+)
+
+const usage = `Usage:
+  words import [flags] FILE   import a CSV/TSV word list
+  words export [flags]        write the word list as CSV/TSV to stdout
+
+Run "words import -h" or "words export -h" for flags.`
+
+func main() {
+	// Load .env file
+	_ = godotenv.Load()
+
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	dbPath := os.Getenv("DB_PATH")
+	if dbPath == "" {
+		dbPath = "languagepapi.db"
+	}
+
+	switch os.Args[1] {
+	case "import":
+		runImport(dbPath, os.Args[2:])
+	case "export":
+		runExport(dbPath, os.Args[2:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func runImport(dbPath string, args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	username := fs.String("user", "", "owner of the new cards (default: shared cards)")
+	islandID := fs.Int64("island", 1, "island for rows without one")
+	mappingSpec := fs.String("map", "", `column mapping, e.g. "term=Spanish,translation=2,rank=5"`)
+	duplicatesFlag := fs.String("duplicates", "skip", "existing terms: skip, merge or overwrite")
+	tsv := fs.Bool("tsv", false, "tab-separated input (default: detect)")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		log.Fatal("import needs exactly one file")
+	}
+	mapping, err := service.ParseColumnMapping(*mappingSpec)
+	if err != nil {
+		log.Fatal(err)
+	}
+	duplicates, err := service.ParseDuplicateMode(*duplicatesFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := db.Init(dbPath); err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	opts := service.WordImportOptions{
+		Mapping:         mapping,
+		Duplicates:      duplicates,
+		DefaultIslandID: *islandID,
+	}
+	if *tsv || strings.HasSuffix(strings.ToLower(fs.Arg(0)), ".tsv") {
+		opts.Delimiter = '\t'
+	}
+
+	result, err := service.NewWordListService().Import(lookupUser(*username), data, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, e := range result.Errors {
+		fmt.Println(e)
+	}
+	fmt.Printf("Created: %d, Merged: %d, Overwritten: %d, Skipped (duplicates): %d, Failed: %d\n",
+		result.Created, result.Merged, result.Overwritten, result.Skipped, result.Failed)
+}
+
+func runExport(dbPath string, args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	username := fs.String("user", "", "include this user's own cards (default: shared cards only)")
+	islandID := fs.Int64("island", 0, "only this island (default: all)")
+	query := fs.String("q", "", "only words matching this search")
+	tsv := fs.Bool("tsv", false, "tab-separated output")
+	fs.Parse(args)
+
+	if err := db.Init(dbPath); err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	cards, err := repository.FilterCards(lookupUser(*username), *islandID, *query)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	delimiter := ','
+	if *tsv {
+		delimiter = '\t'
+	}
+	data, err := service.NewWordListService().Export(cards, delimiter)
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Stdout.Write(data)
+}
+
+// lookupUser returns the ID for a username, or 0 when none is given
+func lookupUser(username string) int64 {
+	if username == "" {
+		return 0
+	}
+	user, err := repository.GetUserByUsername(username)
+	if err != nil {
+		log.Fatalf("unknown user %q", username)
+	}
+	return user.ID
+}
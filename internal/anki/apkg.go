@@ -0,0 +1,154 @@
+// Package anki reads and writes Anki deck packages (.apkg): a zip holding a
+// collection SQLite database (schema 11) and a numbered media store.
+package anki
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// CardType mirrors Anki's cards.type column
+type CardType int
+
+const (
+	CardNew        CardType = 0
+	CardLearning   CardType = 1
+	CardReview     CardType = 2
+	CardRelearning CardType = 3
+)
+
+// ReviewKind mirrors Anki's revlog.type column
+type ReviewKind int
+
+const (
+	ReviewLearn    ReviewKind = 0
+	ReviewReview   ReviewKind = 1
+	ReviewRelearn  ReviewKind = 2
+	ReviewFiltered ReviewKind = 3
+	ReviewManual   ReviewKind = 4
+)
+
+// fieldSeparator joins field values in notes.flds
+const fieldSeparator = "\x1f"
+
+// Deck is a set of notes sharing one note type, plus the media they reference
+type Deck struct {
+	Name  string
+	Model Model
+	Notes []Note
+	Media map[string][]byte // Filename -> contents, referenced as [sound:filename]
+}
+
+// Model is an Anki note type with a single card template
+type Model struct {
+	Name   string
+	Fields []string
+	Front  string
+	Back   string
+	CSS    string
+}
+
+// Note is one Anki note and the scheduling of its first card
+type Note struct {
+	GUID       string
+	FieldNames []string // Set by Read; Write uses the deck's model fields
+	Fields     []string
+	Tags       []string
+	Schedule   *Schedule // nil for a new card
+	Reviews    []Review  // Oldest first
+}
+
+// Schedule is the scheduling state of a card
+type Schedule struct {
+	Type       CardType
+	Due        time.Time
+	Interval   int // Days
+	Factor     int // Ease in permille, 2500 = 250%
+	Reps       int
+	Lapses     int
+	Stability  float64 // FSRS memory state, zero if the card has none
+	Difficulty float64
+	Suspended  bool
+}
+
+// Review is one revlog entry
+type Review struct {
+	Time         time.Time
+	Ease         int // 1-4 (Again..Easy), 0 for manual reschedules
+	Interval     int // Days after the review, 0 for learning steps
+	LastInterval int
+	DurationMs   int
+	Kind         ReviewKind
+}
+
+// Field returns the first non-empty value among the fields named names
+// (case-insensitive), or "" if there is none
+func (n *Note) Field(names ...string) string {
+	for _, name := range names {
+		for i, fieldName := range n.FieldNames {
+			if strings.EqualFold(fieldName, name) && i < len(n.Fields) && strings.TrimSpace(n.Fields[i]) != "" {
+				return n.Fields[i]
+			}
+		}
+	}
+	return ""
+}
+
+// Sound returns the field markup that plays a media file
+func Sound(filename string) string {
+	return "[sound:" + filename + "]"
+}
+
+var (
+	soundTag  = regexp.MustCompile(`\[sound:[^\]]*\]`)
+	lineBreak = regexp.MustCompile(`(?i)<br\s*/?>|</div>|</p>`)
+	htmlTag   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// StripHTML reduces a field to plain text: markup and sound tags are
+// removed, entities decoded and whitespace collapsed
+func StripHTML(s string) string {
+	s = soundTag.ReplaceAllString(s, "")
+	s = lineBreak.ReplaceAllString(s, " ")
+	s = htmlTag.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// schema is the Anki 2.1 collection schema (version 11)
+const schema = `
+CREATE TABLE col (
+    id integer primary key, crt integer not null, mod integer not null, scm integer not null,
+    ver integer not null, dty integer not null, usn integer not null, ls integer not null,
+    conf text not null, models text not null, decks text not null, dconf text not null, tags text not null
+);
+CREATE TABLE notes (
+    id integer primary key, guid text not null, mid integer not null, mod integer not null,
+    usn integer not null, tags text not null, flds text not null, sfld integer not null,
+    csum integer not null, flags integer not null, data text not null
+);
+CREATE TABLE cards (
+    id integer primary key, nid integer not null, did integer not null, ord integer not null,
+    mod integer not null, usn integer not null, type integer not null, queue integer not null,
+    due integer not null, ivl integer not null, factor integer not null, reps integer not null,
+    lapses integer not null, left integer not null, odue integer not null, odid integer not null,
+    flags integer not null, data text not null
+);
+CREATE TABLE revlog (
+    id integer primary key, cid integer not null, usn integer not null, ease integer not null,
+    ivl integer not null, lastIvl integer not null, factor integer not null, time integer not null,
+    type integer not null
+);
+CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null);
+CREATE INDEX ix_notes_usn ON notes (usn);
+CREATE INDEX ix_cards_usn ON cards (usn);
+CREATE INDEX ix_revlog_usn ON revlog (usn);
+CREATE INDEX ix_cards_nid ON cards (nid);
+CREATE INDEX ix_cards_sched ON cards (did, queue, due);
+CREATE INDEX ix_revlog_cid ON revlog (cid);
+CREATE INDEX ix_notes_csum ON notes (csum);
+`
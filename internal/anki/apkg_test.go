@@ -0,0 +1,198 @@
+package anki
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteReadRoundTrip(t *testing.T) {
+	day := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	deck := &Deck{
+		Name: "Español::Core",
+		Model: Model{
+			Name:   "languagepapi",
+			Fields: []string{"Spanish", "English", "Audio"},
+			Front:  "{{Spanish}}",
+			Back:   "{{FrontSide}}<hr id=answer>{{English}} {{Audio}}",
+		},
+		Notes: []Note{
+			{
+				GUID:   "casa-guid",
+				Fields: []string{"la casa", "the house", Sound("casa.mp3")},
+				Tags:   []string{"noun", "island::home"},
+				Schedule: &Schedule{
+					Type: CardReview, Due: day.AddDate(0, 0, 12), Interval: 9, Factor: 2350,
+					Reps: 4, Lapses: 1, Stability: 9.4567, Difficulty: 5.1234,
+				},
+				Reviews: []Review{
+					{Time: day.Add(-72 * time.Hour), Ease: 3, Interval: 0, DurationMs: 4200, Kind: ReviewLearn},
+					{Time: day.Add(-48 * time.Hour), Ease: 1, Interval: 1, LastInterval: 3, DurationMs: 9000, Kind: ReviewRelearn},
+					{Time: day.Add(-48 * time.Hour), Ease: 4, Interval: 9, LastInterval: 1, DurationMs: 1500, Kind: ReviewReview},
+				},
+			},
+			{
+				Fields: []string{"correr", "to run"},
+				Schedule: &Schedule{
+					Type: CardLearning, Due: day.Add(90*time.Minute + 500*time.Millisecond),
+					Reps: 1, Suspended: true,
+				},
+			},
+			{
+				GUID:   "nuevo-guid",
+				Fields: []string{"nuevo", "new", ""},
+			},
+		},
+		Media: map[string][]byte{
+			"casa.mp3":  []byte("ID3 casa"),
+			"notes.txt": []byte("una nota"),
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, deck); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	notes, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(notes) != len(deck.Notes) {
+		t.Fatalf("read %d notes, want %d", len(notes), len(deck.Notes))
+	}
+
+	// Terms and translations
+	for i, want := range []struct{ spanish, english string }{
+		{"la casa", "the house"}, {"correr", "to run"}, {"nuevo", "new"},
+	} {
+		n := notes[i]
+		if got := n.Field("spanish"); got != want.spanish {
+			t.Errorf("note %d Spanish = %q, want %q", i, got, want.spanish)
+		}
+		if got := n.Field("English"); got != want.english {
+			t.Errorf("note %d English = %q, want %q", i, got, want.english)
+		}
+		if !reflect.DeepEqual(n.FieldNames, deck.Model.Fields) {
+			t.Errorf("note %d field names = %v, want %v", i, n.FieldNames, deck.Model.Fields)
+		}
+	}
+	casa := notes[0]
+	if casa.GUID != "casa-guid" || notes[2].GUID != "nuevo-guid" || notes[1].GUID == "" {
+		t.Errorf("GUIDs = %q, %q, %q", casa.GUID, notes[1].GUID, notes[2].GUID)
+	}
+	if !reflect.DeepEqual(casa.Tags, []string{"noun", "island::home"}) {
+		t.Errorf("tags = %v", casa.Tags)
+	}
+	if got := casa.Field("Audio"); got != "[sound:casa.mp3]" {
+		t.Errorf("audio field = %q", got)
+	}
+
+	// Scheduling: review cards are due by day, learning cards to the second,
+	// and the memory state keeps three decimals
+	s := casa.Schedule
+	if s == nil {
+		t.Fatal("review card read back as new")
+	}
+	if s.Type != CardReview || !s.Due.Equal(day.AddDate(0, 0, 12)) || s.Interval != 9 ||
+		s.Factor != 2350 || s.Reps != 4 || s.Lapses != 1 || s.Suspended {
+		t.Errorf("review schedule = %+v", *s)
+	}
+	if s.Stability != 9.457 || s.Difficulty != 5.123 {
+		t.Errorf("memory state = %v/%v, want 9.457/5.123", s.Stability, s.Difficulty)
+	}
+
+	s = notes[1].Schedule
+	if s == nil {
+		t.Fatal("learning card read back as new")
+	}
+	if s.Type != CardLearning || !s.Due.Equal(day.Add(90*time.Minute)) || !s.Suspended ||
+		s.Factor != defaultFactor || s.Reps != 1 || s.Stability != 0 {
+		t.Errorf("learning schedule = %+v", *s)
+	}
+	if notes[2].Schedule != nil {
+		t.Errorf("new card schedule = %+v, want nil", *notes[2].Schedule)
+	}
+
+	// Review log: learning steps come back as interval 0, and reviews in the
+	// same millisecond keep their order
+	if len(casa.Reviews) != 3 {
+		t.Fatalf("read %d reviews, want 3", len(casa.Reviews))
+	}
+	for i, want := range deck.Notes[0].Reviews {
+		got := casa.Reviews[i]
+		if got.Ease != want.Ease || got.Interval != want.Interval || got.LastInterval != want.LastInterval ||
+			got.DurationMs != want.DurationMs || got.Kind != want.Kind {
+			t.Errorf("review %d = %+v, want %+v", i, got, want)
+		}
+		if d := got.Time.Sub(want.Time); d < 0 || d > time.Millisecond {
+			t.Errorf("review %d time = %s, want %s", i, got.Time, want.Time)
+		}
+	}
+	if len(notes[1].Reviews) != 0 || len(notes[2].Reviews) != 0 {
+		t.Errorf("cards without reviews read %d and %d", len(notes[1].Reviews), len(notes[2].Reviews))
+	}
+
+	// Media: numbered files listed in the media index
+	media := readMedia(t, buf.Bytes())
+	if !reflect.DeepEqual(media, deck.Media) {
+		t.Errorf("media = %q, want %q", media, deck.Media)
+	}
+}
+
+func TestReadRejectsNonPackage(t *testing.T) {
+	data := []byte("not a zip")
+	if _, err := Read(bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Error("Read(non-zip) = nil error")
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	addZipFile(zw, "collection.anki21b", []byte{0x28, 0xb5, 0x2f, 0xfd})
+	zw.Close()
+	if _, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != ErrNewFormat {
+		t.Errorf("Read(anki21b only) = %v, want ErrNewFormat", err)
+	}
+}
+
+// readMedia returns the media files of a package by name
+func readMedia(t *testing.T, apkg []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(apkg), int64(len(apkg)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	read := func(name string) []byte {
+		f := files[name]
+		if f == nil {
+			t.Fatalf("package has no %q", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	var index map[string]string
+	if err := json.Unmarshal(read("media"), &index); err != nil {
+		t.Fatalf("media index: %v", err)
+	}
+	media := make(map[string][]byte, len(index))
+	for key, name := range index {
+		media[name] = read(key)
+	}
+	return media
+}
@@ -0,0 +1,223 @@
+package anki
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNewFormat is returned for packages exported only in the compressed
+// format introduced in Anki 2.1.50
+var ErrNewFormat = errors.New(`this deck uses the newer Anki package format; export it again with "Support older Anki versions" ticked`)
+
+// dayBasedDueLimit separates due values counted in days from Unix timestamps
+const dayBasedDueLimit = 1_000_000_000
+
+// Read extracts the notes of an .apkg with the scheduling and review history
+// of each note's first card. Media files are not extracted.
+func Read(r io.ReaderAt, size int64) ([]Note, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("not an Anki package: %w", err)
+	}
+
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	// Newer exports put a placeholder collection.anki2 next to the real,
+	// zstd-compressed collection.anki21b
+	collection := files["collection.anki21"]
+	if collection == nil {
+		if files["collection.anki21b"] != nil {
+			return nil, ErrNewFormat
+		}
+		collection = files["collection.anki2"]
+	}
+	if collection == nil {
+		return nil, errors.New("not an Anki package: no collection found")
+	}
+
+	path, err := extractTemp(collection)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(path)
+
+	return readCollection(path)
+}
+
+func extractTemp(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	tmp, err := os.CreateTemp("", "apkg-*.anki2")
+	if err != nil {
+		return "", err
+	}
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, rc); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// readCollection loads notes, their first card and its revlog
+func readCollection(path string) ([]Note, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var crt int64
+	var modelsJSON string
+	if err := db.QueryRow(`SELECT crt, models FROM col`).Scan(&crt, &modelsJSON); err != nil {
+		return nil, fmt.Errorf("reading collection: %w", err)
+	}
+	fieldNames, err := modelFieldNames(modelsJSON)
+	if err != nil {
+		return nil, err
+	}
+	// Due days count whole days from crt, so add them in UTC where a DST
+	// change can't move them by an hour
+	created := time.Unix(crt, 0).UTC()
+
+	rows, err := db.Query(`
+		SELECT n.id, n.guid, n.mid, n.flds, n.tags,
+		       c.id, c.type, c.queue, c.due, c.ivl, c.factor, c.reps, c.lapses, c.data
+		FROM notes n
+		JOIN cards c ON c.nid = n.id
+		ORDER BY n.id, c.ord, c.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("reading notes: %w", err)
+	}
+	defer rows.Close()
+
+	var notes []Note
+	var cardIDs []int64
+	lastNoteID := int64(-1)
+	for rows.Next() {
+		var n Note
+		var noteID, mid, cardID, due int64
+		var flds, tags, data string
+		var s Schedule
+		var queue int
+		if err := rows.Scan(&noteID, &n.GUID, &mid, &flds, &tags,
+			&cardID, &s.Type, &queue, &due, &s.Interval, &s.Factor, &s.Reps, &s.Lapses, &data); err != nil {
+			return nil, err
+		}
+		// Only the first card of each note is imported
+		if noteID == lastNoteID {
+			continue
+		}
+		lastNoteID = noteID
+
+		n.Fields = strings.Split(flds, fieldSeparator)
+		n.FieldNames = fieldNames[mid]
+		n.Tags = strings.Fields(tags)
+		if s.Type != CardNew {
+			s.Suspended = queue == -1
+			if due >= dayBasedDueLimit {
+				s.Due = time.Unix(due, 0)
+			} else {
+				s.Due = created.AddDate(0, 0, int(due))
+			}
+			s.Stability, s.Difficulty = memoryState(data)
+			n.Schedule = &s
+		}
+
+		notes = append(notes, n)
+		cardIDs = append(cardIDs, cardID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, cardID := range cardIDs {
+		if notes[i].Reviews, err = readRevlog(db, cardID); err != nil {
+			return nil, err
+		}
+	}
+	return notes, nil
+}
+
+// readRevlog returns a card's reviews, oldest first
+func readRevlog(db *sql.DB, cardID int64) ([]Review, error) {
+	rows, err := db.Query(`
+		SELECT id, ease, ivl, lastIvl, time, type FROM revlog WHERE cid = ? ORDER BY id
+	`, cardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reviews []Review
+	for rows.Next() {
+		var r Review
+		var id int64
+		if err := rows.Scan(&id, &r.Ease, &r.Interval, &r.LastInterval, &r.DurationMs, &r.Kind); err != nil {
+			return nil, err
+		}
+		r.Time = time.UnixMilli(id)
+		// Negative intervals are learning steps in seconds
+		r.Interval = max(r.Interval, 0)
+		r.LastInterval = max(r.LastInterval, 0)
+		reviews = append(reviews, r)
+	}
+	return reviews, rows.Err()
+}
+
+// modelFieldNames maps each note type ID to its field names in order
+func modelFieldNames(modelsJSON string) (map[int64][]string, error) {
+	var models map[string]struct {
+		ID   int64 `json:"id"`
+		Flds []struct {
+			Name string `json:"name"`
+			Ord  int    `json:"ord"`
+		} `json:"flds"`
+	}
+	if err := json.Unmarshal([]byte(modelsJSON), &models); err != nil {
+		return nil, fmt.Errorf("reading note types: %w", err)
+	}
+
+	names := make(map[int64][]string, len(models))
+	for _, m := range models {
+		fields := make([]string, len(m.Flds))
+		for _, f := range m.Flds {
+			if f.Ord >= 0 && f.Ord < len(fields) {
+				fields[f.Ord] = f.Name
+			}
+		}
+		names[m.ID] = fields
+	}
+	return names, nil
+}
+
+// memoryState reads the FSRS stability and difficulty from cards.data
+func memoryState(data string) (stability, difficulty float64) {
+	if data == "" {
+		return 0, 0
+	}
+	var state struct {
+		S float64 `json:"s"`
+		D float64 `json:"d"`
+	}
+	if json.Unmarshal([]byte(data), &state) != nil {
+		return 0, 0
+	}
+	return state.S, state.D
+}
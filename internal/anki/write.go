@@ -0,0 +1,311 @@
+package anki
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultFactor = 2500
+	defaultDeckID = 1
+	// learnStepSeconds is written as the interval of learning-step reviews,
+	// which Anki stores as negative seconds
+	learnStepSeconds = 600
+)
+
+// Write packages a deck as an .apkg. Review cards are due in days since
+// the collection's creation time, learning cards at a Unix timestamp.
+func Write(w io.Writer, deck *Deck) error {
+	if len(deck.Model.Fields) == 0 {
+		return errors.New("note type needs at least one field")
+	}
+
+	tmp, err := os.CreateTemp("", "apkg-*.anki2")
+	if err != nil {
+		return err
+	}
+	path := tmp.Name()
+	tmp.Close()
+	defer os.Remove(path)
+
+	if err := writeCollection(path, deck); err != nil {
+		return err
+	}
+	collection, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	if err := addZipFile(zw, "collection.anki2", collection); err != nil {
+		return err
+	}
+
+	// Media files are stored as "0", "1", ... with a JSON index mapping them to names
+	names := make([]string, 0, len(deck.Media))
+	for name := range deck.Media {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	index := make(map[string]string, len(names))
+	for i, name := range names {
+		key := strconv.Itoa(i)
+		index[key] = name
+		if err := addZipFile(zw, key, deck.Media[name]); err != nil {
+			return err
+		}
+	}
+	indexJSON, _ := json.Marshal(index)
+	if err := addZipFile(zw, "media", indexJSON); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func addZipFile(zw *zip.Writer, name string, data []byte) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// writeCollection creates the collection database at path
+func writeCollection(path string, deck *Deck) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	crt := collectionCreated(deck, now)
+	modelID := stableID(deck.Model.Name + "\x00" + strings.Join(deck.Model.Fields, "\x00"))
+	deckID := stableID(deck.Name)
+
+	conf, models, decks, dconf := collectionJSON(deck, modelID, deckID, now)
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO col (id, crt, mod, scm, ver, dty, usn, ls, conf, models, decks, dconf, tags)
+		VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')
+	`, crt.Unix(), now.UnixMilli(), now.UnixMilli(), conf, models, decks, dconf); err != nil {
+		return err
+	}
+
+	// Note and card IDs are millisecond timestamps in Anki; offsetting from
+	// now keeps them unique within the package
+	baseID := now.UnixMilli()
+	revlogIDs := make(map[int64]bool)
+	for i, note := range deck.Notes {
+		id := baseID + int64(i)
+		fields := make([]string, len(deck.Model.Fields))
+		copy(fields, note.Fields)
+
+		guid := note.GUID
+		if guid == "" {
+			guid = strconv.FormatInt(id, 36)
+		}
+		sortField := StripHTML(fields[0])
+		tags := ""
+		if len(note.Tags) > 0 {
+			tags = " " + strings.Join(note.Tags, " ") + " "
+		}
+
+		if _, err := tx.Exec(`
+			INSERT INTO notes (id, guid, mid, mod, usn, tags, flds, sfld, csum, flags, data)
+			VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')
+		`, id, guid, modelID, now.Unix(), tags, strings.Join(fields, fieldSeparator), sortField, checksum(sortField)); err != nil {
+			return err
+		}
+
+		cardType, queue, due, ivl, factor, reps, lapses, data := cardColumns(note.Schedule, i, crt)
+		if _, err := tx.Exec(`
+			INSERT INTO cards (id, nid, did, ord, mod, usn, type, queue, due, ivl, factor, reps, lapses, left, odue, odid, flags, data)
+			VALUES (?, ?, ?, 0, ?, -1, ?, ?, ?, ?, ?, ?, ?, 0, 0, 0, 0, ?)
+		`, id, id, deckID, now.Unix(), cardType, queue, due, ivl, factor, reps, lapses, data); err != nil {
+			return err
+		}
+
+		for _, r := range note.Reviews {
+			revID := r.Time.UnixMilli()
+			for revlogIDs[revID] {
+				revID++
+			}
+			revlogIDs[revID] = true
+
+			if _, err := tx.Exec(`
+				INSERT INTO revlog (id, cid, usn, ease, ivl, lastIvl, factor, time, type)
+				VALUES (?, ?, -1, ?, ?, ?, ?, ?, ?)
+			`, revID, id, r.Ease, revlogInterval(r.Interval), revlogInterval(r.LastInterval),
+				defaultFactor, r.DurationMs, r.Kind); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// cardColumns converts a schedule to Anki's cards columns. New cards are
+// due by position; suspended cards keep their type but sit in queue -1.
+func cardColumns(s *Schedule, position int, crt time.Time) (cardType, queue int, due int64, ivl, factor, reps, lapses int, data string) {
+	if s == nil || s.Type == CardNew {
+		return int(CardNew), 0, int64(position + 1), 0, 0, 0, 0, ""
+	}
+
+	cardType = int(s.Type)
+	switch s.Type {
+	case CardReview:
+		queue = 2
+		due = int64(math.Floor(s.Due.Sub(crt).Hours() / 24))
+	default:
+		queue = 1
+		due = s.Due.Unix()
+	}
+	if s.Suspended {
+		queue = -1
+	}
+
+	factor = s.Factor
+	if factor == 0 {
+		factor = defaultFactor
+	}
+	if s.Stability > 0 {
+		state, _ := json.Marshal(map[string]float64{
+			"s": math.Round(s.Stability*1000) / 1000,
+			"d": math.Round(s.Difficulty*1000) / 1000,
+		})
+		data = string(state)
+	}
+	return cardType, queue, due, s.Interval, factor, s.Reps, s.Lapses, data
+}
+
+// revlogInterval stores days as-is and learning steps as negative seconds
+func revlogInterval(days int) int {
+	if days > 0 {
+		return days
+	}
+	return -learnStepSeconds
+}
+
+// collectionCreated picks a creation day at or before every due date and
+// review, so day-based due values are never negative
+func collectionCreated(deck *Deck, now time.Time) time.Time {
+	earliest := now
+	for _, n := range deck.Notes {
+		if n.Schedule != nil && !n.Schedule.Due.IsZero() && n.Schedule.Due.Before(earliest) {
+			earliest = n.Schedule.Due
+		}
+		for _, r := range n.Reviews {
+			if r.Time.Before(earliest) {
+				earliest = r.Time
+			}
+		}
+	}
+	earliest = earliest.UTC()
+	return time.Date(earliest.Year(), earliest.Month(), earliest.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// collectionJSON builds the col table's conf, models, decks and dconf
+// columns for a single deck with a single note type
+func collectionJSON(deck *Deck, modelID, deckID int64, now time.Time) (conf, models, decks, dconf string) {
+	fields := make([]map[string]any, len(deck.Model.Fields))
+	for i, name := range deck.Model.Fields {
+		fields[i] = map[string]any{
+			"name": name, "ord": i, "sticky": false, "rtl": false,
+			"font": "Arial", "size": 20, "media": []string{},
+		}
+	}
+
+	confJSON, _ := json.Marshal(map[string]any{
+		"nextPos": len(deck.Notes) + 1, "estTimes": true, "activeDecks": []int64{deckID},
+		"sortType": "noteFld", "timeLim": 0, "sortBackwards": false, "addToCur": true,
+		"curDeck": deckID, "newSpread": 0, "dueCounts": true, "curModel": modelID,
+		"collapseTime": 1200,
+	})
+
+	modelsJSON, _ := json.Marshal(map[string]any{
+		strconv.FormatInt(modelID, 10): map[string]any{
+			"id": modelID, "name": deck.Model.Name, "type": 0, "mod": now.Unix(), "usn": -1,
+			"sortf": 0, "did": deckID, "flds": fields, "css": deck.Model.CSS,
+			"tmpls": []map[string]any{{
+				"name": "Card 1", "ord": 0, "qfmt": deck.Model.Front, "afmt": deck.Model.Back,
+				"did": nil, "bqfmt": "", "bafmt": "",
+			}},
+			"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+			"latexPost": "\\end{document}",
+			"req":       []any{[]any{0, "any", []int{0}}},
+			"tags":      []string{}, "vers": []int{},
+		},
+	})
+
+	deckJSON := func(id int64, name string) map[string]any {
+		return map[string]any{
+			"id": id, "name": name, "mod": now.Unix(), "usn": -1, "desc": "", "dyn": 0,
+			"conf": 1, "collapsed": false, "browserCollapsed": false,
+			"extendNew": 0, "extendRev": 0,
+			"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+		}
+	}
+	decksJSON, _ := json.Marshal(map[string]any{
+		strconv.Itoa(defaultDeckID):   deckJSON(defaultDeckID, "Default"),
+		strconv.FormatInt(deckID, 10): deckJSON(deckID, deck.Name),
+	})
+
+	dconfJSON, _ := json.Marshal(map[string]any{
+		"1": map[string]any{
+			"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true,
+			"timer": 0, "replayq": true, "dyn": false,
+			"new": map[string]any{
+				"delays": []float64{1, 10}, "ints": []int{1, 4, 0}, "initialFactor": defaultFactor,
+				"order": 1, "perDay": 20, "bury": false,
+			},
+			"rev": map[string]any{
+				"perDay": 200, "ease4": 1.3, "ivlFct": 1, "maxIvl": 36500, "hardFactor": 1.2, "bury": false,
+			},
+			"lapse": map[string]any{
+				"delays": []float64{10}, "mult": 0, "minInt": 1, "leechFails": 8, "leechAction": 1,
+			},
+		},
+	})
+
+	return string(confJSON), string(modelsJSON), string(decksJSON), string(dconfJSON)
+}
+
+// checksum is Anki's duplicate-detection hash: the first 8 hex digits of
+// the SHA-1 of the sort field
+func checksum(s string) int64 {
+	sum := sha1.Sum([]byte(s))
+	return int64(binary.BigEndian.Uint32(sum[:4]))
+}
+
+// stableID derives a millisecond-style ID from a name so re-exports reuse
+// the same note type and deck in Anki instead of creating copies
+func stableID(name string) int64 {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return 1_500_000_000_000 + int64(h.Sum32())
+}
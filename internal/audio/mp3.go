@@ -0,0 +1,132 @@
+package audio
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrNoFrames is returned when the data holds no MPEG audio frames in the requested range
+var ErrNoFrames = errors.New("no MP3 frames in range")
+
+// Bitrates in kbps for Layer III, indexed by [mpeg1?][bitrate index]
+var bitrates = [2][16]int{
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},     // MPEG-2 / 2.5
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}, // MPEG-1
+}
+
+// Sample rates in Hz, indexed by [version bits][sample rate index]
+var sampleRates = [4][3]int{
+	{11025, 12000, 8000},  // MPEG-2.5
+	{0, 0, 0},             // reserved
+	{22050, 24000, 16000}, // MPEG-2
+	{44100, 48000, 32000}, // MPEG-1
+}
+
+// frame describes one MPEG Layer III frame header
+type frame struct {
+	length     int
+	durationMs float64
+}
+
+// CutMP3 returns the MPEG Layer III frames between startMs and endMs.
+// Cutting happens on frame boundaries (about 26ms each), so no re-encoding
+// is needed; the leading ID3 tag and any Xing/Info header are dropped.
+func CutMP3(data []byte, startMs, endMs int) ([]byte, error) {
+	pos := skipID3v2(data)
+
+	var out bytes.Buffer
+	elapsed := 0.0
+	first := true
+	for pos+4 <= len(data) && elapsed < float64(endMs) {
+		f, ok := parseFrame(data[pos:])
+		if !ok {
+			// Resync on the next byte (junk between frames or a trailing tag)
+			pos++
+			continue
+		}
+		if pos+f.length > len(data) {
+			break
+		}
+
+		body := data[pos : pos+f.length]
+		if first {
+			first = false
+			if isInfoFrame(body) {
+				pos += f.length
+				continue
+			}
+		}
+		if elapsed+f.durationMs > float64(startMs) {
+			out.Write(body)
+		}
+		elapsed += f.durationMs
+		pos += f.length
+	}
+
+	if out.Len() == 0 {
+		return nil, ErrNoFrames
+	}
+	return out.Bytes(), nil
+}
+
+// skipID3v2 returns the offset just past a leading ID3v2 tag, if any
+func skipID3v2(data []byte) int {
+	if len(data) < 10 || string(data[:3]) != "ID3" {
+		return 0
+	}
+	// Tag size is a 28-bit syncsafe integer
+	size := int(data[6]&0x7f)<<21 | int(data[7]&0x7f)<<14 | int(data[8]&0x7f)<<7 | int(data[9]&0x7f)
+	end := 10 + size
+	if data[5]&0x10 != 0 {
+		end += 10 // footer
+	}
+	if end > len(data) {
+		return len(data)
+	}
+	return end
+}
+
+// parseFrame decodes a Layer III frame header at the start of b
+func parseFrame(b []byte) (frame, bool) {
+	if b[0] != 0xff || b[1]&0xe0 != 0xe0 {
+		return frame{}, false
+	}
+	version := int(b[1]>>3) & 0x03
+	layer := int(b[1]>>1) & 0x03
+	bitrateIndex := int(b[2] >> 4)
+	rateIndex := int(b[2]>>2) & 0x03
+	padding := int(b[2]>>1) & 0x01
+
+	if version == 1 || layer != 1 || rateIndex == 3 {
+		return frame{}, false
+	}
+	mpeg1 := 0
+	if version == 3 {
+		mpeg1 = 1
+	}
+	bitrate := bitrates[mpeg1][bitrateIndex] * 1000
+	sampleRate := sampleRates[version][rateIndex]
+	if bitrate == 0 || sampleRate == 0 {
+		return frame{}, false
+	}
+
+	samples := 576
+	if mpeg1 == 1 {
+		samples = 1152
+	}
+	return frame{
+		length:     samples/8*bitrate/sampleRate + padding,
+		durationMs: float64(samples) * 1000 / float64(sampleRate),
+	}, true
+}
+
+// isInfoFrame reports whether a frame carries a Xing/Info/VBRI header
+// describing the whole file rather than audio
+func isInfoFrame(body []byte) bool {
+	n := len(body)
+	if n > 64 {
+		n = 64
+	}
+	head := body[:n]
+	return bytes.Contains(head, []byte("Xing")) || bytes.Contains(head, []byte("Info")) || bytes.Contains(head, []byte("VBRI"))
+}
@@ -0,0 +1,235 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"languagepapi/internal/db"
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+)
+
+// Service generates memory bridges, example sentences and hints with an LLM
+type Service struct {
+	llm LLM
+}
+
+// BridgeResponse represents the JSON response from the LLM
+type BridgeResponse struct {
+	Hindi   *string `json:"hindi"`
+	Dutch   *string `json:"dutch"`
+	English *string `json:"english"`
+}
+
+// NewService creates a bridge service using the LLM configured in the
+// environment
+func NewService(ctx context.Context) (*Service, error) {
+	llm, err := NewLLM(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewServiceWithLLM(llm), nil
+}
+
+// NewServiceWithLLM creates a bridge service using llm
+func NewServiceWithLLM(llm LLM) *Service {
+	return &Service{llm: llm}
+}
+
+// GenerateContent sends a prompt to the LLM and returns the text response
+func (s *Service) GenerateContent(ctx context.Context, prompt string) (string, error) {
+	return s.llm.GenerateContent(ctx, prompt)
+}
+
+// GenerateBridges generates bridges for a single word
+func (s *Service) GenerateBridges(ctx context.Context, term, translation string) (*BridgeResponse, error) {
+	prompt := fmt.Sprintf(`You are helping a polyglot learn Spanish. They speak English, Dutch, and Hindi.
+
+Given the Spanish word "%s" meaning "%s":
+
+Generate memory bridges to help remember this word:
+
+1. Hindi Phonetic Bridge: Find phonetic similarity to Hindi words/sounds (use Devanagari if helpful). Only if genuinely useful.
+
+2. Dutch Syntax Bridge: Identify grammatical patterns similar to Dutch (V2 rule, word order, gender, cognates). Only if genuinely useful.
+
+3. English Cognate: Find English words with same Latin/Romance root or similar spelling/sound. Only if genuinely useful.
+
+Respond ONLY with valid JSON in this exact format (use null for unhelpful bridges):
+{"hindi": "...", "dutch": "...", "english": "..."}
+
+Be concise - max %d characters per bridge. Focus on the most memorable connection.`, term, translation, MaxBridgeLength)
+
+	return Structured[BridgeResponse]{
+		Task:     "bridges",
+		Prompt:   prompt,
+		Schema:   bridgeSchema,
+		Validate: validateBridges,
+	}.Generate(ctx, s.llm)
+}
+
+// MaxBridgeLength is the longest bridge in characters the LLM may produce
+const MaxBridgeLength = 50
+
+var bridgeSchema = ObjectSchema(map[string]Schema{
+	"hindi":   NullableStringSchema(MaxBridgeLength),
+	"dutch":   NullableStringSchema(MaxBridgeLength),
+	"english": NullableStringSchema(MaxBridgeLength),
+})
+
+func validateBridges(b *BridgeResponse) error {
+	bridges := []struct {
+		name  string
+		value *string
+	}{{"hindi", b.Hindi}, {"dutch", b.Dutch}, {"english", b.English}}
+	for _, bridge := range bridges {
+		if bridge.value == nil {
+			continue
+		}
+		if n := utf8.RuneCountInString(*bridge.value); n > MaxBridgeLength {
+			return fmt.Errorf("%s bridge is %d characters, max %d", bridge.name, n, MaxBridgeLength)
+		}
+	}
+	return nil
+}
+
+// GenerateAndSaveBridges generates bridges for a card and saves them to the
+// database. With replace, the card's existing bridges are deleted once the
+// new ones have been generated.
+func (s *Service) GenerateAndSaveBridges(ctx context.Context, cardID int64, replace bool) error {
+	// Get the card
+	card, err := repository.GetCard(cardID)
+	if err != nil {
+		return fmt.Errorf("failed to get card: %w", err)
+	}
+
+	// Generate bridges, bypassing the cache when the user asked for new ones
+	if replace {
+		ctx = WithFreshResponse(ctx)
+	}
+	bridges, err := s.GenerateBridges(ctx, card.Term, card.Translation)
+	if err != nil {
+		return fmt.Errorf("failed to generate bridges: %w", err)
+	}
+
+	if replace {
+		if err := repository.DeleteBridgesForCard(cardID); err != nil {
+			return fmt.Errorf("failed to delete bridges: %w", err)
+		}
+	}
+
+	// Save bridges to database
+	if bridges.Hindi != nil && *bridges.Hindi != "" {
+		_, err = db.DB.Exec(`
+			INSERT INTO bridges (card_id, bridge_type, bridge_content) VALUES (?, ?, ?)
+		`, cardID, models.BridgeHindiPhonetic, *bridges.Hindi)
+		if err != nil {
+			log.Printf("Failed to save Hindi bridge: %v", err)
+		}
+	}
+
+	if bridges.Dutch != nil && *bridges.Dutch != "" {
+		_, err = db.DB.Exec(`
+			INSERT INTO bridges (card_id, bridge_type, bridge_content) VALUES (?, ?, ?)
+		`, cardID, models.BridgeDutchSyntax, *bridges.Dutch)
+		if err != nil {
+			log.Printf("Failed to save Dutch bridge: %v", err)
+		}
+	}
+
+	if bridges.English != nil && *bridges.English != "" {
+		_, err = db.DB.Exec(`
+			INSERT INTO bridges (card_id, bridge_type, bridge_content) VALUES (?, ?, ?)
+		`, cardID, models.BridgeEnglishCognate, *bridges.English)
+		if err != nil {
+			log.Printf("Failed to save English bridge: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// GenerateExampleSentence generates an example sentence for a word
+func (s *Service) GenerateExampleSentence(ctx context.Context, term, translation string) (string, error) {
+	prompt := fmt.Sprintf(`Generate a simple, natural Spanish sentence using the word "%s" (meaning: %s).
+
+Rules:
+- Use everyday, conversational Spanish
+- Keep it under 15 words
+- Include the word in a natural context
+- Return ONLY the Spanish sentence, nothing else`, term, translation)
+
+	return s.llm.GenerateContent(WithFeature(ctx, "example"), prompt)
+}
+
+// GenerateHint generates a hint for a card when the user gets it wrong
+func (s *Service) GenerateHint(ctx context.Context, term, translation string, bridges []models.Bridge) (string, error) {
+	bridgeInfo := ""
+	for _, b := range bridges {
+		bridgeInfo += fmt.Sprintf("- %s: %s\n", b.BridgeType, b.BridgeContent)
+	}
+
+	prompt := fmt.Sprintf(`The user is trying to remember the Spanish word "%s" meaning "%s".
+
+Existing memory bridges:
+%s
+
+Generate a SHORT (1-2 sentence) hint to help them remember. Be creative and memorable.
+Return ONLY the hint text.`, term, translation, bridgeInfo)
+
+	return s.llm.GenerateContent(WithFeature(ctx, "hint"), prompt)
+}
+
+// GenerateCards generates vocabulary cards for a topic
+func (s *Service) GenerateCards(ctx context.Context, topic string, count int) ([]CardSuggestion, error) {
+	prompt := fmt.Sprintf(`Generate %d Spanish vocabulary words for the topic: "%s"
+
+Return ONLY valid JSON in this format:
+{"cards": [{"term": "Spanish word", "translation": "English translation", "example": "Example sentence in Spanish"}]}
+
+Focus on practical, commonly used words.`, count, topic)
+
+	result, err := Structured[cardSuggestions]{
+		Task:   "cards",
+		Prompt: prompt,
+		Schema: ObjectSchema(map[string]Schema{
+			"cards": ArraySchema(cardSuggestionSchema, count, count),
+		}),
+		Validate: func(c *cardSuggestions) error {
+			if len(c.Cards) != count {
+				return fmt.Errorf("got %d cards, want %d", len(c.Cards), count)
+			}
+			for i, card := range c.Cards {
+				if strings.TrimSpace(card.Term) == "" || strings.TrimSpace(card.Translation) == "" {
+					return fmt.Errorf("card %d has an empty term or translation", i+1)
+				}
+			}
+			return nil
+		},
+	}.Generate(ctx, s.llm)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Cards, nil
+}
+
+type cardSuggestions struct {
+	Cards []CardSuggestion `json:"cards"`
+}
+
+var cardSuggestionSchema = ObjectSchema(map[string]Schema{
+	"term":        StringSchema(0),
+	"translation": StringSchema(0),
+	"example":     StringSchema(0),
+})
+
+// CardSuggestion represents an AI-generated card suggestion
+type CardSuggestion struct {
+	Term        string `json:"term"`
+	Translation string `json:"translation"`
+	Example     string `json:"example"`
+}
@@ -0,0 +1,257 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// FakeReply is what Fake answers to prompts that don't ask for JSON
+const FakeReply = "This is a fake response."
+
+// Fake is a deterministic LLM for tests and offline use. It never makes
+// network calls: it answers with the canned response whose key occurs in
+// the prompt. Otherwise a request with a schema gets the prompt's example
+// response if that fits the schema, or the smallest response that does
+// (see GenerateJSON); other prompts get an empty JSON array or object when
+// they ask for one, or FakeReply.
+type Fake struct {
+	// Responses maps prompt substrings to replies. Keys are tried in
+	// sorted order, so the reply for a prompt never varies.
+	Responses map[string]string
+
+	mu      sync.Mutex
+	prompts []string
+}
+
+// Name returns the provider name
+func (f *Fake) Name() string { return ProviderFake }
+
+// Model returns "fake"
+func (f *Fake) Model() string { return "fake" }
+
+// Complete answers like GenerateJSON, or GenerateContent for requests
+// without a schema, counting roughly four characters per token
+func (f *Fake) Complete(ctx context.Context, req Request) (*Response, error) {
+	var text string
+	var err error
+	if req.Schema != nil {
+		text, err = f.GenerateJSON(ctx, req.Prompt, req.Schema)
+	} else {
+		text, err = f.GenerateContent(ctx, req.Prompt)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Response{
+		Text:         text,
+		Model:        f.Model(),
+		InputTokens:  len(req.Prompt)/4 + 1,
+		OutputTokens: len(text)/4 + 1,
+	}, nil
+}
+
+// GenerateContent returns the canned reply for prompt
+func (f *Fake) GenerateContent(ctx context.Context, prompt string) (string, error) {
+	if err := f.receive(ctx, prompt); err != nil {
+		return "", err
+	}
+	if reply, ok := f.canned(prompt); ok {
+		return reply, nil
+	}
+
+	switch {
+	case strings.Contains(prompt, "JSON array"):
+		return "[]", nil
+	case strings.Contains(prompt, "JSON"):
+		return "{}", nil
+	default:
+		return FakeReply, nil
+	}
+}
+
+// GenerateJSON returns the canned reply for prompt, or else a response
+// that fits schema: the first example in the prompt that does, leaving out
+// templates with "..." placeholders, or one built from the schema with the
+// fewest items and "fake" strings
+func (f *Fake) GenerateJSON(ctx context.Context, prompt string, schema Schema) (string, error) {
+	if err := f.receive(ctx, prompt); err != nil {
+		return "", err
+	}
+	if reply, ok := f.canned(prompt); ok {
+		return reply, nil
+	}
+	if example, ok := promptExample(prompt, schema); ok {
+		return example, nil
+	}
+	data, err := json.Marshal(fakeValue(schema, "value"))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// receive records a prompt unless ctx is done
+func (f *Fake) receive(ctx context.Context, prompt string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.prompts = append(f.prompts, prompt)
+	f.mu.Unlock()
+	return nil
+}
+
+// canned returns the reply of the first key in prompt
+func (f *Fake) canned(prompt string) (string, bool) {
+	keys := make([]string, 0, len(f.Responses))
+	for k := range f.Responses {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if strings.Contains(prompt, k) {
+			return f.Responses[k], true
+		}
+	}
+	return "", false
+}
+
+// promptExample finds the first JSON value in prompt that fits schema
+func promptExample(prompt string, schema Schema) (string, bool) {
+	for i, r := range prompt {
+		if r != '{' && r != '[' {
+			continue
+		}
+		var raw json.RawMessage
+		if err := json.NewDecoder(strings.NewReader(prompt[i:])).Decode(&raw); err != nil {
+			continue
+		}
+		if strings.Contains(string(raw), "...") {
+			continue
+		}
+		var v any
+		if json.Unmarshal(raw, &v) == nil && fits(v, schema) {
+			return string(raw), true
+		}
+	}
+	return "", false
+}
+
+// fits reports whether a decoded JSON value matches the subset of JSON
+// Schema built by ObjectSchema and the other builders
+func fits(v any, schema Schema) bool {
+	for _, t := range schemaTypes(schema) {
+		switch t {
+		case "null":
+			if v == nil {
+				return true
+			}
+		case "string":
+			if s, ok := v.(string); ok {
+				max, _ := schema["maxLength"].(int)
+				return max == 0 || utf8.RuneCountInString(s) <= max
+			}
+		case "integer":
+			if n, ok := v.(float64); ok && n == math.Trunc(n) {
+				lo, _ := schema["minimum"].(int)
+				hi, _ := schema["maximum"].(int)
+				return n >= float64(lo) && n <= float64(hi)
+			}
+		case "array":
+			items, ok := v.([]any)
+			if !ok {
+				continue
+			}
+			lo, _ := schema["minItems"].(int)
+			hi, _ := schema["maxItems"].(int)
+			if len(items) < lo || (hi > 0 && len(items) > hi) {
+				return false
+			}
+			itemSchema, _ := schema["items"].(Schema)
+			for _, item := range items {
+				if !fits(item, itemSchema) {
+					return false
+				}
+			}
+			return true
+		case "object":
+			obj, ok := v.(map[string]any)
+			if !ok {
+				continue
+			}
+			props, _ := schema["properties"].(map[string]any)
+			if len(obj) != len(props) {
+				return false // ObjectSchema requires every property and no others
+			}
+			for name, prop := range props {
+				value, ok := obj[name]
+				if !ok || !fits(value, prop.(Schema)) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// fakeValue builds the smallest value that fits schema. Strings are "fake"
+// and the name of their property, numbered within arrays so the items
+// differ.
+func fakeValue(schema Schema, name string) any {
+	types := schemaTypes(schema)
+	if slices.Contains(types, "null") {
+		return nil
+	}
+	switch types[0] {
+	case "object":
+		props, _ := schema["properties"].(map[string]any)
+		obj := make(map[string]any, len(props))
+		for prop, s := range props {
+			obj[prop] = fakeValue(s.(Schema), prop)
+		}
+		return obj
+	case "array":
+		n, _ := schema["minItems"].(int)
+		itemSchema, _ := schema["items"].(Schema)
+		items := make([]any, n)
+		for i := range items {
+			items[i] = fakeValue(itemSchema, fmt.Sprintf("%s %d", name, i+1))
+		}
+		return items
+	case "integer":
+		n, _ := schema["minimum"].(int)
+		return n
+	default:
+		s := "fake " + strings.ReplaceAll(name, "_", " ")
+		if max, _ := schema["maxLength"].(int); max > 0 && utf8.RuneCountInString(s) > max {
+			s = string([]rune(s)[:max])
+		}
+		return s
+	}
+}
+
+// schemaTypes returns the type or types a schema allows
+func schemaTypes(schema Schema) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	}
+	return []string{"string"}
+}
+
+// Prompts returns the prompts received so far
+func (f *Fake) Prompts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.prompts...)
+}
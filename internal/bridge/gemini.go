@@ -2,280 +2,85 @@ package bridge
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"log"
 	"strings"
-	"time"
 
 	"google.golang.org/genai"
-
-	"languagepapi/internal/db"
-	"languagepapi/internal/models"
-	"languagepapi/internal/repository"
 )
 
-// GeminiService handles bridge generation using Gemini API
-type GeminiService struct {
+const defaultGeminiModel = "gemini-2.5-flash"
+
+// Gemini generates content with the Gemini API
+type Gemini struct {
 	client *genai.Client
 	model  string
 }
 
-// BridgeResponse represents the JSON response from Gemini
-type BridgeResponse struct {
-	Hindi   *string `json:"hindi"`
-	Dutch   *string `json:"dutch"`
-	English *string `json:"english"`
-}
-
-// NewGeminiService creates a new Gemini service
-func NewGeminiService(ctx context.Context) (*GeminiService, error) {
-	// The client gets the API key from the environment variable GEMINI_API_KEY
-	client, err := genai.NewClient(ctx, nil)
+// NewGemini creates a Gemini client. An empty model uses gemini-2.5-flash
+// and an empty API key falls back to GEMINI_API_KEY.
+func NewGemini(ctx context.Context, model, apiKey string) (*Gemini, error) {
+	var cfg *genai.ClientConfig
+	if apiKey != "" {
+		cfg = &genai.ClientConfig{APIKey: apiKey, Backend: genai.BackendGeminiAPI}
+	}
+	client, err := genai.NewClient(ctx, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
 	}
 
-	return &GeminiService{
-		client: client,
-		model:  "gemini-2.5-flash",
-	}, nil
-}
-
-// Close closes the Gemini client
-func (s *GeminiService) Close() {
-	// New SDK doesn't require explicit close
-}
-
-// GenerateContent sends a prompt to Gemini and returns the text response
-func (s *GeminiService) GenerateContent(ctx context.Context, prompt string) (string, error) {
-	result, err := s.client.Models.GenerateContent(ctx, s.model, genai.Text(prompt), nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate content: %w", err)
+	if model == "" {
+		model = defaultGeminiModel
 	}
-
-	text := result.Text()
-	return strings.TrimSpace(text), nil
+	return &Gemini{client: client, model: model}, nil
 }
 
-// GenerateBridges generates bridges for a single word
-func (s *GeminiService) GenerateBridges(ctx context.Context, term, translation string) (*BridgeResponse, error) {
-	prompt := fmt.Sprintf(`You are helping a polyglot learn Spanish. They speak English, Dutch, and Hindi.
-
-Given the Spanish word "%s" meaning "%s":
-
-Generate memory bridges to help remember this word:
-
-1. Hindi Phonetic Bridge: Find phonetic similarity to Hindi words/sounds (use Devanagari if helpful). Only if genuinely useful.
-
-2. Dutch Syntax Bridge: Identify grammatical patterns similar to Dutch (V2 rule, word order, gender, cognates). Only if genuinely useful.
+// Name returns the provider name
+func (s *Gemini) Name() string { return ProviderGemini }
 
-3. English Cognate: Find English words with same Latin/Romance root or similar spelling/sound. Only if genuinely useful.
+// Model returns the model requests are sent to
+func (s *Gemini) Model() string { return s.model }
 
-Respond ONLY with valid JSON in this exact format (use null for unhelpful bridges):
-{"hindi": "...", "dutch": "...", "english": "..."}
-
-Be concise - max 50 characters per bridge. Focus on the most memorable connection.`, term, translation)
-
-	result, err := s.client.Models.GenerateContent(ctx, s.model, genai.Text(prompt), nil)
+// GenerateContent sends a prompt to Gemini and returns the text response
+func (s *Gemini) GenerateContent(ctx context.Context, prompt string) (string, error) {
+	resp, err := s.Complete(ctx, Request{Prompt: prompt})
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate content: %w", err)
+		return "", err
 	}
-
-	text := result.Text()
-
-	// Clean up the response (remove markdown code blocks if present)
-	text = strings.TrimSpace(text)
-	text = strings.TrimPrefix(text, "```json")
-	text = strings.TrimPrefix(text, "```")
-	text = strings.TrimSuffix(text, "```")
-	text = strings.TrimSpace(text)
-
-	// Parse JSON
-	var bridges BridgeResponse
-	if err := json.Unmarshal([]byte(text), &bridges); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w (response: %s)", err, text)
-	}
-
-	return &bridges, nil
+	return resp.Text, nil
 }
 
-// GenerateAndSaveBridges generates bridges for a card and saves them to the database
-func (s *GeminiService) GenerateAndSaveBridges(ctx context.Context, cardID int64) error {
-	// Get the card
-	card, err := repository.GetCard(cardID)
-	if err != nil {
-		return fmt.Errorf("failed to get card: %w", err)
-	}
-
-	// Generate bridges
-	bridges, err := s.GenerateBridges(ctx, card.Term, card.Translation)
+// GenerateJSON sends a prompt to Gemini with the response constrained to
+// schema
+func (s *Gemini) GenerateJSON(ctx context.Context, prompt string, schema Schema) (string, error) {
+	resp, err := s.Complete(ctx, Request{Prompt: prompt, Schema: schema})
 	if err != nil {
-		return fmt.Errorf("failed to generate bridges: %w", err)
-	}
-
-	// Save bridges to database
-	if bridges.Hindi != nil && *bridges.Hindi != "" {
-		_, err = db.DB.Exec(`
-			INSERT INTO bridges (card_id, bridge_type, bridge_content) VALUES (?, ?, ?)
-		`, cardID, models.BridgeHindiPhonetic, *bridges.Hindi)
-		if err != nil {
-			log.Printf("Failed to save Hindi bridge: %v", err)
-		}
+		return "", err
 	}
-
-	if bridges.Dutch != nil && *bridges.Dutch != "" {
-		_, err = db.DB.Exec(`
-			INSERT INTO bridges (card_id, bridge_type, bridge_content) VALUES (?, ?, ?)
-		`, cardID, models.BridgeDutchSyntax, *bridges.Dutch)
-		if err != nil {
-			log.Printf("Failed to save Dutch bridge: %v", err)
-		}
-	}
-
-	if bridges.English != nil && *bridges.English != "" {
-		_, err = db.DB.Exec(`
-			INSERT INTO bridges (card_id, bridge_type, bridge_content) VALUES (?, ?, ?)
-		`, cardID, models.BridgeEnglishCognate, *bridges.English)
-		if err != nil {
-			log.Printf("Failed to save English bridge: %v", err)
-		}
-	}
-
-	return nil
+	return resp.Text, nil
 }
 
-// GenerateExampleSentence generates an example sentence for a word
-func (s *GeminiService) GenerateExampleSentence(ctx context.Context, term, translation string) (string, error) {
-	prompt := fmt.Sprintf(`Generate a simple, natural Spanish sentence using the word "%s" (meaning: %s).
-
-Rules:
-- Use everyday, conversational Spanish
-- Keep it under 15 words
-- Include the word in a natural context
-- Return ONLY the Spanish sentence, nothing else`, term, translation)
-
-	result, err := s.client.Models.GenerateContent(ctx, s.model, genai.Text(prompt), nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate content: %w", err)
-	}
-
-	return strings.TrimSpace(result.Text()), nil
-}
-
-// GenerateHint generates a hint for a card when the user gets it wrong
-func (s *GeminiService) GenerateHint(ctx context.Context, term, translation string, bridges []models.Bridge) (string, error) {
-	bridgeInfo := ""
-	for _, b := range bridges {
-		bridgeInfo += fmt.Sprintf("- %s: %s\n", b.BridgeType, b.BridgeContent)
-	}
-
-	prompt := fmt.Sprintf(`The user is trying to remember the Spanish word "%s" meaning "%s".
-
-Existing memory bridges:
-%s
-
-Generate a SHORT (1-2 sentence) hint to help them remember. Be creative and memorable.
-Return ONLY the hint text.`, term, translation, bridgeInfo)
-
-	result, err := s.client.Models.GenerateContent(ctx, s.model, genai.Text(prompt), nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate content: %w", err)
+// Complete sends a request to Gemini and reports its token usage.
+// Thinking tokens are billed as output.
+func (s *Gemini) Complete(ctx context.Context, req Request) (*Response, error) {
+	var config *genai.GenerateContentConfig
+	if req.Schema != nil {
+		config = &genai.GenerateContentConfig{
+			ResponseMIMEType:   "application/json",
+			ResponseJsonSchema: req.Schema,
+		}
 	}
-
-	return strings.TrimSpace(result.Text()), nil
-}
-
-// GenerateCards generates vocabulary cards for a topic
-func (s *GeminiService) GenerateCards(ctx context.Context, topic string, count int) ([]CardSuggestion, error) {
-	prompt := fmt.Sprintf(`Generate %d Spanish vocabulary words for the topic: "%s"
-
-Return ONLY valid JSON array in this format:
-[{"term": "Spanish word", "translation": "English translation", "example": "Example sentence in Spanish"}]
-
-Focus on practical, commonly used words.`, count, topic)
-
-	result, err := s.client.Models.GenerateContent(ctx, s.model, genai.Text(prompt), nil)
+	result, err := s.client.Models.GenerateContent(ctx, s.model, genai.Text(req.Prompt), config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate content: %w", err)
 	}
 
-	text := result.Text()
-
-	// Clean up the response
-	text = strings.TrimSpace(text)
-	text = strings.TrimPrefix(text, "```json")
-	text = strings.TrimPrefix(text, "```")
-	text = strings.TrimSuffix(text, "```")
-	text = strings.TrimSpace(text)
-
-	var cards []CardSuggestion
-	if err := json.Unmarshal([]byte(text), &cards); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	return cards, nil
-}
-
-// CardSuggestion represents an AI-generated card suggestion
-type CardSuggestion struct {
-	Term        string `json:"term"`
-	Translation string `json:"translation"`
-	Example     string `json:"example"`
-}
-
-// GenerateBridgesForBatch generates bridges for multiple cards with rate limiting
-func GenerateBridgesForBatch(ctx context.Context, limit int) error {
-	service, err := NewGeminiService(ctx)
-	if err != nil {
-		return err
-	}
-	defer service.Close()
-
-	// Get cards without bridges
-	rows, err := db.DB.Query(`
-		SELECT c.id, c.term, c.translation
-		FROM cards c
-		LEFT JOIN bridges b ON b.card_id = c.id
-		WHERE b.id IS NULL
-		ORDER BY c.frequency_rank ASC
-		LIMIT ?
-	`, limit)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
-	type cardInfo struct {
-		ID          int64
-		Term        string
-		Translation string
+	resp := &Response{Text: strings.TrimSpace(result.Text()), Model: s.model}
+	if result.ModelVersion != "" {
+		resp.Model = result.ModelVersion
 	}
-
-	var cards []cardInfo
-	for rows.Next() {
-		var c cardInfo
-		if err := rows.Scan(&c.ID, &c.Term, &c.Translation); err != nil {
-			continue
-		}
-		cards = append(cards, c)
-	}
-
-	log.Printf("Generating bridges for %d cards...", len(cards))
-
-	for i, card := range cards {
-		log.Printf("[%d/%d] %s - %s", i+1, len(cards), card.Term, card.Translation)
-
-		err := service.GenerateAndSaveBridges(ctx, card.ID)
-		if err != nil {
-			log.Printf("  Error: %v", err)
-		} else {
-			log.Printf("  Done")
-		}
-
-		// Rate limiting: 15 requests per minute for free tier
-		time.Sleep(4 * time.Second)
+	if u := result.UsageMetadata; u != nil {
+		resp.InputTokens = int(u.PromptTokenCount)
+		resp.OutputTokens = int(u.CandidatesTokenCount + u.ThoughtsTokenCount)
 	}
-
-	return nil
+	return resp, nil
 }
@@ -0,0 +1,121 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// LLM generates text from a prompt
+type LLM interface {
+	GenerateContent(ctx context.Context, prompt string) (string, error)
+}
+
+// Request is one prompt sent to a provider
+type Request struct {
+	Prompt string
+	Schema Schema // Constrains the response to JSON matching it if set
+}
+
+// Response is a provider's reply with the tokens it was billed for
+type Response struct {
+	Text         string
+	Model        string
+	InputTokens  int
+	OutputTokens int
+}
+
+// Provider is a model API. NewLLM wraps it in the cache, the usage ledger
+// and the monthly budget.
+type Provider interface {
+	LLM
+	Name() string
+	Model() string
+	Complete(ctx context.Context, req Request) (*Response, error)
+}
+
+// Provider names accepted in Config.Provider
+const (
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai"
+	ProviderFake   = "fake"
+)
+
+// Config selects the LLM provider and model
+type Config struct {
+	Provider string // gemini (default), openai or fake
+	Model    string // Provider default if empty
+	BaseURL  string // OpenAI-compatible server, e.g. http://localhost:11434/v1 for Ollama
+	APIKey   string // Gemini falls back to GEMINI_API_KEY, OpenAI to OPENAI_API_KEY
+}
+
+// ConfigFromEnv reads LLM_PROVIDER, LLM_MODEL, LLM_BASE_URL and LLM_API_KEY
+func ConfigFromEnv() Config {
+	return Config{
+		Provider: strings.ToLower(strings.TrimSpace(os.Getenv("LLM_PROVIDER"))),
+		Model:    strings.TrimSpace(os.Getenv("LLM_MODEL")),
+		BaseURL:  strings.TrimSpace(os.Getenv("LLM_BASE_URL")),
+		APIKey:   strings.TrimSpace(os.Getenv("LLM_API_KEY")),
+	}
+}
+
+// providers holds one client per configuration, so NewLLM doesn't build a
+// new client for every call
+var (
+	providersMu sync.Mutex
+	providers   = make(map[Config]Provider)
+)
+
+// NewLLM returns the LLM configured in the environment, metered by the
+// response cache, usage ledger and monthly budget (see Metered). The
+// environment is read on every call, so callers create one per use after
+// .env is loaded.
+func NewLLM(ctx context.Context) (LLM, error) {
+	cfg := ConfigFromEnv()
+
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	provider, ok := providers[cfg]
+	if !ok {
+		var err error
+		if provider, err = newProvider(ctx, cfg); err != nil {
+			return nil, err
+		}
+		providers[cfg] = provider
+	}
+	return NewMetered(provider), nil
+}
+
+// NewLLMFromConfig creates the provider described by cfg, without the
+// cache and usage tracking NewLLM adds
+func NewLLMFromConfig(ctx context.Context, cfg Config) (LLM, error) {
+	return newProvider(ctx, cfg)
+}
+
+func newProvider(ctx context.Context, cfg Config) (Provider, error) {
+	switch cfg.Provider {
+	case "", ProviderGemini:
+		gemini, err := NewGemini(ctx, cfg.Model, cfg.APIKey)
+		if err != nil {
+			return nil, err
+		}
+		return gemini, nil
+	case ProviderOpenAI:
+		return NewOpenAI(cfg.BaseURL, cfg.Model, cfg.APIKey), nil
+	case ProviderFake:
+		return &Fake{}, nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q", cfg.Provider)
+	}
+}
+
+// cleanJSON strips the markdown code fence models like to wrap JSON in
+func cleanJSON(text string) string {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+	return strings.TrimSpace(text)
+}
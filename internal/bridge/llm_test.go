@@ -0,0 +1,96 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAIGenerateContent(t *testing.T) {
+	var got chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("path = %q, want /v1/chat/completions", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("Authorization = %q, want Bearer secret", auth)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"  hola  "}}]}`))
+	}))
+	defer server.Close()
+
+	llm := NewOpenAI(server.URL+"/v1/", "llama3", "secret")
+	text, err := llm.GenerateContent(context.Background(), "say hi")
+	if err != nil {
+		t.Fatalf("GenerateContent() error = %v", err)
+	}
+	if text != "hola" {
+		t.Errorf("GenerateContent() = %q, want %q", text, "hola")
+	}
+	if got.Model != "llama3" || len(got.Messages) != 1 || got.Messages[0].Content != "say hi" {
+		t.Errorf("request = %+v, want model llama3 with one user message", got)
+	}
+}
+
+func TestOpenAIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"message":"model not found"}}`))
+	}))
+	defer server.Close()
+
+	_, err := NewOpenAI(server.URL, "missing", "").GenerateContent(context.Background(), "hi")
+	if err == nil || err.Error() != "failed to generate content: model not found" {
+		t.Errorf("GenerateContent() error = %v, want model not found", err)
+	}
+}
+
+func TestFake(t *testing.T) {
+	fake := &Fake{Responses: map[string]string{
+		"hablar": `{"hindi": null, "dutch": null, "english": "fable"}`,
+	}}
+	service := NewServiceWithLLM(fake)
+
+	bridges, err := service.GenerateBridges(context.Background(), "hablar", "to speak")
+	if err != nil {
+		t.Fatalf("GenerateBridges() error = %v", err)
+	}
+	if bridges.English == nil || *bridges.English != "fable" || bridges.Hindi != nil {
+		t.Errorf("GenerateBridges() = %+v, want only an English bridge", bridges)
+	}
+
+	tests := []struct {
+		prompt string
+		want   string
+	}{
+		{"Return ONLY a JSON array of strings", "[]"},
+		{"Return ONLY valid JSON", "{}"},
+		{"Return ONLY the hint text.", FakeReply},
+	}
+	for _, tt := range tests {
+		got, err := fake.GenerateContent(context.Background(), tt.prompt)
+		if err != nil || got != tt.want {
+			t.Errorf("GenerateContent(%q) = %q, %v; want %q", tt.prompt, got, err, tt.want)
+		}
+	}
+
+	if n := len(fake.Prompts()); n != 4 {
+		t.Errorf("len(Prompts()) = %d, want 4", n)
+	}
+}
+
+func TestNewLLMFromConfig(t *testing.T) {
+	if _, err := NewLLMFromConfig(context.Background(), Config{Provider: "nope"}); err == nil {
+		t.Error("NewLLMFromConfig(nope) error = nil, want unknown provider")
+	}
+	llm, err := NewLLMFromConfig(context.Background(), Config{Provider: ProviderFake})
+	if err != nil {
+		t.Fatalf("NewLLMFromConfig(fake) error = %v", err)
+	}
+	if _, ok := llm.(*Fake); !ok {
+		t.Errorf("NewLLMFromConfig(fake) = %T, want *Fake", llm)
+	}
+}
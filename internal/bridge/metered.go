@@ -0,0 +1,157 @@
+package bridge
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+
+	"languagepapi/internal/models"
+)
+
+type featureKey struct{}
+
+// WithFeature tags the AI calls made with ctx for the usage ledger, e.g.
+// "bridges" or "lyrics_translation"
+func WithFeature(ctx context.Context, feature string) context.Context {
+	return context.WithValue(ctx, featureKey{}, feature)
+}
+
+// FeatureFrom returns the tag set by WithFeature, or "other"
+func FeatureFrom(ctx context.Context) string {
+	if feature, ok := ctx.Value(featureKey{}).(string); ok && feature != "" {
+		return feature
+	}
+	return "other"
+}
+
+type freshKey struct{}
+
+// WithFreshResponse skips the cache lookup for calls made with ctx, for when
+// the user asks to regenerate something. The new response replaces the
+// cached one.
+func WithFreshResponse(ctx context.Context) context.Context {
+	return context.WithValue(ctx, freshKey{}, true)
+}
+
+// UsageStore keeps what Metered needs between calls: cached responses,
+// the usage ledger and this month's spend
+type UsageStore interface {
+	// CachedResponse returns the response cached under key; ok is false
+	// when there is none
+	CachedResponse(key string) (text string, ok bool, err error)
+	SaveCachedResponse(key, feature, model, text string) error
+	DeleteCachedResponse(key string) error
+	SaveUsage(u *models.AIUsage) error
+	CostThisMonth() (float64, error)
+}
+
+var usageStore UsageStore
+
+// SetUsageStore gives Metered and CheckBudget their storage. The service
+// layer sets it once the database is open.
+func SetUsageStore(s UsageStore) {
+	usageStore = s
+}
+
+// Metered wraps a provider with a content-addressed response cache, the
+// usage ledger and the monthly budget. Without a usage store it passes
+// calls straight through.
+type Metered struct {
+	provider Provider
+}
+
+// NewMetered wraps a provider
+func NewMetered(provider Provider) *Metered {
+	return &Metered{provider: provider}
+}
+
+// GenerateContent implements LLM
+func (m *Metered) GenerateContent(ctx context.Context, prompt string) (string, error) {
+	return m.generate(ctx, Request{Prompt: prompt})
+}
+
+// GenerateJSON implements JSONLLM
+func (m *Metered) GenerateJSON(ctx context.Context, prompt string, schema Schema) (string, error) {
+	return m.generate(ctx, Request{Prompt: prompt, Schema: schema})
+}
+
+// Forget drops the cached response to a request, so a response that failed
+// validation isn't served again
+func (m *Metered) Forget(prompt string, schema Schema) {
+	if usageStore == nil {
+		return
+	}
+	if err := usageStore.DeleteCachedResponse(m.cacheKey(Request{Prompt: prompt, Schema: schema})); err != nil {
+		log.Printf("Failed to drop cached AI response: %v", err)
+	}
+}
+
+func (m *Metered) generate(ctx context.Context, req Request) (string, error) {
+	store := usageStore
+	if store == nil {
+		resp, err := m.provider.Complete(ctx, req)
+		if err != nil {
+			return "", err
+		}
+		return resp.Text, nil
+	}
+
+	feature := FeatureFrom(ctx)
+	key := m.cacheKey(req)
+	if fresh, _ := ctx.Value(freshKey{}).(bool); !fresh {
+		text, ok, err := store.CachedResponse(key)
+		if err != nil {
+			log.Printf("Failed to read AI cache: %v", err)
+		}
+		if ok {
+			m.record(store, &models.AIUsage{Feature: feature, Provider: m.provider.Name(), Model: m.provider.Model(), Cached: true})
+			return text, nil
+		}
+	}
+
+	if err := CheckBudget(); err != nil {
+		return "", err
+	}
+
+	resp, err := m.provider.Complete(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	m.record(store, &models.AIUsage{
+		Feature:      feature,
+		Provider:     m.provider.Name(),
+		Model:        resp.Model,
+		InputTokens:  resp.InputTokens,
+		OutputTokens: resp.OutputTokens,
+		CostUSD:      EstimateCost(resp.Model, resp.InputTokens, resp.OutputTokens),
+	})
+	if resp.Text != "" {
+		if err := store.SaveCachedResponse(key, feature, resp.Model, resp.Text); err != nil {
+			log.Printf("Failed to cache AI response: %v", err)
+		}
+	}
+	return resp.Text, nil
+}
+
+func (m *Metered) record(store UsageStore, u *models.AIUsage) {
+	if err := store.SaveUsage(u); err != nil {
+		log.Printf("Failed to record AI usage: %v", err)
+	}
+}
+
+// cacheKey hashes everything that determines a response: the provider,
+// the model, the schema and the prompt
+func (m *Metered) cacheKey(req Request) string {
+	// Maps marshal with sorted keys, so equal schemas hash equally
+	data, _ := json.Marshal(struct {
+		Provider string `json:"provider"`
+		Model    string `json:"model"`
+		Schema   Schema `json:"schema,omitempty"`
+		Prompt   string `json:"prompt"`
+	}{m.provider.Name(), m.provider.Model(), req.Schema, req.Prompt})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,176 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	defaultOpenAIModel   = "gpt-4o-mini"
+)
+
+// OpenAI talks to any server implementing the OpenAI chat completions API,
+// including llama.cpp's server and Ollama
+type OpenAI struct {
+	baseURL string
+	model   string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewOpenAI creates an OpenAI-compatible client. Empty arguments fall back
+// to api.openai.com, gpt-4o-mini and OPENAI_API_KEY; local servers usually
+// need no key.
+func NewOpenAI(baseURL, model, apiKey string) *OpenAI {
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+	if model == "" {
+		model = defaultOpenAIModel
+	}
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+	return &OpenAI{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		model:   model,
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []chatMessage   `json:"messages"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+type responseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *jsonSchema `json:"json_schema,omitempty"`
+}
+
+type jsonSchema struct {
+	Name   string `json:"name"`
+	Schema Schema `json:"schema"`
+}
+
+type chatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Name returns the provider name
+func (s *OpenAI) Name() string { return ProviderOpenAI }
+
+// Model returns the model requests are sent to
+func (s *OpenAI) Model() string { return s.model }
+
+// GenerateContent sends the prompt as a single user message and returns
+// the first choice
+func (s *OpenAI) GenerateContent(ctx context.Context, prompt string) (string, error) {
+	resp, err := s.Complete(ctx, Request{Prompt: prompt})
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// GenerateJSON asks for a reply matching schema through response_format.
+// Servers that ignore it still get the format spelled out in the prompt.
+func (s *OpenAI) GenerateJSON(ctx context.Context, prompt string, schema Schema) (string, error) {
+	resp, err := s.Complete(ctx, Request{Prompt: prompt, Schema: schema})
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// Complete sends a request and reports the token usage the server returns
+func (s *OpenAI) Complete(ctx context.Context, req Request) (*Response, error) {
+	request := chatRequest{
+		Model:    s.model,
+		Messages: []chatMessage{{Role: "user", Content: req.Prompt}},
+	}
+	if req.Schema != nil {
+		request.ResponseFormat = &responseFormat{
+			Type:       "json_schema",
+			JSONSchema: &jsonSchema{Name: "response", Schema: req.Schema},
+		}
+	}
+	return s.complete(ctx, request)
+}
+
+func (s *OpenAI) complete(ctx context.Context, request chatRequest) (*Response, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate content: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var result chatResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to generate content: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to generate content: %s", result.Error.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to generate content: %s", resp.Status)
+	}
+	if len(result.Choices) == 0 {
+		return nil, fmt.Errorf("failed to generate content: empty response")
+	}
+
+	model := result.Model
+	if model == "" {
+		model = s.model
+	}
+	return &Response{
+		Text:         strings.TrimSpace(result.Choices[0].Message.Content),
+		Model:        model,
+		InputTokens:  result.Usage.PromptTokens,
+		OutputTokens: result.Usage.CompletionTokens,
+	}, nil
+}
@@ -0,0 +1,192 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// MaxAttempts bounds how often a structured request is sent, counting the
+// re-prompts after a rejected response
+const MaxAttempts = 3
+
+// ErrInvalidResponse is returned when no attempt produced a valid response
+var ErrInvalidResponse = errors.New("invalid AI response")
+
+// Schema is a JSON Schema describing the expected response
+type Schema map[string]any
+
+// JSONLLM is implemented by providers that can constrain a response to a
+// JSON schema. Other providers only see the format described in the prompt.
+type JSONLLM interface {
+	GenerateJSON(ctx context.Context, prompt string, schema Schema) (string, error)
+}
+
+// Rejection describes a response that failed to decode or validate
+type Rejection struct {
+	Task     string
+	Attempt  int
+	Reason   string
+	Response string
+}
+
+// RejectionStore keeps rejected responses for later inspection
+type RejectionStore interface {
+	SaveRejection(r Rejection) error
+}
+
+var rejectionStore RejectionStore
+
+// SetRejectionStore makes the default RecordRejection keep rejected
+// responses in s as well as logging them. The service layer sets it once the
+// database is open; nil only logs.
+func SetRejectionStore(s RejectionStore) {
+	rejectionStore = s
+}
+
+// RecordRejection is called for every rejected response. The default logs
+// it and saves it to the store set with SetRejectionStore.
+var RecordRejection = func(r Rejection) {
+	log.Printf("Rejected %s response (attempt %d): %s", r.Task, r.Attempt, r.Reason)
+	if rejectionStore == nil {
+		return
+	}
+	if err := rejectionStore.SaveRejection(r); err != nil {
+		log.Printf("Failed to record AI rejection: %v", err)
+	}
+}
+
+// Structured is a request whose response must be JSON matching Schema
+type Structured[T any] struct {
+	Task     string // Short name recorded with rejections
+	Prompt   string // Should still describe the format for providers without schema support
+	Schema   Schema
+	Validate func(*T) error // Checks what a schema can't express; optional
+}
+
+// Generate sends the request and returns the first response that decodes
+// and validates. Rejected responses are recorded and the model is asked
+// again with the reason, up to MaxAttempts in total. Provider errors are
+// returned immediately. Calls are tagged with Task in the usage ledger.
+func (r Structured[T]) Generate(ctx context.Context, llm LLM) (*T, error) {
+	ctx = WithFeature(ctx, r.Task)
+	prompt := r.Prompt
+	var reason string
+	for attempt := 1; attempt <= MaxAttempts; attempt++ {
+		response, err := r.send(ctx, llm, prompt)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := r.parse(response)
+		if err == nil {
+			return result, nil
+		}
+
+		reason = err.Error()
+		RecordRejection(Rejection{Task: r.Task, Attempt: attempt, Reason: reason, Response: response})
+		if f, ok := llm.(interface{ Forget(string, Schema) }); ok {
+			f.Forget(prompt, r.Schema)
+		}
+		prompt = retryPrompt(r.Prompt, response, reason)
+	}
+
+	return nil, fmt.Errorf("%w for %s after %d attempts: %s", ErrInvalidResponse, r.Task, MaxAttempts, reason)
+}
+
+func (r Structured[T]) send(ctx context.Context, llm LLM, prompt string) (string, error) {
+	if j, ok := llm.(JSONLLM); ok && r.Schema != nil {
+		return j.GenerateJSON(ctx, prompt, r.Schema)
+	}
+	return llm.GenerateContent(ctx, prompt)
+}
+
+func (r Structured[T]) parse(response string) (*T, error) {
+	var result T
+	if err := json.Unmarshal([]byte(cleanJSON(response)), &result); err != nil {
+		return nil, fmt.Errorf("response is not valid JSON: %v", err)
+	}
+	if r.Validate != nil {
+		if err := r.Validate(&result); err != nil {
+			return nil, err
+		}
+	}
+	return &result, nil
+}
+
+// maxEchoedResponse keeps re-prompts from growing with runaway responses
+const maxEchoedResponse = 4000
+
+// retryPrompt repeats the original prompt with the rejected response and
+// the reason it was rejected
+func retryPrompt(prompt, response, reason string) string {
+	if len(response) > maxEchoedResponse {
+		response = response[:maxEchoedResponse] + "..."
+	}
+
+	var b strings.Builder
+	b.WriteString(prompt)
+	b.WriteString("\n\nYour previous response was rejected: ")
+	b.WriteString(reason)
+	b.WriteString("\nPrevious response:\n")
+	b.WriteString(response)
+	b.WriteString("\n\nRespond again with corrected JSON only.")
+	return b.String()
+}
+
+// Schema builders for the small subset of JSON Schema the providers share
+
+// ObjectSchema describes an object with the given properties, all required
+func ObjectSchema(properties map[string]Schema) Schema {
+	required := make([]string, 0, len(properties))
+	props := make(map[string]any, len(properties))
+	for name, schema := range properties {
+		required = append(required, name)
+		props[name] = schema
+	}
+	sort.Strings(required) // Stable order so requests are reproducible
+	return Schema{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// ArraySchema describes an array of items with optional length bounds
+// (0 means unbounded)
+func ArraySchema(items Schema, minItems, maxItems int) Schema {
+	s := Schema{"type": "array", "items": items}
+	if minItems > 0 {
+		s["minItems"] = minItems
+	}
+	if maxItems > 0 {
+		s["maxItems"] = maxItems
+	}
+	return s
+}
+
+// StringSchema describes a string, limited to maxLength characters if > 0
+func StringSchema(maxLength int) Schema {
+	s := Schema{"type": "string"}
+	if maxLength > 0 {
+		s["maxLength"] = maxLength
+	}
+	return s
+}
+
+// NullableStringSchema describes a string or null
+func NullableStringSchema(maxLength int) Schema {
+	s := StringSchema(maxLength)
+	s["type"] = []string{"string", "null"}
+	return s
+}
+
+// IntegerSchema describes an integer in [min, max]
+func IntegerSchema(min, max int) Schema {
+	return Schema{"type": "integer", "minimum": min, "maximum": max}
+}
@@ -0,0 +1,129 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// scripted replies with the next response on every call
+type scripted struct {
+	responses []string
+	prompts   []string
+}
+
+func (s *scripted) GenerateContent(ctx context.Context, prompt string) (string, error) {
+	s.prompts = append(s.prompts, prompt)
+	response := s.responses[0]
+	s.responses = s.responses[1:]
+	return response, nil
+}
+
+func recordRejections(t *testing.T) *[]Rejection {
+	var rejections []Rejection
+	saved := RecordRejection
+	RecordRejection = func(r Rejection) { rejections = append(rejections, r) }
+	t.Cleanup(func() { RecordRejection = saved })
+	return &rejections
+}
+
+func TestStructuredRetriesWithReason(t *testing.T) {
+	rejections := recordRejections(t)
+	llm := &scripted{responses: []string{
+		"not json",
+		`{"hindi": null, "dutch": null, "english": "` + strings.Repeat("x", MaxBridgeLength+1) + `"}`,
+		"```json\n{\"hindi\": null, \"dutch\": \"de/het\", \"english\": null}\n```",
+	}}
+
+	bridges, err := NewServiceWithLLM(llm).GenerateBridges(context.Background(), "el", "the")
+	if err != nil {
+		t.Fatalf("GenerateBridges() error = %v", err)
+	}
+	if bridges.Dutch == nil || *bridges.Dutch != "de/het" {
+		t.Errorf("GenerateBridges() = %+v, want the third response", bridges)
+	}
+
+	if len(*rejections) != 2 {
+		t.Fatalf("rejections = %+v, want 2", *rejections)
+	}
+	if r := (*rejections)[1]; r.Task != "bridges" || r.Attempt != 2 || !strings.Contains(r.Reason, "english bridge is 51 characters") {
+		t.Errorf("second rejection = %+v", r)
+	}
+	if !strings.Contains(llm.prompts[2], "english bridge is 51 characters") {
+		t.Errorf("retry prompt does not mention the rejection reason:\n%s", llm.prompts[2])
+	}
+}
+
+func TestStructuredGivesUp(t *testing.T) {
+	rejections := recordRejections(t)
+
+	fake := &Fake{Responses: map[string]string{"food": `{"cards": []}`}}
+	_, err := NewServiceWithLLM(fake).GenerateCards(context.Background(), "food", 3)
+	if !errors.Is(err, ErrInvalidResponse) {
+		t.Fatalf("GenerateCards() error = %v, want ErrInvalidResponse", err)
+	}
+	if len(*rejections) != MaxAttempts {
+		t.Errorf("len(rejections) = %d, want %d", len(*rejections), MaxAttempts)
+	}
+}
+
+func TestStructuredWithFake(t *testing.T) {
+	rejections := recordRejections(t)
+	ctx := context.Background()
+	service := NewServiceWithLLM(&Fake{})
+
+	// The bridges prompt only shows a "..." template, so the response is
+	// built from the schema: every bridge null
+	bridges, err := service.GenerateBridges(ctx, "hablar", "to speak")
+	if err != nil || bridges.Hindi != nil || bridges.Dutch != nil || bridges.English != nil {
+		t.Errorf("GenerateBridges() = %+v, %v; want no bridges", bridges, err)
+	}
+	// The example has one card, too few for the schema
+	cards, err := service.GenerateCards(ctx, "food", 3)
+	if err != nil || len(cards) != 3 || cards[0].Term == "" {
+		t.Errorf("GenerateCards() = %+v, %v; want 3 cards", cards, err)
+	}
+
+	type blank struct {
+		Sentence string `json:"sentence"`
+		Answer   string `json:"answer"`
+	}
+	fb, err := Structured[blank]{
+		Task:   "fill_blank",
+		Prompt: `Return ONLY valid JSON: {"sentence": "Yo ____ español.", "answer": "hablo"}`,
+		Schema: ObjectSchema(map[string]Schema{"sentence": StringSchema(0), "answer": StringSchema(0)}),
+		Validate: func(b *blank) error {
+			if !strings.Contains(b.Sentence, "____") {
+				return errors.New("no blank")
+			}
+			return nil
+		},
+	}.Generate(ctx, NewMetered(&Fake{}))
+	if err != nil || fb.Answer != "hablo" {
+		t.Errorf("Generate() = %+v, %v; want the prompt's example", fb, err)
+	}
+
+	if len(*rejections) != 0 {
+		t.Errorf("rejections = %+v, want none", *rejections)
+	}
+}
+
+func TestOpenAIGenerateJSON(t *testing.T) {
+	var got chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{\"hindi\":null,\"dutch\":null,\"english\":null}"}}]}`))
+	}))
+	defer server.Close()
+
+	if _, err := NewServiceWithLLM(NewOpenAI(server.URL, "", "")).GenerateBridges(context.Background(), "y", "and"); err != nil {
+		t.Fatalf("GenerateBridges() error = %v", err)
+	}
+	if got.ResponseFormat == nil || got.ResponseFormat.Type != "json_schema" || got.ResponseFormat.JSONSchema.Schema["type"] != "object" {
+		t.Errorf("response_format = %+v, want a json_schema object", got.ResponseFormat)
+	}
+}
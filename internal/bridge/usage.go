@@ -0,0 +1,122 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Price is a model's list price in USD per million tokens
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// prices by model name prefix; the longest matching prefix wins. Unknown
+// models, local ones included, cost nothing unless LLM_PRICE_INPUT and
+// LLM_PRICE_OUTPUT are set.
+var prices = map[string]Price{
+	"gemini-2.5-pro":        {1.25, 10},
+	"gemini-2.5-flash":      {0.30, 2.50},
+	"gemini-2.5-flash-lite": {0.10, 0.40},
+	"gemini-2.0-flash":      {0.10, 0.40},
+	"gemini-2.0-flash-lite": {0.075, 0.30},
+	"gpt-4o":                {2.50, 10},
+	"gpt-4o-mini":           {0.15, 0.60},
+	"gpt-4.1":               {2, 8},
+	"gpt-4.1-mini":          {0.40, 1.60},
+	"gpt-4.1-nano":          {0.10, 0.40},
+}
+
+// PriceFor returns the price of a model, from LLM_PRICE_INPUT and
+// LLM_PRICE_OUTPUT if either is set
+func PriceFor(model string) Price {
+	input, output := os.Getenv("LLM_PRICE_INPUT"), os.Getenv("LLM_PRICE_OUTPUT")
+	if input != "" || output != "" {
+		var p Price
+		p.Input, _ = strconv.ParseFloat(strings.TrimSpace(input), 64)
+		p.Output, _ = strconv.ParseFloat(strings.TrimSpace(output), 64)
+		return p
+	}
+
+	model = strings.TrimPrefix(strings.ToLower(model), "models/")
+	var best string
+	for prefix := range prices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	return prices[best]
+}
+
+// EstimateCost returns the cost in USD of a call with the given token counts
+func EstimateCost(model string, inputTokens, outputTokens int) float64 {
+	p := PriceFor(model)
+	return (float64(inputTokens)*p.Input + float64(outputTokens)*p.Output) / 1e6
+}
+
+// ErrBudgetExceeded is returned instead of calling the provider once the
+// monthly budget is spent in block mode
+var ErrBudgetExceeded = errors.New("monthly AI budget exceeded")
+
+// Budget modes accepted in AI_BUDGET_MODE
+const (
+	BudgetBlock = "block"
+	BudgetWarn  = "warn"
+)
+
+// Budget is the monthly AI spending limit
+type Budget struct {
+	LimitUSD float64 // 0 for no limit
+	Mode     string  // BudgetBlock or BudgetWarn
+}
+
+// BudgetFromEnv reads AI_MONTHLY_BUDGET (USD) and AI_BUDGET_MODE (block,
+// the default, or warn)
+func BudgetFromEnv() Budget {
+	b := Budget{Mode: BudgetBlock}
+	b.LimitUSD, _ = strconv.ParseFloat(strings.TrimSpace(os.Getenv("AI_MONTHLY_BUDGET")), 64)
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("AI_BUDGET_MODE")), BudgetWarn) {
+		b.Mode = BudgetWarn
+	}
+	return b
+}
+
+var (
+	warnedMu    sync.Mutex
+	warnedMonth string
+)
+
+// CheckBudget returns ErrBudgetExceeded once this month's spend reaches the
+// budget in block mode. In warn mode it logs once a month instead. Without a
+// usage store there is no spend to check.
+func CheckBudget() error {
+	b := BudgetFromEnv()
+	if b.LimitUSD <= 0 || usageStore == nil {
+		return nil
+	}
+	spent, err := usageStore.CostThisMonth()
+	if err != nil {
+		log.Printf("Failed to read AI spend: %v", err)
+		return nil
+	}
+	if spent < b.LimitUSD {
+		return nil
+	}
+
+	if b.Mode == BudgetWarn {
+		warnedMu.Lock()
+		defer warnedMu.Unlock()
+		if month := time.Now().UTC().Format("2006-01"); warnedMonth != month {
+			warnedMonth = month
+			log.Printf("Warning: AI spend this month ($%.2f) has reached the $%.2f budget", spent, b.LimitUSD)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: $%.2f of $%.2f spent", ErrBudgetExceeded, spent, b.LimitUSD)
+}
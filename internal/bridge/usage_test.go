@@ -0,0 +1,100 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"languagepapi/internal/models"
+)
+
+func TestEstimateCost(t *testing.T) {
+	tests := []struct {
+		model   string
+		in, out int
+		want    float64
+	}{
+		{"gemini-2.5-flash", 1_000_000, 1_000_000, 2.80},
+		{"gemini-2.5-flash-lite", 1_000_000, 0, 0.10}, // Longest prefix wins
+		{"gpt-4o-mini-2024-07-18", 2_000_000, 1_000_000, 0.90},
+		{"llama3.1", 1_000_000, 1_000_000, 0},
+	}
+	for _, tt := range tests {
+		if got := EstimateCost(tt.model, tt.in, tt.out); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("EstimateCost(%q) = %v, want %v", tt.model, got, tt.want)
+		}
+	}
+
+	t.Setenv("LLM_PRICE_INPUT", "1")
+	t.Setenv("LLM_PRICE_OUTPUT", "2")
+	if got := EstimateCost("llama3.1", 500_000, 500_000); math.Abs(got-1.5) > 1e-9 {
+		t.Errorf("EstimateCost() with price overrides = %v, want 1.5", got)
+	}
+}
+
+// memoryUsageStore is a UsageStore kept in memory
+type memoryUsageStore struct {
+	cache map[string]string
+	usage []models.AIUsage
+}
+
+func (s *memoryUsageStore) CachedResponse(key string) (string, bool, error) {
+	text, ok := s.cache[key]
+	return text, ok, nil
+}
+
+func (s *memoryUsageStore) SaveCachedResponse(key, feature, model, text string) error {
+	s.cache[key] = text
+	return nil
+}
+
+func (s *memoryUsageStore) DeleteCachedResponse(key string) error {
+	delete(s.cache, key)
+	return nil
+}
+
+func (s *memoryUsageStore) SaveUsage(u *models.AIUsage) error {
+	s.usage = append(s.usage, *u)
+	return nil
+}
+
+func (s *memoryUsageStore) CostThisMonth() (float64, error) {
+	var total float64
+	for _, u := range s.usage {
+		total += u.CostUSD
+	}
+	return total, nil
+}
+
+func TestMeteredUsageStore(t *testing.T) {
+	store := &memoryUsageStore{cache: map[string]string{}}
+	SetUsageStore(store)
+	t.Cleanup(func() { SetUsageStore(nil) })
+	t.Setenv("LLM_PRICE_INPUT", "1000000")
+	t.Setenv("LLM_PRICE_OUTPUT", "0")
+
+	fake := &Fake{}
+	m := NewMetered(fake)
+	ctx := WithFeature(context.Background(), "test")
+	for range 2 {
+		if _, err := m.GenerateContent(ctx, "hola"); err != nil {
+			t.Fatalf("GenerateContent() error = %v", err)
+		}
+	}
+	if n := len(fake.Prompts()); n != 1 {
+		t.Errorf("provider called %d times, want 1 with the second call cached", n)
+	}
+	if len(store.usage) != 2 || store.usage[0].Cached || !store.usage[1].Cached {
+		t.Errorf("usage ledger = %+v, want one call then one cache hit", store.usage)
+	}
+
+	t.Setenv("AI_MONTHLY_BUDGET", "1")
+	if _, err := m.GenerateContent(ctx, "adiós"); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("GenerateContent() over budget error = %v, want ErrBudgetExceeded", err)
+	}
+	m.Forget("hola", nil)
+	if len(store.cache) != 0 {
+		t.Errorf("cache after Forget = %v, want empty", store.cache)
+	}
+}
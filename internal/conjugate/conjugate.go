@@ -0,0 +1,390 @@
+// Package conjugate conjugates Spanish verbs by rule: the three regular
+// classes, stem-changing and spelling-changing verbs, a table of common
+// irregulars and their prefixed compounds (mantener, proponer), and
+// reflexive verbs. Every form comes with notes on how it is built, which
+// the conjugation drills show when explaining an answer.
+package conjugate
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Person is a grammatical person and number
+type Person int
+
+const (
+	Yo Person = iota
+	Tu
+	El
+	Nosotros
+	Vosotros
+	Ellos
+)
+
+// Persons lists the persons in table order
+var Persons = []Person{Yo, Tu, El, Nosotros, Vosotros, Ellos}
+
+var (
+	pronouns        = [6]string{"yo", "tú", "él/ella/usted", "nosotros", "vosotros", "ellos/ellas/ustedes"}
+	commandPronouns = [6]string{"", "tú", "usted", "nosotros", "vosotros", "ustedes"}
+)
+
+func (p Person) String() string {
+	if p < Yo || p > Ellos {
+		return fmt.Sprintf("Person(%d)", int(p))
+	}
+	return pronouns[p]
+}
+
+// Pronoun is the subject of the person's form in tense t. Commands address
+// tú, usted, nosotros, vosotros or ustedes.
+func (p Person) Pronoun(t Tense) string {
+	if t.Mood() == MoodImperative {
+		return commandPronouns[p]
+	}
+	return p.String()
+}
+
+// Mood groups the tenses
+type Mood string
+
+const (
+	MoodIndicative  Mood = "indicative"
+	MoodSubjunctive Mood = "subjunctive"
+	MoodImperative  Mood = "imperative"
+)
+
+// Tense is a tense of a mood, e.g. the present subjunctive
+type Tense string
+
+const (
+	Present                   Tense = "present"
+	Preterite                 Tense = "preterite"
+	Imperfect                 Tense = "imperfect"
+	Future                    Tense = "future"
+	Conditional               Tense = "conditional"
+	PresentPerfect            Tense = "present_perfect"
+	Pluperfect                Tense = "pluperfect"
+	PastAnterior              Tense = "past_anterior"
+	FuturePerfect             Tense = "future_perfect"
+	ConditionalPerfect        Tense = "conditional_perfect"
+	PresentSubjunctive        Tense = "present_subjunctive"
+	ImperfectSubjunctive      Tense = "imperfect_subjunctive"
+	FutureSubjunctive         Tense = "future_subjunctive"
+	PresentPerfectSubjunctive Tense = "present_perfect_subjunctive"
+	PluperfectSubjunctive     Tense = "pluperfect_subjunctive"
+	FuturePerfectSubjunctive  Tense = "future_perfect_subjunctive"
+	Imperative                Tense = "imperative"
+	NegativeImperative        Tense = "negative_imperative"
+)
+
+type tenseInfo struct {
+	label   string
+	spanish string
+	mood    Mood
+	aux     Tense // For compound tenses, the tense of haber
+}
+
+var tenses = map[Tense]tenseInfo{
+	Present:                   {"Present", "presente", MoodIndicative, ""},
+	Preterite:                 {"Preterite", "pretérito indefinido", MoodIndicative, ""},
+	Imperfect:                 {"Imperfect", "pretérito imperfecto", MoodIndicative, ""},
+	Future:                    {"Future", "futuro", MoodIndicative, ""},
+	Conditional:               {"Conditional", "condicional", MoodIndicative, ""},
+	PresentPerfect:            {"Present perfect", "pretérito perfecto", MoodIndicative, Present},
+	Pluperfect:                {"Pluperfect", "pretérito pluscuamperfecto", MoodIndicative, Imperfect},
+	PastAnterior:              {"Past anterior", "pretérito anterior", MoodIndicative, Preterite},
+	FuturePerfect:             {"Future perfect", "futuro perfecto", MoodIndicative, Future},
+	ConditionalPerfect:        {"Conditional perfect", "condicional perfecto", MoodIndicative, Conditional},
+	PresentSubjunctive:        {"Present subjunctive", "presente de subjuntivo", MoodSubjunctive, ""},
+	ImperfectSubjunctive:      {"Imperfect subjunctive", "imperfecto de subjuntivo", MoodSubjunctive, ""},
+	FutureSubjunctive:         {"Future subjunctive", "futuro de subjuntivo", MoodSubjunctive, ""},
+	PresentPerfectSubjunctive: {"Present perfect subjunctive", "pretérito perfecto de subjuntivo", MoodSubjunctive, PresentSubjunctive},
+	PluperfectSubjunctive:     {"Pluperfect subjunctive", "pluscuamperfecto de subjuntivo", MoodSubjunctive, ImperfectSubjunctive},
+	FuturePerfectSubjunctive:  {"Future perfect subjunctive", "futuro perfecto de subjuntivo", MoodSubjunctive, FutureSubjunctive},
+	Imperative:                {"Imperative", "imperativo afirmativo", MoodImperative, ""},
+	NegativeImperative:        {"Negative imperative", "imperativo negativo", MoodImperative, ""},
+}
+
+// Tenses lists every tense in conjugation table order
+var Tenses = []Tense{
+	Present, Preterite, Imperfect, Future, Conditional,
+	PresentPerfect, Pluperfect, PastAnterior, FuturePerfect, ConditionalPerfect,
+	PresentSubjunctive, ImperfectSubjunctive, FutureSubjunctive,
+	PresentPerfectSubjunctive, PluperfectSubjunctive, FuturePerfectSubjunctive,
+	Imperative, NegativeImperative,
+}
+
+// Valid reports whether t is a known tense
+func (t Tense) Valid() bool {
+	_, ok := tenses[t]
+	return ok
+}
+
+// Label is the English name of the tense, e.g. "Present subjunctive"
+func (t Tense) Label() string { return tenses[t].label }
+
+// Spanish is the Spanish name of the tense, e.g. "presente de subjuntivo"
+func (t Tense) Spanish() string { return tenses[t].spanish }
+
+func (t Tense) Mood() Mood { return tenses[t].mood }
+
+// Compound reports whether the tense is formed with haber
+func (t Tense) Compound() bool { return tenses[t].aux != "" }
+
+// HasPerson reports whether the tense has a form for p; commands have no yo
+func (t Tense) HasPerson(p Person) bool {
+	return p >= Yo && p <= Ellos && !(t.Mood() == MoodImperative && p == Yo)
+}
+
+var (
+	// ErrNotVerb is returned for words that aren't Spanish infinitives
+	ErrNotVerb = errors.New("not a Spanish infinitive")
+	// ErrNoForm is returned for a person a tense lacks, e.g. a yo command
+	ErrNoForm = errors.New("no such form")
+)
+
+// Conjugation is one conjugated form
+type Conjugation struct {
+	Form         string
+	Alternatives []string // Other accepted forms, e.g. hablase for hablara
+	Notes        []string // How the form is built, e.g. "stem change e → ie"
+}
+
+// Accepted lists the form and its alternatives
+func (c Conjugation) Accepted() []string {
+	return append([]string{c.Form}, c.Alternatives...)
+}
+
+// infinitive matches verbs ending in -ar, -er or -ir (-ír for oír, reír)
+var infinitive = regexp.MustCompile(`^(ir|[a-zñü]+(ar|er|ir|ír))$`)
+
+// verb is a parsed infinitive
+type verb struct {
+	inf       string // Without the reflexive se
+	plain     string // Without a written accent: oír → oir
+	reflexive bool
+	class     byte   // 'a', 'e' or 'i'
+	stem      string // The infinitive minus its ending
+	irr       *irregular
+	prefix    string // Before the irregular base of a compound, "man" in mantener
+	base      string // The irregular base, or the verb itself
+	change    string // Stem change, see stemChanges
+}
+
+func parse(s string) (*verb, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	v := &verb{}
+	if rest, ok := strings.CutSuffix(s, "se"); ok && infinitive.MatchString(rest) {
+		s, v.reflexive = rest, true
+	}
+	if !infinitive.MatchString(s) {
+		return nil, ErrNotVerb
+	}
+
+	v.inf = s
+	v.plain = strings.ReplaceAll(s, "í", "i")
+	v.class = v.plain[len(v.plain)-2]
+	v.stem = v.plain[:len(v.plain)-2]
+	v.base = s
+
+	v.prefix, v.base = compound(s, irregulars, func(i *irregular) bool { return i.compounds })
+	if v.base != "" {
+		v.irr = irregulars[v.base]
+	} else {
+		v.base = s
+	}
+	v.change = stemChanges[s]
+	if v.change == "" && v.prefix != "" {
+		v.change = stemChanges[v.base]
+	}
+	return v, nil
+}
+
+// IsInfinitive reports whether s looks like a Spanish infinitive, reflexive
+// ones like levantarse included
+func IsInfinitive(s string) bool {
+	_, err := parse(s)
+	return err == nil
+}
+
+// Conjugate returns the form of a verb for a tense and person
+func Conjugate(inf string, t Tense, p Person) (Conjugation, error) {
+	v, err := parse(inf)
+	if err != nil {
+		return Conjugation{}, err
+	}
+	if !t.Valid() {
+		return Conjugation{}, fmt.Errorf("unknown tense %q", t)
+	}
+	if !t.HasPerson(p) {
+		return Conjugation{}, ErrNoForm
+	}
+	return v.conjugate(t, p), nil
+}
+
+// Table returns the six forms of a tense in person order. Persons the tense
+// lacks have an empty form.
+func Table(inf string, t Tense) ([]Conjugation, error) {
+	v, err := parse(inf)
+	if err != nil {
+		return nil, err
+	}
+	if !t.Valid() {
+		return nil, fmt.Errorf("unknown tense %q", t)
+	}
+	table := make([]Conjugation, len(Persons))
+	for _, p := range Persons {
+		if t.HasPerson(p) {
+			table[p] = v.conjugate(t, p)
+		}
+	}
+	return table, nil
+}
+
+// Participle returns the past participle
+func Participle(inf string) (string, error) {
+	v, err := parse(inf)
+	if err != nil {
+		return "", err
+	}
+	part, _ := v.participle()
+	return part, nil
+}
+
+// Gerund returns the gerund. Reflexive verbs take se at the end:
+// levantándose.
+func Gerund(inf string) (string, error) {
+	v, err := parse(inf)
+	if err != nil {
+		return "", err
+	}
+	g := v.gerund()
+	if v.reflexive {
+		g = v.reflect(g, Imperative, El) // Attached like a command's pronoun
+	}
+	return g, nil
+}
+
+// Base returns the irregular verb a prefixed verb is conjugated like, e.g.
+// tener for mantener, or "" if there is none
+func Base(inf string) string {
+	v, err := parse(inf)
+	if err != nil || v.prefix == "" {
+		return ""
+	}
+	return v.base
+}
+
+// Irregulars lists the verbs the conjugator knows by name, sorted: the
+// irregular verbs, the stem-changing verbs and those with an irregular
+// participle. Every other verb is conjugated by the rules of its class.
+func Irregulars() []string {
+	seen := make(map[string]bool)
+	for inf := range irregulars {
+		seen[inf] = true
+	}
+	for inf := range stemChanges {
+		seen[inf] = true
+	}
+	for inf := range participles {
+		if inf != "scribir" && inf != "solver" { // Bases of describir, resolver
+			seen[inf] = true
+		}
+	}
+	list := make([]string, 0, len(seen))
+	for inf := range seen {
+		list = append(list, inf)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Cell is a place in a verb's conjugation
+type Cell struct {
+	Tense  Tense
+	Person Person
+}
+
+// Lookup finds where a form appears in a verb's conjugation, e.g. hablaba
+// is both the yo and the él imperfect of hablar
+func Lookup(inf, form string) []Cell {
+	v, err := parse(inf)
+	if err != nil {
+		return nil
+	}
+	form = strings.Join(strings.Fields(strings.ToLower(form)), " ")
+	var cells []Cell
+	for _, t := range Tenses {
+		for _, p := range Persons {
+			if !t.HasPerson(p) {
+				continue
+			}
+			for _, accepted := range v.conjugate(t, p).Accepted() {
+				if accepted == form {
+					cells = append(cells, Cell{t, p})
+					break
+				}
+			}
+		}
+	}
+	return cells
+}
+
+var haber = &verb{inf: "haber", plain: "haber", class: 'e', stem: "hab", irr: irregulars["haber"], base: "haber"}
+
+func (v *verb) conjugate(t Tense, p Person) Conjugation {
+	var c Conjugation
+	if aux := tenses[t].aux; aux != "" {
+		h := haber.conjugate(aux, p)
+		part, note := v.participle()
+		c.Form = h.Form + " " + part
+		for _, alt := range h.Alternatives {
+			c.Alternatives = append(c.Alternatives, alt+" "+part)
+		}
+		c.Notes = []string{fmt.Sprintf("haber in the %s (%s) + the past participle %s", strings.ToLower(aux.Label()), h.Form, part)}
+		if note != "" {
+			c.Notes = append(c.Notes, note)
+		}
+	} else {
+		var f form
+		switch t {
+		case Present:
+			f = v.present(p)
+		case Preterite:
+			f = v.preterite(p)
+		case Imperfect:
+			f = v.imperfect(p)
+		case Future, Conditional:
+			f = v.future(t, p)
+		case PresentSubjunctive:
+			f = v.presentSubjunctive(p)
+		case ImperfectSubjunctive:
+			f = v.pastSubjunctive("ra", p)
+			c.Alternatives = []string{v.pastSubjunctive("se", p).text}
+		case FutureSubjunctive:
+			f = v.pastSubjunctive("re", p)
+		case Imperative, NegativeImperative:
+			f = v.imperative(p, t == NegativeImperative)
+		}
+		c.Form, c.Notes = f.text, f.notes
+	}
+
+	if v.reflexive {
+		c.Form = v.reflect(c.Form, t, p)
+		for i, alt := range c.Alternatives {
+			c.Alternatives[i] = v.reflect(alt, t, p)
+		}
+		pron := reflexivePronouns[p]
+		if t == Imperative {
+			c.Notes = append(c.Notes, fmt.Sprintf("%s is attached to the end of affirmative commands", pron))
+		} else {
+			c.Notes = append(c.Notes, fmt.Sprintf("reflexive: %s goes before the verb", pron))
+		}
+	}
+	return c
+}
@@ -0,0 +1,116 @@
+package conjugate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestConjugate(t *testing.T) {
+	tests := []struct {
+		verb  string
+		tense Tense
+		want  string // The six persons, comma separated; "-" for none
+	}{
+		{"hablar", Present, "hablo, hablas, habla, hablamos, habláis, hablan"},
+		{"comer", Preterite, "comí, comiste, comió, comimos, comisteis, comieron"},
+		{"vivir", Imperfect, "vivía, vivías, vivía, vivíamos, vivíais, vivían"},
+		{"pensar", Present, "pienso, piensas, piensa, pensamos, pensáis, piensan"},
+		{"dormir", PresentSubjunctive, "duerma, duermas, duerma, durmamos, durmáis, duerman"},
+		{"pedir", Preterite, "pedí, pediste, pidió, pedimos, pedisteis, pidieron"},
+		{"jugar", PresentSubjunctive, "juegue, juegues, juegue, juguemos, juguéis, jueguen"},
+		{"buscar", Preterite, "busqué, buscaste, buscó, buscamos, buscasteis, buscaron"},
+		{"empezar", PresentSubjunctive, "empiece, empieces, empiece, empecemos, empecéis, empiecen"},
+		{"seguir", Present, "sigo, sigues, sigue, seguimos, seguís, siguen"},
+		{"conocer", Present, "conozco, conoces, conoce, conocemos, conocéis, conocen"},
+		{"construir", Preterite, "construí, construiste, construyó, construimos, construisteis, construyeron"},
+		{"leer", Preterite, "leí, leíste, leyó, leímos, leísteis, leyeron"},
+		{"enviar", Present, "envío, envías, envía, enviamos, enviáis, envían"},
+		{"ser", Imperfect, "era, eras, era, éramos, erais, eran"},
+		{"ir", Present, "voy, vas, va, vamos, vais, van"},
+		{"tener", Future, "tendré, tendrás, tendrá, tendremos, tendréis, tendrán"},
+		{"hacer", Preterite, "hice, hiciste, hizo, hicimos, hicisteis, hicieron"},
+		{"decir", ImperfectSubjunctive, "dijera, dijeras, dijera, dijéramos, dijerais, dijeran"},
+		{"conducir", Preterite, "conduje, condujiste, condujo, condujimos, condujisteis, condujeron"},
+		{"mantener", Imperative, "-, mantén, mantenga, mantengamos, mantened, mantengan"},
+		{"prever", Present, "preveo, prevés, prevé, prevemos, prevéis, prevén"},
+		{"ir", Imperative, "-, ve, vaya, vamos, id, vayan"},
+		{"hablar", NegativeImperative, "-, no hables, no hable, no hablemos, no habléis, no hablen"},
+		{"escribir", PresentPerfect, "he escrito, has escrito, ha escrito, hemos escrito, habéis escrito, han escrito"},
+		{"volver", PluperfectSubjunctive, "hubiera vuelto, hubieras vuelto, hubiera vuelto, hubiéramos vuelto, hubierais vuelto, hubieran vuelto"},
+		{"levantarse", Present, "me levanto, te levantas, se levanta, nos levantamos, os levantáis, se levantan"},
+		{"levantarse", Imperative, "-, levántate, levántese, levantémonos, levantaos, levántense"},
+		{"vestirse", Imperative, "-, vístete, vístase, vistámonos, vestíos, vístanse"},
+		{"adquirir", Present, "adquiero, adquieres, adquiere, adquirimos, adquirís, adquieren"},
+		{"adquirir", Preterite, "adquirí, adquiriste, adquirió, adquirimos, adquiristeis, adquirieron"},
+		{"satisfacer", Present, "satisfago, satisfaces, satisface, satisfacemos, satisfacéis, satisfacen"},
+		{"satisfacer", Preterite, "satisfice, satisficiste, satisfizo, satisficimos, satisficisteis, satisficieron"},
+		{"satisfacer", PresentPerfect, "he satisfecho, has satisfecho, ha satisfecho, hemos satisfecho, habéis satisfecho, han satisfecho"},
+		{"errar", Present, "yerro, yerras, yerra, erramos, erráis, yerran"},
+		{"errar", PresentSubjunctive, "yerre, yerres, yerre, erremos, erréis, yerren"},
+		{"irse", NegativeImperative, "-, no te vayas, no se vaya, no nos vayamos, no os vayáis, no se vayan"},
+	}
+
+	for _, tt := range tests {
+		table, err := Table(tt.verb, tt.tense)
+		if err != nil {
+			t.Fatalf("Table(%q, %s): %v", tt.verb, tt.tense, err)
+		}
+		var got []string
+		for _, c := range table {
+			if c.Form == "" {
+				c.Form = "-"
+			}
+			got = append(got, c.Form)
+		}
+		if strings.Join(got, ", ") != tt.want {
+			t.Errorf("%s %s:\n got %s\nwant %s", tt.verb, tt.tense, strings.Join(got, ", "), tt.want)
+		}
+	}
+}
+
+func TestConjugateNotes(t *testing.T) {
+	c, err := Conjugate("pensar", Present, Yo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Form != "pienso" || !contains(c.Notes, "stem change e → ie") {
+		t.Errorf("pensar yo present = %q %v", c.Form, c.Notes)
+	}
+
+	c, _ = Conjugate("hablar", ImperfectSubjunctive, Yo)
+	if c.Form != "hablara" || len(c.Alternatives) != 1 || c.Alternatives[0] != "hablase" {
+		t.Errorf("hablar yo imperfect subjunctive = %q %v", c.Form, c.Alternatives)
+	}
+
+	if _, err := Conjugate("hablar", Imperative, Yo); !errors.Is(err, ErrNoForm) {
+		t.Errorf("yo command: err = %v, want ErrNoForm", err)
+	}
+	for _, word := range []string{"casa", "rápido", "hablarme"} {
+		if IsInfinitive(word) {
+			t.Errorf("IsInfinitive(%q) = true", word)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	cells := Lookup("hablar", "hablaban")
+	if len(cells) != 1 || cells[0] != (Cell{Imperfect, Ellos}) {
+		t.Errorf("Lookup(hablaban) = %v", cells)
+	}
+	if cells := Lookup("hablar", "habló"); len(cells) != 1 || cells[0] != (Cell{Preterite, El}) {
+		t.Errorf("Lookup(habló) = %v", cells)
+	}
+	if cells := Lookup("hablar", "hablo"); len(cells) != 1 || cells[0] != (Cell{Present, Yo}) {
+		t.Errorf("Lookup(hablo) = %v", cells)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
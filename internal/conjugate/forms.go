@@ -0,0 +1,387 @@
+package conjugate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// form is a conjugated form and the notes explaining how it was built
+type form struct {
+	text  string
+	notes []string
+}
+
+func (f *form) note(n string) {
+	if n == "" {
+		return
+	}
+	for _, existing := range f.notes {
+		if existing == n {
+			return
+		}
+	}
+	f.notes = append(f.notes, n)
+}
+
+// parts notes the stem and ending a form was built from, e.g. "habl- + -o"
+func (f *form) parts(stem, ending string) {
+	f.note(fmt.Sprintf("%s- + -%s", stem, ending))
+}
+
+// Regular endings by verb class
+var endings = map[byte]map[Tense][6]string{
+	'a': {
+		Present:            {"o", "as", "a", "amos", "áis", "an"},
+		Preterite:          {"é", "aste", "ó", "amos", "asteis", "aron"},
+		Imperfect:          {"aba", "abas", "aba", "ábamos", "abais", "aban"},
+		PresentSubjunctive: {"e", "es", "e", "emos", "éis", "en"},
+	},
+	'e': {
+		Present:            {"o", "es", "e", "emos", "éis", "en"},
+		Preterite:          {"í", "iste", "ió", "imos", "isteis", "ieron"},
+		Imperfect:          {"ía", "ías", "ía", "íamos", "íais", "ían"},
+		PresentSubjunctive: {"a", "as", "a", "amos", "áis", "an"},
+	},
+	'i': {
+		Present:            {"o", "es", "e", "imos", "ís", "en"},
+		Preterite:          {"í", "iste", "ió", "imos", "isteis", "ieron"},
+		Imperfect:          {"ía", "ías", "ía", "íamos", "íais", "ían"},
+		PresentSubjunctive: {"a", "as", "a", "amos", "áis", "an"},
+	},
+}
+
+var (
+	futureEndings      = [6]string{"é", "ás", "á", "emos", "éis", "án"}
+	conditionalEndings = [6]string{"ía", "ías", "ía", "íamos", "íais", "ían"}
+	// strongEndings follow irregular preterite stems and are never stressed
+	strongEndings = [6]string{"e", "iste", "o", "imos", "isteis", "ieron"}
+	// pastSubjunctiveEndings follow the preterite ellos form minus -ron
+	pastSubjunctiveEndings = map[string][6]string{
+		"ra": {"ra", "ras", "ra", "ramos", "rais", "ran"},
+		"se": {"se", "ses", "se", "semos", "seis", "sen"},
+		"re": {"re", "res", "re", "remos", "reis", "ren"},
+	}
+)
+
+// boot reports whether a person stresses the stem in the present, where
+// stem changes happen
+func boot(p Person) bool {
+	return p != Nosotros && p != Vosotros
+}
+
+// weakChange reports whether an -ir verb's stem change also shows, weakened,
+// in unstressed forms: sintió, durmamos
+func (v *verb) weakChange() bool {
+	return v.class == 'i' && (v.change == "ie" || v.change == "ue" || v.change == "i")
+}
+
+// uir reports whether the verb inserts y like construir: construyo
+func (v *verb) uir() bool {
+	return v.class == 'i' && strings.HasSuffix(v.plain, "uir") &&
+		!strings.HasSuffix(v.plain, "guir") && !strings.HasSuffix(v.plain, "quir")
+}
+
+// zc reports whether the yo form ends in -zco like conocer: conozco
+func (v *verb) zc() bool {
+	r := []rune(v.stem)
+	return v.class != 'a' && v.change == "" && len(r) > 1 &&
+		r[len(r)-1] == 'c' && isVowel(r[len(r)-2])
+}
+
+// vowelStem reports whether the stem ends in a pronounced vowel, as in
+// leer, oír and construir
+func (v *verb) vowelStem() bool {
+	r := []rune(v.stem)
+	if len(r) == 0 || !isVowel(r[len(r)-1]) {
+		return false
+	}
+	return !strings.HasSuffix(v.stem, "gu") && !strings.HasSuffix(v.stem, "qu")
+}
+
+// pre joins the prefix of a compound verb to a whole form of its base,
+// keeping the stress: ten → mantén
+func (v *verb) pre(s string) string {
+	if v.prefix == "" {
+		return s
+	}
+	return prefixed(v.prefix, s)
+}
+
+func (v *verb) present(p Person) form {
+	var f form
+	if v.irr != nil && v.irr.present != nil {
+		f.text = v.pre(v.irr.present[p])
+		f.note(v.base + " is irregular in the present")
+		return f
+	}
+	if p == Yo && v.irr != nil && v.irr.yo != "" {
+		f.text = v.pre(v.irr.yo)
+		f.note("irregular yo form")
+		return f
+	}
+
+	end := endings[v.class][Present][p]
+	stem := v.stem
+	if boot(p) && v.change != "" {
+		stem = changeStem(stem, v.change, false)
+		f.note(changeNote(v.change, false))
+	}
+	switch {
+	case boot(p) && v.uir():
+		f.text = stem + "y" + end
+		f.note("-uir verbs add y before the ending")
+	case p == Yo && v.zc():
+		f.text = strings.TrimSuffix(stem, "c") + "zco"
+		f.note("a vowel + -cer or -cir gives -zco in the yo form")
+	default:
+		var n string
+		f.text, n = join(stem, end, v.class)
+		f.note(n)
+		f.parts(stem, end)
+	}
+	return f
+}
+
+// yoStem is the present subjunctive stem taken from an irregular yo form
+// (tengo → teng-), or "" when the verb's yo form is regular
+func (v *verb) yoStem() string {
+	switch {
+	case v.irr != nil && v.irr.present != nil:
+		return strings.TrimSuffix(v.pre(v.irr.present[Yo]), "o")
+	case v.irr != nil && v.irr.yo != "":
+		return strings.TrimSuffix(v.pre(v.irr.yo), "o")
+	case v.uir():
+		return v.stem + "y"
+	case v.zc():
+		return strings.TrimSuffix(v.stem, "c") + "zc"
+	}
+	return ""
+}
+
+func (v *verb) presentSubjunctive(p Person) form {
+	var f form
+	if v.irr != nil && v.irr.subjunctive != nil {
+		f.text = v.pre(v.irr.subjunctive[p])
+		f.note(v.base + " is irregular in the present subjunctive")
+		return f
+	}
+
+	end := endings[v.class][PresentSubjunctive][p]
+	if stem := v.yoStem(); stem != "" {
+		f.text = stem + end
+		f.note("built on the yo form " + v.present(Yo).text)
+		f.parts(stem, end)
+		return f
+	}
+
+	stem := v.stem
+	switch {
+	case boot(p) && v.change != "":
+		stem = changeStem(stem, v.change, false)
+		f.note(changeNote(v.change, false))
+	case v.weakChange():
+		stem = changeStem(stem, v.change, true)
+		f.note(changeNote(v.change, true))
+	}
+	var n string
+	f.text, n = join(stem, end, v.class)
+	f.note(n)
+	f.parts(stem, end)
+	return f
+}
+
+// strongStem is the irregular preterite stem, e.g. tuv- for tener
+func (v *verb) strongStem() string {
+	switch {
+	case v.irr != nil && v.irr.pretStem != "":
+		return v.prefix + v.irr.pretStem
+	case strings.HasSuffix(v.plain, "ducir"):
+		return strings.TrimSuffix(v.stem, "c") + "j"
+	}
+	return ""
+}
+
+func (v *verb) preterite(p Person) form {
+	var f form
+	if v.irr != nil && v.irr.preterite != nil {
+		f.text = v.pre(v.irr.preterite[p])
+		f.note(v.base + " is irregular in the preterite")
+		return f
+	}
+
+	if stem := v.strongStem(); stem != "" {
+		end := strongEndings[p]
+		switch {
+		case p == Ellos && strings.HasSuffix(stem, "j"):
+			end = "eron"
+			f.note("-ieron loses its i after j")
+		case p == El && strings.HasSuffix(stem, "c"):
+			stem = strings.TrimSuffix(stem, "c") + "z"
+		}
+		f.text = stem + end
+		f.note(fmt.Sprintf("irregular preterite stem %s- with unstressed endings", stem))
+		return f
+	}
+
+	end := endings[v.class][Preterite][p]
+	stem := v.stem
+	if v.weakChange() && (p == El || p == Ellos) {
+		stem = changeStem(stem, v.change, true)
+		f.note(changeNote(v.change, true))
+	}
+	if v.class != 'a' && v.vowelStem() {
+		switch {
+		case p == El || p == Ellos:
+			end = "y" + strings.TrimPrefix(end, "i")
+			f.note("an unstressed i between vowels becomes y")
+		case strings.HasPrefix(end, "i") && strings.ContainsAny(v.stem[len(v.stem)-1:], "aeo"):
+			end = "í" + strings.TrimPrefix(end, "i")
+		}
+	}
+	var n string
+	f.text, n = join(stem, end, v.class)
+	f.note(n)
+	f.parts(stem, end)
+	return f
+}
+
+func (v *verb) imperfect(p Person) form {
+	var f form
+	if v.irr != nil && v.irr.imperfect != nil {
+		f.text = v.pre(v.irr.imperfect[p])
+		f.note(v.base + " is irregular in the imperfect")
+		return f
+	}
+	end := endings[v.class][Imperfect][p]
+	f.text = v.stem + end
+	f.parts(v.stem, end)
+	return f
+}
+
+// future builds the future and conditional on the infinitive
+func (v *verb) future(t Tense, p Person) form {
+	var f form
+	end := futureEndings[p]
+	if t == Conditional {
+		end = conditionalEndings[p]
+	}
+	if v.irr != nil && v.irr.futureStem != "" {
+		stem := v.prefix + v.irr.futureStem
+		f.text = stem + end
+		f.note(fmt.Sprintf("irregular stem %s- instead of the infinitive", stem))
+		return f
+	}
+	f.text = v.plain + end
+	f.note(fmt.Sprintf("%s + -%s", v.plain, end))
+	return f
+}
+
+// pastSubjunctive builds the imperfect (-ra and -se) and future (-re)
+// subjunctive on the preterite ellos form minus -ron
+func (v *verb) pastSubjunctive(kind string, p Person) form {
+	var f form
+	pret := v.preterite(Ellos).text
+	stem := strings.TrimSuffix(pret, "ron")
+	if p == Nosotros {
+		stem = accentLast(stem)
+	}
+	end := pastSubjunctiveEndings[kind][p]
+	f.text = stem + end
+	f.note(fmt.Sprintf("from the preterite ellos form %s: %s- + -%s", pret, stem, end))
+	return f
+}
+
+func (v *verb) imperative(p Person, negative bool) form {
+	var f form
+	switch {
+	case negative:
+		f = v.presentSubjunctive(p)
+		f.text = "no " + f.text
+		f.note("negative commands use the present subjunctive")
+	case p == Tu && v.irr != nil && v.irr.imperative != "" && (v.prefix == "" || v.base != "decir"):
+		f.text = v.pre(v.irr.imperative)
+		f.note("irregular tú command")
+	case p == Tu:
+		f = v.present(El)
+		f.note("tú commands look like the él present")
+	case p == Vosotros:
+		f.text = strings.TrimSuffix(v.inf, "r") + "d"
+		f.note("the infinitive with -r replaced by -d")
+	case p == Nosotros && v.irr != nil && v.irr.nosotrosImp != "":
+		f.text = v.irr.nosotrosImp
+		f.note("irregular nosotros command")
+	default:
+		f = v.presentSubjunctive(p)
+		f.note("usted, ustedes and nosotros commands use the present subjunctive")
+	}
+	return f
+}
+
+// participle returns the past participle and a note if it is irregular
+func (v *verb) participle() (string, string) {
+	if prefix, base := compound(v.inf, participles, nil); base != "" {
+		return prefix + participles[base], "irregular participle"
+	}
+	switch {
+	case v.class == 'a':
+		return v.stem + "ado", ""
+	case v.vowelStem() && strings.ContainsAny(v.stem[len(v.stem)-1:], "aeo"):
+		return v.stem + "ído", ""
+	}
+	return v.stem + "ido", ""
+}
+
+// gerund returns the -ndo form: hablando, durmiendo, leyendo
+func (v *verb) gerund() string {
+	switch {
+	case v.inf == "ir":
+		return "yendo"
+	case v.base == "poder":
+		return "pudiendo"
+	case v.class == 'a':
+		return v.stem + "ando"
+	}
+	stem := v.stem
+	if v.weakChange() {
+		stem = changeStem(stem, v.change, true)
+	}
+	switch {
+	case strings.HasSuffix(stem, "i"), strings.HasSuffix(stem, "ñ"), strings.HasSuffix(stem, "ll"):
+		return stem + "endo" // riendo, gruñendo
+	case v.vowelStem():
+		return stem + "yendo"
+	}
+	return stem + "iendo"
+}
+
+var reflexivePronouns = [6]string{"me", "te", "se", "nos", "os", "se"}
+
+// reflect adds the reflexive pronoun: before the verb, or attached to the
+// end of affirmative commands
+func (v *verb) reflect(s string, t Tense, p Person) string {
+	pron := reflexivePronouns[p]
+	switch t {
+	case NegativeImperative:
+		return "no " + pron + " " + strings.TrimPrefix(s, "no ")
+	case Imperative:
+	default:
+		return pron + " " + s
+	}
+
+	if v.inf == "ir" && p == Vosotros {
+		return "idos"
+	}
+	w := []rune(s)
+	stress := stressed(w)
+	switch p {
+	case Nosotros:
+		w = []rune(strings.TrimSuffix(s, "s")) // levantemos → levantémonos
+	case Vosotros:
+		w = []rune(strings.TrimSuffix(s, "d")) // levantad → levantaos
+	}
+	w = append(w, []rune(pron)...)
+	if !hasAccent(s) && stress >= 0 && stressed(w) != stress {
+		w[stress] = accented[w[stress]]
+	}
+	return string(w)
+}
@@ -0,0 +1,200 @@
+package conjugate
+
+import "strings"
+
+// join adds an ending to a regular stem, keeping the sound of the stem's
+// last consonant: busc+é → busqué, coj+o from coger, sig+o from seguir.
+// The second result describes the spelling change, if any.
+func join(stem, ending string, class byte) (string, string) {
+	if ending == "" {
+		return stem, ""
+	}
+	first := fold([]rune(ending)[0])
+	front := first == "e" || first == "i"
+
+	type change struct{ from, to, note string }
+	var changes []change
+	switch {
+	case class == 'a' && front:
+		changes = []change{
+			{"gu", "gü", "gu → gü before e"},
+			{"c", "qu", "c → qu before e"},
+			{"g", "gu", "g → gu before e"},
+			{"z", "c", "z → c before e"},
+		}
+	case class != 'a' && (first == "a" || first == "o"):
+		changes = []change{
+			{"gu", "g", "gu → g before a and o"},
+			{"qu", "c", "qu → c before a and o"},
+			{"g", "j", "g → j before a and o"},
+			{"c", "z", "c → z before a and o"},
+		}
+	}
+	for _, c := range changes {
+		if s, ok := strings.CutSuffix(stem, c.from); ok {
+			return s + c.to + ending, "spelling change " + c.note
+		}
+	}
+	return stem + ending, ""
+}
+
+// changeStem applies a stem change to the last matching vowel of stem. The
+// weak change is the one -ir verbs take in unstressed forms: e → i, o → u.
+func changeStem(stem, change string, weak bool) string {
+	var from []string
+	var to string
+	switch change {
+	case "ie":
+		from, to = []string{"e"}, "ie"
+		if weak {
+			to = "i"
+		}
+	case "ue":
+		from, to = []string{"o", "u"}, "ue"
+		if weak {
+			to = "u"
+		}
+	case "i":
+		from, to = []string{"e"}, "i"
+	case "í", "ú":
+		if weak {
+			return stem
+		}
+		from, to = []string{fold([]rune(change)[0])}, change
+	default:
+		return stem
+	}
+	for _, f := range from {
+		if i := strings.LastIndex(stem, f); i >= 0 {
+			return stem[:i] + to + stem[i+len(f):]
+		}
+	}
+	return stem
+}
+
+// changeNote describes a stem change for the explanation of a form
+func changeNote(change string, weak bool) string {
+	switch {
+	case change == "ie" && weak, change == "i":
+		return "stem change e → i"
+	case change == "ie":
+		return "stem change e → ie"
+	case change == "ue" && weak:
+		return "stem change o → u"
+	case change == "ue":
+		return "stem change o → ue"
+	case change == "í":
+		return "the stem's i is stressed: í"
+	case change == "ú":
+		return "the stem's u is stressed: ú"
+	}
+	return ""
+}
+
+func fold(r rune) string {
+	switch r {
+	case 'á':
+		return "a"
+	case 'é':
+		return "e"
+	case 'í':
+		return "i"
+	case 'ó':
+		return "o"
+	case 'ú', 'ü':
+		return "u"
+	}
+	return string(r)
+}
+
+var accented = map[rune]rune{'a': 'á', 'e': 'é', 'i': 'í', 'o': 'ó', 'u': 'ú'}
+
+func hasAccent(s string) bool {
+	return strings.ContainsAny(s, "áéíóú")
+}
+
+func isVowel(r rune) bool {
+	return strings.ContainsRune("aeiouáéíóúü", r)
+}
+
+// isStrong reports whether a vowel keeps its own syllable next to another
+// strong one: a, e, o and stressed í, ú
+func isStrong(r rune) bool {
+	return strings.ContainsRune("aeoáéóíú", r)
+}
+
+// nuclei returns the start and end of each syllable nucleus (a vowel or a
+// diphthong) of w. The u of que, qui, gue and gui is silent.
+func nuclei(w []rune) [][2]int {
+	var out [][2]int
+	for i := 0; i < len(w); i++ {
+		if !isVowel(w[i]) {
+			continue
+		}
+		if w[i] == 'u' && i > 0 && (w[i-1] == 'q' || w[i-1] == 'g') && i+1 < len(w) && (w[i+1] == 'e' || w[i+1] == 'i') {
+			continue
+		}
+		start := i
+		for i+1 < len(w) && isVowel(w[i+1]) && !(isStrong(w[i]) && isStrong(w[i+1])) {
+			i++
+		}
+		out = append(out, [2]int{start, i})
+	}
+	return out
+}
+
+// stressed returns the index of the stressed vowel of w: the written
+// accent if there is one, else the second-to-last syllable of words ending
+// in a vowel, n or s and the last syllable of the rest
+func stressed(w []rune) int {
+	for i, r := range w {
+		if strings.ContainsRune("áéíóú", r) {
+			return i
+		}
+	}
+	ns := nuclei(w)
+	if len(ns) == 0 {
+		return -1
+	}
+	n := ns[len(ns)-1]
+	if last := w[len(w)-1]; len(ns) > 1 && (isVowel(last) || last == 'n' || last == 's') {
+		n = ns[len(ns)-2]
+	}
+	for i := n[0]; i <= n[1]; i++ {
+		if isStrong(w[i]) {
+			return i
+		}
+	}
+	return n[1]
+}
+
+// prefixed adds a prefix to a word, writing an accent where needed to keep
+// the stress on the same vowel: pon → propón, ves → prevés
+func prefixed(prefix, word string) string {
+	if hasAccent(word) {
+		return prefix + word
+	}
+	stress := stressed([]rune(word))
+	w := []rune(prefix + word)
+	if stress < 0 {
+		return string(w)
+	}
+	stress += len([]rune(prefix))
+	if stressed(w) != stress {
+		w[stress] = accented[w[stress]]
+	}
+	return string(w)
+}
+
+// accentLast writes an accent on the last vowel of s: habla → hablá for
+// habláramos
+func accentLast(s string) string {
+	w := []rune(s)
+	for i := len(w) - 1; i >= 0; i-- {
+		if a, ok := accented[w[i]]; ok {
+			w[i] = a
+			break
+		}
+	}
+	return string(w)
+}
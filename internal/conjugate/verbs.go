@@ -0,0 +1,179 @@
+package conjugate
+
+import "strings"
+
+// irregular holds the forms of a verb the rules can't derive. Empty fields
+// fall back to the rules.
+type irregular struct {
+	present     *[6]string // Whole present indicative
+	yo          string     // Only the present yo form, e.g. "tengo"
+	preterite   *[6]string // Whole preterite
+	pretStem    string     // Strong preterite stem, e.g. "tuv"
+	imperfect   *[6]string
+	futureStem  string     // Future and conditional stem, e.g. "tendr"
+	subjunctive *[6]string // Whole present subjunctive
+	imperative  string     // Affirmative tú form, e.g. "ten"
+	nosotrosImp string     // Affirmative nosotros form, e.g. "vamos"
+	participle  string
+	compounds   bool // Prefixed verbs conjugate alike, e.g. mantener, proponer
+}
+
+var irregulars = map[string]*irregular{
+	"ser": {
+		present:     &[6]string{"soy", "eres", "es", "somos", "sois", "son"},
+		preterite:   &[6]string{"fui", "fuiste", "fue", "fuimos", "fuisteis", "fueron"},
+		imperfect:   &[6]string{"era", "eras", "era", "éramos", "erais", "eran"},
+		subjunctive: &[6]string{"sea", "seas", "sea", "seamos", "seáis", "sean"},
+		imperative:  "sé",
+	},
+	"ir": {
+		present:     &[6]string{"voy", "vas", "va", "vamos", "vais", "van"},
+		preterite:   &[6]string{"fui", "fuiste", "fue", "fuimos", "fuisteis", "fueron"},
+		imperfect:   &[6]string{"iba", "ibas", "iba", "íbamos", "ibais", "iban"},
+		subjunctive: &[6]string{"vaya", "vayas", "vaya", "vayamos", "vayáis", "vayan"},
+		imperative:  "ve",
+		nosotrosImp: "vamos",
+	},
+	"estar": {
+		present:     &[6]string{"estoy", "estás", "está", "estamos", "estáis", "están"},
+		pretStem:    "estuv",
+		subjunctive: &[6]string{"esté", "estés", "esté", "estemos", "estéis", "estén"},
+	},
+	"haber": {
+		present:     &[6]string{"he", "has", "ha", "hemos", "habéis", "han"},
+		pretStem:    "hub",
+		futureStem:  "habr",
+		subjunctive: &[6]string{"haya", "hayas", "haya", "hayamos", "hayáis", "hayan"},
+		imperative:  "he",
+	},
+	"dar": {
+		present:     &[6]string{"doy", "das", "da", "damos", "dais", "dan"},
+		preterite:   &[6]string{"di", "diste", "dio", "dimos", "disteis", "dieron"},
+		subjunctive: &[6]string{"dé", "des", "dé", "demos", "deis", "den"},
+	},
+	"ver": {
+		present:    &[6]string{"veo", "ves", "ve", "vemos", "veis", "ven"},
+		preterite:  &[6]string{"vi", "viste", "vio", "vimos", "visteis", "vieron"},
+		imperfect:  &[6]string{"veía", "veías", "veía", "veíamos", "veíais", "veían"},
+		participle: "visto",
+		compounds:  true, // prever
+	},
+	"saber": {
+		yo:          "sé",
+		pretStem:    "sup",
+		futureStem:  "sabr",
+		subjunctive: &[6]string{"sepa", "sepas", "sepa", "sepamos", "sepáis", "sepan"},
+	},
+	"oír": {
+		present: &[6]string{"oigo", "oyes", "oye", "oímos", "oís", "oyen"},
+	},
+	"oler": {
+		present:     &[6]string{"huelo", "hueles", "huele", "olemos", "oléis", "huelen"},
+		subjunctive: &[6]string{"huela", "huelas", "huela", "olamos", "oláis", "huelan"},
+	},
+	"errar": {
+		present:     &[6]string{"yerro", "yerras", "yerra", "erramos", "erráis", "yerran"},
+		subjunctive: &[6]string{"yerre", "yerres", "yerre", "erremos", "erréis", "yerren"},
+	},
+	"adquirir": {
+		present:     &[6]string{"adquiero", "adquieres", "adquiere", "adquirimos", "adquirís", "adquieren"},
+		subjunctive: &[6]string{"adquiera", "adquieras", "adquiera", "adquiramos", "adquiráis", "adquieran"},
+	},
+	"satisfacer": {
+		yo:         "satisfago",
+		pretStem:   "satisfic",
+		futureStem: "satisfar",
+		imperative: "satisfaz",
+		participle: "satisfecho",
+	},
+	"reír": {
+		present:     &[6]string{"río", "ríes", "ríe", "reímos", "reís", "ríen"},
+		preterite:   &[6]string{"reí", "reíste", "rio", "reímos", "reísteis", "rieron"},
+		subjunctive: &[6]string{"ría", "rías", "ría", "riamos", "riáis", "rían"},
+		compounds:   true, // sonreír, freír
+	},
+	"tener":  {yo: "tengo", pretStem: "tuv", futureStem: "tendr", imperative: "ten", compounds: true},
+	"venir":  {yo: "vengo", pretStem: "vin", futureStem: "vendr", imperative: "ven", compounds: true},
+	"poner":  {yo: "pongo", pretStem: "pus", futureStem: "pondr", imperative: "pon", participle: "puesto", compounds: true},
+	"hacer":  {yo: "hago", pretStem: "hic", futureStem: "har", imperative: "haz", participle: "hecho", compounds: true},
+	"decir":  {yo: "digo", pretStem: "dij", futureStem: "dir", imperative: "di", participle: "dicho", compounds: true},
+	"salir":  {yo: "salgo", futureStem: "saldr", imperative: "sal", compounds: true},
+	"traer":  {yo: "traigo", pretStem: "traj", compounds: true},
+	"caer":   {yo: "caigo", compounds: true},
+	"valer":  {yo: "valgo", futureStem: "valdr", compounds: true},
+	"poder":  {pretStem: "pud", futureStem: "podr"},
+	"querer": {pretStem: "quis", futureStem: "querr"},
+	"caber":  {yo: "quepo", pretStem: "cup", futureStem: "cabr"},
+	"andar":  {pretStem: "anduv"},
+}
+
+// stemChanges lists the stem-changing verbs. The change applies to the
+// stressed stem vowel (present "boot" forms); -ir verbs also take the weak
+// change (e → i, o → u) in the preterite and some subjunctive forms.
+// "í" and "ú" mark -iar and -uar verbs that stress the i or u.
+var stemChanges = map[string]string{
+	// e → ie
+	"pensar": "ie", "empezar": "ie", "comenzar": "ie", "cerrar": "ie", "despertar": "ie",
+	"entender": "ie", "perder": "ie", "querer": "ie", "preferir": "ie", "sentir": "ie",
+	"mentir": "ie", "divertir": "ie", "convertir": "ie", "sugerir": "ie", "advertir": "ie",
+	"recomendar": "ie", "negar": "ie", "nevar": "ie", "sentar": "ie", "atravesar": "ie",
+	"defender": "ie", "encender": "ie", "gobernar": "ie", "calentar": "ie", "confesar": "ie",
+	"regar": "ie", "tropezar": "ie", "fregar": "ie", "consentir": "ie", "arrepentir": "ie",
+	"herir": "ie", "hervir": "ie", "referir": "ie", "requerir": "ie", "tener": "ie",
+	"venir": "ie", "merendar": "ie", "temblar": "ie", "apretar": "ie", "descender": "ie",
+	"errar":    "ie", // ie → ye at the start of the word: yerro
+	"adquirir": "ie", // i → ie: adquiero
+	// o → ue, u → ue
+	"poder": "ue", "contar": "ue", "costar": "ue", "encontrar": "ue", "mostrar": "ue",
+	"recordar": "ue", "probar": "ue", "soñar": "ue", "volar": "ue", "acostar": "ue",
+	"almorzar": "ue", "volver": "ue", "devolver": "ue", "envolver": "ue", "resolver": "ue",
+	"mover": "ue", "llover": "ue", "doler": "ue", "morder": "ue", "dormir": "ue",
+	"morir": "ue", "soler": "ue", "torcer": "ue", "colgar": "ue", "rogar": "ue",
+	"sonar": "ue", "demostrar": "ue", "aprobar": "ue", "comprobar": "ue", "renovar": "ue",
+	"promover": "ue", "jugar": "ue", "forzar": "ue", "esforzar": "ue", "acordar": "ue",
+	"cocer": "ue", "oler": "ue",
+	// e → i
+	"pedir": "i", "servir": "i", "repetir": "i", "seguir": "i", "conseguir": "i",
+	"perseguir": "i", "vestir": "i", "medir": "i", "competir": "i", "elegir": "i",
+	"corregir": "i", "despedir": "i", "impedir": "i", "gemir": "i", "decir": "i",
+	"reír": "i", "rendir": "i", "concebir": "i",
+	// Stressed i or u
+	"enviar": "í", "confiar": "í", "guiar": "í", "variar": "í", "esquiar": "í",
+	"criar": "í", "fiar": "í", "desviar": "í", "ampliar": "í", "resfriar": "í",
+	"continuar": "ú", "actuar": "ú", "graduar": "ú", "evaluar": "ú", "situar": "ú",
+	"acentuar": "ú", "insinuar": "ú",
+}
+
+// participles are the irregular past participles. Prefixed verbs share
+// them: describir → descrito, devolver → devuelto.
+var participles = map[string]string{
+	"abrir": "abierto", "cubrir": "cubierto", "decir": "dicho", "escribir": "escrito",
+	"hacer": "hecho", "morir": "muerto", "poner": "puesto", "romper": "roto",
+	"ver": "visto", "volver": "vuelto", "scribir": "scrito", "solver": "suelto", "freír": "frito",
+	"satisfacer": "satisfecho", "imprimir": "impreso", "pudrir": "podrido",
+}
+
+// prefixes that form compounds of irregular verbs, e.g. man+tener
+var prefixes = []string{
+	"a", "ab", "abs", "com", "con", "contra", "de", "des", "dis", "di", "en", "entre",
+	"equi", "ex", "f", "im", "in", "inter", "man", "o", "ob", "pos", "pre", "pro", "re",
+	"sobre", "son", "sos", "su", "sus", "tran", "tras", "trans",
+}
+
+// compound splits a verb into a prefix and a base found in table, trying
+// the verb itself first. ok, if set, says which bases take prefixes.
+func compound[T any](inf string, table map[string]T, ok func(T) bool) (prefix, base string) {
+	if _, found := table[inf]; found {
+		return "", inf
+	}
+	for _, p := range prefixes {
+		rest, found := strings.CutPrefix(inf, p)
+		if !found {
+			continue
+		}
+		if v, found := table[rest]; found && (ok == nil || ok(v)) {
+			return p, rest
+		}
+	}
+	return "", ""
+}
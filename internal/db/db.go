@@ -16,7 +16,9 @@ var DB *sql.DB
 
 func Init(path string) error {
 	var err error
-	DB, err = sql.Open("sqlite", path)
+	// Background job workers write concurrently with requests, so wait for
+	// locks instead of failing with SQLITE_BUSY
+	DB, err = sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)")
 	if err != nil {
 		return err
 	}
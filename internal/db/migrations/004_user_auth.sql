@@ -0,0 +1,19 @@
+-- Multi-user accounts: password hashes, cookie sessions and card ownership
+
+-- bcrypt hash of the user's password (NULL for legacy accounts that have not set one)
+ALTER TABLE users ADD COLUMN password_hash TEXT;
+
+-- Login sessions, referenced by the session cookie
+CREATE TABLE IF NOT EXISTS user_sessions (
+    token TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user ON user_sessions(user_id);
+
+-- Who added a card (NULL for the shared curriculum and song vocabulary)
+ALTER TABLE cards ADD COLUMN user_id INTEGER REFERENCES users(id);
+
+CREATE INDEX IF NOT EXISTS idx_cards_user ON cards(user_id);
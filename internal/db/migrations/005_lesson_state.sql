@@ -0,0 +1,45 @@
+-- Persist in-progress daily and song lessons so they survive restarts
+-- and can be resumed from another tab or device
+
+-- Cards of a daily lesson in their shown order, with assigned mode and result
+CREATE TABLE IF NOT EXISTS lesson_session_cards (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id INTEGER NOT NULL REFERENCES lesson_sessions(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    card_id INTEGER NOT NULL REFERENCES cards(id) ON DELETE CASCADE,
+    mode TEXT NOT NULL,
+    is_new INTEGER DEFAULT 0,
+    is_song_vocab INTEGER DEFAULT 0,
+    song_title TEXT,
+    rating INTEGER,              -- NULL until answered (or skipped)
+    time_spent_ms INTEGER DEFAULT 0,
+    xp_earned INTEGER DEFAULT 0,
+    answered_at DATETIME,        -- set when answered or skipped
+    UNIQUE(session_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_lesson_session_cards_session ON lesson_session_cards(session_id, position);
+
+-- Position within a song lesson
+ALTER TABLE song_sessions ADD COLUMN current_phase TEXT;
+ALTER TABLE song_sessions ADD COLUMN current_index INTEGER DEFAULT 0;
+
+-- Vocab cards and blanks of a song lesson, with answers
+CREATE TABLE IF NOT EXISTS song_session_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id INTEGER NOT NULL REFERENCES song_sessions(id) ON DELETE CASCADE,
+    item_type TEXT NOT NULL CHECK(item_type IN ('vocab', 'blank')),
+    position INTEGER NOT NULL,
+    vocab_id INTEGER REFERENCES song_vocabulary(id) ON DELETE CASCADE,
+    mode TEXT,                   -- vocab: standard/reverse
+    line_id INTEGER REFERENCES song_lines(id) ON DELETE CASCADE,
+    blank_word TEXT,
+    blank_index INTEGER,
+    rating INTEGER,              -- vocab answer
+    user_answer TEXT,            -- blank answer
+    is_correct INTEGER DEFAULT 0,
+    answered_at DATETIME,
+    UNIQUE(session_id, item_type, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_song_session_items_session ON song_session_items(session_id, item_type, position);
@@ -0,0 +1,12 @@
+-- Per-user FSRS weights fitted from review_logs by the optimizer
+
+CREATE TABLE IF NOT EXISTS user_fsrs_params (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    weights TEXT NOT NULL,               -- JSON array of 19 FSRS weights
+    review_count INTEGER NOT NULL,       -- Reviews the weights were trained on
+    log_loss_before REAL NOT NULL,       -- With default weights
+    log_loss_after REAL NOT NULL,
+    rmse_before REAL NOT NULL,
+    rmse_after REAL NOT NULL,
+    optimized_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,33 @@
+-- Curricula as data: built-in plans plus user-defined ones
+
+CREATE TABLE IF NOT EXISTS curricula (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    slug TEXT UNIQUE,                                   -- Stable key for built-in curricula
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE, -- Owner; NULL for built-in
+    phases TEXT NOT NULL,                               -- JSON array of phases
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_curricula_user ON curricula(user_id);
+
+-- Which curriculum a journey follows (NULL = the default 14-day sprint)
+ALTER TABLE curriculum_journey ADD COLUMN curriculum_id INTEGER REFERENCES curricula(id);
+
+-- Built-in curricula
+INSERT OR IGNORE INTO curricula (slug, name, description, phases) VALUES
+    ('sprint-14', '14-Day Sprint', 'Intensive sprint through the 1000 most frequent words (~70 new words/day)', '[
+        {"id": 1, "name": "Sprint Week 1", "description": "Core 500 most frequent words", "start_day": 1, "end_day": 7, "new_cards_per_day": 72, "target_islands": [1, 2, 3], "mode_weights": {"standard": 60, "reverse": 30, "typing": 10}},
+        {"id": 2, "name": "Sprint Week 2", "description": "Advanced 500 words + Review", "start_day": 8, "end_day": 14, "new_cards_per_day": 72, "target_islands": [4, 5, 6, 7, 8, 9], "mode_weights": {"standard": 50, "reverse": 35, "typing": 15}}
+    ]'),
+    ('relaxed-30', '30-Day Relaxed', 'A calmer month covering the core vocabulary (~30 new words/day)', '[
+        {"id": 1, "name": "Foundations", "description": "Core essentials and common words", "start_day": 1, "end_day": 10, "new_cards_per_day": 25, "target_islands": [1, 2, 3], "mode_weights": {"standard": 70, "reverse": 25, "typing": 5}},
+        {"id": 2, "name": "Building Up", "description": "Expanding vocabulary", "start_day": 11, "end_day": 20, "new_cards_per_day": 30, "target_islands": [3, 4, 5], "mode_weights": {"standard": 55, "reverse": 30, "typing": 15}},
+        {"id": 3, "name": "Stretch", "description": "Remaining islands", "start_day": 21, "end_day": 30, "new_cards_per_day": 30, "target_islands": [6, 7, 8, 9], "mode_weights": {"standard": 45, "reverse": 35, "typing": 20}}
+    ]'),
+    ('steady-90', '90-Day Steady', 'Slow and steady over three months (~12 new words/day)', '[
+        {"id": 1, "name": "Month 1", "description": "Core essentials and common words", "start_day": 1, "end_day": 30, "new_cards_per_day": 12, "target_islands": [1, 2, 3], "mode_weights": {"standard": 70, "reverse": 25, "typing": 5}},
+        {"id": 2, "name": "Month 2", "description": "Expanding vocabulary", "start_day": 31, "end_day": 60, "new_cards_per_day": 12, "target_islands": [4, 5, 6], "mode_weights": {"standard": 55, "reverse": 30, "typing": 15}},
+        {"id": 3, "name": "Month 3", "description": "Advanced islands", "start_day": 61, "end_day": 90, "new_cards_per_day": 12, "target_islands": [7, 8, 9], "mode_weights": {"standard": 45, "reverse": 35, "typing": 20}}
+    ]');
@@ -0,0 +1,12 @@
+-- AI responses that failed schema validation, kept for prompt debugging
+
+CREATE TABLE IF NOT EXISTS ai_rejections (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task TEXT NOT NULL,                  -- e.g. bridges, mcq, lyrics_translation
+    attempt INTEGER NOT NULL,            -- 1-based attempt that was rejected
+    reason TEXT NOT NULL,                -- Decode or validation error
+    response TEXT NOT NULL,              -- Raw model output
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_rejections_task ON ai_rejections(task, created_at);
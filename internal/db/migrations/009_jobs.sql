@@ -0,0 +1,28 @@
+-- Persistent background job queue for AI and lyrics work
+
+CREATE TABLE IF NOT EXISTS job_batches (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,                                      -- e.g. "Bridges for 50 cards"
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,  -- Who started it; NULL from the CLI
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    batch_id INTEGER REFERENCES job_batches(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,                                      -- bridges, example, question, grammar, lyrics, translate_lyrics
+    payload TEXT NOT NULL DEFAULT '{}',                      -- JSON arguments for the kind
+    status TEXT NOT NULL DEFAULT 'pending',                  -- pending, running, done, failed, cancelled
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 4,
+    last_error TEXT NOT NULL DEFAULT '',
+    run_after DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,   -- Backoff: not claimed before this
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    started_at DATETIME,
+    finished_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_pending ON jobs(status, run_after);
+CREATE INDEX IF NOT EXISTS idx_jobs_batch ON jobs(batch_id);
+CREATE INDEX IF NOT EXISTS idx_jobs_user ON jobs(user_id, created_at);
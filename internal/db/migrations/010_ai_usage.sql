@@ -0,0 +1,23 @@
+-- Content-addressed cache of AI responses and a ledger of every AI call
+
+CREATE TABLE IF NOT EXISTS ai_cache (
+    key TEXT PRIMARY KEY,                    -- SHA-256 of provider, model, schema and prompt
+    feature TEXT NOT NULL,
+    model TEXT NOT NULL,
+    response TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS ai_usage (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    feature TEXT NOT NULL,                   -- bridges, mcq, grammar, lyrics_translation, ...
+    provider TEXT NOT NULL,
+    model TEXT NOT NULL,
+    input_tokens INTEGER NOT NULL DEFAULT 0,
+    output_tokens INTEGER NOT NULL DEFAULT 0,
+    cost_usd REAL NOT NULL DEFAULT 0,        -- Estimated from the model's list price
+    cached INTEGER NOT NULL DEFAULT 0,       -- Served from ai_cache, nothing billed
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_usage_created ON ai_usage(created_at);
@@ -0,0 +1,37 @@
+-- Conjugation drills: FSRS progress for each verb card and tense, and a log
+-- of the answers
+
+CREATE TABLE IF NOT EXISTS conjugation_progress (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    card_id INTEGER NOT NULL REFERENCES cards(id) ON DELETE CASCADE,
+    tense TEXT NOT NULL,                 -- present, preterite, present_subjunctive, ...
+    stability REAL DEFAULT 0,
+    difficulty REAL DEFAULT 0,
+    elapsed_days INTEGER DEFAULT 0,
+    scheduled_days INTEGER DEFAULT 0,
+    reps INTEGER DEFAULT 0,
+    lapses INTEGER DEFAULT 0,
+    state TEXT DEFAULT 'new' CHECK(state IN ('new', 'learning', 'review', 'relearning')),
+    due DATETIME,
+    last_review DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, card_id, tense)
+);
+
+CREATE INDEX IF NOT EXISTS idx_conjugation_progress_user_due ON conjugation_progress(user_id, due);
+
+CREATE TABLE IF NOT EXISTS conjugation_logs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    card_id INTEGER NOT NULL REFERENCES cards(id) ON DELETE CASCADE,
+    tense TEXT NOT NULL,
+    person INTEGER NOT NULL,             -- 0 yo to 5 ellos
+    answer TEXT NOT NULL,
+    expected TEXT NOT NULL,
+    rating INTEGER NOT NULL CHECK(rating IN (1, 2, 3, 4)),
+    review_duration_ms INTEGER,
+    reviewed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_conjugation_logs_user ON conjugation_logs(user_id, reviewed_at);
@@ -0,0 +1,26 @@
+-- Words of song lines linked to the shared cards their dictionary form
+-- matches (quiero → querer), and the words that match no card
+
+CREATE TABLE IF NOT EXISTS song_line_words (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    song_id INTEGER NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    line_id INTEGER NOT NULL REFERENCES song_lines(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,           -- Index among the line's whitespace-separated words
+    word TEXT NOT NULL,                  -- Lowercased, punctuation trimmed
+    lemma TEXT NOT NULL,                 -- The card term it matched, e.g. querer
+    card_id INTEGER NOT NULL REFERENCES cards(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_song_line_words_song ON song_line_words(song_id);
+CREATE INDEX IF NOT EXISTS idx_song_line_words_card ON song_line_words(card_id);
+
+CREATE TABLE IF NOT EXISTS song_unmatched_words (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    song_id INTEGER NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    word TEXT NOT NULL,
+    occurrences INTEGER NOT NULL DEFAULT 1,
+    UNIQUE(song_id, word)
+);
+
+-- When the song's words were last linked; cards added since mean relinking
+ALTER TABLE songs ADD COLUMN words_linked_at DATETIME;
@@ -0,0 +1,11 @@
+-- Word timings of song lines, from the <mm:ss.xx> tags of enhanced LRC
+
+CREATE TABLE IF NOT EXISTS song_word_timings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    line_id INTEGER NOT NULL REFERENCES song_lines(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,           -- Order within the line
+    text TEXT NOT NULL,                  -- A word or syllable as written
+    start_time_ms INTEGER NOT NULL,
+    end_time_ms INTEGER NOT NULL,
+    UNIQUE(line_id, position)
+);
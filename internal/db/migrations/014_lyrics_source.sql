@@ -0,0 +1,2 @@
+-- Where a song's lyrics came from: lrc_file, id3_sylt, id3_uslt or lrclib
+ALTER TABLE songs ADD COLUMN lyrics_source TEXT;
@@ -0,0 +1,13 @@
+-- Saved states of a song's lines, so edits in the timing editor can be
+-- undone. The first edit also records the lines as they were imported.
+
+CREATE TABLE IF NOT EXISTS song_line_versions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    song_id INTEGER NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL, -- NULL for the imported lines
+    note TEXT NOT NULL DEFAULT '',       -- e.g. "Split line 4"
+    lines TEXT NOT NULL,                 -- JSON array of the lines with their word timings
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_song_line_versions_song ON song_line_versions(song_id, id);
@@ -0,0 +1,11 @@
+-- Songs are scheduled with FSRS like cards, which also needs the days
+-- since the last review and the days it was scheduled for
+ALTER TABLE song_progress ADD COLUMN elapsed_days INTEGER DEFAULT 0;
+ALTER TABLE song_progress ADD COLUMN scheduled_days INTEGER DEFAULT 0;
+
+UPDATE song_progress
+SET scheduled_days = MAX(CAST(ROUND(julianday(substr(due, 1, 19)) - julianday(substr(last_review, 1, 19))) AS INTEGER), 0)
+WHERE due IS NOT NULL AND last_review IS NOT NULL;
+
+-- The old formula never set a difficulty; start reviewed songs in the middle
+UPDATE song_progress SET difficulty = 5 WHERE reps > 0 AND difficulty = 0;
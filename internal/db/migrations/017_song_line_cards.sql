@@ -0,0 +1,9 @@
+-- Each song line is also a sentence card (cards.source = 'song_line') with
+-- its own progress and review log
+ALTER TABLE song_lines ADD COLUMN card_id INTEGER REFERENCES cards(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_song_lines_card ON song_lines(card_id);
+
+-- The lines a song lesson studies, picked by mastery, as a JSON array of
+-- line ids; NULL means every line
+ALTER TABLE song_sessions ADD COLUMN line_ids TEXT;
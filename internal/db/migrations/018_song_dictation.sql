@@ -0,0 +1,51 @@
+-- Dictation lessons: the learner types each line as it plays. The mode is
+-- checked by the table, so song_sessions is rebuilt to allow it.
+PRAGMA foreign_keys = OFF;
+
+CREATE TABLE song_sessions_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    song_id INTEGER NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    session_date DATE NOT NULL,
+    mode TEXT NOT NULL CHECK(mode IN ('vocab', 'lyrics', 'listening', 'full', 'dictation')),
+    vocab_reviewed INTEGER DEFAULT 0,
+    vocab_correct INTEGER DEFAULT 0,
+    lines_studied INTEGER DEFAULT 0,
+    blanks_correct INTEGER DEFAULT 0,
+    blanks_total INTEGER DEFAULT 0,
+    xp_earned INTEGER DEFAULT 0,
+    completed_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    current_phase TEXT,
+    current_index INTEGER DEFAULT 0,
+    line_ids TEXT,
+    dictation_correct INTEGER DEFAULT 0, -- Words typed right
+    dictation_total INTEGER DEFAULT 0    -- Words dictated
+);
+
+INSERT INTO song_sessions_new (
+    id, user_id, song_id, session_date, mode, vocab_reviewed, vocab_correct, lines_studied,
+    blanks_correct, blanks_total, xp_earned, completed_at, created_at, current_phase, current_index, line_ids
+)
+SELECT id, user_id, song_id, session_date, mode, vocab_reviewed, vocab_correct, lines_studied,
+       blanks_correct, blanks_total, xp_earned, completed_at, created_at, current_phase, current_index, line_ids
+FROM song_sessions;
+
+DROP TABLE song_sessions;
+ALTER TABLE song_sessions_new RENAME TO song_sessions;
+CREATE INDEX IF NOT EXISTS idx_song_sessions_user_date ON song_sessions(user_id, session_date);
+
+PRAGMA foreign_keys = ON;
+
+-- The lines of a dictation lesson, with what was typed
+CREATE TABLE IF NOT EXISTS song_session_dictations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id INTEGER NOT NULL REFERENCES song_sessions(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    line_id INTEGER NOT NULL REFERENCES song_lines(id) ON DELETE CASCADE,
+    user_answer TEXT,
+    words_correct INTEGER DEFAULT 0,
+    words_total INTEGER DEFAULT 0,
+    answered_at DATETIME,
+    UNIQUE(session_id, position)
+);
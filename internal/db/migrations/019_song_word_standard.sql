@@ -0,0 +1,5 @@
+-- The standard spelling of a colloquial lyric word (pa' → para, vel → ver),
+-- NULL when written the standard way. Songs are relinked to fill it in.
+ALTER TABLE song_line_words ADD COLUMN standard TEXT;
+
+UPDATE songs SET words_linked_at = NULL;
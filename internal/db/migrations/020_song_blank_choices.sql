@@ -0,0 +1,3 @@
+-- The options of a multiple-choice blank as a JSON array, NULL for a blank
+-- to type
+ALTER TABLE song_session_items ADD COLUMN choices TEXT;
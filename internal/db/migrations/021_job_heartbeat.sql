@@ -0,0 +1,4 @@
+-- Refreshed while a process runs the job, so a server starting up can tell
+-- jobs a crash left running from ones another process (e.g. a CLI batch)
+-- is still working on
+ALTER TABLE jobs ADD COLUMN heartbeat_at DATETIME;
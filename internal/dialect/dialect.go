@@ -0,0 +1,202 @@
+// Package dialect maps the colloquial spellings of Caribbean Spanish, as
+// written in reggaeton lyrics, to standard Spanish: elisions (pa' → para,
+// to' → todo, na' → nada), -ao for -ado (cansao → cansado), a dropped final
+// -s (vamo → vamos) or -d (verdá → verdad), an aspirated s written h (ehto
+// → esto) and l for r at the end of a syllable (vel → ver, amol → amor).
+// The readings are guesses, some safer than others (see Rule.Certain), so
+// callers check them against a lexicon where they can, as with package
+// lemma.
+package dialect
+
+import (
+	"strings"
+
+	"languagepapi/internal/lemma"
+)
+
+// Rule is the change that turns a colloquial spelling into a standard one
+type Rule string
+
+const (
+	RuleElision    Rule = "elision"    // pa' → para
+	RuleAdo        Rule = "ado"        // cansao → cansado, perdío → perdido
+	RuleFinalS     Rule = "final_s"    // vamo → vamos
+	RuleFinalD     Rule = "final_d"    // verdá → verdad
+	RuleAspiration Rule = "aspiration" // ehto → esto
+	RuleLambda     Rule = "lambdacism" // vel → ver
+)
+
+// Certain reports whether the rule can be trusted without a lexicon: the
+// spellings it reads are seldom standard words. A final s or an r can be
+// dropped or swapped from words that are (la for las, mal for mar).
+func (r Rule) Certain() bool {
+	return r != RuleFinalS && r != RuleLambda
+}
+
+// Reading is a standard spelling a colloquial word may stand for
+type Reading struct {
+	Standard string
+	Rule     Rule
+}
+
+// elisions are clipped words, without their apostrophe, and the words they
+// stand for. They are certain.
+var elisions = map[string]string{
+	"pa":    "para",
+	"to":    "todo",
+	"toa":   "toda",
+	"tos":   "todos",
+	"toas":  "todas",
+	"na":    "nada",
+	"toy":   "estoy",
+	"tas":   "estás",
+	"ta":    "está",
+	"tamo":  "estamos",
+	"tamos": "estamos",
+	"tan":   "están",
+	"d":     "de",
+	"q":     "que",
+	"k":     "que",
+	"xq":    "porque",
+	"pq":    "porque",
+}
+
+// standardWords are frequent standard words that a dropped final s or a
+// swapped r would misread: la is not las, no is not nos, mal is not mar.
+// The uncertain rules leave them alone. It is not a lexicon, just the words
+// lyrics are full of.
+var standardWords = func() map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.Fields(`
+		a e o u la lo le me te se mi tu su de no ni si sí ya yo
+		que qué quien quién cuando cuándo como cómo donde dónde porque
+		para pero sino hasta entre sobre desde contra hacia bajo ante
+		una uno ella esa ese eso esta este esto aquella aquello
+		nada todo toda algo poco mucho mucha otro otra mismo misma cada casi
+		siempre nunca ahora aquí allí ahí acá allá tarde noche
+		mía mío tuya tuyo suya suyo nuestra nuestro
+		el al del aquel mal sol tal cual mil alma alto calma culpa palma
+	`) {
+		words[w] = true
+	}
+	return words
+}()
+
+// Standard lists the standard spellings a lowercased word may stand for,
+// likeliest first. A word already standard usually has none; the word
+// itself is never listed.
+func Standard(word string) []Reading {
+	w := strings.NewReplacer("'", "", "’", "").Replace(word)
+	if w == "" {
+		return nil
+	}
+
+	var out []Reading
+	add := func(s string, rule Rule) {
+		if s == word || s == w {
+			return
+		}
+		for _, r := range out {
+			if r.Standard == s {
+				return
+			}
+		}
+		out = append(out, Reading{Standard: s, Rule: rule})
+	}
+
+	if s, ok := elisions[w]; ok {
+		add(s, RuleElision)
+	}
+	for _, e := range []struct{ from, to string }{
+		{"aos", "ados"}, {"ao", "ado"}, {"ía", "ida"}, {"ío", "ido"}, {"íos", "idos"},
+	} {
+		if base, ok := strings.CutSuffix(w, e.from); ok && len([]rune(base)) >= 3 {
+			add(base+e.to, RuleAdo)
+		}
+	}
+	if base, ok := strings.CutSuffix(w, "á"); ok && base != "" {
+		add(base+"ad", RuleFinalD)
+	}
+	if base, ok := strings.CutSuffix(w, "é"); ok && base != "" {
+		add(base+"ed", RuleFinalD)
+	}
+	if s := aspirated(w); s != w {
+		add(s, RuleAspiration)
+	}
+	if standardWords[w] {
+		return out
+	}
+	for _, s := range lambdacisms(w) {
+		add(s, RuleLambda)
+	}
+	if len([]rune(w)) >= 2 && isVowel(lastRune(w)) {
+		add(w+"s", RuleFinalS)
+	}
+	return out
+}
+
+// Match returns the likeliest reading of a word whose lemma is known, as
+// lemma.Match does, reading it as standard Spanish if the word as written
+// matches nothing. standard is the spelling matched, "" if the word's own.
+func Match(word string, known func(lemma string) bool) (a lemma.Analysis, standard string, ok bool) {
+	if a, ok := lemma.Match(word, known); ok {
+		return a, "", true
+	}
+	for _, r := range Standard(lemma.Clean(word)) {
+		if a, ok := lemma.Match(r.Standard, known); ok {
+			return a, r.Standard, true
+		}
+	}
+	return lemma.Analysis{}, "", false
+}
+
+// aspirated writes s for an h sounded like one, before a consonant: ehto
+// → esto, mihmo → mismo. The h of ch is left alone.
+func aspirated(w string) string {
+	r := []rune(w)
+	for i := 1; i+1 < len(r); i++ {
+		if r[i] == 'h' && r[i-1] != 'c' && isVowel(r[i-1]) && !isVowel(r[i+1]) {
+			r[i] = 's'
+		}
+	}
+	return string(r)
+}
+
+// lambdacisms reads each l ending a syllable, before a consonant or at the
+// end of the word, as r: puelta → puerta, amol → amor. All of them are
+// read as r first, then each on its own.
+func lambdacisms(w string) []string {
+	r := []rune(w)
+	var at []int
+	for i := 1; i < len(r); i++ {
+		if r[i] == 'l' && isVowel(r[i-1]) && (i+1 == len(r) || (!isVowel(r[i+1]) && r[i+1] != 'l')) {
+			at = append(at, i)
+		}
+	}
+	if len(at) == 0 {
+		return nil
+	}
+
+	all := append([]rune(nil), r...)
+	for _, i := range at {
+		all[i] = 'r'
+	}
+	out := []string{string(all)}
+	if len(at) > 1 {
+		for _, i := range at {
+			one := append([]rune(nil), r...)
+			one[i] = 'r'
+			out = append(out, string(one))
+		}
+	}
+	return out
+}
+
+func isVowel(r rune) bool {
+	return strings.ContainsRune("aeiouáéíóúü", r)
+}
+
+func lastRune(s string) rune {
+	r := []rune(s)
+	return r[len(r)-1]
+}
@@ -0,0 +1,79 @@
+package dialect
+
+import "testing"
+
+func TestStandard(t *testing.T) {
+	tests := []struct{ word, want string }{
+		{"pa'", "para"},
+		{"to’", "todo"},
+		{"na", "nada"},
+		{"toy", "estoy"},
+		{"cansao", "cansado"},
+		{"perdío", "perdido"},
+		{"verdá", "verdad"},
+		{"usté", "usted"},
+		{"ehto", "esto"},
+		{"vel", "ver"},
+		{"puelta", "puerta"},
+		{"vamo", "vamos"},
+	}
+	for _, tt := range tests {
+		found := false
+		for _, r := range Standard(tt.word) {
+			found = found || r.Standard == tt.want
+		}
+		if !found {
+			t.Errorf("Standard(%q) = %v, want %q among them", tt.word, Standard(tt.word), tt.want)
+		}
+	}
+
+	if got := Standard("pa'"); got[0].Standard != "para" || !got[0].Rule.Certain() {
+		t.Errorf(`Standard("pa'") = %v, want the elision "para" first`, got)
+	}
+	for _, w := range []string{"bailar", "los", "mujer"} {
+		if got := Standard(w); len(got) > 0 {
+			t.Errorf("Standard(%q) = %v, want none", w, got)
+		}
+	}
+
+	// Standard words aren't read as other standard words
+	for _, tt := range []struct{ word, wrong string }{
+		{"la", "las"},
+		{"no", "nos"},
+		{"que", "ques"},
+		{"mal", "mar"},
+		{"el", "er"},
+		{"alma", "arma"},
+	} {
+		for _, r := range Standard(tt.word) {
+			if r.Standard == tt.wrong {
+				t.Errorf("Standard(%q) = %v, want no %q", tt.word, Standard(tt.word), tt.wrong)
+			}
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	lexicon := map[string]bool{"para": true, "ver": true, "cansado": true, "ir": true, "mar": true, "mal": true,
+		"las": true, "nos": true}
+	known := func(lemma string) bool { return lexicon[lemma] }
+
+	tests := []struct{ word, lemma, standard string }{
+		{"Pa'", "para", "para"},
+		{"vel", "ver", "ver"},
+		{"cansao", "cansado", "cansado"},
+		{"vamo", "ir", "vamos"},
+		{"mal", "mal", ""}, // Standard as written, not mar
+	}
+	for _, tt := range tests {
+		a, standard, ok := Match(tt.word, known)
+		if !ok || a.Lemma != tt.lemma || standard != tt.standard {
+			t.Errorf("Match(%q) = %q %q %v, want %q %q", tt.word, a.Lemma, standard, ok, tt.lemma, tt.standard)
+		}
+	}
+	for _, w := range []string{"xyzzy", "la", "no"} {
+		if a, standard, ok := Match(w, known); ok {
+			t.Errorf("Match(%q) = %q %q, want no match", w, a.Lemma, standard)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package fsrs
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	gofsrs "github.com/open-spaced-repetition/go-fsrs/v3"
+	"languagepapi/internal/models"
+)
+
+// MinOptimizerReviews is the minimum number of reviews with a prior review
+// on an earlier day needed before fitted weights are trusted
+const MinOptimizerReviews = 200
+
+// ErrNotEnoughReviews is returned when the review history is too short to fit
+var ErrNotEnoughReviews = errors.New("not enough review history to optimize FSRS weights")
+
+const (
+	optimizerIterations = 120
+	optimizerLearnRate  = 0.05
+	optimizerEpsilon    = 1e-4
+	rmseBins            = 20
+)
+
+// weightBounds keeps each weight in the range the reference FSRS optimizer allows
+var weightBounds = [19][2]float64{
+	{0.001, 100}, {0.001, 100}, {0.001, 100}, {0.001, 100},
+	{1, 10}, {0.001, 4}, {0.001, 4}, {0.001, 0.75},
+	{0, 4.5}, {0, 0.8}, {0.001, 3.5}, {0.001, 5},
+	{0.001, 0.25}, {0.001, 0.9}, {0, 4}, {0, 1},
+	{1, 6}, {0, 2}, {0, 2},
+}
+
+// OptimizeResult holds fitted weights and how well they predict recall
+// compared to the defaults
+type OptimizeResult struct {
+	Weights       []float64
+	ReviewCount   int // Reviews used for training (prior review on an earlier day)
+	LogLossBefore float64
+	LogLossAfter  float64
+	RMSEBefore    float64 // Calibration RMSE over binned predictions
+	RMSEAfter     float64
+}
+
+// trainItem is one card's review history as ratings and days since the previous review
+type trainItem struct {
+	ratings []gofsrs.Rating
+	elapsed []float64
+}
+
+// DefaultWeights returns the stock FSRS weights
+func DefaultWeights() []float64 {
+	w := gofsrs.DefaultWeights()
+	return w[:]
+}
+
+// Optimize fits FSRS weights to a user's review logs by minimising the log
+// loss of predicted recall on every review that follows a gap of at least
+// one day. Logs may be in any order; they are grouped per card by time.
+func Optimize(logs []models.ReviewLog) (*OptimizeResult, error) {
+	items, count := buildTrainItems(logs)
+	if count < MinOptimizerReviews {
+		return nil, ErrNotEnoughReviews
+	}
+
+	params := gofsrs.DefaultParam()
+	w := params.W
+	lossBefore, rmseBefore := evaluate(items, w, params)
+
+	// Adam with central-difference gradients; 19 weights keeps this cheap
+	var m, v [19]float64
+	beta1, beta2 := 0.9, 0.999
+	best, bestLoss := w, lossBefore
+	for step := 1; step <= optimizerIterations; step++ {
+		var grad [19]float64
+		for i := range w {
+			h := optimizerEpsilon * math.Max(1, math.Abs(w[i]))
+			up, down := w, w
+			up[i] = clampWeight(i, w[i]+h)
+			down[i] = clampWeight(i, w[i]-h)
+			if up[i] == down[i] {
+				continue
+			}
+			lu, _ := evaluate(items, up, params)
+			ld, _ := evaluate(items, down, params)
+			grad[i] = (lu - ld) / (up[i] - down[i])
+		}
+
+		for i := range w {
+			m[i] = beta1*m[i] + (1-beta1)*grad[i]
+			v[i] = beta2*v[i] + (1-beta2)*grad[i]*grad[i]
+			mHat := m[i] / (1 - math.Pow(beta1, float64(step)))
+			vHat := v[i] / (1 - math.Pow(beta2, float64(step)))
+			w[i] = clampWeight(i, w[i]-optimizerLearnRate*mHat/(math.Sqrt(vHat)+1e-8))
+		}
+
+		if loss, _ := evaluate(items, w, params); loss < bestLoss {
+			best, bestLoss = w, loss
+		}
+	}
+
+	_, rmseAfter := evaluate(items, best, params)
+	return &OptimizeResult{
+		Weights:       best[:],
+		ReviewCount:   count,
+		LogLossBefore: lossBefore,
+		LogLossAfter:  bestLoss,
+		RMSEBefore:    rmseBefore,
+		RMSEAfter:     rmseAfter,
+	}, nil
+}
+
+// buildTrainItems groups logs per card in review order and counts the
+// reviews that can be scored (not the first, and not on the same day)
+func buildTrainItems(logs []models.ReviewLog) ([]trainItem, int) {
+	sorted := make([]models.ReviewLog, len(logs))
+	copy(sorted, logs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].CardID != sorted[j].CardID {
+			return sorted[i].CardID < sorted[j].CardID
+		}
+		return sorted[i].ReviewedAt.Before(sorted[j].ReviewedAt)
+	})
+
+	var items []trainItem
+	count := 0
+	for i := 0; i < len(sorted); {
+		j := i
+		var item trainItem
+		var prev time.Time
+		for ; j < len(sorted) && sorted[j].CardID == sorted[i].CardID; j++ {
+			elapsed := 0.0
+			if j > i {
+				elapsed = daysBetween(prev, sorted[j].ReviewedAt)
+				if elapsed > 0 {
+					count++
+				}
+			}
+			item.ratings = append(item.ratings, toFSRSRating(sorted[j].Rating))
+			item.elapsed = append(item.elapsed, elapsed)
+			prev = sorted[j].ReviewedAt
+		}
+		if len(item.ratings) > 1 {
+			items = append(items, item)
+		}
+		i = j
+	}
+	return items, count
+}
+
+// daysBetween counts calendar days between two review times
+func daysBetween(a, b time.Time) float64 {
+	a = a.UTC()
+	b = b.UTC()
+	da := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	db := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return math.Max(0, math.Round(db.Sub(da).Hours()/24))
+}
+
+// evaluate replays every history with weights w and returns the mean log
+// loss and the binned calibration RMSE of the recall predictions
+func evaluate(items []trainItem, w gofsrs.Weights, params gofsrs.Parameters) (float64, float64) {
+	var lossSum float64
+	var n int
+	var binPred, binActual, binCount [rmseBins]float64
+
+	for _, item := range items {
+		s := math.Max(w[item.ratings[0]-1], 0.1)
+		d := initDifficulty(w, item.ratings[0])
+
+		for k := 1; k < len(item.ratings); k++ {
+			r := item.ratings[k]
+			t := item.elapsed[k]
+
+			if t == 0 {
+				// Same-day review: short-term stability, not scored
+				s = s * math.Exp(w[17]*(float64(r-3)+w[18]))
+			} else {
+				p := math.Pow(1+params.Factor*t/s, params.Decay)
+				p = math.Min(math.Max(p, 1e-6), 1-1e-6)
+				recalled := 0.0
+				if r > gofsrs.Again {
+					recalled = 1
+				}
+				lossSum -= recalled*math.Log(p) + (1-recalled)*math.Log(1-p)
+				n++
+
+				bin := int(p * rmseBins)
+				if bin >= rmseBins {
+					bin = rmseBins - 1
+				}
+				binPred[bin] += p
+				binActual[bin] += recalled
+				binCount[bin]++
+
+				if r == gofsrs.Again {
+					s = w[11] * math.Pow(d, -w[12]) * (math.Pow(s+1, w[13]) - 1) * math.Exp((1-p)*w[14])
+				} else {
+					hardPenalty, easyBonus := 1.0, 1.0
+					if r == gofsrs.Hard {
+						hardPenalty = w[15]
+					}
+					if r == gofsrs.Easy {
+						easyBonus = w[16]
+					}
+					s = s * (1 + math.Exp(w[8])*(11-d)*math.Pow(s, -w[9])*(math.Exp((1-p)*w[10])-1)*hardPenalty*easyBonus)
+				}
+			}
+			s = math.Max(s, 0.01)
+
+			deltaD := -w[6] * float64(r-3)
+			next := d + (10-d)*deltaD/9
+			d = constrain(w[7]*initDifficulty(w, gofsrs.Easy)+(1-w[7])*next, 1, 10)
+		}
+	}
+
+	if n == 0 {
+		return 0, 0
+	}
+
+	var sq float64
+	for i := range binCount {
+		if binCount[i] == 0 {
+			continue
+		}
+		diff := binPred[i]/binCount[i] - binActual[i]/binCount[i]
+		sq += diff * diff * binCount[i]
+	}
+	return lossSum / float64(n), math.Sqrt(sq / float64(n))
+}
+
+func initDifficulty(w gofsrs.Weights, r gofsrs.Rating) float64 {
+	return constrain(w[4]-math.Exp(w[5]*float64(r-1))+1, 1, 10)
+}
+
+func clampWeight(i int, v float64) float64 {
+	return constrain(v, weightBounds[i][0], weightBounds[i][1])
+}
+
+func constrain(v, lo, hi float64) float64 {
+	return math.Min(math.Max(v, lo), hi)
+}
@@ -0,0 +1,61 @@
+package fsrs
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"languagepapi/internal/models"
+)
+
+// syntheticLogs simulates review histories where recall decays faster than
+// the default weights predict
+func syntheticLogs(cards int) []models.ReviewLog {
+	rng := rand.New(rand.NewSource(1))
+	start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	gaps := []int{1, 2, 4, 8, 16}
+
+	var logs []models.ReviewLog
+	for c := 0; c < cards; c++ {
+		at := start
+		logs = append(logs, models.ReviewLog{CardID: int64(c), Rating: models.RatingGood, ReviewedAt: at})
+		for _, gap := range gaps {
+			at = at.Add(time.Duration(gap) * 24 * time.Hour)
+			rating := models.RatingGood
+			if rng.Float64() > math.Pow(0.8, float64(gap)/4) {
+				rating = models.RatingAgain
+			}
+			logs = append(logs, models.ReviewLog{CardID: int64(c), Rating: rating, ReviewedAt: at})
+		}
+	}
+	return logs
+}
+
+func TestOptimize(t *testing.T) {
+	result, err := Optimize(syntheticLogs(100))
+	if err != nil {
+		t.Fatalf("Optimize() error = %v", err)
+	}
+
+	if result.ReviewCount != 500 {
+		t.Errorf("ReviewCount = %d, want 500", result.ReviewCount)
+	}
+	if len(result.Weights) != 19 {
+		t.Fatalf("len(Weights) = %d, want 19", len(result.Weights))
+	}
+	if result.LogLossAfter >= result.LogLossBefore {
+		t.Errorf("LogLossAfter = %.4f, want less than LogLossBefore %.4f", result.LogLossAfter, result.LogLossBefore)
+	}
+	if result.RMSEAfter >= result.RMSEBefore {
+		t.Errorf("RMSEAfter = %.4f, want less than RMSEBefore %.4f", result.RMSEAfter, result.RMSEBefore)
+	}
+}
+
+func TestOptimizeNotEnoughReviews(t *testing.T) {
+	_, err := Optimize(syntheticLogs(10))
+	if !errors.Is(err, ErrNotEnoughReviews) {
+		t.Errorf("Optimize() error = %v, want ErrNotEnoughReviews", err)
+	}
+}
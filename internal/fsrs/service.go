@@ -27,6 +27,16 @@ func NewServiceWithRetention(requestRetention float64) *Service {
 	return &Service{fsrs: gofsrs.NewFSRS(params)}
 }
 
+// NewServiceWithWeights creates an FSRS service with fitted weights (see Optimize).
+// Falls back to the default weights if the slice has the wrong length.
+func NewServiceWithWeights(weights []float64) *Service {
+	params := gofsrs.DefaultParam()
+	if len(weights) == len(params.W) {
+		copy(params.W[:], weights)
+	}
+	return &Service{fsrs: gofsrs.NewFSRS(params)}
+}
+
 // toFSRSCard converts our CardProgress to go-fsrs Card
 func (s *Service) toFSRSCard(p *models.CardProgress) gofsrs.Card {
 	card := gofsrs.NewCard()
@@ -0,0 +1,373 @@
+// Package grading checks typed answers: it forgives what doesn't matter
+// (case, articles, punctuation), accepts any of a card's alternative
+// answers, scores typos by edit distance, explains the difference and
+// turns the result into an FSRS rating.
+package grading
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"languagepapi/internal/models"
+)
+
+// Verdict is how close an answer came
+type Verdict string
+
+const (
+	Exact  Verdict = "exact"  // Right, accents included
+	Accent Verdict = "accent" // Right apart from accents or ñ
+	Typo   Verdict = "typo"   // Within the typo tolerance of the expected answer
+	Wrong  Verdict = "wrong"
+)
+
+// Result is a graded answer
+type Result struct {
+	Verdict  Verdict
+	Answer   string   // What was typed
+	Expected string   // The accepted answer closest to it
+	Distance int      // Edit distance ignoring accents
+	Score    float64  // 1 for a perfect answer down to 0
+	Notes    []string // The differences explained, e.g. `missing accent on "é"`
+	Standard string   // The standard spelling of a colloquial word, set by GradeWord when matched by it
+}
+
+// Correct reports whether the answer counts as known
+func (r Result) Correct() bool {
+	return r.Verdict != Wrong
+}
+
+// maxNotes keeps the explanation readable for badly wrong answers
+const maxNotes = 4
+
+// Grade grades an answer against expected, which may hold several accepted
+// answers separated by commas, slashes or semicolons
+func Grade(answer, expected string) Result {
+	best := Result{Verdict: Wrong, Answer: answer, Expected: strings.TrimSpace(expected), Distance: -1}
+	typed := []rune(Normalize(answer))
+	if len(typed) == 0 {
+		return best
+	}
+
+	for _, alt := range Alternatives(expected) {
+		want := []rune(Normalize(alt))
+		if len(want) == 0 {
+			continue
+		}
+		r := compare(typed, want)
+		r.Answer, r.Expected = answer, alt
+		if best.Distance < 0 || better(r, best) {
+			best = r
+		}
+	}
+	if best.Distance < 0 {
+		best.Distance = 0
+	}
+	return best
+}
+
+// better orders results by verdict, then by distance
+func better(a, b Result) bool {
+	rank := map[Verdict]int{Exact: 3, Accent: 2, Typo: 1, Wrong: 0}
+	if rank[a.Verdict] != rank[b.Verdict] {
+		return rank[a.Verdict] > rank[b.Verdict]
+	}
+	return a.Distance < b.Distance
+}
+
+// Tolerance is the number of typos accepted in an answer of n letters
+func Tolerance(n int) int {
+	switch {
+	case n <= 3:
+		return 0
+	case n <= 7:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// compare grades normalized runes against one normalized expected answer
+func compare(typed, want []rune) Result {
+	ops := editOps(typed, want)
+
+	var r Result
+	accents := false
+	for _, o := range ops {
+		switch {
+		case o.kind == opMatch && o.typed != o.want:
+			accents = true
+			r.addNote(accentNote(o.typed, o.want))
+		case o.kind != opMatch:
+			r.Distance++
+			r.addNote(o.note())
+		}
+	}
+
+	switch {
+	case r.Distance == 0 && !accents:
+		r.Verdict = Exact
+	case r.Distance == 0:
+		r.Verdict = Accent
+	case r.Distance <= Tolerance(len(want)):
+		r.Verdict = Typo
+	default:
+		r.Verdict = Wrong
+	}
+
+	r.Score = 1 - float64(r.Distance)/float64(max(len(want), len(typed)))
+	if accents {
+		r.Score -= 0.05
+	}
+	r.Score = max(r.Score, 0)
+	return r
+}
+
+func (r *Result) addNote(note string) {
+	for _, n := range r.Notes {
+		if n == note {
+			return
+		}
+	}
+	if len(r.Notes) < maxNotes {
+		r.Notes = append(r.Notes, note)
+	}
+}
+
+// Response time thresholds for rating a right answer. Typing it is allowed
+// typingTime per letter on top.
+const (
+	fastAnswer = 4 * time.Second
+	slowAnswer = 15 * time.Second
+	typingTime = 250 * time.Millisecond
+)
+
+// Rate maps a graded answer and the time taken to answer to an FSRS rating:
+// wrong is Again, a typo is Hard, and a right answer is Easy when fast, Hard
+// when slow and Good otherwise. A missing accent caps the rating at Good.
+// A zero elapsed time means unknown and never rates Easy.
+func Rate(r Result, elapsed time.Duration) models.Rating {
+	switch r.Verdict {
+	case Wrong:
+		return models.RatingAgain
+	case Typo:
+		return models.RatingHard
+	}
+
+	typing := time.Duration(utf8.RuneCountInString(r.Expected)) * typingTime
+	switch {
+	case elapsed > slowAnswer+typing:
+		return models.RatingHard
+	case r.Verdict == Exact && elapsed > 0 && elapsed <= fastAnswer+typing:
+		return models.RatingEasy
+	default:
+		return models.RatingGood
+	}
+}
+
+var (
+	// "bonito/a", "trabajador/a": a slash followed by a gender ending
+	genderSuffix  = regexp.MustCompile(`^(\pL+?)(o|os)?/(a|as)$`)
+	parenthetical = regexp.MustCompile(`\([^)]*\)`)
+	separators    = regexp.MustCompile(`[,/;]`)
+)
+
+// Alternatives splits expected into its accepted answers. Text in brackets
+// is optional, so "to run (a business)" accepts "to run" and "to run a
+// business", and "bonito/a" accepts "bonito" and "bonita".
+func Alternatives(expected string) []string {
+	var alts []string
+	add := func(s string) {
+		s = strings.Join(strings.Fields(s), " ")
+		if s == "" {
+			return
+		}
+		for _, a := range alts {
+			if a == s {
+				return
+			}
+		}
+		alts = append(alts, s)
+	}
+
+	for _, word := range strings.Fields(expected) {
+		if m := genderSuffix.FindStringSubmatch(strings.Trim(word, ",;")); m != nil {
+			// Spell out the feminine form so the slash doesn't split it
+			expected = strings.Replace(expected, m[0], m[1]+m[2]+", "+m[1]+m[3], 1)
+		}
+	}
+
+	for _, part := range separators.Split(expected, -1) {
+		add(parenthetical.ReplaceAllString(part, ""))
+		add(strings.NewReplacer("(", "", ")", "").Replace(part))
+	}
+	return alts
+}
+
+// articles are dropped from the start of answers. Spanish "lo" and "a" are
+// left alone: "lo siento" and "a veces" need them.
+var articles = map[string]bool{
+	"el": true, "la": true, "los": true, "las": true,
+	"un": true, "una": true, "unos": true, "unas": true,
+	"the": true, "an": true, "to": true,
+}
+
+// Normalize lowercases s, drops punctuation and leading articles and
+// collapses whitespace. Accents are kept; Grade compares them separately.
+func Normalize(s string) string {
+	s = strings.ToLower(s)
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '-' || r == '_':
+			return ' '
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			return -1
+		}
+		return r
+	}, s)
+
+	words := strings.Fields(s)
+	for len(words) > 1 && articles[words[0]] {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// foldRune strips the accent from a letter. The mapping is one rune to one
+// rune so edit operations line up with the original text.
+func foldRune(r rune) rune {
+	switch r {
+	case 'á', 'à', 'â', 'ä':
+		return 'a'
+	case 'é', 'è', 'ê', 'ë':
+		return 'e'
+	case 'í', 'ì', 'î', 'ï':
+		return 'i'
+	case 'ó', 'ò', 'ô', 'ö':
+		return 'o'
+	case 'ú', 'ù', 'û', 'ü':
+		return 'u'
+	case 'ñ':
+		return 'n'
+	case 'ç':
+		return 'c'
+	}
+	return r
+}
+
+// Fold strips accents from s
+func Fold(s string) string {
+	return strings.Map(foldRune, s)
+}
+
+func accentNote(typed, want rune) string {
+	switch {
+	case want == 'ñ':
+		return `"ñ", not "n"`
+	case foldRune(want) == want:
+		return fmt.Sprintf(`no accent on "%c"`, want)
+	case foldRune(typed) == typed:
+		return fmt.Sprintf(`missing accent on "%c"`, want)
+	default:
+		return fmt.Sprintf(`"%c", not "%c"`, want, typed)
+	}
+}
+
+type opKind int
+
+const (
+	opMatch opKind = iota
+	opSubstitute
+	opMissing   // In the expected answer but not typed
+	opExtra     // Typed but not in the expected answer
+	opTranspose // Two neighbouring letters swapped
+)
+
+// op is one step of the edit from the typed answer to the expected one
+type op struct {
+	kind  opKind
+	typed rune // For opTranspose, the first of the swapped letters as typed
+	want  rune // For opTranspose, the second
+}
+
+func (o op) note() string {
+	switch o.kind {
+	case opSubstitute:
+		return fmt.Sprintf(`"%c", not "%c"`, o.want, o.typed)
+	case opMissing:
+		if o.want == ' ' {
+			return "missing space"
+		}
+		return fmt.Sprintf(`missing "%c"`, o.want)
+	case opExtra:
+		if o.typed == ' ' {
+			return "extra space"
+		}
+		return fmt.Sprintf(`extra "%c"`, o.typed)
+	case opTranspose:
+		return fmt.Sprintf(`"%c%c", not "%c%c"`, o.want, o.typed, o.typed, o.want)
+	}
+	return ""
+}
+
+// editOps returns the cheapest edit from typed to want, with letters that
+// only differ in accents counting as matches (optimal string alignment
+// distance, so a swap of neighbours costs one)
+func editOps(typed, want []rune) []op {
+	n, m := len(typed), len(want)
+	same := func(i, j int) bool { return foldRune(typed[i]) == foldRune(want[j]) }
+
+	d := make([][]int, n+1)
+	for i := range d {
+		d[i] = make([]int, m+1)
+		d[i][0] = i
+	}
+	for j := 0; j <= m; j++ {
+		d[0][j] = j
+	}
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			cost := 1
+			if same(i-1, j-1) {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && same(i-1, j-2) && same(i-2, j-1) {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	// Walk back from the end, preferring matches so accent notes point at
+	// the right letters
+	var ops []op
+	i, j := n, m
+	for i > 0 || j > 0 {
+		switch {
+		case i > 0 && j > 0 && same(i-1, j-1) && d[i][j] == d[i-1][j-1]:
+			ops = append(ops, op{opMatch, typed[i-1], want[j-1]})
+			i, j = i-1, j-1
+		case i > 1 && j > 1 && same(i-1, j-2) && same(i-2, j-1) && d[i][j] == d[i-2][j-2]+1:
+			ops = append(ops, op{opTranspose, typed[i-2], typed[i-1]})
+			i, j = i-2, j-2
+		case i > 0 && j > 0 && d[i][j] == d[i-1][j-1]+1:
+			ops = append(ops, op{opSubstitute, typed[i-1], want[j-1]})
+			i, j = i-1, j-1
+		case j > 0 && d[i][j] == d[i][j-1]+1:
+			ops = append(ops, op{kind: opMissing, want: want[j-1]})
+			j--
+		default:
+			ops = append(ops, op{kind: opExtra, typed: typed[i-1]})
+			i--
+		}
+	}
+
+	for l, r := 0, len(ops)-1; l < r; l, r = l+1, r-1 {
+		ops[l], ops[r] = ops[r], ops[l]
+	}
+	return ops
+}
@@ -0,0 +1,110 @@
+package grading
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"languagepapi/internal/models"
+)
+
+func TestGrade(t *testing.T) {
+	tests := []struct {
+		answer, expected string
+		verdict          Verdict
+		notes            []string
+	}{
+		{"El perro", "perro", Exact, nil},
+		{"¿Qué tal?", "qué tal", Exact, nil},
+		{"to eat", "to eat, to dine", Exact, nil},
+		{"dine", "to eat / to dine", Exact, nil},
+		{"bonita", "bonito/a", Exact, nil},
+		{"to run a business", "to run (a business)", Exact, nil},
+		{"cafe", "café", Accent, []string{`missing accent on "é"`}},
+		{"nino", "niño", Accent, []string{`"ñ", not "n"`}},
+		{"mañaan", "mañana", Typo, []string{`"na", not "an"`}},
+		{"hablr", "hablar", Typo, []string{`missing "a"`}},
+		{"gato", "perro", Wrong, []string{`missing "p"`, `"e", not "g"`, `"r", not "a"`, `"r", not "t"`}},
+		{"sol", "sal", Wrong, []string{`"a", not "o"`}}, // Too short for a typo
+		{"", "perro", Wrong, nil},
+	}
+	for _, tt := range tests {
+		got := Grade(tt.answer, tt.expected)
+		if got.Verdict != tt.verdict || !reflect.DeepEqual(got.Notes, tt.notes) {
+			t.Errorf("Grade(%q, %q) = %s %q, want %s %q", tt.answer, tt.expected, got.Verdict, got.Notes, tt.verdict, tt.notes)
+		}
+	}
+}
+
+func TestRate(t *testing.T) {
+	exact := Grade("perro", "perro")
+	tests := []struct {
+		result  Result
+		elapsed time.Duration
+		want    models.Rating
+	}{
+		{exact, 3 * time.Second, models.RatingEasy},
+		{exact, 8 * time.Second, models.RatingGood},
+		{exact, 30 * time.Second, models.RatingHard},
+		{exact, 0, models.RatingGood},
+		{Grade("cafe", "café"), 2 * time.Second, models.RatingGood},
+		{Grade("hablr", "hablar"), 2 * time.Second, models.RatingHard},
+		{Grade("gato", "perro"), 2 * time.Second, models.RatingAgain},
+	}
+	for _, tt := range tests {
+		if got := Rate(tt.result, tt.elapsed); got != tt.want {
+			t.Errorf("Rate(%s %q, %s) = %v, want %v", tt.result.Verdict, tt.result.Answer, tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestGradeLine(t *testing.T) {
+	got := GradeLine("yo quiero ir pa la playa mañaan", "Yo quiero irme pa' la playa, ¡mañana!", nil)
+	var words []string
+	for _, w := range got.Words {
+		words = append(words, string(w.Verdict)+":"+w.Typed+"/"+w.Expected)
+	}
+	want := []string{
+		"exact:yo/Yo", "exact:quiero/quiero", "wrong:ir/irme", "exact:pa/pa'",
+		"exact:la/la", "exact:playa/playa,", "typo:mañaan/¡mañana!",
+	}
+	if !reflect.DeepEqual(words, want) || got.Correct != 6 || got.Total != 7 {
+		t.Errorf("GradeLine = %d/%d %q, want 6/7 %q", got.Correct, got.Total, words, want)
+	}
+
+	// A missed word and an extra one cost only themselves
+	got = GradeLine("para todo el mundo entero", "pa' to' mundo entero", nil)
+	if got.Correct != 4 || got.Total != 4 || len(got.Words) != 5 || !got.Words[2].Extra() {
+		t.Errorf("GradeLine with an extra word = %d/%d %+v", got.Correct, got.Total, got.Words)
+	}
+	got = GradeLine("la luna", "la luna baila", nil)
+	if got.Correct != 2 || !got.Words[2].Missed() {
+		t.Errorf("GradeLine with a missed word = %d/%d %+v", got.Correct, got.Total, got.Words)
+	}
+}
+
+func TestGradeWordDialect(t *testing.T) {
+	tests := []struct {
+		typed, expected, standard string
+		correct                   bool
+	}{
+		{"para", "pa'", "", true},
+		{"pa", "para", "", true},
+		{"cansado", "cansao", "", true},
+		{"ver", "vel", "", false}, // Could be a real word: needs the lexicon
+		{"ver", "vel", "ver", true},
+		{"mar", "mal", "", false},
+		{"la", "las", "", false},
+	}
+	for _, tt := range tests {
+		r := GradeWord(tt.typed, tt.expected, tt.standard)
+		if r.Correct() != tt.correct {
+			t.Errorf("GradeWord(%q, %q, %q) = %s, want correct %v", tt.typed, tt.expected, tt.standard, r.Verdict, tt.correct)
+		}
+	}
+
+	got := GradeLine("te quiero ver", "te quiero vel", map[int]string{2: "ver"})
+	if got.Correct != 3 || got.Words[2].Colloquial() != "ver" || got.Words[0].Colloquial() != "" {
+		t.Errorf("GradeLine with a known standard spelling = %d/%d %+v", got.Correct, got.Total, got.Words)
+	}
+}
@@ -0,0 +1,181 @@
+package grading
+
+import (
+	"fmt"
+	"strings"
+
+	"languagepapi/internal/dialect"
+	"languagepapi/internal/lemma"
+)
+
+// WordResult is one word of a line graded word by word
+type WordResult struct {
+	Expected string // As written in the line, "" for a word typed but not in it
+	Typed    string // "" for a word of the line that was not typed
+	Verdict  Verdict
+	Standard string // The standard spelling of a colloquial word of the line, if known or matched by
+}
+
+// Missed reports whether the word of the line was left out
+func (w WordResult) Missed() bool {
+	return w.Typed == ""
+}
+
+// Extra reports whether the word was typed but is not in the line
+func (w WordResult) Extra() bool {
+	return w.Expected == ""
+}
+
+// Colloquial returns the standard spelling of the line's word when it is
+// written colloquially, e.g. "para" for "pa'", else ""
+func (w WordResult) Colloquial() string {
+	if w.Expected == "" || Normalize(w.Standard) == Normalize(w.Expected) {
+		return ""
+	}
+	return w.Standard
+}
+
+// LineResult is a typed line graded word by word
+type LineResult struct {
+	Words   []WordResult // The line's words in order, extra words where typed
+	Correct int          // Words of the line typed right, accents and typos forgiven
+	Total   int          // Words of the line
+}
+
+// Accuracy is the share of the line's words typed right, from 0 to 1
+func (r LineResult) Accuracy() float64 {
+	if r.Total == 0 {
+		return 0
+	}
+	return float64(r.Correct) / float64(r.Total)
+}
+
+// GradeWord grades one typed word against a word of a line like Grade,
+// accepting a colloquial spelling of the lyrics like "pa'" or "cansao" for
+// the standard "para" or "cansado", and the other way round. Spellings only
+// a lexicon can tell apart (vel for ver, vamo for vamos) are accepted when
+// standard, the known standard spelling of expected, is given.
+func GradeWord(typed, expected, standard string) Result {
+	best := Grade(typed, expected)
+	if best.Verdict == Exact {
+		return best
+	}
+	try := func(r Result, std string) {
+		if better(r, best) {
+			r.Answer, r.Expected, r.Standard = typed, expected, std
+			best = r
+		}
+	}
+	if standard != "" {
+		try(Grade(typed, standard), standard)
+	}
+	for _, d := range dialect.Standard(lemma.Clean(expected)) {
+		if d.Rule.Certain() {
+			try(Grade(typed, d.Standard), d.Standard)
+		}
+	}
+	for _, d := range dialect.Standard(lemma.Clean(typed)) {
+		if d.Rule.Certain() {
+			try(Grade(d.Standard, expected), d.Standard)
+		}
+	}
+	if best.Standard != "" && best.Correct() {
+		best.addNote(fmt.Sprintf("%q is colloquial for %q", colloquial(typed, expected, best.Standard), best.Standard))
+	}
+	return best
+}
+
+// colloquial picks which of the typed and expected words is the colloquial
+// spelling of standard
+func colloquial(typed, expected, standard string) string {
+	if Normalize(expected) == Normalize(standard) {
+		return lemma.Clean(typed)
+	}
+	return lemma.Clean(expected)
+}
+
+// GradeLine grades a typed line against the expected one word by word. The
+// typed words are lined up with the line's first, so a missed or extra word
+// costs only itself. standards holds the known standard spellings of the
+// line's colloquial words by their index in strings.Fields(expected); it
+// may be nil.
+func GradeLine(answer, expected string, standards map[int]string) LineResult {
+	typed, want := lineWords(answer), lineWords(expected)
+	n, m := len(typed), len(want)
+
+	right := make([][]Result, n)
+	for i := range typed {
+		right[i] = make([]Result, m)
+		for j := range want {
+			right[i][j] = GradeWord(typed[i].text, want[j].text, standards[want[j].index])
+		}
+	}
+	cost := func(i, j int) int {
+		if right[i][j].Correct() {
+			return 0
+		}
+		return 1
+	}
+
+	// Edit distance over words, a wrong word costing as much as a missed one
+	d := make([][]int, n+1)
+	for i := range d {
+		d[i] = make([]int, m+1)
+		d[i][0] = i
+	}
+	for j := 0; j <= m; j++ {
+		d[0][j] = j
+	}
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost(i-1, j-1))
+		}
+	}
+
+	result := LineResult{Total: m}
+	i, j := n, m
+	for i > 0 || j > 0 {
+		switch {
+		case i > 0 && j > 0 && d[i][j] == d[i-1][j-1]+cost(i-1, j-1):
+			r := right[i-1][j-1]
+			w := WordResult{Expected: want[j-1].text, Typed: typed[i-1].text, Verdict: r.Verdict}
+			if std := standards[want[j-1].index]; std != "" {
+				w.Standard = std
+			} else if r.Correct() {
+				w.Standard = r.Standard
+			}
+			if r.Correct() {
+				result.Correct++
+			}
+			result.Words = append(result.Words, w)
+			i, j = i-1, j-1
+		case j > 0 && d[i][j] == d[i][j-1]+1:
+			result.Words = append(result.Words, WordResult{Expected: want[j-1].text, Verdict: Wrong, Standard: standards[want[j-1].index]})
+			j--
+		default:
+			result.Words = append(result.Words, WordResult{Typed: typed[i-1].text, Verdict: Wrong})
+			i--
+		}
+	}
+	for l, r := 0, len(result.Words)-1; l < r; l, r = l+1, r-1 {
+		result.Words[l], result.Words[r] = result.Words[r], result.Words[l]
+	}
+	return result
+}
+
+// lineWord is a word of a line with its index among the line's fields
+type lineWord struct {
+	text  string
+	index int
+}
+
+// lineWords splits a line into words, leaving out stray punctuation
+func lineWords(s string) []lineWord {
+	var words []lineWord
+	for i, f := range strings.Fields(s) {
+		if Normalize(f) != "" {
+			words = append(words, lineWord{f, i})
+		}
+	}
+	return words
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"languagepapi/components"
+	"languagepapi/internal/repository"
+	"languagepapi/internal/service"
+)
+
+// maxAnkiUpload limits uploaded .apkg files; decks with media get large
+const maxAnkiUpload = 200 << 20
+
+var ankiService = service.NewAnkiService()
+
+// HandleAnki renders the Anki export/import page
+func HandleAnki(w http.ResponseWriter, r *http.Request) {
+	renderAnki(w, r, "", false)
+}
+
+// HandleAnkiExport downloads the user's cards as an .apkg
+func HandleAnkiExport(w http.ResponseWriter, r *http.Request) {
+	islandID, _ := strconv.ParseInt(r.URL.Query().Get("island"), 10, 64)
+
+	data, err := ankiService.Export(currentUserID(r), islandID, SongsPath)
+	if err != nil {
+		log.Printf("Anki export failed: %v", err)
+		http.Error(w, "Failed to export deck", http.StatusInternalServerError)
+		return
+	}
+
+	filename := "languagepapi.apkg"
+	if islandID > 0 {
+		filename = fmt.Sprintf("languagepapi-island-%d.apkg", islandID)
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Write(data)
+}
+
+// HandleAnkiImport adds the notes of an uploaded .apkg to an island
+func HandleAnkiImport(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxAnkiUpload)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	islandID, err := strconv.ParseInt(r.FormValue("island_id"), 10, 64)
+	if err != nil {
+		renderAnki(w, r, "Choose an island to import into", false)
+		return
+	}
+	if _, err := repository.GetIsland(islandID); err != nil {
+		renderAnki(w, r, "Unknown island", false)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		renderAnki(w, r, "Choose an .apkg file", false)
+		return
+	}
+	defer file.Close()
+
+	result, err := ankiService.Import(currentUserID(r), islandID, file, header.Size)
+	if err != nil {
+		log.Printf("Anki import failed: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		renderAnki(w, r, "Import failed: "+err.Error(), false)
+		return
+	}
+
+	message := fmt.Sprintf("Imported %d words (%d with review history, %d reviews).", result.Imported, result.WithHistory, result.Reviews)
+	if result.Duplicates > 0 {
+		message += fmt.Sprintf(" Skipped %d you already had.", result.Duplicates)
+	}
+	if result.Skipped > 0 {
+		message += fmt.Sprintf(" Skipped %d notes without a term and translation.", result.Skipped)
+	}
+	renderAnki(w, r, message, true)
+}
+
+func renderAnki(w http.ResponseWriter, r *http.Request, message string, success bool) {
+	islands, _ := repository.GetAllIslands()
+	components.AnkiPage(islands, message, success).Render(r.Context(), w)
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"languagepapi/internal/service"
+)
+
+// apiPrefix is the path prefix of the JSON API; apiV1 is the current version
+const (
+	apiPrefix = "/api/"
+	apiV1     = "/api/v1"
+)
+
+// maxAPIBody limits JSON request bodies
+const maxAPIBody = 1 << 20
+
+// apiRoute describes one JSON API endpoint. The same table registers the
+// handlers and generates the OpenAPI document, so the two can't drift.
+type apiRoute struct {
+	Method   string
+	Path     string // relative to apiV1, with {name} path parameters
+	Tag      string
+	Summary  string
+	Query    []apiParam
+	Request  any // zero value of the JSON request body, nil if none
+	Response any // zero value of the JSON response body, nil for 204
+	Status   int // success status, 200 if unset
+	Public   bool
+	Handler  http.HandlerFunc
+}
+
+// apiParam documents a query parameter
+type apiParam struct {
+	Name        string
+	Type        string // OpenAPI type: "integer" or "string"
+	Description string
+}
+
+// apiRoutes lists every /api/v1 endpoint
+func apiRoutes() []apiRoute {
+	return []apiRoute{
+		{Method: "POST", Path: "/auth/login", Tag: "auth", Summary: "Log in and get a bearer token",
+			Request: apiLoginInput{}, Response: apiLoginResult{}, Public: true, Handler: apiLogin},
+
+		{Method: "GET", Path: "/cards", Tag: "cards", Summary: "List shared cards and your own cards",
+			Query: []apiParam{
+				{Name: "island", Type: "integer", Description: "Only cards on this island"},
+				{Name: "q", Type: "string", Description: "Only cards whose term, translation or example contains this"},
+			},
+			Response: []apiCard{}, Handler: apiListCards},
+		{Method: "POST", Path: "/cards", Tag: "cards", Summary: "Add a card",
+			Request: apiCardInput{}, Response: apiCard{}, Status: http.StatusCreated, Handler: apiCreateCard},
+		{Method: "GET", Path: "/cards/{id}", Tag: "cards", Summary: "Get a card with its bridges and your progress",
+			Response: apiCard{}, Handler: apiGetCard},
+		{Method: "PUT", Path: "/cards/{id}", Tag: "cards", Summary: "Update one of your cards; bridges are replaced when given",
+			Request: apiCardInput{}, Response: apiCard{}, Handler: apiUpdateCard},
+		{Method: "DELETE", Path: "/cards/{id}", Tag: "cards", Summary: "Delete one of your cards",
+			Status: http.StatusNoContent, Handler: apiDeleteCard},
+		{Method: "GET", Path: "/cards/{id}/bridges", Tag: "cards", Summary: "List a card's bridges",
+			Response: []apiBridge{}, Handler: apiGetBridges},
+		{Method: "PUT", Path: "/cards/{id}/bridges", Tag: "cards", Summary: "Replace the bridges of one of your cards",
+			Request: []apiBridge{}, Response: []apiBridge{}, Handler: apiReplaceBridges},
+
+		{Method: "GET", Path: "/islands", Tag: "islands", Summary: "List islands with your progress",
+			Response: []apiIsland{}, Handler: apiListIslands},
+		{Method: "GET", Path: "/islands/{id}", Tag: "islands", Summary: "Get an island with your progress",
+			Response: apiIsland{}, Handler: apiGetIsland},
+
+		{Method: "GET", Path: "/queue", Tag: "reviews", Summary: "Cards to review now: due cards first, then new ones",
+			Query: []apiParam{
+				{Name: "limit", Type: "integer", Description: "Maximum cards (default: your reviews per session)"},
+			},
+			Response: apiQueue{}, Handler: apiGetQueue},
+		{Method: "POST", Path: "/reviews", Tag: "reviews", Summary: "Submit a review for a card",
+			Request: apiReviewInput{}, Response: apiReviewResult{}, Handler: apiSubmitReview},
+		{Method: "POST", Path: "/answers/check", Tag: "reviews", Summary: "Grade a typed answer for a card and get the rating it earns",
+			Request: apiAnswerInput{}, Response: apiGrade{}, Handler: apiCheckAnswer},
+
+		{Method: "GET", Path: "/lesson", Tag: "lessons", Summary: "Start or resume today's lesson",
+			Response: apiLesson{}, Handler: apiGetLesson},
+		{Method: "POST", Path: "/lesson/review", Tag: "lessons", Summary: "Answer the current lesson card",
+			Request: apiReviewInput{}, Response: apiLesson{}, Handler: apiLessonReview},
+		{Method: "POST", Path: "/lesson/skip", Tag: "lessons", Summary: "Skip the current lesson card",
+			Response: apiLesson{}, Handler: apiLessonSkip},
+
+		{Method: "GET", Path: "/songs", Tag: "songs", Summary: "List songs, with the ones due for review",
+			Response: apiSongHome{}, Handler: apiListSongs},
+		{Method: "GET", Path: "/songs/{id}", Tag: "songs", Summary: "Get a song with its lyrics and vocabulary",
+			Response: apiSongDetail{}, Handler: apiGetSong},
+		{Method: "POST", Path: "/songs/{id}/lesson", Tag: "song lessons", Summary: "Start or resume today's lesson for a song",
+			Request: apiSongLessonInput{}, Response: apiSongLesson{}, Handler: apiStartSongLesson},
+		{Method: "GET", Path: "/songs/{id}/lesson", Tag: "song lessons", Summary: "Get the unfinished lesson for a song",
+			Response: apiSongLesson{}, Handler: apiGetSongLesson},
+		{Method: "POST", Path: "/songs/{id}/lesson/vocab", Tag: "song lessons", Summary: "Rate the current vocabulary card",
+			Request: apiRatingInput{}, Response: apiSongLesson{}, Handler: apiSongLessonVocab},
+		{Method: "POST", Path: "/songs/{id}/lesson/next-phase", Tag: "song lessons", Summary: "Move on to the next phase",
+			Response: apiSongLesson{}, Handler: apiSongLessonNextPhase},
+		{Method: "POST", Path: "/songs/{id}/lesson/next-line", Tag: "song lessons", Summary: "Mark the current line studied and move on",
+			Response: apiSongLesson{}, Handler: apiSongLessonNextLine},
+		{Method: "POST", Path: "/songs/{id}/lesson/skip-line", Tag: "song lessons", Summary: "Skip the current line",
+			Response: apiSongLesson{}, Handler: apiSongLessonSkipLine},
+		{Method: "POST", Path: "/songs/{id}/lesson/blank", Tag: "song lessons", Summary: "Answer the current fill-in-the-blank",
+			Request: apiBlankInput{}, Response: apiSongLesson{}, Handler: apiSongLessonBlank},
+		{Method: "POST", Path: "/songs/{id}/lesson/dictation", Tag: "song lessons", Summary: "Answer the current dictated line, graded word by word",
+			Request: apiBlankInput{}, Response: apiSongLesson{}, Handler: apiSongLessonDictation},
+		{Method: "POST", Path: "/songs/{id}/lesson/next-dictation", Tag: "song lessons", Summary: "Move on from an answered dictated line",
+			Response: apiSongLesson{}, Handler: apiSongLessonNextDictation},
+		{Method: "POST", Path: "/songs/{id}/lesson/complete", Tag: "song lessons", Summary: "Finish the lesson now",
+			Response: apiSongLesson{}, Handler: apiSongLessonComplete},
+		{Method: "POST", Path: "/songs/{id}/lines/edits", Tag: "lyrics", Summary: "Retime, shift, split, merge or retranslate a song's lines, saved as a new version (admins only)",
+			Request: apiLineEditsInput{}, Response: apiSongLines{}, Handler: apiEditSongLines},
+		{Method: "POST", Path: "/songs/{id}/lines/undo", Tag: "lyrics", Summary: "Undo the latest edit of a song's lines (admins only)",
+			Response: apiSongLines{}, Handler: apiUndoSongLines},
+		{Method: "GET", Path: "/songs/{id}/lines/versions", Tag: "lyrics", Summary: "List the saved versions of a song's lines, newest first",
+			Response: []apiSongLinesVersion{}, Handler: apiListSongLinesVersions},
+
+		{Method: "GET", Path: "/stats", Tag: "stats", Summary: "Level, streaks, daily goal, card counts and achievements",
+			Response: apiStats{}, Handler: apiGetStats},
+
+		{Method: "GET", Path: "/settings", Tag: "settings", Summary: "Get your settings",
+			Response: apiSettings{}, Handler: apiGetSettings},
+		{Method: "PUT", Path: "/settings", Tag: "settings", Summary: "Save your settings",
+			Request: apiSettings{}, Response: apiSettings{}, Handler: apiSaveSettings},
+	}
+}
+
+// RegisterAPI adds the JSON API and its OpenAPI document to mux
+func RegisterAPI(mux *http.ServeMux) {
+	for _, route := range apiRoutes() {
+		mux.HandleFunc(route.Method+" "+apiV1+route.Path, route.Handler)
+	}
+	mux.HandleFunc("GET "+apiV1+"/openapi.json", HandleOpenAPI)
+
+	// Unknown API paths get a JSON 404 rather than the home page
+	for _, method := range []string{"GET", "POST", "PUT", "DELETE"} {
+		mux.HandleFunc(method+" "+apiPrefix, func(w http.ResponseWriter, r *http.Request) {
+			writeAPIError(w, http.StatusNotFound, "not found")
+		})
+	}
+}
+
+// apiError is the body of every API error response
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// writeJSON writes v as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("api: encode response: %v", err)
+	}
+}
+
+// writeAPIError writes a JSON error response
+func writeAPIError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, apiError{Error: msg})
+}
+
+// apiRequireAdmin writes a 403 and returns false unless the current user is
+// an admin
+func apiRequireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if !isAdmin(r) {
+		writeAPIError(w, http.StatusForbidden, "admins only")
+		return false
+	}
+	return true
+}
+
+// apiInternalError logs err and writes a generic 500
+func apiInternalError(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("api: %s %s: %v", r.Method, r.URL.Path, err)
+	writeAPIError(w, http.StatusInternalServerError, "internal error")
+}
+
+// decodeJSON reads the request body into v, writing a 400 on failure
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxAPIBody)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// apiPathID parses a numeric path parameter, writing a 400 on failure
+func apiPathID(w http.ResponseWriter, r *http.Request, name string) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+	if err != nil || id < 1 {
+		writeAPIError(w, http.StatusBadRequest, "invalid "+name)
+		return 0, false
+	}
+	return id, true
+}
+
+// apiLogin exchanges a username and password for a session token, usable
+// as "Authorization: Bearer <token>"
+func apiLogin(w http.ResponseWriter, r *http.Request) {
+	var in apiLoginInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+
+	token, user, err := authService.Login(in.Username, in.Password)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			writeAPIError(w, http.StatusUnauthorized, "invalid username or password")
+			return
+		}
+		apiInternalError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, apiLoginResult{
+		Token:     token,
+		ExpiresAt: time.Now().Add(service.SessionDuration),
+		User:      apiUser{ID: user.ID, Username: user.Username},
+	})
+}
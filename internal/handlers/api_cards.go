@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+)
+
+// validBridgeTypes are the bridge types a client may send
+var validBridgeTypes = map[models.BridgeType]bool{
+	models.BridgeHindiPhonetic:  true,
+	models.BridgeDutchSyntax:    true,
+	models.BridgeEnglishCognate: true,
+}
+
+func apiListCards(w http.ResponseWriter, r *http.Request) {
+	islandID, _ := strconv.ParseInt(r.URL.Query().Get("island"), 10, 64)
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	cards, err := repository.FilterCards(currentUserID(r), islandID, query)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+
+	out := make([]apiCard, 0, len(cards))
+	for i := range cards {
+		out = append(out, toAPICard(&cards[i], nil))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func apiCreateCard(w http.ResponseWriter, r *http.Request) {
+	var in apiCardInput
+	if !decodeJSON(w, r, &in) || !validateCardInput(w, &in) {
+		return
+	}
+	if in.IslandID == 0 {
+		in.IslandID = 1 // Default to first island, as the add form does
+	}
+
+	card := &models.Card{
+		IslandID:        sql.NullInt64{Int64: in.IslandID, Valid: true},
+		Term:            in.Term,
+		Translation:     in.Translation,
+		ExampleSentence: in.Example,
+		Notes:           in.Notes,
+		UserID:          sql.NullInt64{Int64: currentUserID(r), Valid: true},
+	}
+	if in.FrequencyRank != nil {
+		card.FrequencyRank = sql.NullInt64{Int64: *in.FrequencyRank, Valid: true}
+	}
+	if err := repository.CreateCard(card); err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	if err := createBridges(card.ID, in.Bridges); err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+
+	writeCard(w, r, http.StatusCreated, card.ID)
+}
+
+func apiGetCard(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiPathID(w, r, "id")
+	if !ok {
+		return
+	}
+	if _, ok := apiVisibleCard(w, r, id); !ok {
+		return
+	}
+	writeCard(w, r, http.StatusOK, id)
+}
+
+func apiUpdateCard(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiPathID(w, r, "id")
+	if !ok {
+		return
+	}
+	card, ok := apiEditableCard(w, r, id)
+	if !ok {
+		return
+	}
+
+	var in apiCardInput
+	if !decodeJSON(w, r, &in) || !validateCardInput(w, &in) {
+		return
+	}
+
+	if in.IslandID != 0 {
+		card.IslandID = sql.NullInt64{Int64: in.IslandID, Valid: true}
+	}
+	card.Term = in.Term
+	card.Translation = in.Translation
+	card.ExampleSentence = in.Example
+	card.Notes = in.Notes
+	if err := repository.UpdateCard(card); err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+
+	var rank sql.NullInt64
+	if in.FrequencyRank != nil {
+		rank = sql.NullInt64{Int64: *in.FrequencyRank, Valid: true}
+	}
+	if err := repository.UpdateCardFrequencyRank(id, rank); err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+
+	if in.Bridges != nil {
+		if err := repository.DeleteBridgesForCard(id); err != nil {
+			apiInternalError(w, r, err)
+			return
+		}
+		if err := createBridges(id, in.Bridges); err != nil {
+			apiInternalError(w, r, err)
+			return
+		}
+	}
+
+	writeCard(w, r, http.StatusOK, id)
+}
+
+func apiDeleteCard(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiPathID(w, r, "id")
+	if !ok {
+		return
+	}
+	if _, ok := apiEditableCard(w, r, id); !ok {
+		return
+	}
+	if err := repository.DeleteCard(id); err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func apiGetBridges(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiPathID(w, r, "id")
+	if !ok {
+		return
+	}
+	if _, ok := apiVisibleCard(w, r, id); !ok {
+		return
+	}
+
+	bridges, err := repository.GetBridgesForCard(id)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toAPIBridges(bridges))
+}
+
+func apiReplaceBridges(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiPathID(w, r, "id")
+	if !ok {
+		return
+	}
+	if _, ok := apiEditableCard(w, r, id); !ok {
+		return
+	}
+
+	var bridges []apiBridge
+	if !decodeJSON(w, r, &bridges) || !validateBridges(w, bridges) {
+		return
+	}
+	if err := repository.DeleteBridgesForCard(id); err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	if err := createBridges(id, bridges); err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+
+	saved, err := repository.GetBridgesForCard(id)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toAPIBridges(saved))
+}
+
+func apiListIslands(w http.ResponseWriter, r *http.Request) {
+	islands, err := repository.GetAllIslandsWithStats(currentUserID(r))
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+
+	out := make([]apiIsland, 0, len(islands))
+	for i := range islands {
+		out = append(out, toAPIIsland(&islands[i]))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func apiGetIsland(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiPathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	island, err := repository.GetIslandStats(currentUserID(r), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, "island not found")
+		return
+	}
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toAPIIsland(island))
+}
+
+// apiVisibleCard loads a shared card or one of the user's own cards,
+// writing a 404 if there is no such card and a 403 if it is another
+// user's
+func apiVisibleCard(w http.ResponseWriter, r *http.Request, id int64) (*models.Card, bool) {
+	card, err := repository.GetCard(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, "card not found")
+		return nil, false
+	}
+	if err != nil {
+		apiInternalError(w, r, err)
+		return nil, false
+	}
+	if card.UserID.Valid && card.UserID.Int64 != currentUserID(r) {
+		writeAPIError(w, http.StatusForbidden, "card belongs to another user")
+		return nil, false
+	}
+	return card, true
+}
+
+// apiEditableCard loads one of the user's own cards for a write. Shared
+// cards are read-only, as on the words pages, since every learner's
+// progress hangs off them.
+func apiEditableCard(w http.ResponseWriter, r *http.Request, id int64) (*models.Card, bool) {
+	card, ok := apiVisibleCard(w, r, id)
+	if !ok {
+		return nil, false
+	}
+	if !card.UserID.Valid {
+		writeAPIError(w, http.StatusForbidden, "shared cards are read-only")
+		return nil, false
+	}
+	return card, true
+}
+
+// writeCard responds with a card, its bridges and the user's progress
+func writeCard(w http.ResponseWriter, r *http.Request, status int, id int64) {
+	card, err := repository.GetCardWithBridges(id)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+
+	progress, err := repository.GetProgress(currentUserID(r), id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		apiInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, status, toAPICard(card, progress))
+}
+
+// validateCardInput trims a card body and writes a 400 if it is incomplete
+func validateCardInput(w http.ResponseWriter, in *apiCardInput) bool {
+	in.Term = strings.TrimSpace(in.Term)
+	in.Translation = strings.TrimSpace(in.Translation)
+	if in.Term == "" || in.Translation == "" {
+		writeAPIError(w, http.StatusBadRequest, "term and translation are required")
+		return false
+	}
+	return validateBridges(w, in.Bridges)
+}
+
+// validateBridges writes a 400 for empty or unknown bridges
+func validateBridges(w http.ResponseWriter, bridges []apiBridge) bool {
+	for _, b := range bridges {
+		if !validBridgeTypes[b.Type] {
+			writeAPIError(w, http.StatusBadRequest, "unknown bridge type "+strconv.Quote(string(b.Type)))
+			return false
+		}
+		if strings.TrimSpace(b.Content) == "" {
+			writeAPIError(w, http.StatusBadRequest, "bridge content is required")
+			return false
+		}
+	}
+	return true
+}
+
+// createBridges stores bridges for a card whose old bridges are gone
+func createBridges(cardID int64, bridges []apiBridge) error {
+	for _, b := range bridges {
+		if err := repository.CreateBridge(&models.Bridge{
+			CardID:        cardID,
+			BridgeType:    b.Type,
+			BridgeContent: strings.TrimSpace(b.Content),
+			Explanation:   b.Explanation,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
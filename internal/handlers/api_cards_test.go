@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAPICardOwnership(t *testing.T) {
+	openTestDB(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+	shared := createTestCard(t, "compartido", 0)
+	alicesCard := createTestCard(t, "mío", alice)
+	bobsCard := createTestCard(t, "suyo", bob)
+
+	cardBody := `{"term": "nuevo", "translation": "new"}`
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		cardID  int64
+		body    string
+		want    int
+	}{
+		{"get shared", apiGetCard, http.MethodGet, shared, "", http.StatusOK},
+		{"get another user's", apiGetCard, http.MethodGet, alicesCard, "", http.StatusForbidden},
+		{"get missing", apiGetCard, http.MethodGet, 999999, "", http.StatusNotFound},
+		{"update shared", apiUpdateCard, http.MethodPut, shared, cardBody, http.StatusForbidden},
+		{"update another user's", apiUpdateCard, http.MethodPut, alicesCard, cardBody, http.StatusForbidden},
+		{"update own", apiUpdateCard, http.MethodPut, bobsCard, cardBody, http.StatusOK},
+		{"replace shared bridges", apiReplaceBridges, http.MethodPut, shared, `[]`, http.StatusForbidden},
+		{"delete shared", apiDeleteCard, http.MethodDelete, shared, "", http.StatusForbidden},
+		{"delete another user's", apiDeleteCard, http.MethodDelete, alicesCard, "", http.StatusForbidden},
+		{"delete own", apiDeleteCard, http.MethodDelete, bobsCard, "", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		id := strconv.FormatInt(tt.cardID, 10)
+		r := httptest.NewRequest(tt.method, "/api/v1/cards/"+id, strings.NewReader(tt.body))
+		r.SetPathValue("id", id)
+		w := httptest.NewRecorder()
+		tt.handler(w, asUser(r, bob))
+		if w.Code != tt.want {
+			t.Errorf("%s: got %d, want %d (%s)", tt.name, w.Code, tt.want, w.Body)
+		}
+	}
+
+	for cardID, want := range map[int64]int{shared: http.StatusOK, alicesCard: http.StatusForbidden} {
+		body := fmt.Sprintf(`{"card_id": %d, "answer": "mío"}`, cardID)
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/answers/check", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		apiCheckAnswer(w, asUser(r, bob))
+		if w.Code != want {
+			t.Errorf("check answer for card %d: got %d, want %d (%s)", cardID, w.Code, want, w.Body)
+		}
+	}
+}
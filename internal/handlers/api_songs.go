@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+	"languagepapi/internal/service"
+)
+
+// validSongModes are the song lesson modes a client may start
+var validSongModes = map[models.SongMode]bool{
+	models.SongModeVocab:     true,
+	models.SongModeLyrics:    true,
+	models.SongModeListening: true,
+	models.SongModeFull:      true,
+	models.SongModeDictation: true,
+}
+
+func apiListSongs(w http.ResponseWriter, r *http.Request) {
+	data, err := songService.GetSongHomeData(currentUserID(r))
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, apiSongHome{
+		Due:          toAPISongs(data.DueSongs),
+		Available:    toAPISongs(data.AvailableSongs),
+		SongsLearned: data.TotalSongsLearned,
+	})
+}
+
+func apiGetSong(w http.ResponseWriter, r *http.Request) {
+	songID, ok := apiPathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	song, err := repository.GetSongWithDetails(songID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, "song not found")
+		return
+	}
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	progress, _ := repository.GetSongProgress(currentUserID(r), songID)
+
+	out := apiSongDetail{
+		Song:       toAPISong(song, progress),
+		Lines:      make([]apiSongLine, 0, len(song.Lines)),
+		Vocabulary: make([]apiSongVocab, 0, len(song.Vocabulary)),
+	}
+	for i := range song.Lines {
+		out.Lines = append(out.Lines, *toAPISongLine(&song.Lines[i]))
+	}
+	for i := range song.Vocabulary {
+		out.Vocabulary = append(out.Vocabulary, *toAPISongVocab(&song.Vocabulary[i]))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func apiStartSongLesson(w http.ResponseWriter, r *http.Request) {
+	songID, ok := apiPathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var in apiSongLessonInput
+	if r.ContentLength != 0 && !decodeJSON(w, r, &in) {
+		return
+	}
+	if in.Mode == "" {
+		in.Mode = models.SongModeFull
+	}
+	if !validSongModes[in.Mode] {
+		writeAPIError(w, http.StatusBadRequest, "mode must be vocab, lyrics, listening, dictation or full")
+		return
+	}
+
+	if _, err := repository.GetSong(songID); errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, "song not found")
+		return
+	} else if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+
+	lesson, err := songService.StartOrResumeSongLesson(currentUserID(r), songID, in.Mode)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	writeSongLesson(w, r, lesson)
+}
+
+func apiGetSongLesson(w http.ResponseWriter, r *http.Request) {
+	if lesson, ok := apiActiveSongLesson(w, r); ok {
+		writeSongLesson(w, r, lesson)
+	}
+}
+
+func apiSongLessonVocab(w http.ResponseWriter, r *http.Request) {
+	var in apiRatingInput
+	if !decodeJSON(w, r, &in) || !validateRating(w, in.Rating) {
+		return
+	}
+	if lesson, ok := apiActiveSongLesson(w, r); ok {
+		rateSongVocab(lesson, models.Rating(in.Rating))
+		writeSongLesson(w, r, lesson)
+	}
+}
+
+func apiSongLessonNextPhase(w http.ResponseWriter, r *http.Request) {
+	if lesson, ok := apiActiveSongLesson(w, r); ok {
+		nextSongPhase(currentUserID(r), lesson)
+		writeSongLesson(w, r, lesson)
+	}
+}
+
+func apiSongLessonNextLine(w http.ResponseWriter, r *http.Request) {
+	if lesson, ok := apiActiveSongLesson(w, r); ok {
+		nextSongLine(lesson, true)
+		writeSongLesson(w, r, lesson)
+	}
+}
+
+func apiSongLessonSkipLine(w http.ResponseWriter, r *http.Request) {
+	if lesson, ok := apiActiveSongLesson(w, r); ok {
+		nextSongLine(lesson, false)
+		writeSongLesson(w, r, lesson)
+	}
+}
+
+func apiSongLessonBlank(w http.ResponseWriter, r *http.Request) {
+	var in apiBlankInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+	if lesson, ok := apiActiveSongLesson(w, r); ok {
+		submitSongBlank(currentUserID(r), lesson, in.Answer)
+		writeSongLesson(w, r, lesson)
+	}
+}
+
+func apiSongLessonDictation(w http.ResponseWriter, r *http.Request) {
+	var in apiBlankInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+	if lesson, ok := apiActiveSongLesson(w, r); ok {
+		submitSongDictation(currentUserID(r), lesson, in.Answer)
+		writeSongLesson(w, r, lesson)
+	}
+}
+
+func apiSongLessonNextDictation(w http.ResponseWriter, r *http.Request) {
+	if lesson, ok := apiActiveSongLesson(w, r); ok {
+		nextSongDictation(lesson)
+		writeSongLesson(w, r, lesson)
+	}
+}
+
+func apiSongLessonComplete(w http.ResponseWriter, r *http.Request) {
+	if lesson, ok := apiActiveSongLesson(w, r); ok {
+		lesson.CurrentPhase = models.SongPhaseComplete
+		writeSongLesson(w, r, lesson)
+	}
+}
+
+// apiActiveSongLesson loads the user's unfinished lesson for the song in
+// the path, writing a 404 if there is none
+func apiActiveSongLesson(w http.ResponseWriter, r *http.Request) (*models.SongLesson, bool) {
+	songID, ok := apiPathID(w, r, "id")
+	if !ok {
+		return nil, false
+	}
+
+	lesson, err := songService.GetActiveSongLesson(currentUserID(r), songID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, "no unfinished lesson for this song")
+		return nil, false
+	}
+	if err != nil {
+		apiInternalError(w, r, err)
+		return nil, false
+	}
+	return lesson, true
+}
+
+// writeSongLesson responds with the lesson's current item. A lesson that
+// has reached its last phase is recorded as complete and its summary
+// returned.
+func writeSongLesson(w http.ResponseWriter, r *http.Request, lesson *models.SongLesson) {
+	settleSongPhase(lesson)
+
+	out := apiSongLesson{
+		SongID: lesson.Song.ID,
+		Mode:   getSongMode(lesson),
+		Phase:  lesson.CurrentPhase,
+		Index:  lesson.CurrentIndex,
+	}
+
+	switch lesson.CurrentPhase {
+	case models.SongPhaseVocabPreview:
+		card := &lesson.VocabCards[lesson.CurrentIndex]
+		out.Total = len(lesson.VocabCards)
+		out.Vocab = toAPISongVocab(&card.SongVocab)
+		out.Vocab.Mode = card.Mode
+
+	case models.SongPhaseLineBreakdown:
+		out.Total = len(lesson.Lines)
+		out.Line = toAPISongLine(&lesson.Lines[lesson.CurrentIndex])
+
+	case models.SongPhaseFillBlanks:
+		blank := &lesson.Blanks[lesson.CurrentIndex]
+		out.Total = len(lesson.Blanks)
+		out.Blank = &apiSongBlank{WordIndex: blank.BlankIndex, Choices: blank.Choices}
+		if blank.Line != nil {
+			out.Blank.LineNumber = blank.Line.LineNumber
+			out.Blank.Text = service.RenderBlankLine(blank.Line.SpanishText, blank.BlankIndex)
+			out.Blank.StartMs = blank.Line.StartTimeMs
+			out.Blank.EndMs = blank.Line.EndTimeMs
+		}
+
+	case models.SongPhaseDictation:
+		out.Total = len(lesson.Dictations)
+		out.Dictation = toAPISongDictation(&lesson.Dictations[lesson.CurrentIndex])
+
+	case models.SongPhaseComplete:
+		summary := songLessonSummaryFor(currentUserID(r), lesson)
+		out.Summary = &apiSongLessonSummary{
+			VocabReviewed:    summary.VocabReviewed,
+			VocabCorrect:     summary.VocabCorrect,
+			LinesStudied:     summary.LinesStudied,
+			BlanksCorrect:    summary.BlanksCorrect,
+			BlanksTotal:      summary.BlanksTotal,
+			DictationCorrect: summary.DictationCorrect,
+			DictationTotal:   summary.DictationTotal,
+			Accuracy:         summary.Accuracy,
+			XPEarned:         summary.XPEarned,
+			Message:          summary.Message,
+			Achievements:     toAPIAchievements(summary.Achievements),
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func apiListSongLinesVersions(w http.ResponseWriter, r *http.Request) {
+	songID, ok := apiPathID(w, r, "id")
+	if !ok {
+		return
+	}
+	if _, err := repository.GetSong(songID); errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, "song not found")
+		return
+	} else if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+
+	versions, err := repository.GetSongLinesVersions(songID)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	out := make([]apiSongLinesVersion, 0, len(versions))
+	for i, v := range versions {
+		out = append(out, apiSongLinesVersion{
+			ID:        v.ID,
+			Note:      v.Note,
+			Lines:     len(v.Lines),
+			CreatedAt: v.CreatedAt,
+			Current:   i == 0,
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func apiEditSongLines(w http.ResponseWriter, r *http.Request) {
+	if !apiRequireAdmin(w, r) {
+		return
+	}
+	songID, ok := apiPathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var in apiLineEditsInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+
+	edits := make([]service.LineEdit, len(in.Edits))
+	for i, e := range in.Edits {
+		edits[i] = service.LineEdit{Op: e.Op, Line: e.Line, Ms: e.Ms, StartMs: e.StartMs, EndMs: e.EndMs, Word: e.Word, Text: e.Text}
+	}
+	err := lyricsService.EditSongLines(currentUserID(r), songID, edits)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeAPIError(w, http.StatusNotFound, "song not found")
+		return
+	case errors.Is(err, service.ErrInvalidEdit):
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		apiInternalError(w, r, err)
+		return
+	}
+	writeSongLines(w, r, songID, "")
+}
+
+func apiUndoSongLines(w http.ResponseWriter, r *http.Request) {
+	if !apiRequireAdmin(w, r) {
+		return
+	}
+	songID, ok := apiPathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	version, err := lyricsService.UndoSongLines(songID)
+	if errors.Is(err, service.ErrNothingToUndo) {
+		writeAPIError(w, http.StatusConflict, "no lyrics edit to undo")
+		return
+	}
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	writeSongLines(w, r, songID, version.Note)
+}
+
+// writeSongLines responds with a song's current lines
+func writeSongLines(w http.ResponseWriter, r *http.Request, songID int64, undone string) {
+	lines, err := repository.GetSongLines(songID)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	out := apiSongLines{Lines: make([]apiSongLine, 0, len(lines)), Undone: undone}
+	for i := range lines {
+		out.Lines = append(out.Lines, *toAPISongLine(&lines[i]))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
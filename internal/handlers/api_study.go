@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"languagepapi/internal/grading"
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+	"languagepapi/internal/service"
+)
+
+// maxQueueCards caps the queue endpoint's limit parameter
+const maxQueueCards = 200
+
+func apiGetQueue(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		settings, _ := service.LoadUserSettings(userID)
+		limit = settings.ReviewsPerSession
+	}
+	limit = min(limit, maxQueueCards)
+
+	session, err := reviewService.StartSession(userID, limit)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	dueCount, err := repository.CountDueCards(userID)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	newCount, err := repository.CountNewCards(userID)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+
+	queue := apiQueue{DueCount: dueCount, NewCount: newCount, Cards: make([]apiCard, 0, len(session.Cards))}
+	for i := range session.Cards {
+		card := &session.Cards[i]
+		attachBridges(userID, &card.Card)
+		queue.Cards = append(queue.Cards, toAPICard(&card.Card, card.Progress))
+	}
+	writeJSON(w, http.StatusOK, queue)
+}
+
+func apiSubmitReview(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	var in apiReviewInput
+	if !decodeJSON(w, r, &in) || !validateRating(w, in.Rating) {
+		return
+	}
+	card, ok := apiVisibleCard(w, r, in.CardID)
+	if !ok {
+		return
+	}
+
+	progress, err := repository.GetProgress(userID, card.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		apiInternalError(w, r, err)
+		return
+	}
+
+	// Review the card on its own, as lessons do
+	session := &models.ReviewSession{
+		UserID: userID,
+		Cards:  []models.CardWithProgress{{Card: *card, Progress: progress}},
+	}
+	result, err := reviewService.SubmitReview(session, card.ID, models.Rating(in.Rating), in.DurationMs)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, apiReviewResult{
+		CardID:       card.ID,
+		XPEarned:     result.XPEarned,
+		NextDue:      result.NextDue,
+		IntervalDays: result.Interval,
+		Progress:     toAPIProgress(session.Cards[0].Progress),
+	})
+}
+
+func apiCheckAnswer(w http.ResponseWriter, r *http.Request) {
+	var in apiAnswerInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+	card, ok := apiVisibleCard(w, r, in.CardID)
+	if !ok {
+		return
+	}
+
+	var expected string
+	switch in.Field {
+	case "", "term":
+		expected = card.Term
+	case "translation":
+		expected = card.Translation
+	default:
+		writeAPIError(w, http.StatusBadRequest, "field must be term or translation")
+		return
+	}
+
+	result := grading.Grade(in.Answer, expected)
+	notes := result.Notes
+	if notes == nil {
+		notes = []string{}
+	}
+	writeJSON(w, http.StatusOK, apiGrade{
+		Verdict:  result.Verdict,
+		Correct:  result.Correct(),
+		Expected: result.Expected,
+		Distance: result.Distance,
+		Score:    result.Score,
+		Notes:    notes,
+		Rating:   int(grading.Rate(result, time.Duration(in.DurationMs)*time.Millisecond)),
+	})
+}
+
+func apiGetLesson(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+	defer lockLesson(userID)()
+
+	active, err := lessonService.StartOrResumeLesson(userID)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	unfinished := active.Session != nil && !active.Session.CompletedAt.Valid
+	writeLesson(w, r, active, unfinished && active.Position >= len(active.Lesson.Cards))
+}
+
+func apiLessonReview(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	var in apiReviewInput
+	if !decodeJSON(w, r, &in) || !validateRating(w, in.Rating) {
+		return
+	}
+
+	defer lockLesson(userID)()
+
+	active, ok := apiActiveLesson(w, r)
+	if !ok {
+		return
+	}
+	if active.Position >= len(active.Lesson.Cards) {
+		writeLesson(w, r, active, false)
+		return
+	}
+	if current := active.Lesson.Cards[active.Position]; current.ID != in.CardID {
+		writeAPIError(w, http.StatusConflict, fmt.Sprintf("the current lesson card is %d", current.ID))
+		return
+	}
+
+	if err := answerLessonCard(userID, active, models.Rating(in.Rating), in.DurationMs); err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	writeLesson(w, r, active, active.Position >= len(active.Lesson.Cards))
+}
+
+func apiLessonSkip(w http.ResponseWriter, r *http.Request) {
+	defer lockLesson(currentUserID(r))()
+
+	active, ok := apiActiveLesson(w, r)
+	if !ok {
+		return
+	}
+	if active.Position >= len(active.Lesson.Cards) {
+		writeLesson(w, r, active, false)
+		return
+	}
+
+	if err := skipLessonCard(active); err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	writeLesson(w, r, active, active.Position >= len(active.Lesson.Cards))
+}
+
+// apiActiveLesson loads today's lesson, writing a 404 if none was started
+func apiActiveLesson(w http.ResponseWriter, r *http.Request) (*models.ActiveLesson, bool) {
+	active, err := lessonService.GetActiveLesson(currentUserID(r))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, "no lesson started today")
+		return nil, false
+	}
+	if err != nil {
+		apiInternalError(w, r, err)
+		return nil, false
+	}
+	return active, true
+}
+
+// writeLesson responds with the lesson's current card, or its summary once
+// every card is done. justFinished is passed on to lessonSummaryFor.
+func writeLesson(w http.ResponseWriter, r *http.Request, active *models.ActiveLesson, justFinished bool) {
+	userID := currentUserID(r)
+	lesson := active.Lesson
+
+	out := apiLesson{
+		DayNumber:  lesson.DayNumber,
+		TotalDays:  lesson.TotalDays,
+		Position:   active.Position,
+		TotalCards: len(lesson.Cards),
+		XPEarned:   active.XPEarned,
+	}
+	if lesson.Phase != nil {
+		out.Phase = lesson.Phase.Name
+	}
+
+	switch {
+	case len(lesson.Cards) == 0:
+		// Nothing to study today
+	case active.Position < len(lesson.Cards):
+		card := &lesson.Cards[active.Position]
+		attachBridges(userID, &card.Card)
+		out.Current = &apiLessonCard{
+			Card:      toAPICard(&card.Card, card.Progress),
+			Mode:      card.Mode,
+			IsNew:     card.IsNew,
+			SongTitle: card.SongTitle,
+			Previews:  toAPIPreviews(reviewService.GetSchedulingPreview(userID, &card.CardWithProgress)),
+		}
+	default:
+		summary := lessonSummaryFor(userID, active, justFinished)
+		out.Summary = &apiLessonSummary{
+			TotalCards:   summary.TotalCards,
+			CorrectCount: summary.CorrectCount,
+			Accuracy:     summary.Accuracy,
+			TotalTimeMs:  summary.TotalTimeMs,
+			XPEarned:     summary.XPEarned,
+			NewLearned:   summary.NewLearned,
+			Message:      summary.Message,
+			Results:      toAPICardResults(summary.CardResults),
+			Achievements: toAPIAchievements(summary.Achievements),
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func apiGetStats(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	stats, err := service.GetGamificationStats(userID)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	total, learned, due, mastered, err := repository.GetCardProgressStats(userID)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+
+	out := apiStats{
+		Level:          stats.Level,
+		TotalXP:        stats.CurrentXP,
+		XPForNextLevel: stats.XPForNextLevel,
+		LevelProgress:  stats.XPProgress,
+		CurrentStreak:  stats.CurrentStreak,
+		LongestStreak:  stats.LongestStreak,
+		ActiveToday:    stats.IsActiveToday,
+		DailyGoal:      stats.DailyGoal,
+		DailyProgress:  stats.DailyProgress,
+		Cards:          apiCardStats{Total: total, Learned: learned, Due: due, Mastered: mastered},
+		Achievements:   make([]apiAchievement, 0, len(stats.Achievements)),
+	}
+	for _, a := range stats.Achievements {
+		out.Achievements = append(out.Achievements, apiAchievement{
+			Name:        a.Name,
+			Description: a.Description,
+			Icon:        a.Icon,
+			XPReward:    a.XPReward,
+			Earned:      a.Earned,
+			EarnedAt:    nullTime(a.EarnedAt),
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func apiGetSettings(w http.ResponseWriter, r *http.Request) {
+	settings, _ := service.LoadUserSettings(currentUserID(r))
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// apiSaveSettings updates the settings given in the body; omitted fields
+// keep their current values
+func apiSaveSettings(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	settings, _ := service.LoadUserSettings(userID)
+	if !decodeJSON(w, r, settings) {
+		return
+	}
+	normalizeSettings(settings)
+
+	if err := repository.SaveUserSettings(userID, settings); err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// validateRating writes a 400 unless rating is 1-4
+func validateRating(w http.ResponseWriter, rating int) bool {
+	if rating < int(models.RatingAgain) || rating > int(models.RatingEasy) {
+		writeAPIError(w, http.StatusBadRequest, "rating must be 1 (again) to 4 (easy)")
+		return false
+	}
+	return true
+}
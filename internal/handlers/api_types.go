@@ -0,0 +1,578 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/url"
+	"sort"
+	"time"
+
+	"languagepapi/internal/fsrs"
+	"languagepapi/internal/grading"
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+	"languagepapi/internal/service"
+)
+
+// JSON shapes of the /api/v1 endpoints. Models use sql.Null* types and
+// Go field names, so responses are mapped into these instead of encoding
+// models directly. Fields without omitempty are documented as required in
+// the OpenAPI document.
+
+type apiLoginInput struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type apiLoginResult struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	User      apiUser   `json:"user"`
+}
+
+type apiUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+type apiCard struct {
+	ID            int64        `json:"id"`
+	IslandID      *int64       `json:"island_id,omitempty"`
+	Term          string       `json:"term"`
+	Translation   string       `json:"translation"`
+	Example       string       `json:"example,omitempty"`
+	Notes         string       `json:"notes,omitempty"`
+	AudioURL      string       `json:"audio_url,omitempty"`
+	FrequencyRank *int64       `json:"frequency_rank,omitempty"`
+	Source        string       `json:"source,omitempty"`
+	SourceSongID  *int64       `json:"source_song_id,omitempty"`
+	Shared        bool         `json:"shared"`
+	Bridges       []apiBridge  `json:"bridges,omitempty"`
+	Progress      *apiProgress `json:"progress,omitempty"`
+}
+
+// apiCardInput creates or updates a card. On update, a null bridges list
+// keeps the card's bridges and any list replaces them.
+type apiCardInput struct {
+	IslandID      int64       `json:"island_id,omitempty"`
+	Term          string      `json:"term"`
+	Translation   string      `json:"translation"`
+	Example       string      `json:"example,omitempty"`
+	Notes         string      `json:"notes,omitempty"`
+	FrequencyRank *int64      `json:"frequency_rank,omitempty"`
+	Bridges       []apiBridge `json:"bridges,omitempty"`
+}
+
+type apiBridge struct {
+	Type        models.BridgeType `json:"type"`
+	Content     string            `json:"content"`
+	Explanation string            `json:"explanation,omitempty"`
+}
+
+type apiProgress struct {
+	State         models.CardState `json:"state"`
+	Stability     float64          `json:"stability"`
+	Difficulty    float64          `json:"difficulty"`
+	Reps          int              `json:"reps"`
+	Lapses        int              `json:"lapses"`
+	ScheduledDays int              `json:"scheduled_days"`
+	Due           *time.Time       `json:"due,omitempty"`
+	LastReview    *time.Time       `json:"last_review,omitempty"`
+}
+
+type apiIsland struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	Icon          string `json:"icon"`
+	UnlockXP      int    `json:"unlock_xp"`
+	TotalCards    int    `json:"total_cards"`
+	LearnedCards  int    `json:"learned_cards"`
+	DueCards      int    `json:"due_cards"`
+	MasteredCards int    `json:"mastered_cards"`
+}
+
+type apiQueue struct {
+	DueCount int       `json:"due_count"`
+	NewCount int       `json:"new_count"`
+	Cards    []apiCard `json:"cards"`
+}
+
+type apiReviewInput struct {
+	CardID     int64 `json:"card_id"`
+	Rating     int   `json:"rating"` // 1 again, 2 hard, 3 good, 4 easy
+	DurationMs int   `json:"duration_ms,omitempty"`
+}
+
+type apiAnswerInput struct {
+	CardID     int64  `json:"card_id"`
+	Answer     string `json:"answer"`
+	Field      string `json:"field,omitempty"` // Which side the answer is for: term (default) or translation
+	DurationMs int    `json:"duration_ms,omitempty"`
+}
+
+// apiGrade is a graded answer with the rating it earns; submit the rating
+// to /reviews or /lesson/review to record it
+type apiGrade struct {
+	Verdict  grading.Verdict `json:"verdict"` // exact, accent, typo or wrong
+	Correct  bool            `json:"correct"`
+	Expected string          `json:"expected"`
+	Distance int             `json:"distance"`
+	Score    float64         `json:"score"`
+	Notes    []string        `json:"notes"`
+	Rating   int             `json:"rating"`
+}
+
+type apiReviewResult struct {
+	CardID       int64        `json:"card_id"`
+	XPEarned     int          `json:"xp_earned"`
+	NextDue      time.Time    `json:"next_due"`
+	IntervalDays int          `json:"interval_days"`
+	Progress     *apiProgress `json:"progress,omitempty"`
+}
+
+// apiLesson is the state of today's lesson: the card to answer next, or
+// the summary once every card is done
+type apiLesson struct {
+	DayNumber  int               `json:"day_number"`
+	TotalDays  int               `json:"total_days"`
+	Phase      string            `json:"phase,omitempty"`
+	Position   int               `json:"position"`
+	TotalCards int               `json:"total_cards"`
+	XPEarned   int               `json:"xp_earned"`
+	Current    *apiLessonCard    `json:"current,omitempty"`
+	Summary    *apiLessonSummary `json:"summary,omitempty"`
+}
+
+type apiLessonCard struct {
+	Card      apiCard      `json:"card"`
+	Mode      string       `json:"mode"`
+	IsNew     bool         `json:"is_new"`
+	SongTitle string       `json:"song_title,omitempty"`
+	Previews  []apiPreview `json:"previews"`
+}
+
+// apiPreview is where a card would be scheduled for one rating
+type apiPreview struct {
+	Rating       int       `json:"rating"`
+	IntervalDays int       `json:"interval_days"`
+	NextDue      time.Time `json:"next_due"`
+}
+
+type apiLessonSummary struct {
+	TotalCards   int              `json:"total_cards"`
+	CorrectCount int              `json:"correct_count"`
+	Accuracy     int              `json:"accuracy"`
+	TotalTimeMs  int64            `json:"total_time_ms"`
+	XPEarned     int              `json:"xp_earned"`
+	NewLearned   int              `json:"new_learned"`
+	Message      string           `json:"message"`
+	Results      []apiCardResult  `json:"results"`
+	Achievements []apiAchievement `json:"achievements,omitempty"`
+}
+
+type apiCardResult struct {
+	CardID      int64  `json:"card_id"`
+	Term        string `json:"term"`
+	Translation string `json:"translation"`
+	Rating      int    `json:"rating"`
+	TimeSpentMs int64  `json:"time_spent_ms"`
+	Mode        string `json:"mode"`
+	Correct     bool   `json:"correct"`
+	IsNew       bool   `json:"is_new"`
+}
+
+type apiAchievement struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Icon        string     `json:"icon"`
+	XPReward    int        `json:"xp_reward"`
+	Earned      bool       `json:"earned"`
+	EarnedAt    *time.Time `json:"earned_at,omitempty"`
+}
+
+type apiSong struct {
+	ID              int64            `json:"id"`
+	Title           string           `json:"title"`
+	Artist          string           `json:"artist"`
+	Album           string           `json:"album,omitempty"`
+	Difficulty      int              `json:"difficulty"`
+	DurationSeconds int              `json:"duration_seconds"`
+	YouTubeID       string           `json:"youtube_id,omitempty"`
+	ThumbnailURL    string           `json:"thumbnail_url,omitempty"`
+	AudioURL        string           `json:"audio_url,omitempty"`
+	Progress        *apiSongProgress `json:"progress,omitempty"`
+}
+
+type apiSongProgress struct {
+	State             models.CardState `json:"state"`
+	Stability         float64          `json:"stability"`
+	Difficulty        float64          `json:"difficulty"`
+	Reps              int              `json:"reps"`
+	Lapses            int              `json:"lapses"`
+	ScheduledDays     int              `json:"scheduled_days"`
+	Retrievability    float64          `json:"retrievability,omitempty"` // In song lists
+	Due               *time.Time       `json:"due,omitempty"`
+	LastReview        *time.Time       `json:"last_review,omitempty"`
+	VocabComplete     bool             `json:"vocab_complete"`
+	LyricsComplete    bool             `json:"lyrics_complete"`
+	ListeningComplete bool             `json:"listening_complete"`
+	TotalListens      int              `json:"total_listens"`
+}
+
+type apiSongHome struct {
+	Due          []apiSong `json:"due"`
+	Available    []apiSong `json:"available"`
+	SongsLearned int       `json:"songs_learned"`
+}
+
+type apiSongDetail struct {
+	Song       apiSong        `json:"song"`
+	Lines      []apiSongLine  `json:"lines"`
+	Vocabulary []apiSongVocab `json:"vocabulary"`
+}
+
+type apiSongLine struct {
+	LineNumber int    `json:"line_number"`
+	StartMs    int    `json:"start_ms"`
+	EndMs      int    `json:"end_ms"`
+	Spanish    string `json:"spanish"`
+	English    string `json:"english,omitempty"`
+}
+
+type apiSongVocab struct {
+	Word        string `json:"word"`
+	Translation string `json:"translation"`
+	KeyVocab    bool   `json:"key_vocab"`
+	CardID      *int64 `json:"card_id,omitempty"`
+	Mode        string `json:"mode,omitempty"`
+}
+
+type apiSongLessonInput struct {
+	Mode models.SongMode `json:"mode,omitempty"` // vocab, lyrics, listening, dictation or full (default)
+}
+
+type apiRatingInput struct {
+	Rating int `json:"rating"`
+}
+
+type apiBlankInput struct {
+	Answer string `json:"answer"`
+}
+
+// apiSongLesson is the state of a song lesson: the current phase and the
+// item to work on in it, or the summary once the lesson is complete
+type apiSongLesson struct {
+	SongID    int64                 `json:"song_id"`
+	Mode      models.SongMode       `json:"mode"`
+	Phase     models.SongPhase      `json:"phase"`
+	Index     int                   `json:"index"`
+	Total     int                   `json:"total"`
+	Vocab     *apiSongVocab         `json:"vocab,omitempty"`
+	Line      *apiSongLine          `json:"line,omitempty"`
+	Blank     *apiSongBlank         `json:"blank,omitempty"`
+	Dictation *apiSongDictation     `json:"dictation,omitempty"`
+	Summary   *apiSongLessonSummary `json:"summary,omitempty"`
+}
+
+// apiSongBlank is a lyric line with one word blanked out
+type apiSongBlank struct {
+	LineNumber int      `json:"line_number"`
+	Text       string   `json:"text"`
+	WordIndex  int      `json:"word_index"`
+	StartMs    int      `json:"start_ms"`
+	EndMs      int      `json:"end_ms"`
+	Choices    []string `json:"choices,omitempty"` // Options to pick the word from; none to type it
+}
+
+// apiSongDictation is a lyric line to type as it plays. Once answered it
+// has the line and the answer graded word by word.
+type apiSongDictation struct {
+	LineNumber   int                `json:"line_number"`
+	StartMs      int                `json:"start_ms"`
+	EndMs        int                `json:"end_ms"`
+	Answered     bool               `json:"answered"`
+	Answer       string             `json:"answer,omitempty"`
+	Spanish      string             `json:"spanish,omitempty"`
+	English      string             `json:"english,omitempty"`
+	WordsCorrect int                `json:"words_correct"`
+	WordsTotal   int                `json:"words_total"`
+	Words        []apiDictationWord `json:"words,omitempty"`
+}
+
+// apiDictationWord is one word of a graded dictation. Expected is empty for
+// a word typed but not sung, typed for a word left out.
+type apiDictationWord struct {
+	Expected string `json:"expected,omitempty"`
+	Typed    string `json:"typed,omitempty"`
+	Verdict  string `json:"verdict"`            // exact, accent, typo or wrong
+	Standard string `json:"standard,omitempty"` // Standard spelling of a colloquial word, e.g. "para" for "pa'"
+}
+
+type apiSongLessonSummary struct {
+	VocabReviewed    int              `json:"vocab_reviewed"`
+	VocabCorrect     int              `json:"vocab_correct"`
+	LinesStudied     int              `json:"lines_studied"`
+	BlanksCorrect    int              `json:"blanks_correct"`
+	BlanksTotal      int              `json:"blanks_total"`
+	DictationCorrect int              `json:"dictation_correct"`
+	DictationTotal   int              `json:"dictation_total"`
+	Accuracy         int              `json:"accuracy"`
+	XPEarned         int              `json:"xp_earned"`
+	Message          string           `json:"message"`
+	Achievements     []apiAchievement `json:"achievements,omitempty"`
+}
+
+// apiLineEdit is one change to a song's lines. Lines are numbered from 1,
+// words from 0.
+type apiLineEdit struct {
+	Op      service.LineEditOp `json:"op"`                 // offset, time, text, translation, split or merge
+	Line    int                `json:"line,omitempty"`     // 0 with offset for every line
+	Ms      int                `json:"ms,omitempty"`       // offset: how far; split: where (optional)
+	StartMs int                `json:"start_ms,omitempty"` // time
+	EndMs   int                `json:"end_ms,omitempty"`   // time
+	Word    int                `json:"word,omitempty"`     // split: the first word of the new line
+	Text    string             `json:"text,omitempty"`     // text and translation
+}
+
+type apiLineEditsInput struct {
+	Edits []apiLineEdit `json:"edits"`
+}
+
+// apiSongLines is a song's lines after an edit or an undo
+type apiSongLines struct {
+	Lines  []apiSongLine `json:"lines"`
+	Undone string        `json:"undone,omitempty"` // The undone edit
+}
+
+type apiSongLinesVersion struct {
+	ID        int64     `json:"id"`
+	Note      string    `json:"note"`
+	Lines     int       `json:"lines"`
+	CreatedAt time.Time `json:"created_at"`
+	Current   bool      `json:"current"`
+}
+
+type apiStats struct {
+	Level          int              `json:"level"`
+	TotalXP        int              `json:"total_xp"`
+	XPForNextLevel int              `json:"xp_for_next_level"`
+	LevelProgress  float64          `json:"level_progress"`
+	CurrentStreak  int              `json:"current_streak"`
+	LongestStreak  int              `json:"longest_streak"`
+	ActiveToday    bool             `json:"active_today"`
+	DailyGoal      int              `json:"daily_goal"`
+	DailyProgress  int              `json:"daily_progress"`
+	Cards          apiCardStats     `json:"cards"`
+	Achievements   []apiAchievement `json:"achievements"`
+}
+
+type apiCardStats struct {
+	Total    int `json:"total"`
+	Learned  int `json:"learned"`
+	Due      int `json:"due"`
+	Mastered int `json:"mastered"`
+}
+
+// apiSettings already carries its JSON names
+type apiSettings = repository.UserSettings
+
+func nullInt(n sql.NullInt64) *int64 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int64
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func toAPICard(c *models.Card, p *models.CardProgress) apiCard {
+	return apiCard{
+		ID:            c.ID,
+		IslandID:      nullInt(c.IslandID),
+		Term:          c.Term,
+		Translation:   c.Translation,
+		Example:       c.ExampleSentence,
+		Notes:         c.Notes,
+		AudioURL:      c.AudioURL,
+		FrequencyRank: nullInt(c.FrequencyRank),
+		Source:        c.Source,
+		SourceSongID:  nullInt(c.SourceSongID),
+		Shared:        !c.UserID.Valid,
+		Bridges:       toAPIBridges(c.Bridges),
+		Progress:      toAPIProgress(p),
+	}
+}
+
+func toAPIBridges(bridges []models.Bridge) []apiBridge {
+	out := make([]apiBridge, 0, len(bridges))
+	for _, b := range bridges {
+		out = append(out, apiBridge{Type: b.BridgeType, Content: b.BridgeContent, Explanation: b.Explanation})
+	}
+	return out
+}
+
+func toAPIProgress(p *models.CardProgress) *apiProgress {
+	if p == nil {
+		return nil
+	}
+	return &apiProgress{
+		State:         p.State,
+		Stability:     p.Stability,
+		Difficulty:    p.Difficulty,
+		Reps:          p.Reps,
+		Lapses:        p.Lapses,
+		ScheduledDays: p.ScheduledDays,
+		Due:           nullTime(p.Due),
+		LastReview:    nullTime(p.LastReview),
+	}
+}
+
+func toAPIIsland(s *models.IslandStats) apiIsland {
+	return apiIsland{
+		ID:            s.ID,
+		Name:          s.Name,
+		Description:   s.Description,
+		Icon:          s.Icon,
+		UnlockXP:      s.UnlockXP,
+		TotalCards:    s.TotalCards,
+		LearnedCards:  s.LearnedCards,
+		DueCards:      s.DueCards,
+		MasteredCards: s.MasteredCards,
+	}
+}
+
+// toAPIPreviews orders a scheduling preview by rating
+func toAPIPreviews(preview map[models.Rating]fsrs.SchedulingPreview) []apiPreview {
+	out := make([]apiPreview, 0, len(preview))
+	for rating, p := range preview {
+		out = append(out, apiPreview{Rating: int(rating), IntervalDays: p.Interval, NextDue: p.NextDue})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Rating < out[j].Rating })
+	return out
+}
+
+func toAPICardResults(results []models.CardResult) []apiCardResult {
+	out := make([]apiCardResult, 0, len(results))
+	for _, cr := range results {
+		out = append(out, apiCardResult{
+			CardID:      cr.CardID,
+			Term:        cr.Term,
+			Translation: cr.Translation,
+			Rating:      int(cr.Rating),
+			TimeSpentMs: cr.TimeSpentMs,
+			Mode:        cr.Mode,
+			Correct:     cr.WasCorrect,
+			IsNew:       cr.IsNew,
+		})
+	}
+	return out
+}
+
+// toAPIAchievements maps newly earned achievements
+func toAPIAchievements(achievements []models.Achievement) []apiAchievement {
+	var out []apiAchievement
+	for _, a := range achievements {
+		out = append(out, apiAchievement{
+			Name:        a.Name,
+			Description: a.Description,
+			Icon:        a.Icon,
+			XPReward:    a.XPReward,
+			Earned:      true,
+		})
+	}
+	return out
+}
+
+func toAPISong(s *models.Song, p *models.SongProgress) apiSong {
+	out := apiSong{
+		ID:              s.ID,
+		Title:           s.Title,
+		Artist:          s.Artist,
+		Album:           s.Album,
+		Difficulty:      s.Difficulty,
+		DurationSeconds: s.DurationSeconds,
+		YouTubeID:       s.YouTubeID,
+		ThumbnailURL:    s.ThumbnailURL,
+	}
+	if s.AudioPath != "" {
+		out.AudioURL = (&url.URL{Path: "/audio/" + s.AudioPath}).String()
+	}
+	if p != nil {
+		out.Progress = &apiSongProgress{
+			State:             p.State,
+			Stability:         p.Stability,
+			Difficulty:        p.Difficulty,
+			Reps:              p.Reps,
+			Lapses:            p.Lapses,
+			ScheduledDays:     p.ScheduledDays,
+			Due:               nullTime(p.Due),
+			LastReview:        nullTime(p.LastReview),
+			VocabComplete:     p.VocabComplete,
+			LyricsComplete:    p.LyricsComplete,
+			ListeningComplete: p.ListeningComplete,
+			TotalListens:      p.TotalListens,
+		}
+	}
+	return out
+}
+
+func toAPISongs(songs []models.SongWithProgress) []apiSong {
+	out := make([]apiSong, 0, len(songs))
+	for i := range songs {
+		song := toAPISong(&songs[i].Song, songs[i].Progress)
+		if song.Progress != nil {
+			song.Progress.Retrievability = songs[i].Retrievability
+		}
+		out = append(out, song)
+	}
+	return out
+}
+
+func toAPISongLine(l *models.SongLine) *apiSongLine {
+	return &apiSongLine{
+		LineNumber: l.LineNumber,
+		StartMs:    l.StartTimeMs,
+		EndMs:      l.EndTimeMs,
+		Spanish:    l.SpanishText,
+		English:    l.EnglishText,
+	}
+}
+
+// toAPISongDictation hides the line's text until it has been typed
+func toAPISongDictation(d *models.SongDictation) *apiSongDictation {
+	out := &apiSongDictation{
+		LineNumber:   d.Line.LineNumber,
+		StartMs:      d.Line.StartTimeMs,
+		EndMs:        d.Line.EndTimeMs,
+		Answered:     d.Answered,
+		WordsCorrect: d.WordsCorrect,
+		WordsTotal:   d.WordsTotal,
+	}
+	if !d.Answered {
+		return out
+	}
+	out.Answer, out.Spanish, out.English = d.UserAnswer, d.Line.SpanishText, d.Line.EnglishText
+	for _, w := range service.GradeDictation(d.Line, d.UserAnswer).Words {
+		out.Words = append(out.Words, apiDictationWord{
+			Expected: w.Expected,
+			Typed:    w.Typed,
+			Verdict:  string(w.Verdict),
+			Standard: w.Colloquial(),
+		})
+	}
+	return out
+}
+
+func toAPISongVocab(v *models.SongVocab) *apiSongVocab {
+	return &apiSongVocab{
+		Word:        v.Word,
+		Translation: v.Translation,
+		KeyVocab:    v.IsKeyVocab,
+		CardID:      nullInt(v.CardID),
+	}
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"languagepapi/components"
+	"languagepapi/internal/service"
+)
+
+// sessionCookieName is the cookie holding the login session token
+const sessionCookieName = "languagepapi_session"
+
+type contextKey string
+
+// userIDKey is the request context key for the authenticated user ID
+const userIDKey contextKey = "userID"
+
+var authService = service.NewAuthService()
+
+// publicPaths are reachable without logging in
+var publicPaths = []string{"/login", "/register", "/static/", "/api/v1/auth/login", "/api/v1/openapi.json"}
+
+// RequireAuth resolves the session cookie, or for API clients a bearer
+// token, and stores the user ID in the request context. Unauthenticated
+// requests are redirected to /login, or get a 401 on the API.
+func RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, p := range publicPaths {
+			if r.URL.Path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(r.URL.Path, p)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		var token string
+		if cookie, err := r.Cookie(sessionCookieName); err == nil {
+			token = cookie.Value
+		}
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			token = strings.TrimSpace(bearer)
+		}
+
+		var userID int64
+		if token != "" {
+			var err error
+			userID, err = authService.UserIDForToken(token)
+			if err != nil && !errors.Is(err, service.ErrInvalidCredentials) {
+				log.Printf("session lookup failed: %v", err)
+			}
+		}
+		if userID == 0 {
+			if strings.HasPrefix(r.URL.Path, apiPrefix) {
+				writeAPIError(w, http.StatusUnauthorized, "authentication required")
+				return
+			}
+			redirectToLogin(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userIDKey, userID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// currentUserID returns the authenticated user for a request.
+// Only valid for handlers behind RequireAuth.
+func currentUserID(r *http.Request) int64 {
+	userID, _ := r.Context().Value(userIDKey).(int64)
+	return userID
+}
+
+// requireOwner writes a 403 and returns false if ownerID is not the current user
+func requireOwner(w http.ResponseWriter, r *http.Request, ownerID int64) bool {
+	if ownerID != currentUserID(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// isAdmin reports whether the current user is an admin (see
+// AuthService.IsAdmin), logging a failed check as not an admin
+func isAdmin(r *http.Request) bool {
+	admin, err := authService.IsAdmin(currentUserID(r))
+	if err != nil {
+		log.Printf("Failed to check admin: %v", err)
+	}
+	return admin
+}
+
+// requireAdmin writes a 403 and returns false unless the current user is an
+// admin
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if !isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// redirectToLogin sends the browser to the login page, using HX-Redirect
+// for HTMX requests so the whole page is replaced
+func redirectToLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", "/login")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// setSessionCookie stores the session token in an HTTP-only cookie
+func setSessionCookie(w http.ResponseWriter, r *http.Request, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  time.Now().Add(service.SessionDuration),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// HandleLoginPage renders the login form
+func HandleLoginPage(w http.ResponseWriter, r *http.Request) {
+	components.Login("", "").Render(r.Context(), w)
+}
+
+// HandleLogin verifies credentials and starts a session
+func HandleLogin(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	username := r.FormValue("username")
+	token, _, err := authService.Login(username, r.FormValue("password"))
+	if err != nil {
+		msg := "Invalid username or password"
+		if !errors.Is(err, service.ErrInvalidCredentials) {
+			log.Printf("login failed: %v", err)
+			msg = "Login failed, please try again"
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		components.Login(username, msg).Render(r.Context(), w)
+		return
+	}
+
+	setSessionCookie(w, r, token)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// HandleRegisterPage renders the registration form
+func HandleRegisterPage(w http.ResponseWriter, r *http.Request) {
+	components.Register("", "").Render(r.Context(), w)
+}
+
+// HandleRegister creates an account and logs the new user in
+func HandleRegister(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	username := r.FormValue("username")
+	password := r.FormValue("password")
+	if password != r.FormValue("confirm") {
+		w.WriteHeader(http.StatusBadRequest)
+		components.Register(username, "Passwords do not match").Render(r.Context(), w)
+		return
+	}
+
+	if _, err := authService.Register(username, password); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		components.Register(username, err.Error()).Render(r.Context(), w)
+		return
+	}
+
+	token, _, err := authService.Login(username, password)
+	if err != nil {
+		http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+
+	setSessionCookie(w, r, token)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// HandleLogout ends the current session
+func HandleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		authService.Logout(cookie.Value)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", "/login")
+		return
+	}
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
@@ -9,14 +9,15 @@ import (
 
 // HandleCalendar renders the calendar/stats page
 func HandleCalendar(w http.ResponseWriter, r *http.Request) {
-	data, err := service.GetCalendarData(defaultUserID)
+	userID := currentUserID(r)
+	data, err := service.GetCalendarData(userID)
 	if err != nil {
 		http.Error(w, "Failed to load calendar data", http.StatusInternalServerError)
 		return
 	}
 
 	// Check and award any new achievements
-	service.CheckAndAwardAchievements(defaultUserID)
+	service.CheckAndAwardAchievements(userID)
 
 	components.Calendar(data).Render(r.Context(), w)
 }
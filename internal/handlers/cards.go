@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"context"
 	"database/sql"
 	"log"
 	"net/http"
@@ -12,12 +11,15 @@ import (
 	"languagepapi/internal/bridge"
 	"languagepapi/internal/models"
 	"languagepapi/internal/repository"
+	"languagepapi/internal/service"
 )
 
 const cardsPerPage = 50
 
 // HandleWords renders the words list page
 func HandleWords(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
 	// Parse query params
 	pageStr := r.URL.Query().Get("page")
 	islandStr := r.URL.Query().Get("island")
@@ -43,10 +45,10 @@ func HandleWords(w http.ResponseWriter, r *http.Request) {
 
 	if searchQuery != "" {
 		// Search mode
-		cards, err = repository.SearchCards(searchQuery, filterIsland)
+		cards, err = repository.SearchCards(userID, searchQuery, filterIsland)
 		totalCards = len(cards)
 	} else if filterIsland > 0 {
-		cards, err = repository.GetCardsByIsland(filterIsland)
+		cards, err = repository.GetCardsByIsland(userID, filterIsland)
 		totalCards = len(cards)
 		// Apply pagination manually for filtered results
 		start := (page - 1) * cardsPerPage
@@ -59,8 +61,8 @@ func HandleWords(w http.ResponseWriter, r *http.Request) {
 			cards = cards[start:end]
 		}
 	} else {
-		totalCards, _ = repository.CountCards()
-		cards, err = repository.GetAllCards(cardsPerPage, (page-1)*cardsPerPage)
+		totalCards, _ = repository.CountCards(userID)
+		cards, err = repository.GetAllCards(userID, cardsPerPage, (page-1)*cardsPerPage)
 	}
 
 	if err != nil {
@@ -115,6 +117,7 @@ func HandleCreateCard(w http.ResponseWriter, r *http.Request) {
 		Term:            term,
 		Translation:     translation,
 		ExampleSentence: example,
+		UserID:          sql.NullInt64{Int64: currentUserID(r), Valid: true},
 	}
 
 	if err := repository.CreateCard(card); err != nil {
@@ -150,23 +153,11 @@ func HandleCreateCard(w http.ResponseWriter, r *http.Request) {
 		hasBridges = true
 	}
 
-	// Generate AI bridges if requested and no manual bridges provided
+	// Generate AI bridges in the background if requested and no manual bridges provided
 	if generateBridges && !hasBridges {
-		go func(cardID int64) {
-			ctx := context.Background()
-			gemini, err := bridge.NewGeminiService(ctx)
-			if err != nil {
-				log.Printf("Failed to create Gemini service: %v", err)
-				return
-			}
-			defer gemini.Close()
-
-			if err := gemini.GenerateAndSaveBridges(ctx, cardID); err != nil {
-				log.Printf("Failed to generate bridges for card %d: %v", cardID, err)
-			} else {
-				log.Printf("Generated bridges for card %d", cardID)
-			}
-		}(card.ID)
+		if _, err := jobService.EnqueueBridges(currentUserID(r), card.ID, false); err != nil {
+			log.Printf("Failed to queue bridges for card %d: %v", card.ID, err)
+		}
 	}
 
 	islands, _ := repository.GetAllIslands()
@@ -182,6 +173,10 @@ func HandleEditCard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorizeCard(w, r, id) {
+		return
+	}
+
 	card, err := repository.GetCardWithBridges(id)
 	if err != nil {
 		http.Error(w, "Card not found", http.StatusNotFound)
@@ -201,6 +196,10 @@ func HandleUpdateCard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorizeCard(w, r, id) {
+		return
+	}
+
 	if err := r.ParseForm(); err != nil {
 		http.Error(w, "Invalid form", http.StatusBadRequest)
 		return
@@ -273,6 +272,7 @@ func HandleUpdateCard(w http.ResponseWriter, r *http.Request) {
 
 // HandleSearchCards searches for cards
 func HandleSearchCards(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 	query := strings.TrimSpace(r.URL.Query().Get("q"))
 	islandStr := r.URL.Query().Get("island")
 
@@ -285,8 +285,8 @@ func HandleSearchCards(w http.ResponseWriter, r *http.Request) {
 
 	if query == "" {
 		// No search query, show regular list
-		cards, _ := repository.GetAllCards(cardsPerPage, 0)
-		totalCards, _ := repository.CountCards()
+		cards, _ := repository.GetAllCards(userID, cardsPerPage, 0)
+		totalCards, _ := repository.CountCards(userID)
 		totalPages := (totalCards + cardsPerPage - 1) / cardsPerPage
 		if totalPages == 0 {
 			totalPages = 1
@@ -296,7 +296,7 @@ func HandleSearchCards(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Search cards
-	cards, err := repository.SearchCards(query, filterIsland)
+	cards, err := repository.SearchCards(userID, query, filterIsland)
 	if err != nil {
 		http.Error(w, "Search failed", http.StatusInternalServerError)
 		return
@@ -305,7 +305,8 @@ func HandleSearchCards(w http.ResponseWriter, r *http.Request) {
 	components.WordsList(cards, islands, 1, 1, len(cards), filterIsland, query).Render(r.Context(), w)
 }
 
-// HandleGenerateBridges generates AI bridges for an existing card
+// HandleGenerateBridges queues AI bridge generation for an existing card,
+// replacing its bridges once done, and renders the polling placeholder
 func HandleGenerateBridges(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -314,30 +315,50 @@ func HandleGenerateBridges(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := r.Context()
-	gemini, err := bridge.NewGeminiService(ctx)
+	if !authorizeCard(w, r, id) {
+		return
+	}
+
+	jobID, err := jobService.EnqueueBridges(currentUserID(r), id, true)
 	if err != nil {
-		http.Error(w, "AI service unavailable", http.StatusServiceUnavailable)
+		http.Error(w, "Failed to queue bridge generation", http.StatusInternalServerError)
 		return
 	}
-	defer gemini.Close()
+	renderEditBridges(w, r, id, jobID)
+}
 
-	// Delete existing bridges first
-	repository.DeleteBridgesForCard(id)
+// HandleCardBridges renders a card's bridge fields, or the placeholder while
+// the generation job in ?job= is unfinished
+func HandleCardBridges(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
 
-	if err := gemini.GenerateAndSaveBridges(ctx, id); err != nil {
-		http.Error(w, "Failed to generate bridges", http.StatusInternalServerError)
+	if !authorizeCard(w, r, id) {
 		return
 	}
 
-	// Return updated card bridges
-	card, err := repository.GetCardWithBridges(id)
+	jobID, _ := strconv.ParseInt(r.URL.Query().Get("job"), 10, 64)
+	renderEditBridges(w, r, id, jobID)
+}
+
+func renderEditBridges(w http.ResponseWriter, r *http.Request, cardID, jobID int64) {
+	var job *models.Job
+	if jobID != 0 {
+		j, err := repository.GetJob(jobID)
+		if err == nil && j.Kind == service.JobBridges && (!j.UserID.Valid || j.UserID.Int64 == currentUserID(r)) {
+			job = j
+		}
+	}
+
+	bridges, err := repository.GetBridgesForCard(cardID)
 	if err != nil {
-		http.Error(w, "Card not found", http.StatusNotFound)
+		http.Error(w, "Failed to load bridges", http.StatusInternalServerError)
 		return
 	}
-
-	components.CardBridges(card.Bridges).Render(r.Context(), w)
+	components.EditBridges(cardID, bridges, job).Render(r.Context(), w)
 }
 
 // HandleGenerateExample generates an AI example sentence for a card
@@ -349,6 +370,10 @@ func HandleGenerateExample(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorizeCard(w, r, id) {
+		return
+	}
+
 	card, err := repository.GetCard(id)
 	if err != nil {
 		http.Error(w, "Card not found", http.StatusNotFound)
@@ -356,14 +381,14 @@ func HandleGenerateExample(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
-	gemini, err := bridge.NewGeminiService(ctx)
+	ai, err := bridge.NewService(ctx)
 	if err != nil {
 		http.Error(w, "AI service unavailable", http.StatusServiceUnavailable)
 		return
 	}
-	defer gemini.Close()
 
-	example, err := gemini.GenerateExampleSentence(ctx, card.Term, card.Translation)
+	// The user asked for an example, so don't hand back the cached one
+	example, err := ai.GenerateExampleSentence(bridge.WithFreshResponse(ctx), card.Term, card.Translation)
 	if err != nil {
 		http.Error(w, "Failed to generate example", http.StatusInternalServerError)
 		return
@@ -388,6 +413,10 @@ func HandleDeleteCard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorizeCard(w, r, id) {
+		return
+	}
+
 	if err := repository.DeleteCard(id); err != nil {
 		http.Error(w, "Failed to delete card", http.StatusInternalServerError)
 		return
@@ -396,3 +425,36 @@ func HandleDeleteCard(w http.ResponseWriter, r *http.Request) {
 	// Return empty response to remove the element
 	components.WordDeleted().Render(r.Context(), w)
 }
+
+// visibleCard loads a card the current user may study: a shared card or one
+// of their own. Writes a 404 for a missing card and a 403 for another user's
+// card, as apiVisibleCard does for the API.
+func visibleCard(w http.ResponseWriter, r *http.Request, id int64) (*models.Card, bool) {
+	card, err := repository.GetCard(id)
+	if err != nil {
+		http.Error(w, "Card not found", http.StatusNotFound)
+		return nil, false
+	}
+	if card.UserID.Valid && !requireOwner(w, r, card.UserID.Int64) {
+		return nil, false
+	}
+	return card, true
+}
+
+// authorizeCard checks that a card exists and that the current user may
+// modify it. Cards added by a user belong to that user. Shared cards (no
+// owner) are read-only: every learner's progress and review history hangs
+// off them, so one user must not be able to rewrite or delete them.
+// Writes the error response on failure.
+func authorizeCard(w http.ResponseWriter, r *http.Request, id int64) bool {
+	card, err := repository.GetCard(id)
+	if err != nil {
+		http.Error(w, "Card not found", http.StatusNotFound)
+		return false
+	}
+	if !card.UserID.Valid {
+		http.Error(w, "Shared cards are read-only", http.StatusForbidden)
+		return false
+	}
+	return requireOwner(w, r, card.UserID.Int64)
+}
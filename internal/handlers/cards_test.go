@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"languagepapi/internal/db"
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+)
+
+// openTestDB points the repository at a fresh database for one test
+func openTestDB(t *testing.T) {
+	t.Helper()
+	if err := db.Init(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+}
+
+func createTestUser(t *testing.T, username string) int64 {
+	t.Helper()
+	user, err := repository.CreateUser(username, "")
+	if err != nil {
+		t.Fatalf("create user %s: %v", username, err)
+	}
+	return user.ID
+}
+
+// createTestCard adds a card owned by ownerID, or a shared card for 0
+func createTestCard(t *testing.T, term string, ownerID int64) int64 {
+	t.Helper()
+	card := &models.Card{
+		IslandID:    sql.NullInt64{Int64: 1, Valid: true},
+		Term:        term,
+		Translation: "test " + term,
+		UserID:      sql.NullInt64{Int64: ownerID, Valid: ownerID != 0},
+	}
+	if err := repository.CreateCard(card); err != nil {
+		t.Fatalf("create card %s: %v", term, err)
+	}
+	return card.ID
+}
+
+// asUser returns r as an authenticated request from userID
+func asUser(r *http.Request, userID int64) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), userIDKey, userID))
+}
+
+func TestDeleteCardOwnership(t *testing.T) {
+	openTestDB(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+	shared := createTestCard(t, "compartido", 0)
+	alicesCard := createTestCard(t, "mío", alice)
+	bobsCard := createTestCard(t, "suyo", bob)
+
+	tests := []struct {
+		name   string
+		cardID int64
+		want   int
+	}{
+		{"shared card", shared, http.StatusForbidden},
+		{"another user's card", alicesCard, http.StatusForbidden},
+		{"own card", bobsCard, http.StatusOK},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodDelete, "/words/"+strconv.FormatInt(tt.cardID, 10), nil)
+		r.SetPathValue("id", strconv.FormatInt(tt.cardID, 10))
+		w := httptest.NewRecorder()
+		HandleDeleteCard(w, asUser(r, bob))
+
+		if w.Code != tt.want {
+			t.Errorf("%s: bob's delete returned %d, want %d", tt.name, w.Code, tt.want)
+		}
+		_, err := repository.GetCard(tt.cardID)
+		if deleted := err != nil; deleted != (tt.want == http.StatusOK) {
+			t.Errorf("%s: deleted = %v after status %d", tt.name, deleted, w.Code)
+		}
+	}
+}
+
+func TestWordsListHidesOtherUsersCards(t *testing.T) {
+	openTestDB(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+	createTestCard(t, "secretoalicia", alice)
+	createTestCard(t, "secretobob", bob)
+
+	for _, url := range []string{"/words", "/words?island=1", "/words?q=secreto", "/words/search?q=secreto"} {
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		w := httptest.NewRecorder()
+		if strings.HasPrefix(url, "/words/search") {
+			HandleSearchCards(w, asUser(r, bob))
+		} else {
+			HandleWords(w, asUser(r, bob))
+		}
+		body := w.Body.String()
+		if strings.Contains(body, "secretoalicia") {
+			t.Errorf("%s: bob sees alice's card", url)
+		}
+		if url != "/words" && !strings.Contains(body, "secretobob") {
+			t.Errorf("%s: bob doesn't see his own card", url)
+		}
+	}
+
+	visible, err := repository.CountCards(bob)
+	if err != nil {
+		t.Fatalf("CountCards() error = %v", err)
+	}
+	all, err := repository.CountCards(0)
+	if err != nil {
+		t.Fatalf("CountCards() error = %v", err)
+	}
+	if visible != all+1 {
+		t.Errorf("CountCards(bob) = %d, want the %d shared cards and his own", visible, all)
+	}
+}
+
+func TestPracticeRejectsOtherUsersCards(t *testing.T) {
+	openTestDB(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+	shared := createTestCard(t, "compartido", 0)
+	alicesCard := createTestCard(t, "mío", alice)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		cardID  int64
+		want    int
+	}{
+		{"check shared", HandleCheckAnswer, shared, http.StatusOK},
+		{"check another user's", HandleCheckAnswer, alicesCard, http.StatusForbidden},
+		{"check lesson answer for another user's", HandleLessonCheckAnswer, alicesCard, http.StatusForbidden},
+		{"review another user's", HandleReview, alicesCard, http.StatusForbidden},
+		// Bob has no practice session, so a visible card gets as far as that
+		{"review shared", HandleReview, shared, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		form := url.Values{"card_id": {strconv.FormatInt(tt.cardID, 10)}, "answer": {"mío"}, "rating": {"3"}}
+		r := httptest.NewRequest(http.MethodPost, "/practice/review", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		tt.handler(w, asUser(r, bob))
+		if w.Code != tt.want {
+			t.Errorf("%s: got %d, want %d (%s)", tt.name, w.Code, tt.want, w.Body)
+		}
+	}
+}
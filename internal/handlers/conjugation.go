@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"languagepapi/components"
+	"languagepapi/internal/conjugate"
+	"languagepapi/internal/service"
+)
+
+var conjugationService = service.NewConjugationService()
+
+// HandleConjugations renders the conjugation drills page with the next drill
+func HandleConjugations(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+	overview, err := conjugationService.Overview(userID)
+	if err != nil {
+		http.Error(w, "Failed to load conjugation drills", http.StatusInternalServerError)
+		return
+	}
+	drill, err := conjugationService.Next(userID)
+	if err != nil {
+		http.Error(w, "Failed to load conjugation drills", http.StatusInternalServerError)
+		return
+	}
+	components.Conjugations(overview, drill).Render(r.Context(), w)
+}
+
+// HandleConjugationNext renders the next drill in place of the last result
+func HandleConjugationNext(w http.ResponseWriter, r *http.Request) {
+	drill, err := conjugationService.Next(currentUserID(r))
+	if err != nil {
+		http.Error(w, "Failed to load the next drill", http.StatusInternalServerError)
+		return
+	}
+	components.ConjugationDrill(drill).Render(r.Context(), w)
+}
+
+// HandleConjugationCheck grades a drill answer, schedules the verb and tense
+// and explains the answer
+func HandleConjugationCheck(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cardID, err := strconv.ParseInt(r.FormValue("card_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid card_id", http.StatusBadRequest)
+		return
+	}
+	tense := conjugate.Tense(r.FormValue("tense"))
+	person, err := strconv.Atoi(r.FormValue("person"))
+	if err != nil || !tense.Valid() || !tense.HasPerson(conjugate.Person(person)) {
+		http.Error(w, "invalid tense or person", http.StatusBadRequest)
+		return
+	}
+	card, ok := visibleCard(w, r, cardID)
+	if !ok {
+		return
+	}
+
+	drill, err := conjugationService.Drill(card, tense, conjugate.Person(person), false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	durationMs, _ := strconv.Atoi(r.FormValue("duration_ms"))
+	result, err := conjugationService.Answer(currentUserID(r), drill, r.FormValue("answer"), time.Duration(durationMs)*time.Millisecond)
+	if err != nil {
+		http.Error(w, "Failed to save the answer", http.StatusInternalServerError)
+		return
+	}
+	components.ConjugationResult(result).Render(r.Context(), w)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"languagepapi/components"
+	"languagepapi/internal/service"
+)
+
+// maxCurriculumUpload limits uploaded curriculum files
+const maxCurriculumUpload = 1 << 20
+
+var curriculumService = service.NewCurriculumService()
+
+// HandleCurricula renders the curriculum picker
+func HandleCurricula(w http.ResponseWriter, r *http.Request) {
+	renderCurricula(w, r, "", false)
+}
+
+// HandleStartCurriculum restarts the user's journey on the chosen curriculum
+func HandleStartCurriculum(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid curriculum ID", http.StatusBadRequest)
+		return
+	}
+
+	curriculum, err := curriculumService.GetCurriculum(id)
+	if err != nil {
+		http.Error(w, "Curriculum not found", http.StatusNotFound)
+		return
+	}
+	if curriculum.UserID.Valid && !requireOwner(w, r, curriculum.UserID.Int64) {
+		return
+	}
+
+	if err := curriculumService.StartCurriculum(userID, curriculum); err != nil {
+		renderCurricula(w, r, "Failed to start curriculum", false)
+		return
+	}
+
+	HandleHome(w, r)
+}
+
+// HandleCreateCurriculum saves a user-defined curriculum from pasted or uploaded JSON
+func HandleCreateCurriculum(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+	r.Body = http.MaxBytesReader(w, r.Body, maxCurriculumUpload)
+	if err := r.ParseMultipartForm(maxCurriculumUpload); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	data := []byte(r.FormValue("definition"))
+	if file, header, err := r.FormFile("file"); err == nil {
+		defer file.Close()
+		if header.Size > 0 {
+			if data, err = io.ReadAll(file); err != nil {
+				http.Error(w, "Failed to read upload", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		renderCurricula(w, r, "Paste a curriculum or choose a file", false)
+		return
+	}
+
+	curriculum, err := curriculumService.CreateCurriculum(userID, data)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		renderCurricula(w, r, err.Error(), false)
+		return
+	}
+
+	renderCurricula(w, r, "Saved "+curriculum.Name+". Start it below when you're ready.", true)
+}
+
+// renderCurricula renders the curriculum page, using the current curriculum
+// as the starting point for a custom one
+func renderCurricula(w http.ResponseWriter, r *http.Request, message string, success bool) {
+	userID := currentUserID(r)
+	curricula, err := curriculumService.ListCurricula(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	current, err := curriculumService.CurrentCurriculum(userID)
+	example := ""
+	if err == nil {
+		example = curriculumService.ExportCurriculum(current)
+	}
+
+	components.Curricula(curricula, current, example, message, success).Render(r.Context(), w)
+}
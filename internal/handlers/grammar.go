@@ -63,9 +63,8 @@ func HandleGrammarForCard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	card, err := repository.GetCard(cardID)
-	if err != nil {
-		http.Error(w, "Card not found", http.StatusNotFound)
+	card, ok := visibleCard(w, r, cardID)
+	if !ok {
 		return
 	}
 
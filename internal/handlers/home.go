@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 
 	"languagepapi/components"
@@ -12,12 +13,17 @@ var lessonService = service.NewLessonService()
 
 // HandleHome renders the journey home page
 func HandleHome(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
 	// Get journey home data
-	data, err := lessonService.GetJourneyHomeData(defaultUserID)
+	data, err := lessonService.GetJourneyHomeData(userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if data.IsAdmin, err = authService.IsAdmin(userID); err != nil {
+		log.Printf("Failed to check admin: %v", err)
+	}
 
 	components.Home(data).Render(r.Context(), w)
 }
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"languagepapi/components"
+	"languagepapi/internal/repository"
+	"languagepapi/internal/service"
+)
+
+// Number of batches and jobs the jobs page lists
+const (
+	jobBatchesShown = 10
+	recentJobsShown = 30
+)
+
+var jobService = service.NewJobService()
+
+// StartJobs starts the background job workers. Call it after .env is loaded.
+func StartJobs(ctx context.Context) {
+	jobService.Start(ctx)
+}
+
+// HandleJobs renders the background jobs page
+func HandleJobs(w http.ResponseWriter, r *http.Request) {
+	renderJobs(w, r, "", false)
+}
+
+// HandleJobsList renders the batch progress and recent jobs the page polls
+func HandleJobsList(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+	batches, err := repository.ListJobBatches(userID, jobBatchesShown)
+	if err != nil {
+		http.Error(w, "Failed to load jobs", http.StatusInternalServerError)
+		return
+	}
+	recent, err := repository.ListRecentJobs(userID, recentJobsShown)
+	if err != nil {
+		http.Error(w, "Failed to load jobs", http.StatusInternalServerError)
+		return
+	}
+	components.JobsList(batches, recent).Render(r.Context(), w)
+}
+
+// HandleStartJobBatch queues a batch chosen on the jobs page
+func HandleStartJobBatch(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+	limit, _ := strconv.Atoi(r.FormValue("limit"))
+
+	batch, err := jobService.StartBatch(currentUserID(r), r.FormValue("kind"), limit)
+	switch {
+	case errors.Is(err, service.ErrNothingToDo):
+		renderJobs(w, r, "Nothing to do: everything already has it.", true)
+	case err != nil:
+		renderJobs(w, r, "Failed to queue batch: "+err.Error(), false)
+	default:
+		renderJobs(w, r, fmt.Sprintf("Queued %s", batch.Name), true)
+	}
+}
+
+// HandleCancelJobBatch cancels the unfinished jobs of a batch
+func HandleCancelJobBatch(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid batch ID", http.StatusBadRequest)
+		return
+	}
+	if err := jobService.CancelBatch(currentUserID(r), id); err != nil {
+		http.Error(w, "Failed to cancel batch", http.StatusInternalServerError)
+		return
+	}
+	HandleJobsList(w, r)
+}
+
+// HandleRetryJobBatch queues the failed jobs of a batch again
+func HandleRetryJobBatch(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid batch ID", http.StatusBadRequest)
+		return
+	}
+	if err := jobService.RetryBatch(currentUserID(r), id); err != nil {
+		http.Error(w, "Failed to retry batch", http.StatusInternalServerError)
+		return
+	}
+	HandleJobsList(w, r)
+}
+
+// HandleCancelJob cancels a single queued or running job
+func HandleCancelJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+	if err := jobService.Cancel(currentUserID(r), id); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	HandleJobsList(w, r)
+}
+
+func renderJobs(w http.ResponseWriter, r *http.Request, message string, success bool) {
+	userID := currentUserID(r)
+	batches, err := repository.ListJobBatches(userID, jobBatchesShown)
+	if err != nil {
+		http.Error(w, "Failed to load jobs", http.StatusInternalServerError)
+		return
+	}
+	recent, err := repository.ListRecentJobs(userID, recentJobsShown)
+	if err != nil {
+		http.Error(w, "Failed to load jobs", http.StatusInternalServerError)
+		return
+	}
+	components.Jobs(batches, recent, message, success).Render(r.Context(), w)
+}
@@ -11,14 +11,27 @@ import (
 	"languagepapi/internal/service"
 )
 
+// lessonLocks serialise each user's lesson answers so a double submit can't
+// record a card twice, without making users wait for each other
 var (
-	// In-memory lesson storage (single user for now)
-	lessons     = make(map[int64]*models.DailyLesson)
-	lessonIdx   = make(map[int64]int) // Current card index
-	lessonStats = make(map[int64]*lessonSessionStats)
-	lessonsLock sync.RWMutex
+	lessonLocks   = make(map[int64]*sync.Mutex)
+	lessonLocksMu sync.Mutex
 )
 
+// lockLesson locks the user's lesson and returns the function unlocking it
+func lockLesson(userID int64) func() {
+	lessonLocksMu.Lock()
+	mu, ok := lessonLocks[userID]
+	if !ok {
+		mu = &sync.Mutex{}
+		lessonLocks[userID] = mu
+	}
+	lessonLocksMu.Unlock()
+
+	mu.Lock()
+	return mu.Unlock
+}
+
 type lessonSessionStats struct {
 	Reviewed    int
 	Correct     int
@@ -28,46 +41,55 @@ type lessonSessionStats struct {
 	CardResults []models.CardResult
 }
 
-// HandleLessonStart starts the daily lesson
+// lessonStatsFor totals the answered cards of a lesson
+func lessonStatsFor(active *models.ActiveLesson) *lessonSessionStats {
+	stats := &lessonSessionStats{
+		XPEarned:    active.XPEarned,
+		CardResults: active.Results,
+	}
+	for _, cr := range active.Results {
+		stats.Reviewed++
+		stats.TotalTimeMs += cr.TimeSpentMs
+		if cr.WasCorrect {
+			stats.Correct++
+			if cr.IsNew {
+				stats.NewLearned++
+			}
+		}
+	}
+	return stats
+}
+
+// HandleLessonStart starts today's lesson, resuming it if already in progress
+// and showing its summary if finished
 func HandleLessonStart(w http.ResponseWriter, r *http.Request) {
-	lessonsLock.Lock()
-	defer lessonsLock.Unlock()
+	userID := currentUserID(r)
+	defer lockLesson(userID)()
 
-	// Build today's lesson
-	lesson, err := lessonService.BuildDailyLesson(defaultUserID)
+	active, err := lessonService.StartOrResumeLesson(userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Store lesson
-	lessons[defaultUserID] = lesson
-	lessonIdx[defaultUserID] = 0
-	lessonStats[defaultUserID] = &lessonSessionStats{}
-
 	// Check if there are any cards
-	if len(lesson.Cards) == 0 {
-		components.LessonEmpty(lesson.DayNumber, lesson.Phase).Render(r.Context(), w)
+	if len(active.Lesson.Cards) == 0 {
+		components.LessonEmpty(active.Lesson).Render(r.Context(), w)
 		return
 	}
-
-	// Get first card
-	card := &lesson.Cards[0]
-
-	// Fetch bridges
-	if len(card.Bridges) == 0 {
-		bridges, _ := repository.GetBridgesForCard(card.ID)
-		card.Bridges = bridges
+	// A lesson worked through shows its summary, completing the session if
+	// the last answer did not get to
+	if active.Position >= len(active.Lesson.Cards) {
+		renderLessonSummary(w, r, userID, active, !active.Session.CompletedAt.Valid)
+		return
 	}
 
-	// Get scheduling preview
-	preview := reviewService.GetSchedulingPreview(&card.CardWithProgress)
-
-	components.LessonCard(card, preview, 1, len(lesson.Cards), lesson.DayNumber, lesson.Phase).Render(r.Context(), w)
+	renderLessonCard(w, r, active)
 }
 
 // HandleLessonReview processes a review within the lesson
 func HandleLessonReview(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 	if err := r.ParseForm(); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -87,227 +109,192 @@ func HandleLessonReview(w http.ResponseWriter, r *http.Request) {
 
 	durationMs, _ := strconv.Atoi(r.FormValue("duration_ms"))
 
-	lessonsLock.Lock()
-	lesson, exists := lessons[defaultUserID]
-	if !exists {
-		lessonsLock.Unlock()
+	defer lockLesson(userID)()
+
+	active, err := lessonService.GetActiveLesson(userID)
+	if err != nil {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
+	if active.Position >= len(active.Lesson.Cards) {
+		renderLessonSummary(w, r, userID, active, false)
+		return
+	}
 
-	currentIdx := lessonIdx[defaultUserID]
-	stats := lessonStats[defaultUserID]
+	// An answer for a card other than the current one comes from a stale tab;
+	// show where the lesson actually is instead of recording it
+	currentCard := &active.Lesson.Cards[active.Position]
+	if currentCard.ID != cardID {
+		renderLessonCard(w, r, active)
+		return
+	}
 
-	// Find the card and get its isNew status
-	var currentCard *models.LessonCard
-	for i := range lesson.Cards {
-		if lesson.Cards[i].ID == cardID {
-			currentCard = &lesson.Cards[i]
-			break
-		}
+	if err := answerLessonCard(userID, active, models.Rating(rating), durationMs); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if active.Position >= len(active.Lesson.Cards) {
+		renderLessonSummary(w, r, userID, active, true)
+		return
+	}
+
+	renderLessonCard(w, r, active)
+}
+
+// HandleLessonCheckAnswer grades a typed answer for the current lesson card
+// and shows the rating it earned. The rating is submitted to
+// HandleLessonReview when the user continues.
+func HandleLessonCheckAnswer(w http.ResponseWriter, r *http.Request) {
+	checkTypedAnswer(w, r, "/lesson/review", ".lesson-container")
+}
+
+// HandleLessonSkip skips the current card in the lesson
+func HandleLessonSkip(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+	defer lockLesson(userID)()
+
+	active, err := lessonService.GetActiveLesson(userID)
+	if err != nil {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	if active.Position >= len(active.Lesson.Cards) {
+		renderLessonSummary(w, r, userID, active, false)
+		return
+	}
+
+	if err := skipLessonCard(active); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Check if lesson is complete (even with skips)
+	if active.Position >= len(active.Lesson.Cards) {
+		renderLessonSummary(w, r, userID, active, true)
+		return
 	}
 
+	renderLessonCard(w, r, active)
+}
+
+// answerLessonCard records a rating for the card at the lesson's current
+// position and moves past it
+func answerLessonCard(userID int64, active *models.ActiveLesson, rating models.Rating, durationMs int) error {
+	currentCard := &active.Lesson.Cards[active.Position]
+
 	// Submit review using the existing review service
 	// We need to use the standard session mechanism but without mode selection
 	// Create a temporary session for this card
 	tempSession := &models.ReviewSession{
-		UserID: defaultUserID,
+		UserID: userID,
 		Cards:  []models.CardWithProgress{currentCard.CardWithProgress},
 	}
 
-	result, err := reviewService.SubmitReview(tempSession, cardID, models.Rating(rating), durationMs)
+	result, err := reviewService.SubmitLessonReview(tempSession, currentCard.ID, rating, durationMs, active.Session.ID, active.Position)
 	if err != nil {
-		lessonsLock.Unlock()
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// Update lesson stats
-	stats.Reviewed++
-	stats.XPEarned += result.XPEarned
-	stats.TotalTimeMs += int64(durationMs)
-	if rating >= 3 {
-		stats.Correct++
-	}
-	if currentCard != nil && currentCard.IsNew && rating >= 3 {
-		stats.NewLearned++
+		return err
 	}
 
 	// Track per-card result
-	cardResult := models.CardResult{
-		CardID:      cardID,
+	active.Results = append(active.Results, models.CardResult{
+		CardID:      currentCard.ID,
 		Term:        currentCard.Term,
 		Translation: currentCard.Translation,
-		Rating:      models.Rating(rating),
+		Rating:      rating,
 		TimeSpentMs: int64(durationMs),
 		Mode:        currentCard.Mode,
-		WasCorrect:  rating >= 3,
+		WasCorrect:  rating >= models.RatingGood,
 		IsNew:       currentCard.IsNew,
-	}
-	stats.CardResults = append(stats.CardResults, cardResult)
+	})
+	active.XPEarned += result.XPEarned
 
 	// Move to next card
-	lessonIdx[defaultUserID] = currentIdx + 1
-	nextIdx := currentIdx + 1
-	lessonsLock.Unlock()
-
-	// Check if lesson is complete
-	if nextIdx >= len(lesson.Cards) {
-		// Mark lesson as complete
-		_, session, _ := repository.IsTodayLessonComplete(defaultUserID)
-		if session == nil {
-			repository.CreateLessonSession(defaultUserID, lesson.DayNumber, lesson.Phase.ID)
-		}
-		session, _ = repository.GetTodayLessonSession(defaultUserID)
-		if session != nil {
-			repository.UpdateLessonSession(session.ID, stats.Reviewed, stats.Correct, stats.NewLearned, stats.XPEarned)
-			repository.CompleteLessonSession(session.ID)
-		}
-
-		// Check for new achievements
-		newAchievements := reviewService.CheckAchievements(defaultUserID)
-
-		// Calculate accuracy
-		accuracy := 0
-		if stats.Reviewed > 0 {
-			accuracy = stats.Correct * 100 / stats.Reviewed
-		}
-
-		// Get motivational message
-		message := service.GetMotivationalMessage(lesson.DayNumber, accuracy, true)
-
-		// Build struggles list (cards with rating 1-2)
-		var struggles []models.CardResult
-		for _, cr := range stats.CardResults {
-			if !cr.WasCorrect {
-				struggles = append(struggles, cr)
-			}
-		}
-
-		// Calculate average time per card
-		avgTime := int64(0)
-		if stats.Reviewed > 0 {
-			avgTime = stats.TotalTimeMs / int64(stats.Reviewed)
-		}
-
-		// Build summary
-		summary := &models.LessonSummary{
-			DayNumber:      lesson.DayNumber,
-			Phase:          lesson.Phase,
-			TotalCards:     stats.Reviewed,
-			CorrectCount:   stats.Correct,
-			Accuracy:       accuracy,
-			TotalTimeMs:    stats.TotalTimeMs,
-			AvgTimePerCard: avgTime,
-			XPEarned:       stats.XPEarned,
-			NewLearned:     stats.NewLearned,
-			CardResults:    stats.CardResults,
-			Struggles:      struggles,
-			Achievements:   newAchievements,
-			Message:        message,
-		}
+	active.Position++
+	return nil
+}
 
-		components.LessonCompleteWithSummary(summary).Render(r.Context(), w)
-		return
+// skipLessonCard moves past the card at the lesson's current position
+// without reviewing it
+func skipLessonCard(active *models.ActiveLesson) error {
+	if err := repository.SkipLessonCard(active.Session.ID, active.Position); err != nil {
+		return err
 	}
+	active.Position++
+	return nil
+}
 
-	// Get next card
-	lessonsLock.RLock()
-	nextCard := &lesson.Cards[nextIdx]
-	lessonsLock.RUnlock()
+// renderLessonCard renders the card at the lesson's current position
+func renderLessonCard(w http.ResponseWriter, r *http.Request, active *models.ActiveLesson) {
+	lesson := active.Lesson
+	card := &lesson.Cards[active.Position]
 
 	// Fetch bridges
-	if len(nextCard.Bridges) == 0 {
-		bridges, _ := repository.GetBridgesForCard(nextCard.ID)
-		nextCard.Bridges = bridges
-	}
+	attachBridges(currentUserID(r), &card.Card)
 
-	preview := reviewService.GetSchedulingPreview(&nextCard.CardWithProgress)
-	components.LessonCard(nextCard, preview, nextIdx+1, len(lesson.Cards), lesson.DayNumber, lesson.Phase).Render(r.Context(), w)
+	preview := reviewService.GetSchedulingPreview(currentUserID(r), &card.CardWithProgress)
+	components.LessonCard(card, preview, active.Position+1, len(lesson.Cards), lesson, ttsEnabled(currentUserID(r))).Render(r.Context(), w)
 }
 
-// HandleLessonSkip skips the current card in the lesson
-func HandleLessonSkip(w http.ResponseWriter, r *http.Request) {
-	lessonsLock.Lock()
-	lesson, exists := lessons[defaultUserID]
-	if !exists {
-		lessonsLock.Unlock()
-		http.Redirect(w, r, "/", http.StatusSeeOther)
-		return
-	}
-
-	currentIdx := lessonIdx[defaultUserID]
-	stats := lessonStats[defaultUserID]
-	lessonIdx[defaultUserID] = currentIdx + 1
-	nextIdx := currentIdx + 1
-	lessonsLock.Unlock()
-
-	// Check if lesson is complete
-	if nextIdx >= len(lesson.Cards) {
-		// Mark lesson as complete even with skips
-		_, session, _ := repository.IsTodayLessonComplete(defaultUserID)
-		if session == nil {
-			repository.CreateLessonSession(defaultUserID, lesson.DayNumber, lesson.Phase.ID)
-		}
-		session, _ = repository.GetTodayLessonSession(defaultUserID)
-		if session != nil {
-			repository.UpdateLessonSession(session.ID, stats.Reviewed, stats.Correct, stats.NewLearned, stats.XPEarned)
-			repository.CompleteLessonSession(session.ID)
-		}
-
-		newAchievements := reviewService.CheckAchievements(defaultUserID)
+// renderLessonSummary renders the lesson summary
+func renderLessonSummary(w http.ResponseWriter, r *http.Request, userID int64, active *models.ActiveLesson, justFinished bool) {
+	components.LessonCompleteWithSummary(lessonSummaryFor(userID, active, justFinished)).Render(r.Context(), w)
+}
 
-		accuracy := 0
-		if stats.Reviewed > 0 {
-			accuracy = stats.Correct * 100 / stats.Reviewed
-		}
+// lessonSummaryFor builds the lesson summary. When the lesson was just
+// finished by this request, its totals are added to today's session and the
+// session is marked complete.
+func lessonSummaryFor(userID int64, active *models.ActiveLesson, justFinished bool) *models.LessonSummary {
+	lesson := active.Lesson
+	stats := lessonStatsFor(active)
+
+	// Mark lesson as complete
+	if justFinished {
+		repository.UpdateLessonSession(active.Session.ID, stats.Reviewed, stats.Correct, stats.NewLearned, stats.XPEarned)
+		repository.CompleteLessonSession(active.Session.ID)
+	}
 
-		message := service.GetMotivationalMessage(lesson.DayNumber, accuracy, true)
+	// Check for new achievements
+	newAchievements := reviewService.CheckAchievements(userID)
 
-		// Build struggles list
-		var struggles []models.CardResult
-		for _, cr := range stats.CardResults {
-			if !cr.WasCorrect {
-				struggles = append(struggles, cr)
-			}
-		}
+	// Calculate accuracy
+	accuracy := 0
+	if stats.Reviewed > 0 {
+		accuracy = stats.Correct * 100 / stats.Reviewed
+	}
 
-		avgTime := int64(0)
-		if stats.Reviewed > 0 {
-			avgTime = stats.TotalTimeMs / int64(stats.Reviewed)
-		}
+	// Get motivational message
+	message := service.GetMotivationalMessage(lesson.DayNumber, lesson.TotalDays, accuracy, true)
 
-		summary := &models.LessonSummary{
-			DayNumber:      lesson.DayNumber,
-			Phase:          lesson.Phase,
-			TotalCards:     stats.Reviewed,
-			CorrectCount:   stats.Correct,
-			Accuracy:       accuracy,
-			TotalTimeMs:    stats.TotalTimeMs,
-			AvgTimePerCard: avgTime,
-			XPEarned:       stats.XPEarned,
-			NewLearned:     stats.NewLearned,
-			CardResults:    stats.CardResults,
-			Struggles:      struggles,
-			Achievements:   newAchievements,
-			Message:        message,
+	// Build struggles list (cards with rating 1-2)
+	var struggles []models.CardResult
+	for _, cr := range stats.CardResults {
+		if !cr.WasCorrect {
+			struggles = append(struggles, cr)
 		}
-
-		components.LessonCompleteWithSummary(summary).Render(r.Context(), w)
-		return
 	}
 
-	// Get next card
-	lessonsLock.RLock()
-	nextCard := &lesson.Cards[nextIdx]
-	lessonsLock.RUnlock()
-
-	// Fetch bridges
-	if len(nextCard.Bridges) == 0 {
-		bridges, _ := repository.GetBridgesForCard(nextCard.ID)
-		nextCard.Bridges = bridges
+	// Calculate average time per card
+	avgTime := int64(0)
+	if stats.Reviewed > 0 {
+		avgTime = stats.TotalTimeMs / int64(stats.Reviewed)
 	}
 
-	preview := reviewService.GetSchedulingPreview(&nextCard.CardWithProgress)
-	components.LessonCard(nextCard, preview, nextIdx+1, len(lesson.Cards), lesson.DayNumber, lesson.Phase).Render(r.Context(), w)
+	// Build summary
+	return &models.LessonSummary{
+		DayNumber:      lesson.DayNumber,
+		TotalDays:      lesson.TotalDays,
+		Phase:          lesson.Phase,
+		TotalCards:     stats.Reviewed,
+		CorrectCount:   stats.Correct,
+		Accuracy:       accuracy,
+		TotalTimeMs:    stats.TotalTimeMs,
+		AvgTimePerCard: avgTime,
+		XPEarned:       stats.XPEarned,
+		NewLearned:     stats.NewLearned,
+		CardResults:    stats.CardResults,
+		Struggles:      struggles,
+		Achievements:   newAchievements,
+		Message:        message,
+	}
 }
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"languagepapi/components"
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+	"languagepapi/internal/service"
+)
+
+var lyricsService = service.NewLyricsService()
+
+// HandleLyricsEditor shows the timing editor for a song's lines. Songs are
+// shared by every user, so only admins may edit their lines.
+func HandleLyricsEditor(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	songID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid song id", http.StatusBadRequest)
+		return
+	}
+	renderLyricsEditor(w, r, songID, "", false)
+}
+
+// HandleSaveLyricsEdits saves the editor form as a new version of the
+// song's lines, applying the button's edit (a merge or the global offset)
+// and splitting lines where "|" was typed
+func HandleSaveLyricsEdits(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	songID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid song id", http.StatusBadRequest)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	// The rows must be the lines as they are now
+	versions, err := repository.GetSongLinesVersions(songID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if fmt.Sprint(latestVersionID(versions)) != r.FormValue("version") {
+		renderLyricsEditor(w, r, songID, "The lyrics changed since you opened the editor; your changes were not saved", false)
+		return
+	}
+	lines, err := repository.GetSongLines(songID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = lyricsService.EditSongLines(currentUserID(r), songID, lyricsFormEdits(r, len(lines)))
+	if errors.Is(err, service.ErrInvalidEdit) {
+		renderLyricsEditor(w, r, songID, strings.TrimPrefix(err.Error(), service.ErrInvalidEdit.Error()+": "), false)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	renderLyricsEditor(w, r, songID, "Saved", true)
+}
+
+// HandleUndoLyricsEdit restores a song's lines to before their last edit
+func HandleUndoLyricsEdit(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	songID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid song id", http.StatusBadRequest)
+		return
+	}
+
+	version, err := lyricsService.UndoSongLines(songID)
+	if errors.Is(err, service.ErrNothingToUndo) {
+		renderLyricsEditor(w, r, songID, "Nothing to undo", false)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	renderLyricsEditor(w, r, songID, "Undid: "+version.Note, true)
+}
+
+func renderLyricsEditor(w http.ResponseWriter, r *http.Request, songID int64, message string, success bool) {
+	song, err := repository.GetSongWithDetails(songID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "song not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	versions, _ := repository.GetSongLinesVersions(songID)
+	components.LyricsEditor(song, versions, latestVersionID(versions), message, success).Render(r.Context(), w)
+}
+
+// latestVersionID is the id of the newest version, 0 if the lines were
+// never edited
+func latestVersionID(versions []models.SongLinesVersion) int64 {
+	if len(versions) == 0 {
+		return 0
+	}
+	return versions[0].ID
+}
+
+// lyricsFormEdits turns the editor form into edits: each row's times and
+// texts, then the button's edit, then the splits typed as "|". Times are
+// in seconds.
+func lyricsFormEdits(r *http.Request, lineCount int) []service.LineEdit {
+	var edits []service.LineEdit
+	type split struct{ line, word int }
+	var splits []split
+	wordCounts := make([]int, lineCount+1)
+
+	for n := 1; n <= lineCount; n++ {
+		if !r.Form.Has(fmt.Sprintf("start_%d", n)) {
+			continue
+		}
+		edits = append(edits, service.LineEdit{
+			Op:      service.EditTime,
+			Line:    n,
+			StartMs: formMs(r.FormValue(fmt.Sprintf("start_%d", n))),
+			EndMs:   formMs(r.FormValue(fmt.Sprintf("end_%d", n))),
+		})
+
+		var words []string
+		cuts := make(map[int]bool)
+		for _, word := range strings.Fields(strings.ReplaceAll(r.FormValue(fmt.Sprintf("text_%d", n)), "|", " | ")) {
+			if word == "|" {
+				cuts[len(words)] = true
+			} else {
+				words = append(words, word)
+			}
+		}
+		for word := range cuts {
+			if word > 0 && word < len(words) {
+				splits = append(splits, split{n, word})
+			}
+		}
+		wordCounts[n] = len(words)
+		edits = append(edits,
+			service.LineEdit{Op: service.EditText, Line: n, Text: strings.Join(words, " ")},
+			service.LineEdit{Op: service.EditTranslation, Line: n, Text: r.FormValue(fmt.Sprintf("english_%d", n))},
+		)
+	}
+
+	op, arg, _ := strings.Cut(r.FormValue("op"), ":")
+	switch op {
+	case "offset":
+		edits = append(edits, service.LineEdit{Op: service.EditOffset, Ms: formMs(r.FormValue("offset"))})
+	case "merge":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 || n >= lineCount {
+			break
+		}
+		edits = append(edits, service.LineEdit{Op: service.EditMerge, Line: n})
+		// The splits come after the merge, which joins the next row to
+		// this one and moves the rows after it up
+		for i := range splits {
+			switch {
+			case splits[i].line == n+1:
+				splits[i] = split{n, splits[i].word + wordCounts[n]}
+			case splits[i].line > n+1:
+				splits[i].line--
+			}
+		}
+	}
+
+	// Split from the last line and word back, so each split leaves the
+	// numbering before it alone
+	sort.Slice(splits, func(i, j int) bool {
+		if splits[i].line != splits[j].line {
+			return splits[i].line > splits[j].line
+		}
+		return splits[i].word > splits[j].word
+	})
+	for _, s := range splits {
+		edits = append(edits, service.LineEdit{Op: service.EditSplit, Line: s.line, Word: s.word})
+	}
+	return edits
+}
+
+// formMs reads a form field in seconds as milliseconds
+func formMs(s string) int {
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0
+	}
+	return int(math.Round(seconds * 1000))
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLyricsEditsRequireAdmin(t *testing.T) {
+	openTestDB(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+	t.Setenv("ADMIN_USERS", "alice")
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		userID  int64
+		body    string
+		want    int
+	}{
+		{"non-admin opens the editor", HandleLyricsEditor, bob, "", http.StatusForbidden},
+		{"non-admin saves edits", HandleSaveLyricsEdits, bob, "", http.StatusForbidden},
+		{"non-admin undoes an edit", HandleUndoLyricsEdit, bob, "", http.StatusForbidden},
+		{"non-admin edits over the API", apiEditSongLines, bob, `{"edits": []}`, http.StatusForbidden},
+		{"non-admin undoes over the API", apiUndoSongLines, bob, "", http.StatusForbidden},
+		// The seeded song has no edits yet
+		{"admin undoes over the API", apiUndoSongLines, alice, "", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/songs/1/lines/undo", strings.NewReader(tt.body))
+		r.SetPathValue("id", "1")
+		w := httptest.NewRecorder()
+		tt.handler(w, asUser(r, tt.userID))
+		if w.Code != tt.want {
+			t.Errorf("%s: got %d, want %d (%s)", tt.name, w.Code, tt.want, w.Body)
+		}
+	}
+}
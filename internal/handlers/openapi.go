@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"languagepapi/internal/models"
+)
+
+// openAPIEnums lists the values of string types the API accepts or returns
+var openAPIEnums = map[reflect.Type][]string{
+	reflect.TypeOf(models.BridgeType("")): {
+		string(models.BridgeHindiPhonetic), string(models.BridgeDutchSyntax), string(models.BridgeEnglishCognate),
+	},
+	reflect.TypeOf(models.CardState("")): {
+		string(models.StateNew), string(models.StateLearning), string(models.StateReview), string(models.StateRelearning),
+	},
+	reflect.TypeOf(models.SongMode("")): {
+		string(models.SongModeVocab), string(models.SongModeLyrics), string(models.SongModeListening), string(models.SongModeFull),
+	},
+	reflect.TypeOf(models.SongPhase("")): {
+		string(models.SongPhaseVocabPreview), string(models.SongPhaseFirstListen), string(models.SongPhaseLineBreakdown),
+		string(models.SongPhaseFillBlanks), string(models.SongPhaseFinalListen), string(models.SongPhaseComplete),
+	},
+}
+
+var pathParamPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// HandleOpenAPI serves the OpenAPI 3 document for /api/v1
+func HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, openAPIDocument())
+}
+
+// openAPIDocument describes apiRoutes, deriving the schemas from the
+// request and response types
+func openAPIDocument() map[string]any {
+	schemas := openAPISchemas{}
+	errorResponse := map[string]any{
+		"description": "Error",
+		"content":     jsonContent(schemas.schemaFor(reflect.TypeOf(apiError{}))),
+	}
+
+	paths := map[string]map[string]any{}
+	for _, route := range apiRoutes() {
+		op := map[string]any{
+			"operationId": operationID(route.Handler),
+			"summary":     route.Summary,
+			"tags":        []string{route.Tag},
+		}
+		if route.Public {
+			op["security"] = []any{}
+		}
+
+		var params []any
+		for _, m := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+			params = append(params, map[string]any{
+				"name": m[1], "in": "path", "required": true,
+				"schema": map[string]any{"type": "integer", "format": "int64"},
+			})
+		}
+		for _, q := range route.Query {
+			params = append(params, map[string]any{
+				"name": q.Name, "in": "query", "description": q.Description,
+				"schema": map[string]any{"type": q.Type},
+			})
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+
+		if route.Request != nil {
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(schemas.schemaFor(reflect.TypeOf(route.Request))),
+			}
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := map[string]any{"description": http.StatusText(status)}
+		if route.Response != nil {
+			success["content"] = jsonContent(schemas.schemaFor(reflect.TypeOf(route.Response)))
+		}
+		op["responses"] = map[string]any{
+			strconv.Itoa(status): success,
+			"default":            errorResponse,
+		}
+
+		if paths[route.Path] == nil {
+			paths[route.Path] = map[string]any{}
+		}
+		paths[route.Path][strings.ToLower(route.Method)] = op
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "languagepapi API",
+			"version":     "1",
+			"description": "JSON API for cards, reviews, lessons and songs. Log in with POST /auth/login and send the token as \"Authorization: Bearer <token>\"; browser session cookies work too.",
+		},
+		"servers":  []any{map[string]any{"url": apiV1}},
+		"security": []any{map[string]any{"bearerAuth": []string{}}},
+		"paths":    paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+// operationID derives an operation ID from the handler name, so
+// apiListCards becomes listCards
+func operationID(h http.HandlerFunc) string {
+	name := runtime.FuncForPC(reflect.ValueOf(h).Pointer()).Name()
+	name = strings.TrimPrefix(name[strings.LastIndex(name, ".")+1:], "api")
+	if name == "" {
+		return ""
+	}
+	return string(unicode.ToLower(rune(name[0]))) + name[1:]
+}
+
+// openAPISchemas collects the named component schemas
+type openAPISchemas map[string]any
+
+// schemaFor returns the schema of t, registering struct types as
+// components and referring to them
+func (s openAPISchemas) schemaFor(t reflect.Type) map[string]any {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.schemaFor(t.Elem())
+	case reflect.Struct:
+		name := strings.TrimPrefix(t.Name(), "api")
+		name = strings.ToUpper(name[:1]) + name[1:]
+		if _, ok := s[name]; !ok {
+			s[name] = nil // Reserve the name so recursive types terminate
+			s[name] = s.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": s.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schemaFor(t.Elem())}
+	case reflect.String:
+		schema := map[string]any{"type": "string"}
+		if values, ok := openAPIEnums[t]; ok {
+			schema["enum"] = values
+		}
+		return schema
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Int, reflect.Int32:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	default:
+		return map[string]any{}
+	}
+}
+
+// structSchema describes a struct's JSON fields; fields without omitempty
+// are always present and so marked required
+func (s openAPISchemas) structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = s.schemaFor(field.Type)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if required != nil {
+		schema["required"] = required
+	}
+	return schema
+}
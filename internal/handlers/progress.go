@@ -10,36 +10,38 @@ import (
 
 // HandleProgress renders the progress overview page
 func HandleProgress(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
 	// Get overview stats
-	stats, err := repository.GetProgressOverviewStats(defaultUserID)
+	stats, err := repository.GetProgressOverviewStats(userID)
 	if err != nil {
 		http.Error(w, "Failed to load stats", http.StatusInternalServerError)
 		return
 	}
 
 	// Get progress by island
-	islands, err := repository.GetLearnedCardsByIsland(defaultUserID)
+	islands, err := repository.GetLearnedCardsByIsland(userID)
 	if err != nil {
 		http.Error(w, "Failed to load island progress", http.StatusInternalServerError)
 		return
 	}
 
 	// Get recently learned words
-	recentWords, err := repository.GetRecentlyLearnedWords(defaultUserID, 20)
+	recentWords, err := repository.GetRecentlyLearnedWords(userID, 20)
 	if err != nil {
 		http.Error(w, "Failed to load recent words", http.StatusInternalServerError)
 		return
 	}
 
 	// Get recent lesson sessions
-	recentLessons, err := repository.GetRecentLessonSessions(defaultUserID, 7)
+	recentLessons, err := repository.GetRecentLessonSessions(userID, 7)
 	if err != nil {
 		http.Error(w, "Failed to load recent lessons", http.StatusInternalServerError)
 		return
 	}
 
 	// Get user stats for XP and streak
-	user, err := repository.GetUser(defaultUserID)
+	user, err := repository.GetUser(userID)
 	totalXP := 0
 	currentStreak := 0
 	if err == nil && user != nil {
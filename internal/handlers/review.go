@@ -4,8 +4,10 @@ import (
 	"net/http"
 	"strconv"
 	"sync"
+	"time"
 
 	"languagepapi/components"
+	"languagepapi/internal/grading"
 	"languagepapi/internal/models"
 	"languagepapi/internal/repository"
 	"languagepapi/internal/service"
@@ -13,43 +15,44 @@ import (
 
 var (
 	reviewService = service.NewReviewService()
-	// In-memory session storage (single user for now)
+	// In-memory session storage, keyed by user ID
 	sessions     = make(map[int64]*models.ReviewSession)
 	sessionsLock sync.RWMutex
 )
 
-const defaultUserID int64 = 1
-
 // HandlePractice starts or continues a review session
 func HandlePractice(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 	mode := r.URL.Query().Get("mode")
 
 	// If no mode specified, show mode selector
 	if mode == "" {
-		dueCount, _ := repository.CountDueCards(defaultUserID)
-		newCount, _ := repository.CountNewCards(defaultUserID)
+		dueCount, _ := repository.CountDueCards(userID)
+		newCount, _ := repository.CountNewCards(userID)
 
 		if dueCount+newCount == 0 {
 			components.PracticeEmpty().Render(r.Context(), w)
 			return
 		}
 
-		components.PracticeModeSelector(dueCount, newCount).Render(r.Context(), w)
+		settings, _ := service.LoadUserSettings(userID)
+		components.PracticeModeSelector(dueCount, newCount, settings.DefaultMode).Render(r.Context(), w)
 		return
 	}
 
 	sessionsLock.Lock()
-	session, exists := sessions[defaultUserID]
+	session, exists := sessions[userID]
 	if !exists || session.CurrentIndex >= len(session.Cards) {
 		// Start new session
 		var err error
-		session, err = reviewService.StartSession(defaultUserID, 20)
+		settings, _ := service.LoadUserSettings(userID)
+		session, err = reviewService.StartSession(userID, settings.ReviewsPerSession)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			sessionsLock.Unlock()
 			return
 		}
-		sessions[defaultUserID] = session
+		sessions[userID] = session
 	}
 	sessionsLock.Unlock()
 
@@ -63,33 +66,54 @@ func HandlePractice(w http.ResponseWriter, r *http.Request) {
 	card, hasMore := reviewService.GetNextCard(session)
 	if !hasMore {
 		// Session complete - check for new achievements
-		newAchievements := reviewService.CheckAchievements(defaultUserID)
+		newAchievements := reviewService.CheckAchievements(userID)
 		stats := reviewService.GetSessionStats(session)
 		components.PracticeComplete(stats.Reviewed, stats.Correct, stats.XPEarned, newAchievements).Render(r.Context(), w)
 		return
 	}
 
 	// Fetch bridges for the card
-	if len(card.Bridges) == 0 {
-		bridges, _ := repository.GetBridgesForCard(card.ID)
-		card.Bridges = bridges
-	}
+	attachBridges(userID, &card.Card)
 
 	// Get scheduling preview for rating buttons
-	preview := reviewService.GetSchedulingPreview(card)
+	preview := reviewService.GetSchedulingPreview(userID, card)
+
+	components.PracticeCard(card, preview, session.CurrentIndex+1, len(session.Cards), mode, ttsEnabled(userID)).Render(r.Context(), w)
+}
+
+// attachBridges loads a card's memory bridges, or clears them if the user
+// has turned bridges off in settings
+func attachBridges(userID int64, card *models.Card) {
+	settings, _ := service.LoadUserSettings(userID)
+	if !settings.ShowBridges {
+		card.Bridges = nil
+		return
+	}
+	if len(card.Bridges) == 0 {
+		card.Bridges, _ = repository.GetBridgesForCard(card.ID)
+	}
+}
 
-	components.PracticeCard(card, preview, session.CurrentIndex+1, len(session.Cards), mode).Render(r.Context(), w)
+// ttsEnabled reports whether the user has Spanish read aloud on flashcards
+func ttsEnabled(userID int64) bool {
+	settings, _ := service.LoadUserSettings(userID)
+	return settings.EnableTTS
 }
 
 // HandlePracticeCard returns just the card content (HTMX partial)
 func HandlePracticeCard(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		settings, _ := service.LoadUserSettings(userID)
+		mode = settings.DefaultMode
+	}
 	if mode == "" {
 		mode = "standard"
 	}
 
 	sessionsLock.RLock()
-	session, exists := sessions[defaultUserID]
+	session, exists := sessions[userID]
 	sessionsLock.RUnlock()
 
 	if !exists {
@@ -99,24 +123,22 @@ func HandlePracticeCard(w http.ResponseWriter, r *http.Request) {
 
 	card, hasMore := reviewService.GetNextCard(session)
 	if !hasMore {
-		newAchievements := reviewService.CheckAchievements(defaultUserID)
+		newAchievements := reviewService.CheckAchievements(userID)
 		stats := reviewService.GetSessionStats(session)
 		components.PracticeComplete(stats.Reviewed, stats.Correct, stats.XPEarned, newAchievements).Render(r.Context(), w)
 		return
 	}
 
 	// Fetch bridges
-	if len(card.Bridges) == 0 {
-		bridges, _ := repository.GetBridgesForCard(card.ID)
-		card.Bridges = bridges
-	}
+	attachBridges(userID, &card.Card)
 
-	preview := reviewService.GetSchedulingPreview(card)
-	components.PracticeCard(card, preview, session.CurrentIndex+1, len(session.Cards), mode).Render(r.Context(), w)
+	preview := reviewService.GetSchedulingPreview(userID, card)
+	components.PracticeCard(card, preview, session.CurrentIndex+1, len(session.Cards), mode, ttsEnabled(userID)).Render(r.Context(), w)
 }
 
 // HandleReview processes a review submission
 func HandleReview(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 	if err := r.ParseForm(); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -127,6 +149,9 @@ func HandleReview(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid card_id", http.StatusBadRequest)
 		return
 	}
+	if _, ok := visibleCard(w, r, cardID); !ok {
+		return
+	}
 
 	rating, err := strconv.Atoi(r.FormValue("rating"))
 	if err != nil || rating < 1 || rating > 4 {
@@ -142,7 +167,7 @@ func HandleReview(w http.ResponseWriter, r *http.Request) {
 	durationMs, _ := strconv.Atoi(r.FormValue("duration_ms"))
 
 	sessionsLock.Lock()
-	session, exists := sessions[defaultUserID]
+	session, exists := sessions[userID]
 	if !exists {
 		sessionsLock.Unlock()
 		http.Error(w, "no active session", http.StatusBadRequest)
@@ -159,7 +184,7 @@ func HandleReview(w http.ResponseWriter, r *http.Request) {
 
 	// Return next card or completion screen
 	if result.SessionDone {
-		newAchievements := reviewService.CheckAchievements(defaultUserID)
+		newAchievements := reviewService.CheckAchievements(userID)
 		components.PracticeComplete(result.TotalReviewed, result.TotalCorrect, result.TotalXP, newAchievements).Render(r.Context(), w)
 		return
 	}
@@ -172,17 +197,46 @@ func HandleReview(w http.ResponseWriter, r *http.Request) {
 	sessionsLock.RUnlock()
 
 	// Fetch bridges
-	if card != nil && len(card.Bridges) == 0 {
-		bridges, _ := repository.GetBridgesForCard(card.ID)
-		card.Bridges = bridges
+	if card != nil {
+		attachBridges(userID, &card.Card)
 	}
 
-	preview := reviewService.GetSchedulingPreview(card)
-	components.PracticeCard(card, preview, cardIndex, cardCount, mode).Render(r.Context(), w)
+	preview := reviewService.GetSchedulingPreview(userID, card)
+	components.PracticeCard(card, preview, cardIndex, cardCount, mode, ttsEnabled(userID)).Render(r.Context(), w)
+}
+
+// HandleCheckAnswer grades a typed practice answer and shows the rating it
+// earned. The rating is submitted to HandleReview when the user continues.
+func HandleCheckAnswer(w http.ResponseWriter, r *http.Request) {
+	checkTypedAnswer(w, r, "/practice/review", ".practice-container")
+}
+
+// checkTypedAnswer grades the answer typed for a card's Spanish term
+func checkTypedAnswer(w http.ResponseWriter, r *http.Request, reviewPath, target string) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cardID, err := strconv.ParseInt(r.FormValue("card_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid card_id", http.StatusBadRequest)
+		return
+	}
+	card, ok := visibleCard(w, r, cardID)
+	if !ok {
+		return
+	}
+
+	durationMs, _ := strconv.Atoi(r.FormValue("duration_ms"))
+	result := grading.Grade(r.FormValue("answer"), card.Term)
+	rating := grading.Rate(result, time.Duration(durationMs)*time.Millisecond)
+	components.TypingResult(result, rating, card.ID, durationMs, reviewPath, target).Render(r.Context(), w)
 }
 
 // HandleSkip skips the current card without rating
 func HandleSkip(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 	if err := r.ParseForm(); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -194,7 +248,7 @@ func HandleSkip(w http.ResponseWriter, r *http.Request) {
 	}
 
 	sessionsLock.Lock()
-	session, exists := sessions[defaultUserID]
+	session, exists := sessions[userID]
 	if !exists {
 		sessionsLock.Unlock()
 		http.Error(w, "no active session", http.StatusBadRequest)
@@ -207,7 +261,7 @@ func HandleSkip(w http.ResponseWriter, r *http.Request) {
 
 	// Check if session is complete
 	if session.CurrentIndex >= len(session.Cards) {
-		newAchievements := reviewService.CheckAchievements(defaultUserID)
+		newAchievements := reviewService.CheckAchievements(userID)
 		stats := reviewService.GetSessionStats(session)
 		components.PracticeComplete(stats.Reviewed, stats.Correct, stats.XPEarned, newAchievements).Render(r.Context(), w)
 		return
@@ -221,19 +275,19 @@ func HandleSkip(w http.ResponseWriter, r *http.Request) {
 	sessionsLock.RUnlock()
 
 	// Fetch bridges
-	if card != nil && len(card.Bridges) == 0 {
-		bridges, _ := repository.GetBridgesForCard(card.ID)
-		card.Bridges = bridges
+	if card != nil {
+		attachBridges(userID, &card.Card)
 	}
 
-	preview := reviewService.GetSchedulingPreview(card)
-	components.PracticeCard(card, preview, cardIndex, cardCount, mode).Render(r.Context(), w)
+	preview := reviewService.GetSchedulingPreview(userID, card)
+	components.PracticeCard(card, preview, cardIndex, cardCount, mode, ttsEnabled(userID)).Render(r.Context(), w)
 }
 
 // HandlePracticeStats returns session stats (HTMX partial)
 func HandlePracticeStats(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 	sessionsLock.RLock()
-	session, exists := sessions[defaultUserID]
+	session, exists := sessions[userID]
 	sessionsLock.RUnlock()
 
 	if !exists {
@@ -1,33 +1,30 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 
 	"languagepapi/components"
+	"languagepapi/internal/fsrs"
 	"languagepapi/internal/repository"
+	"languagepapi/internal/service"
 )
 
 // HandleSettings renders the settings page
 func HandleSettings(w http.ResponseWriter, r *http.Request) {
-	settings, err := repository.GetUserSettings(defaultUserID)
-	if err != nil {
-		// Use defaults
-		settings = &repository.UserSettings{
-			DailyGoal:        20,
-			EnableTTS:        true,
-			ShowBridges:      true,
-			DefaultMode:      "standard",
-			NewCardsPerDay:   10,
-			ReviewsPerSession: 20,
-		}
-	}
+	userID := currentUserID(r)
+	settings, _ := service.LoadUserSettings(userID)
+	fsrsParams, _ := repository.GetFSRSParams(userID)
 
-	components.Settings(settings, "", false).Render(r.Context(), w)
+	components.Settings(settings, fsrsParams, "", false).Render(r.Context(), w)
 }
 
 // HandleSaveSettings saves user settings
 func HandleSaveSettings(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 	if err := r.ParseForm(); err != nil {
 		http.Error(w, "Invalid form", http.StatusBadRequest)
 		return
@@ -38,21 +35,9 @@ func HandleSaveSettings(w http.ResponseWriter, r *http.Request) {
 	reviewsPerSession, _ := strconv.Atoi(r.FormValue("reviews_per_session"))
 	enableTTS := r.FormValue("enable_tts") == "on"
 	showBridges := r.FormValue("show_bridges") == "on"
+	drillVosotros := r.FormValue("drill_vosotros") == "on"
 	defaultMode := r.FormValue("default_mode")
 
-	if dailyGoal < 1 {
-		dailyGoal = 20
-	}
-	if newCardsPerDay < 1 {
-		newCardsPerDay = 10
-	}
-	if reviewsPerSession < 1 {
-		reviewsPerSession = 20
-	}
-	if defaultMode == "" {
-		defaultMode = "standard"
-	}
-
 	settings := &repository.UserSettings{
 		DailyGoal:        dailyGoal,
 		EnableTTS:        enableTTS,
@@ -60,12 +45,55 @@ func HandleSaveSettings(w http.ResponseWriter, r *http.Request) {
 		DefaultMode:      defaultMode,
 		NewCardsPerDay:   newCardsPerDay,
 		ReviewsPerSession: reviewsPerSession,
+		DrillVosotros:    drillVosotros,
+	}
+	normalizeSettings(settings)
+
+	fsrsParams, _ := repository.GetFSRSParams(userID)
+	if err := repository.SaveUserSettings(userID, settings); err != nil {
+		components.Settings(settings, fsrsParams, "Failed to save settings", false).Render(r.Context(), w)
+		return
 	}
 
-	if err := repository.SaveUserSettings(defaultUserID, settings); err != nil {
-		components.Settings(settings, "Failed to save settings", false).Render(r.Context(), w)
+	components.Settings(settings, fsrsParams, "Settings saved!", true).Render(r.Context(), w)
+}
+
+// normalizeSettings replaces missing or out-of-range values with defaults.
+// An empty new-card quota or default mode leaves the curriculum's in place.
+func normalizeSettings(settings *repository.UserSettings) {
+	if settings.DailyGoal < 1 {
+		settings.DailyGoal = 20
+	}
+	if settings.NewCardsPerDay < 0 {
+		settings.NewCardsPerDay = 0
+	}
+	if settings.ReviewsPerSession < 1 {
+		settings.ReviewsPerSession = 20
+	}
+	switch settings.DefaultMode {
+	case "standard", "reverse", "typing":
+	default:
+		settings.DefaultMode = ""
+	}
+}
+
+// HandleOptimizeFSRS fits FSRS weights to the user's review history
+func HandleOptimizeFSRS(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+	settings, _ := service.LoadUserSettings(userID)
+
+	fsrsParams, err := reviewService.OptimizeFSRS(userID)
+	if err != nil {
+		fsrsParams, _ = repository.GetFSRSParams(userID)
+		msg := "Failed to optimize scheduling"
+		if errors.Is(err, fsrs.ErrNotEnoughReviews) {
+			msg = fmt.Sprintf("Need at least %d reviews on separate days to optimize", fsrs.MinOptimizerReviews)
+		} else {
+			log.Printf("fsrs optimize failed for user %d: %v", userID, err)
+		}
+		components.Settings(settings, fsrsParams, msg, false).Render(r.Context(), w)
 		return
 	}
 
-	components.Settings(settings, "Settings saved!", true).Render(r.Context(), w)
+	components.Settings(settings, fsrsParams, "Scheduling optimized!", true).Render(r.Context(), w)
 }
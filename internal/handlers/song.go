@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
-	"sync"
-	"time"
+	"strings"
 
 	"github.com/dhowden/tag"
 
@@ -16,22 +20,48 @@ import (
 	"languagepapi/internal/service"
 )
 
-var (
-	// In-memory song lesson storage
-	songLessons     = make(map[int64]*models.SongLesson)
-	songStats       = make(map[int64]*songSessionStats)
-	songSessions    = make(map[int64]*models.SongSession)
-	songLessonsLock sync.RWMutex
-)
-
 type songSessionStats struct {
 	VocabReviewed int
 	VocabCorrect  int
 	LinesStudied  int
 	BlanksCorrect int
 	BlanksTotal   int
-	XPEarned      int
-	StartTime     time.Time
+	// Words of the dictated lines
+	DictationCorrect int
+	DictationTotal   int
+	XPEarned         int
+}
+
+// songStatsFor totals the answered items of a song lesson
+func songStatsFor(lesson *models.SongLesson) *songSessionStats {
+	stats := &songSessionStats{
+		LinesStudied: lesson.Session.LinesStudied,
+		BlanksTotal:  len(lesson.Blanks),
+	}
+	for _, v := range lesson.VocabCards {
+		if v.Rating == 0 {
+			continue
+		}
+		stats.VocabReviewed++
+		if v.Rating >= models.RatingGood {
+			stats.VocabCorrect++
+			stats.XPEarned += 2
+		}
+	}
+	for _, b := range lesson.Blanks {
+		if b.IsCorrect {
+			stats.BlanksCorrect++
+			stats.XPEarned += 3
+		}
+	}
+	for _, d := range lesson.Dictations {
+		stats.DictationCorrect += d.WordsCorrect
+		stats.DictationTotal += d.WordsTotal
+		if d.WordsTotal > 0 {
+			stats.XPEarned += 3 * d.WordsCorrect / d.WordsTotal
+		}
+	}
+	return stats
 }
 
 var songService = service.NewSongService()
@@ -79,7 +109,8 @@ func HandleAlbumArt(w http.ResponseWriter, r *http.Request) {
 
 // HandleSongHome renders the song lessons browse page
 func HandleSongHome(w http.ResponseWriter, r *http.Request) {
-	data, err := songService.GetSongHomeData(defaultUserID)
+	userID := currentUserID(r)
+	data, err := songService.GetSongHomeData(userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -89,25 +120,31 @@ func HandleSongHome(w http.ResponseWriter, r *http.Request) {
 
 // HandleSongDetail renders the song detail page with mode selection
 func HandleSongDetail(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 	songID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil {
 		http.Error(w, "invalid song id", http.StatusBadRequest)
 		return
 	}
 
+	// Best effort: relink the lyrics if cards were added since
+	_ = service.EnsureSongWords(songID)
+
 	song, err := repository.GetSongWithDetails(songID)
 	if err != nil {
 		http.Error(w, "song not found", http.StatusNotFound)
 		return
 	}
 
-	progress, _ := repository.GetSongProgress(defaultUserID, songID)
+	progress, _ := repository.GetSongProgress(userID, songID)
+	unmatched, _ := repository.GetSongUnmatchedWords(songID)
 
-	components.SongDetail(song, progress).Render(r.Context(), w)
+	components.SongDetail(song, progress, unmatched, isAdmin(r)).Render(r.Context(), w)
 }
 
-// HandleSongStart starts a song lesson
+// HandleSongStart starts a song lesson, resuming today's unfinished one
 func HandleSongStart(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 	songID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil {
 		http.Error(w, "invalid song id", http.StatusBadRequest)
@@ -119,34 +156,147 @@ func HandleSongStart(w http.ResponseWriter, r *http.Request) {
 		mode = models.SongModeFull
 	}
 
-	songLessonsLock.Lock()
-	defer songLessonsLock.Unlock()
-
-	// Build lesson
-	lesson, err := songService.BuildSongLesson(defaultUserID, songID, mode)
+	lesson, err := songService.StartOrResumeSongLesson(userID, songID, mode)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Store in memory
-	songLessons[defaultUserID] = lesson
-	songStats[defaultUserID] = &songSessionStats{
-		StartTime:   time.Now(),
-		BlanksTotal: len(lesson.Blanks),
+	// Render appropriate phase
+	renderCurrentPhase(w, r, lesson)
+}
+
+// activeSongLesson loads the user's unfinished lesson for the song in the
+// path, redirecting to /songs if there is none
+func activeSongLesson(w http.ResponseWriter, r *http.Request) (*models.SongLesson, bool) {
+	songID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid song id", http.StatusBadRequest)
+		return nil, false
 	}
 
-	// Create session in DB
-	session := &models.SongSession{
-		UserID: defaultUserID,
-		SongID: songID,
-		Mode:   mode,
+	lesson, err := songService.GetActiveSongLesson(currentUserID(r), songID)
+	if err != nil {
+		http.Redirect(w, r, "/songs", http.StatusSeeOther)
+		return nil, false
 	}
-	repository.CreateSongSession(session)
-	songSessions[defaultUserID] = session
+	return lesson, true
+}
 
-	// Render appropriate first phase
-	renderCurrentPhase(w, r, lesson)
+// advanceSongLesson moves to the next item, or the next phase once the
+// current phase has count items, and stores the new position
+func advanceSongLesson(lesson *models.SongLesson, count int) {
+	lesson.CurrentIndex++
+	if lesson.CurrentIndex >= count {
+		lesson.CurrentIndex = 0
+		lesson.CurrentPhase = service.GetNextPhase(lesson.CurrentPhase, getSongMode(lesson))
+	}
+	songService.SaveSongLessonPosition(lesson)
+}
+
+// rateSongVocab records a rating for the current vocab preview card
+func rateSongVocab(lesson *models.SongLesson, rating models.Rating) {
+	if lesson.CurrentPhase != models.SongPhaseVocabPreview || lesson.CurrentIndex >= len(lesson.VocabCards) {
+		return
+	}
+	lesson.VocabCards[lesson.CurrentIndex].Rating = rating
+	repository.RecordSongVocabRating(lesson.Session.ID, lesson.CurrentIndex, rating)
+
+	// Move to next vocab card
+	advanceSongLesson(lesson, len(lesson.VocabCards))
+}
+
+// nextSongPhase moves on to the next phase of the lesson
+func nextSongPhase(userID int64, lesson *models.SongLesson) {
+	// Increment listen count when moving past first listen
+	if lesson.CurrentPhase == models.SongPhaseFirstListen {
+		repository.IncrementSongListenCount(userID, lesson.Song.ID)
+	}
+
+	lesson.CurrentIndex = 0
+	lesson.CurrentPhase = service.GetNextPhase(lesson.CurrentPhase, getSongMode(lesson))
+	songService.SaveSongLessonPosition(lesson)
+}
+
+// nextSongLine moves to the next line of the breakdown phase, counting the
+// current one as studied unless it was skipped. Reading a line tests no
+// recall, so its card is reviewed by the blanks or dictation instead.
+func nextSongLine(lesson *models.SongLesson, studied bool) {
+	if lesson.CurrentPhase != models.SongPhaseLineBreakdown || lesson.CurrentIndex >= len(lesson.Lines) {
+		return
+	}
+	if studied {
+		repository.IncrementSongLinesStudied(lesson.Session.ID)
+		lesson.Session.LinesStudied++
+	}
+
+	// Check if breakdown phase is complete
+	advanceSongLesson(lesson, len(lesson.Lines))
+}
+
+// submitSongBlank checks and records an answer for the current blank, which
+// also reviews the card of its line, if no earlier blank did, and, if due,
+// of the blanked word
+func submitSongBlank(userID int64, lesson *models.SongLesson, answer string) {
+	if lesson.CurrentPhase != models.SongPhaseFillBlanks || lesson.CurrentIndex >= len(lesson.Blanks) {
+		return
+	}
+	blank := &lesson.Blanks[lesson.CurrentIndex]
+	if err := songService.AnswerBlank(userID, lesson, answer); err != nil {
+		log.Printf("reviewing blank of song %d for user %d failed: %v", lesson.Song.ID, userID, err)
+	}
+	repository.RecordSongBlankAnswer(lesson.Session.ID, lesson.CurrentIndex, answer, blank.IsCorrect)
+
+	// Check if blanks phase is complete
+	advanceSongLesson(lesson, len(lesson.Blanks))
+}
+
+// submitSongDictation grades and records what was typed for the current
+// dictated line. The lesson stays on the line to show the result.
+func submitSongDictation(userID int64, lesson *models.SongLesson, answer string) {
+	if lesson.CurrentPhase != models.SongPhaseDictation || lesson.CurrentIndex >= len(lesson.Dictations) {
+		return
+	}
+	d := &lesson.Dictations[lesson.CurrentIndex]
+	if d.Answered {
+		return
+	}
+	if err := songService.AnswerDictation(userID, d, answer); err != nil {
+		log.Printf("reviewing dictation of song %d for user %d failed: %v", lesson.Song.ID, userID, err)
+	}
+	repository.RecordSongDictationAnswer(lesson.Session.ID, lesson.CurrentIndex, answer, d.WordsCorrect, d.WordsTotal)
+}
+
+// nextSongDictation moves on from an answered dictated line
+func nextSongDictation(lesson *models.SongLesson) {
+	if lesson.CurrentPhase != models.SongPhaseDictation || lesson.CurrentIndex >= len(lesson.Dictations) ||
+		!lesson.Dictations[lesson.CurrentIndex].Answered {
+		return
+	}
+	advanceSongLesson(lesson, len(lesson.Dictations))
+}
+
+// settleSongPhase moves past phases that have nothing left to show
+func settleSongPhase(lesson *models.SongLesson) {
+	for {
+		var remaining int
+		switch lesson.CurrentPhase {
+		case models.SongPhaseVocabPreview:
+			remaining = len(lesson.VocabCards) - lesson.CurrentIndex
+		case models.SongPhaseLineBreakdown:
+			remaining = len(lesson.Lines) - lesson.CurrentIndex
+		case models.SongPhaseFillBlanks:
+			remaining = len(lesson.Blanks) - lesson.CurrentIndex
+		case models.SongPhaseDictation:
+			remaining = len(lesson.Dictations) - lesson.CurrentIndex
+		default:
+			return
+		}
+		if remaining > 0 {
+			return
+		}
+		lesson.CurrentPhase = service.GetNextPhase(lesson.CurrentPhase, getSongMode(lesson))
+	}
 }
 
 // HandleSongVocabReview processes a vocab card review in song lesson
@@ -162,100 +312,45 @@ func HandleSongVocabReview(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	songLessonsLock.Lock()
-	lesson, exists := songLessons[defaultUserID]
-	if !exists {
-		songLessonsLock.Unlock()
-		http.Redirect(w, r, "/songs", http.StatusSeeOther)
+	lesson, ok := activeSongLesson(w, r)
+	if !ok {
 		return
 	}
 
-	stats := songStats[defaultUserID]
-	stats.VocabReviewed++
-	if rating >= 3 {
-		stats.VocabCorrect++
-		stats.XPEarned += 2
-	}
-
-	// Move to next vocab card
-	lesson.CurrentIndex++
-
-	// Check if vocab phase is complete
-	if lesson.CurrentIndex >= len(lesson.VocabCards) {
-		lesson.CurrentIndex = 0
-		lesson.CurrentPhase = service.GetNextPhase(lesson.CurrentPhase, getSongMode(lesson))
-	}
-	songLessonsLock.Unlock()
-
+	rateSongVocab(lesson, models.Rating(rating))
 	renderCurrentPhase(w, r, lesson)
 }
 
 // HandleSongNextPhase advances to next phase of song lesson
 func HandleSongNextPhase(w http.ResponseWriter, r *http.Request) {
-	songLessonsLock.Lock()
-	lesson, exists := songLessons[defaultUserID]
-	if !exists {
-		songLessonsLock.Unlock()
-		http.Redirect(w, r, "/songs", http.StatusSeeOther)
+	lesson, ok := activeSongLesson(w, r)
+	if !ok {
 		return
 	}
 
-	// Increment listen count when moving past first listen
-	if lesson.CurrentPhase == models.SongPhaseFirstListen {
-		repository.IncrementSongListenCount(defaultUserID, lesson.Song.ID)
-	}
-
-	lesson.CurrentIndex = 0
-	lesson.CurrentPhase = service.GetNextPhase(lesson.CurrentPhase, getSongMode(lesson))
-	songLessonsLock.Unlock()
-
+	nextSongPhase(currentUserID(r), lesson)
 	renderCurrentPhase(w, r, lesson)
 }
 
 // HandleSongNextLine advances to next line in breakdown phase
 func HandleSongNextLine(w http.ResponseWriter, r *http.Request) {
-	songLessonsLock.Lock()
-	lesson, exists := songLessons[defaultUserID]
-	if !exists {
-		songLessonsLock.Unlock()
-		http.Redirect(w, r, "/songs", http.StatusSeeOther)
+	lesson, ok := activeSongLesson(w, r)
+	if !ok {
 		return
 	}
 
-	stats := songStats[defaultUserID]
-	stats.LinesStudied++
-
-	lesson.CurrentIndex++
-
-	// Check if breakdown phase is complete
-	if lesson.CurrentIndex >= len(lesson.Song.Lines) {
-		lesson.CurrentIndex = 0
-		lesson.CurrentPhase = service.GetNextPhase(lesson.CurrentPhase, getSongMode(lesson))
-	}
-	songLessonsLock.Unlock()
-
+	nextSongLine(lesson, true)
 	renderCurrentPhase(w, r, lesson)
 }
 
 // HandleSongSkipLine skips the current line
 func HandleSongSkipLine(w http.ResponseWriter, r *http.Request) {
-	songLessonsLock.Lock()
-	lesson, exists := songLessons[defaultUserID]
-	if !exists {
-		songLessonsLock.Unlock()
-		http.Redirect(w, r, "/songs", http.StatusSeeOther)
+	lesson, ok := activeSongLesson(w, r)
+	if !ok {
 		return
 	}
 
-	lesson.CurrentIndex++
-
-	// Check if breakdown phase is complete
-	if lesson.CurrentIndex >= len(lesson.Song.Lines) {
-		lesson.CurrentIndex = 0
-		lesson.CurrentPhase = service.GetNextPhase(lesson.CurrentPhase, getSongMode(lesson))
-	}
-	songLessonsLock.Unlock()
-
+	nextSongLine(lesson, false)
 	renderCurrentPhase(w, r, lesson)
 }
 
@@ -268,105 +363,112 @@ func HandleSongBlankSubmit(w http.ResponseWriter, r *http.Request) {
 
 	answer := r.FormValue("answer")
 
-	songLessonsLock.Lock()
-	lesson, exists := songLessons[defaultUserID]
-	if !exists {
-		songLessonsLock.Unlock()
-		http.Redirect(w, r, "/songs", http.StatusSeeOther)
+	lesson, ok := activeSongLesson(w, r)
+	if !ok {
 		return
 	}
 
-	stats := songStats[defaultUserID]
-	currentIdx := lesson.CurrentIndex
+	submitSongBlank(currentUserID(r), lesson, answer)
+	renderCurrentPhase(w, r, lesson)
+}
 
-	if currentIdx < len(lesson.Blanks) {
-		blank := &lesson.Blanks[currentIdx]
-		blank.UserAnswer = answer
-		blank.IsCorrect = songService.CheckBlankAnswer(blank, answer)
+// HandleSongDictationSubmit checks what was typed for a dictated line
+func HandleSongDictationSubmit(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-		if blank.IsCorrect {
-			stats.BlanksCorrect++
-			stats.XPEarned += 3
-		}
+	answer := r.FormValue("answer")
+
+	lesson, ok := activeSongLesson(w, r)
+	if !ok {
+		return
 	}
 
-	lesson.CurrentIndex++
+	submitSongDictation(currentUserID(r), lesson, answer)
+	renderCurrentPhase(w, r, lesson)
+}
 
-	// Check if blanks phase is complete
-	if lesson.CurrentIndex >= len(lesson.Blanks) {
-		lesson.CurrentIndex = 0
-		lesson.CurrentPhase = service.GetNextPhase(lesson.CurrentPhase, getSongMode(lesson))
+// HandleSongNextDictation moves on to the next line to dictate
+func HandleSongNextDictation(w http.ResponseWriter, r *http.Request) {
+	lesson, ok := activeSongLesson(w, r)
+	if !ok {
+		return
 	}
-	songLessonsLock.Unlock()
 
+	nextSongDictation(lesson)
 	renderCurrentPhase(w, r, lesson)
 }
 
 // HandleSongComplete completes the song lesson
 func HandleSongComplete(w http.ResponseWriter, r *http.Request) {
-	songLessonsLock.Lock()
-	lesson, exists := songLessons[defaultUserID]
-	if !exists {
-		songLessonsLock.Unlock()
-		http.Redirect(w, r, "/songs", http.StatusSeeOther)
+	lesson, ok := activeSongLesson(w, r)
+	if !ok {
 		return
 	}
+	completeSongLesson(w, r, lesson)
+}
+
+// completeSongLesson records the finished lesson and renders the summary
+func completeSongLesson(w http.ResponseWriter, r *http.Request, lesson *models.SongLesson) {
+	components.SongComplete(songLessonSummaryFor(currentUserID(r), lesson)).Render(r.Context(), w)
+}
 
-	stats := songStats[defaultUserID]
-	session := songSessions[defaultUserID]
+// songLessonSummaryFor records the finished lesson, crediting its XP and
+// song progress, and returns the summary
+func songLessonSummaryFor(userID int64, lesson *models.SongLesson) *models.SongLessonSummary {
+	stats := songStatsFor(lesson)
+	session := lesson.Session
 
 	// Calculate final XP
 	mode := getSongMode(lesson)
-	stats.XPEarned += service.CalculateSongXP(mode, stats.VocabCorrect, stats.VocabReviewed, stats.BlanksCorrect, stats.BlanksTotal)
+	stats.XPEarned += service.CalculateSongXP(mode, stats.VocabCorrect, stats.VocabReviewed, stats.BlanksCorrect, stats.BlanksTotal, stats.DictationCorrect, stats.DictationTotal)
 
-	// Update session in DB
-	if session != nil {
-		repository.UpdateSongSession(session.ID, stats.VocabReviewed, stats.VocabCorrect, stats.LinesStudied, stats.BlanksCorrect, stats.BlanksTotal, stats.XPEarned)
-		repository.CompleteSongSession(session.ID)
-	}
+	// Update session in DB (lines studied are counted as they happen)
+	repository.UpdateSongSession(session.ID, stats.VocabReviewed, stats.VocabCorrect, 0, stats.BlanksCorrect, stats.BlanksTotal, stats.DictationCorrect, stats.DictationTotal, stats.XPEarned)
+	repository.CompleteSongSession(session.ID)
 
 	// Update song progress
-	songService.UpdateSongProgressAfterLesson(defaultUserID, lesson.Song.ID, mode, stats.VocabCorrect, stats.VocabReviewed, stats.BlanksCorrect, stats.BlanksTotal)
+	songService.UpdateSongProgressAfterLesson(userID, lesson.Song.ID, mode, stats.VocabCorrect, stats.VocabReviewed, stats.BlanksCorrect, stats.BlanksTotal, stats.DictationCorrect, stats.DictationTotal)
 
 	// Update user XP
-	repository.UpdateUserXP(defaultUserID, stats.XPEarned)
+	repository.UpdateUserXP(userID, stats.XPEarned)
 
 	// Calculate accuracy
 	accuracy := 0
-	totalItems := stats.VocabReviewed + stats.BlanksTotal
+	totalItems := stats.VocabReviewed + stats.BlanksTotal + stats.DictationTotal
 	if totalItems > 0 {
-		accuracy = (stats.VocabCorrect + stats.BlanksCorrect) * 100 / totalItems
+		accuracy = (stats.VocabCorrect + stats.BlanksCorrect + stats.DictationCorrect) * 100 / totalItems
 	}
 
 	// Build summary
-	summary := &models.SongLessonSummary{
-		Song:          lesson.Song,
-		Mode:          mode,
-		VocabReviewed: stats.VocabReviewed,
-		VocabCorrect:  stats.VocabCorrect,
-		LinesStudied:  stats.LinesStudied,
-		BlanksCorrect: stats.BlanksCorrect,
-		BlanksTotal:   stats.BlanksTotal,
-		Accuracy:      accuracy,
-		XPEarned:      stats.XPEarned,
-		Message:       service.GetSongMotivationalMessage(accuracy),
-	}
-
-	// Clear session data
-	delete(songLessons, defaultUserID)
-	delete(songStats, defaultUserID)
-	delete(songSessions, defaultUserID)
-	songLessonsLock.Unlock()
-
-	components.SongComplete(summary).Render(r.Context(), w)
+	return &models.SongLessonSummary{
+		Song:             lesson.Song,
+		Mode:             mode,
+		VocabReviewed:    stats.VocabReviewed,
+		VocabCorrect:     stats.VocabCorrect,
+		LinesStudied:     stats.LinesStudied,
+		BlanksCorrect:    stats.BlanksCorrect,
+		BlanksTotal:      stats.BlanksTotal,
+		DictationCorrect: stats.DictationCorrect,
+		DictationTotal:   stats.DictationTotal,
+		Accuracy:         accuracy,
+		XPEarned:         stats.XPEarned,
+		Message:          service.GetSongMotivationalMessage(accuracy),
+	}
 }
 
 // getSongMode determines the mode from the lesson
 func getSongMode(lesson *models.SongLesson) models.SongMode {
+	if lesson.Session != nil && lesson.Session.Mode != "" {
+		return lesson.Session.Mode
+	}
+
 	// Infer from what's available
 	hasVocab := len(lesson.VocabCards) > 0
 	hasBlanks := len(lesson.Blanks) > 0
-	hasLines := len(lesson.Song.Lines) > 0
+	hasLines := len(lesson.Lines) > 0
 
 	if hasVocab && hasLines && hasBlanks {
 		return models.SongModeFull
@@ -385,13 +487,10 @@ func getSongMode(lesson *models.SongLesson) models.SongMode {
 
 // renderCurrentPhase renders the appropriate template for the current phase
 func renderCurrentPhase(w http.ResponseWriter, r *http.Request, lesson *models.SongLesson) {
+	settleSongPhase(lesson)
+
 	switch lesson.CurrentPhase {
 	case models.SongPhaseVocabPreview:
-		if len(lesson.VocabCards) == 0 || lesson.CurrentIndex >= len(lesson.VocabCards) {
-			lesson.CurrentPhase = service.GetNextPhase(lesson.CurrentPhase, getSongMode(lesson))
-			renderCurrentPhase(w, r, lesson)
-			return
-		}
 		card := &lesson.VocabCards[lesson.CurrentIndex]
 		components.SongVocabPhase(lesson, lesson.CurrentIndex+1, len(lesson.VocabCards), card).Render(r.Context(), w)
 
@@ -399,35 +498,29 @@ func renderCurrentPhase(w http.ResponseWriter, r *http.Request, lesson *models.S
 		components.SongFirstListen(lesson).Render(r.Context(), w)
 
 	case models.SongPhaseLineBreakdown:
-		if len(lesson.Song.Lines) == 0 || lesson.CurrentIndex >= len(lesson.Song.Lines) {
-			lesson.CurrentPhase = service.GetNextPhase(lesson.CurrentPhase, getSongMode(lesson))
-			renderCurrentPhase(w, r, lesson)
-			return
-		}
 		components.SongLineBreakdown(lesson, lesson.CurrentIndex).Render(r.Context(), w)
 
 	case models.SongPhaseFillBlanks:
-		if len(lesson.Blanks) == 0 || lesson.CurrentIndex >= len(lesson.Blanks) {
-			lesson.CurrentPhase = service.GetNextPhase(lesson.CurrentPhase, getSongMode(lesson))
-			renderCurrentPhase(w, r, lesson)
-			return
-		}
 		blank := &lesson.Blanks[lesson.CurrentIndex]
 		components.SongFillBlanks(lesson, blank, lesson.CurrentIndex+1, len(lesson.Blanks)).Render(r.Context(), w)
 
 	case models.SongPhaseFinalListen:
 		components.SongFinalListen(lesson).Render(r.Context(), w)
 
+	case models.SongPhaseDictation:
+		d := &lesson.Dictations[lesson.CurrentIndex]
+		components.SongDictation(lesson, d, lesson.CurrentIndex+1, len(lesson.Dictations)).Render(r.Context(), w)
+
 	case models.SongPhaseComplete:
-		// Trigger completion handler
-		HandleSongComplete(w, r)
+		completeSongLesson(w, r, lesson)
 
 	default:
 		http.Redirect(w, r, "/songs", http.StatusSeeOther)
 	}
 }
 
-// HandleFetchLyrics fetches lyrics from lrclib.net for a song
+// HandleFetchLyrics queues fetching and translating a song's lyrics from
+// lrclib.net; progress shows on the jobs page
 func HandleFetchLyrics(w http.ResponseWriter, r *http.Request) {
 	songID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil {
@@ -435,13 +528,51 @@ func HandleFetchLyrics(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	lyricsService := service.NewLyricsService()
-	err = lyricsService.FetchAndStoreLyrics(songID)
+	if _, err := repository.GetSong(songID); err != nil {
+		http.Error(w, "song not found", http.StatusNotFound)
+		return
+	}
+
+	jobID, err := jobService.EnqueueLyrics(currentUserID(r), songID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"success": true}`))
+	writeJSON(w, http.StatusAccepted, map[string]any{"success": true, "job_id": jobID})
+}
+
+// lyricsContentTypes maps the lyrics export formats to their media types
+var lyricsContentTypes = map[string]string{
+	service.FormatLRC: "text/plain; charset=utf-8",
+	service.FormatSRT: "application/x-subrip; charset=utf-8",
+	service.FormatVTT: "text/vtt; charset=utf-8",
+}
+
+// HandleSongLyricsExport serves a song's timed lyrics as
+// /songs/{id}/lyrics.lrc, .srt or .vtt for use in other players
+func HandleSongLyricsExport(w http.ResponseWriter, r *http.Request) {
+	songID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid song id", http.StatusBadRequest)
+		return
+	}
+	format := strings.TrimPrefix(path.Ext(r.URL.Path), ".")
+
+	body, err := service.ExportSongLyrics(songID, format)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "song not found", http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrNoLyrics), errors.Is(err, service.ErrUnknownFormat):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", lyricsContentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"song-%d.%s\"", songID, format))
+	w.Write([]byte(body))
 }
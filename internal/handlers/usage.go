@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"languagepapi/components"
+	"languagepapi/internal/bridge"
+	"languagepapi/internal/repository"
+)
+
+// HandleUsage renders this month's AI usage and cost across all accounts,
+// for admins only
+func HandleUsage(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	renderUsage(w, r, "")
+}
+
+// HandleClearAICache drops every cached AI response, for admins only
+func HandleClearAICache(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	n, err := repository.ClearAICache()
+	if err != nil {
+		http.Error(w, "Failed to clear cache", http.StatusInternalServerError)
+		return
+	}
+	renderUsage(w, r, fmt.Sprintf("Cleared %d cached responses", n))
+}
+
+func renderUsage(w http.ResponseWriter, r *http.Request, message string) {
+	features, err := repository.GetAIUsageThisMonth()
+	if err != nil {
+		http.Error(w, "Failed to load usage", http.StatusInternalServerError)
+		return
+	}
+	cached, err := repository.CountAICachedResponses()
+	if err != nil {
+		http.Error(w, "Failed to load usage", http.StatusInternalServerError)
+		return
+	}
+	components.Usage(features, cached, bridge.BudgetFromEnv(), message).Render(r.Context(), w)
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"languagepapi/internal/repository"
+)
+
+func TestUsageRequiresAdmin(t *testing.T) {
+	openTestDB(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+	t.Setenv("ADMIN_USERS", "carol, alice")
+	if err := repository.SaveAICachedResponse("key", "bridges", "fake", "{}"); err != nil {
+		t.Fatalf("save cached response: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		userID  int64
+		want    int
+	}{
+		{"non-admin views usage", HandleUsage, http.MethodGet, bob, http.StatusForbidden},
+		{"non-admin clears cache", HandleClearAICache, http.MethodPost, bob, http.StatusForbidden},
+		{"admin views usage", HandleUsage, http.MethodGet, alice, http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		tt.handler(w, asUser(httptest.NewRequest(tt.method, "/usage", nil), tt.userID))
+		if w.Code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+	if n, _ := repository.CountAICachedResponses(); n != 1 {
+		t.Errorf("cached responses after a non-admin clear = %d, want 1", n)
+	}
+}
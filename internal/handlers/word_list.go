@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"languagepapi/components"
+	"languagepapi/internal/repository"
+	"languagepapi/internal/service"
+)
+
+// maxWordListUpload limits uploaded CSV/TSV files
+const maxWordListUpload = 10 << 20
+
+var wordListService = service.NewWordListService()
+
+// HandleWordsExport downloads the words list, with the page's island and
+// search filters, as CSV (or TSV with format=tsv)
+func HandleWordsExport(w http.ResponseWriter, r *http.Request) {
+	islandID, _ := strconv.ParseInt(r.URL.Query().Get("island"), 10, 64)
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	cards, err := repository.FilterCards(currentUserID(r), islandID, query)
+	if err != nil {
+		http.Error(w, "Failed to load words", http.StatusInternalServerError)
+		return
+	}
+
+	delimiter, ext, contentType := ',', "csv", "text/csv"
+	if r.URL.Query().Get("format") == "tsv" {
+		delimiter, ext, contentType = '\t', "tsv", "text/tab-separated-values"
+	}
+	data, err := wordListService.Export(cards, delimiter)
+	if err != nil {
+		http.Error(w, "Failed to export words", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="languagepapi-words.%s"`, ext))
+	w.Write(data)
+}
+
+// HandleWordsImportPage renders the CSV/TSV import form
+func HandleWordsImportPage(w http.ResponseWriter, r *http.Request) {
+	renderWordsImport(w, r, nil, "", false)
+}
+
+// HandleWordsImport imports an uploaded or pasted CSV/TSV word list
+func HandleWordsImport(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxWordListUpload)
+	if err := r.ParseMultipartForm(maxWordListUpload); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	data := []byte(r.FormValue("data"))
+	if file, header, err := r.FormFile("file"); err == nil {
+		defer file.Close()
+		if header.Size > 0 {
+			if data, err = io.ReadAll(file); err != nil {
+				http.Error(w, "Failed to read upload", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		renderWordsImport(w, r, nil, "Choose a file or paste some rows", false)
+		return
+	}
+
+	duplicates, err := service.ParseDuplicateMode(r.FormValue("duplicates"))
+	if err != nil {
+		renderWordsImport(w, r, nil, err.Error(), false)
+		return
+	}
+	islandID, _ := strconv.ParseInt(r.FormValue("island_id"), 10, 64)
+
+	mapping := make(map[service.WordColumn]string)
+	for _, c := range service.WordColumns {
+		if v := strings.TrimSpace(r.FormValue("map_" + string(c))); v != "" {
+			mapping[c] = v
+		}
+	}
+
+	result, err := wordListService.Import(currentUserID(r), data, service.WordImportOptions{
+		Mapping:         mapping,
+		Duplicates:      duplicates,
+		DefaultIslandID: islandID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		renderWordsImport(w, r, nil, err.Error(), false)
+		return
+	}
+	log.Printf("Word import: %d created, %d merged, %d overwritten, %d skipped, %d failed",
+		result.Created, result.Merged, result.Overwritten, result.Skipped, result.Failed)
+
+	renderWordsImport(w, r, result, "", true)
+}
+
+func renderWordsImport(w http.ResponseWriter, r *http.Request, result *service.WordImportResult, message string, success bool) {
+	islands, _ := repository.GetAllIslands()
+	components.WordsImport(islands, service.WordColumns, result, message, success).Render(r.Context(), w)
+}
@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"languagepapi/internal/db"
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+)
+
+func TestEnqueueDedupsWithinBatch(t *testing.T) {
+	if err := db.Init(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	alice, err := repository.CreateUser("alice", "")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	bob, err := repository.CreateUser("bob", "")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	q := New()
+	q.Register("bridges", Kind{})
+	batch := func(userID int64) sql.NullInt64 {
+		id, err := repository.CreateJobBatch("Bridges", sql.NullInt64{Int64: userID, Valid: true})
+		if err != nil {
+			t.Fatalf("create batch: %v", err)
+		}
+		return sql.NullInt64{Int64: id, Valid: true}
+	}
+	enqueue := func(userID int64, batchID sql.NullInt64) int64 {
+		id, err := q.Enqueue(&models.Job{
+			BatchID: batchID,
+			UserID:  sql.NullInt64{Int64: userID, Valid: true},
+			Kind:    "bridges",
+			Payload: `{"card_id":1}`,
+		})
+		if err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+		return id
+	}
+
+	alicesBatch := batch(alice.ID)
+	first := enqueue(alice.ID, alicesBatch)
+	if again := enqueue(alice.ID, alicesBatch); again != first {
+		t.Errorf("same job in the same batch got job %d, want %d", again, first)
+	}
+	if other := enqueue(alice.ID, batch(alice.ID)); other == first {
+		t.Error("a second batch of alice's reused the first batch's job")
+	}
+	if other := enqueue(bob.ID, batch(bob.ID)); other == first {
+		t.Error("bob's batch reused alice's job")
+	}
+	single := enqueue(bob.ID, sql.NullInt64{})
+	if again := enqueue(bob.ID, sql.NullInt64{}); again != single {
+		t.Errorf("same job outside a batch got job %d, want %d", again, single)
+	}
+}
@@ -0,0 +1,48 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// limiter spaces job starts at least interval apart
+type limiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newLimiter(interval time.Duration) *limiter {
+	return &limiter{interval: interval}
+}
+
+// wait blocks until the next slot. A nil limiter never blocks.
+func (l *limiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(slot)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
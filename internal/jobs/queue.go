@@ -0,0 +1,284 @@
+// Package jobs runs background work from the SQLite-backed jobs table:
+// worker goroutines claim due jobs, wait for their rate limit, run the
+// handler registered for the kind and retry failures with backoff.
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+)
+
+// Retry backoff doubles from baseBackoff up to maxBackoff
+const (
+	baseBackoff        = 30 * time.Second
+	maxBackoff         = 30 * time.Minute
+	defaultMaxAttempts = 4
+	pollInterval       = 2 * time.Second
+	heartbeatInterval  = 30 * time.Second
+)
+
+// StaleAfter is how long a running job can go without a heartbeat before it
+// is taken to be abandoned by a crashed process and queued again
+const StaleAfter = 4 * heartbeatInterval
+
+// Handler does the work for one job. Returning an error retries the job
+// with backoff unless the error is Permanent or the job was cancelled.
+type Handler func(ctx context.Context, job *models.Job) error
+
+// Kind describes how jobs of one kind run
+type Kind struct {
+	Handler     Handler
+	Limit       string // Rate limit the job waits for, see Queue.SetRate; empty for none
+	MaxAttempts int    // Defaults to 4
+}
+
+// Queue runs registered kinds of jobs on worker goroutines
+type Queue struct {
+	mu       sync.Mutex
+	kinds    map[string]Kind
+	limiters map[string]*limiter
+	running  map[int64]context.CancelFunc
+	wake     chan struct{}
+	batchID  int64 // Only claim jobs of this batch if set, see OnlyBatch
+	workers  sync.WaitGroup
+}
+
+// New creates a queue. Register kinds, then Start it.
+func New() *Queue {
+	return &Queue{
+		kinds:    make(map[string]Kind),
+		limiters: make(map[string]*limiter),
+		running:  make(map[int64]context.CancelFunc),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Register sets the handler for a kind. Only registered kinds are claimed.
+func (q *Queue) Register(name string, kind Kind) {
+	if kind.MaxAttempts == 0 {
+		kind.MaxAttempts = defaultMaxAttempts
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.kinds[name] = kind
+}
+
+// SetRate allows perMinute job starts for a limit name. Zero or less
+// removes the limit.
+func (q *Queue) SetRate(limit string, perMinute int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if perMinute <= 0 {
+		delete(q.limiters, limit)
+		return
+	}
+	q.limiters[limit] = newLimiter(time.Minute / time.Duration(perMinute))
+}
+
+// Enqueue adds a job, or finds the user's unfinished job with the same
+// kind and payload in the same batch, and returns its ID
+func (q *Queue) Enqueue(job *models.Job) (int64, error) {
+	q.mu.Lock()
+	kind, ok := q.kinds[job.Kind]
+	q.mu.Unlock()
+	if !ok {
+		return 0, fmt.Errorf("unknown job kind %q", job.Kind)
+	}
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = kind.MaxAttempts
+	}
+
+	if _, err := repository.EnqueueJob(job); err != nil {
+		return 0, err
+	}
+	q.Wake()
+	return job.ID, nil
+}
+
+// Wake makes an idle worker look for jobs now instead of at its next poll
+func (q *Queue) Wake() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Cancel stops a job if a worker of this queue is running it. The caller
+// marks it cancelled in the database.
+func (q *Queue) Cancel(id int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if cancel, ok := q.running[id]; ok {
+		cancel()
+	}
+}
+
+// OnlyBatch restricts the queue to the jobs of one batch, leaving the rest
+// to other processes. Call it before Start.
+func (q *Queue) OnlyBatch(batchID int64) {
+	q.batchID = batchID
+}
+
+// Start launches the worker goroutines. They stop when ctx is cancelled,
+// putting the jobs they were running back in the queue; see Wait. While
+// running, the queue keeps its jobs' heartbeats fresh and requeues the
+// stale jobs of crashed processes.
+func (q *Queue) Start(ctx context.Context, workers int) {
+	for i := 0; i < max(workers, 1); i++ {
+		q.workers.Add(1)
+		go q.work(ctx)
+	}
+	q.workers.Add(1)
+	go q.heartbeat(ctx)
+}
+
+// Wait blocks until the workers have stopped after their context was
+// cancelled
+func (q *Queue) Wait() {
+	q.workers.Wait()
+}
+
+func (q *Queue) work(ctx context.Context) {
+	defer q.workers.Done()
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		job, err := repository.ClaimJob(q.kindNames(), q.batchID)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				log.Printf("Failed to claim job: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.wake:
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+
+		q.run(ctx, job)
+	}
+}
+
+func (q *Queue) heartbeat(ctx context.Context) {
+	defer q.workers.Done()
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := repository.TouchJobs(q.runningIDs()); err != nil {
+			log.Printf("Failed to record job heartbeats: %v", err)
+		}
+		if n, err := repository.ResetStaleJobs(StaleAfter); err != nil {
+			log.Printf("Failed to requeue stale jobs: %v", err)
+		} else if n > 0 {
+			log.Printf("Requeued %d stale jobs", n)
+		}
+	}
+}
+
+func (q *Queue) runningIDs() []int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	ids := make([]int64, 0, len(q.running))
+	for id := range q.running {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (q *Queue) kindNames() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	names := make([]string, 0, len(q.kinds))
+	for name := range q.kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// run executes a claimed job and records the outcome
+func (q *Queue) run(ctx context.Context, job *models.Job) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	q.mu.Lock()
+	kind := q.kinds[job.Kind]
+	lim := q.limiters[kind.Limit]
+	q.running[job.ID] = cancel
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		delete(q.running, job.ID)
+		q.mu.Unlock()
+	}()
+
+	err := lim.wait(jobCtx)
+	if err == nil {
+		err = kind.Handler(jobCtx, job)
+	}
+
+	switch {
+	case err == nil:
+		err = repository.FinishJob(job.ID, models.JobDone, "")
+	case jobCtx.Err() != nil && ctx.Err() == nil:
+		// Cancelled through Cancel; the row is already marked
+		return
+	case ctx.Err() != nil:
+		// Shutting down: back to the queue for the next worker to claim.
+		// ResetStaleJobs catches the ones a crash leaves running.
+		err = repository.RequeueJob(job.ID)
+	case isPermanent(err) || job.Attempts >= job.MaxAttempts:
+		log.Printf("Job %d (%s) failed: %v", job.ID, job.Kind, err)
+		err = repository.FinishJob(job.ID, models.JobFailed, err.Error())
+	default:
+		delay := Backoff(job.Attempts)
+		log.Printf("Job %d (%s) attempt %d failed, retrying in %s: %v", job.ID, job.Kind, job.Attempts, delay, err)
+		err = repository.RetryJobLater(job.ID, err.Error(), delay)
+	}
+	if err != nil {
+		log.Printf("Failed to record outcome of job %d: %v", job.ID, err)
+	}
+}
+
+// Backoff returns the delay before retrying after the given attempt
+func Backoff(attempt int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempt && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error that retrying won't fix, e.g. a deleted card
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+func isPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
@@ -0,0 +1,105 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"languagepapi/internal/db"
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{10, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.attempt); got != tt.want {
+			t.Errorf("Backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	base := errors.New("card 7 not found")
+	err := fmt.Errorf("bridges: %w", Permanent(base))
+	if !isPermanent(err) || !errors.Is(err, base) {
+		t.Errorf("isPermanent(%v) = false or base error lost", err)
+	}
+	if isPermanent(base) {
+		t.Error("isPermanent(plain error) = true")
+	}
+}
+
+func TestLimiterSpacesStarts(t *testing.T) {
+	l := newLimiter(20 * time.Millisecond)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("3 starts took %s, want at least 40ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.wait(ctx); err == nil {
+		t.Error("wait() with cancelled context = nil, want error")
+	}
+
+	var none *limiter
+	if err := none.wait(ctx); err != nil {
+		t.Errorf("nil limiter wait() = %v, want nil", err)
+	}
+}
+
+func TestResetStaleJobsKeepsLiveJobs(t *testing.T) {
+	if err := db.Init(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	q := New()
+	q.Register("bridges", Kind{})
+	var ids []int64
+	for _, payload := range []string{`{"card_id":1}`, `{"card_id":2}`} {
+		id, err := q.Enqueue(&models.Job{Kind: "bridges", Payload: payload})
+		if err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+		if _, err := repository.ClaimJob([]string{"bridges"}, 0); err != nil {
+			t.Fatalf("ClaimJob() error = %v", err)
+		}
+		ids = append(ids, id)
+	}
+	// The first job's process stopped sending heartbeats
+	if _, err := db.DB.Exec(`UPDATE jobs SET heartbeat_at = datetime('now', '-1 hour') WHERE id = ?`, ids[0]); err != nil {
+		t.Fatalf("age heartbeat: %v", err)
+	}
+
+	n, err := repository.ResetStaleJobs(StaleAfter)
+	if err != nil || n != 1 {
+		t.Fatalf("ResetStaleJobs() = %d, %v; want 1", n, err)
+	}
+	for i, want := range []models.JobStatus{models.JobPending, models.JobRunning} {
+		job, err := repository.GetJob(ids[i])
+		if err != nil {
+			t.Fatalf("get job: %v", err)
+		}
+		if job.Status != want {
+			t.Errorf("job %d status = %q, want %q", i, job.Status, want)
+		}
+	}
+}
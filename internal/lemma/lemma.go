@@ -0,0 +1,123 @@
+// Package lemma maps inflected Spanish words to their dictionary forms:
+// conjugated verbs to the infinitive (quiero → querer, bailando → bailar),
+// verbs with pronouns attached to the bare verb (dímelo → decir), and
+// plural and feminine nouns and adjectives to the masculine singular
+// (bonitas → bonito). Verb readings are checked against the conjugate
+// package; noun and adjective readings are guesses, so callers match the
+// lemmas against a lexicon such as the deck's cards.
+package lemma
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Kind is the kind of reading of a word
+type Kind string
+
+const (
+	KindWord    Kind = "word"    // The word as written
+	KindVerb    Kind = "verb"    // A form of a verb
+	KindNominal Kind = "nominal" // A form of a noun or adjective
+)
+
+// Analysis is one reading of a word
+type Analysis struct {
+	Lemma string
+	Kind  Kind
+	Note  string // How the word is formed, e.g. "yo present of querer"
+}
+
+// Token is a word of a line of text
+type Token struct {
+	Text  string // As written, punctuation included
+	Word  string // See Clean
+	Index int    // Position among the whitespace-separated fields
+}
+
+// Clean lowercases a word and trims the punctuation around it
+func Clean(s string) string {
+	return strings.ToLower(strings.TrimFunc(s, func(r rune) bool { return !unicode.IsLetter(r) }))
+}
+
+// Tokenize splits text into words on whitespace, skipping fields with no
+// letters. Index matches the position in strings.Fields(text).
+func Tokenize(text string) []Token {
+	var tokens []Token
+	for i, f := range strings.Fields(text) {
+		if w := Clean(f); w != "" {
+			tokens = append(tokens, Token{Text: f, Word: w, Index: i})
+		}
+	}
+	return tokens
+}
+
+// Analyze lists the readings of a cleaned word, most likely first. The
+// word itself always comes first; no two readings share a lemma.
+func Analyze(word string) []Analysis {
+	if word == "" {
+		return nil
+	}
+	all := []Analysis{{Lemma: word, Kind: KindWord}}
+
+	// Irregular verbs are certain; a plural noun is likelier than the
+	// regular verb form it happens to look like (casas, casar)
+	forms, irregular := verbForms(word, nil)
+	if irregular {
+		all = append(all, verbAnalyses(forms)...)
+	}
+	all = append(all, withClitics(word)...)
+	all = append(all, plurals(word)...)
+	if !irregular {
+		all = append(all, verbAnalyses(forms)...)
+	}
+	all = append(all, genders(word)...)
+	for _, p := range plurals(word) {
+		for _, g := range genders(p.Lemma) {
+			g.Note = strings.Replace(g.Note, "feminine of", "feminine plural of", 1)
+			all = append(all, g)
+		}
+	}
+	all = append(all, diminutives(word)...)
+	for _, p := range plurals(word) {
+		all = append(all, diminutives(p.Lemma)...)
+	}
+
+	seen := make(map[string]bool)
+	var out []Analysis
+	for _, a := range all {
+		if !seen[a.Lemma] {
+			seen[a.Lemma] = true
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// Lemmas lists the dictionary forms a word may come from, the word itself
+// first
+func Lemmas(word string) []string {
+	var lemmas []string
+	for _, a := range Analyze(Clean(word)) {
+		lemmas = append(lemmas, a.Lemma)
+	}
+	return lemmas
+}
+
+// Match returns the likeliest reading of a word whose lemma is known,
+// e.g. a term of the deck
+func Match(word string, known func(lemma string) bool) (Analysis, bool) {
+	for _, a := range Analyze(Clean(word)) {
+		if known(a.Lemma) {
+			return a, true
+		}
+	}
+	return Analysis{}, false
+}
+
+var unaccented = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u")
+
+// unaccent removes written accents, keeping ñ and ü
+func unaccent(s string) string {
+	return unaccented.Replace(s)
+}
@@ -0,0 +1,78 @@
+package lemma
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	lexicon := map[string]bool{}
+	for _, w := range []string{
+		"querer", "bailar", "decir", "quedarse", "ir", "casa", "bonito", "canción",
+		"joven", "vez", "enamorado", "mantener", "hablar", "conocer", "conducir",
+		"buscar", "leer", "construir", "seguir", "pensar", "solo", "poco", "amor",
+		"noche", "dar", "levantarse", "tener", "dormir", "mujer", "compré",
+	} {
+		lexicon[w] = true
+	}
+	known := func(lemma string) bool { return lexicon[lemma] }
+
+	tests := []struct{ word, want string }{
+		{"quiero", "querer"},
+		{"quieres", "querer"},
+		{"quise", "querer"},
+		{"bailando", "bailar"},
+		{"¡Dímelo!", "decir"},
+		{"quédate", "quedarse"},
+		{"vámonos", "ir"},
+		{"casas", "casa"},
+		{"bonitas", "bonito"},
+		{"canciones", "canción"},
+		{"jóvenes", "joven"},
+		{"veces", "vez"},
+		{"enamorada", "enamorado"},
+		{"mantengo", "mantener"},
+		{"mantén", "mantener"},
+		{"hablaban", "hablar"},
+		{"conozco", "conocer"},
+		{"conduje", "conducir"},
+		{"busqué", "buscar"},
+		{"leyó", "leer"},
+		{"construyeron", "construir"},
+		{"sigo", "seguir"},
+		{"piensa", "pensar"},
+		{"solita", "solo"},
+		{"poquito", "poco"},
+		{"amores", "amor"},
+		{"noches", "noche"},
+		{"dame", "dar"},
+		{"levántate", "levantarse"},
+		{"tienes", "tener"},
+		{"durmiendo", "dormir"},
+		{"mujeres", "mujer"},
+		{"compré", "compré"},
+	}
+	for _, tt := range tests {
+		a, ok := Match(tt.word, known)
+		if !ok || a.Lemma != tt.want {
+			t.Errorf("Match(%q) = %q (%s), want %q", tt.word, a.Lemma, a.Note, tt.want)
+		}
+	}
+
+	if _, ok := Match("xyzzy", known); ok {
+		t.Error("Match(xyzzy) matched")
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tokens := Tokenize("¿Qué quieres, mami? — dime")
+	words := []string{"qué", "quieres", "mami", "dime"}
+	if len(tokens) != len(words) {
+		t.Fatalf("Tokenize = %v", tokens)
+	}
+	for i, w := range words {
+		if tokens[i].Word != w {
+			t.Errorf("token %d = %q, want %q", i, tokens[i].Word, w)
+		}
+	}
+	if tokens[3].Index != 4 {
+		t.Errorf("dime index = %d, want 4", tokens[3].Index)
+	}
+}
@@ -0,0 +1,110 @@
+package lemma
+
+import "strings"
+
+// plurals reads w as a plural: casas → casa, amores → amor, veces → vez,
+// canciones → canción, jóvenes → joven
+func plurals(w string) []Analysis {
+	var singulars []string
+	switch {
+	case strings.HasSuffix(w, "ces"):
+		singulars = append(singulars, strings.TrimSuffix(w, "ces")+"z")
+	case strings.HasSuffix(w, "es"):
+		base := strings.TrimSuffix(w, "es")
+		switch {
+		case unaccent(w) != w:
+			// The plural of a word stressed on the third-to-last
+			// syllable keeps an accent the singular doesn't need
+			singulars = append(singulars, unaccent(base))
+		case strings.HasSuffix(base, "n") || strings.HasSuffix(base, "s"):
+			// ...and a final stressed -n or -s loses its accent
+			singulars = append(singulars, accentLast(base))
+		}
+		singulars = append(singulars, base)
+	}
+	if rest, ok := strings.CutSuffix(w, "s"); ok && endsInVowel(rest) {
+		singulars = append(singulars, rest)
+	}
+
+	var out []Analysis
+	for _, s := range singulars {
+		if len([]rune(s)) >= 2 {
+			out = append(out, Analysis{Lemma: s, Kind: KindNominal, Note: "plural of " + s})
+		}
+	}
+	return out
+}
+
+// feminines maps feminine endings to masculine ones, longest first
+var feminines = []struct{ fem, masc string }{
+	{"ora", "or"}, // trabajadora
+	{"ona", "ón"}, // llorona
+	{"esa", "és"}, // francesa
+	{"ana", "án"}, // alemana
+	{"a", "o"},    // bonita
+}
+
+// genders reads w as the feminine of a noun or adjective
+func genders(w string) []Analysis {
+	var out []Analysis
+	for _, f := range feminines {
+		if base, ok := strings.CutSuffix(w, f.fem); ok && len([]rune(base)) >= 2 {
+			m := base + f.masc
+			out = append(out, Analysis{Lemma: m, Kind: KindNominal, Note: "feminine of " + m})
+		}
+	}
+	return out
+}
+
+// diminutives reads w as a diminutive: solita → sola, poquito → poco,
+// amorcito → amor
+func diminutives(w string) []Analysis {
+	var bases []string
+	for _, end := range []string{"ito", "ita"} {
+		base, ok := strings.CutSuffix(w, end)
+		if !ok {
+			continue
+		}
+		vowel := end[2:]
+		switch {
+		case strings.HasSuffix(base, "qu"):
+			bases = append(bases, strings.TrimSuffix(base, "qu")+"c"+vowel)
+		case strings.HasSuffix(base, "gu"):
+			bases = append(bases, strings.TrimSuffix(base, "gu")+"g"+vowel)
+		case strings.HasSuffix(base, "c"):
+			bases = append(bases, strings.TrimSuffix(base, "c"), base+vowel)
+		default:
+			bases = append(bases, base+vowel)
+		}
+	}
+
+	var out []Analysis
+	for _, b := range bases {
+		if len([]rune(b)) < 2 {
+			continue
+		}
+		out = append(out, Analysis{Lemma: b, Kind: KindNominal, Note: "diminutive of " + b})
+		for _, g := range genders(b) {
+			g.Note = "diminutive of " + g.Lemma
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+func endsInVowel(s string) bool {
+	r := []rune(s)
+	return len(r) > 0 && strings.ContainsRune("aeiouáéíóú", r[len(r)-1])
+}
+
+// accentLast writes an accent on the last vowel: cancion → canción
+func accentLast(s string) string {
+	r := []rune(s)
+	for i := len(r) - 1; i >= 0; i-- {
+		if a, ok := map[rune]rune{'a': 'á', 'e': 'é', 'i': 'í', 'o': 'ó', 'u': 'ú'}[r[i]]; ok {
+			r[i] = a
+			break
+		}
+	}
+	return string(r)
+}
@@ -0,0 +1,353 @@
+package lemma
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"languagepapi/internal/conjugate"
+)
+
+type formKind int
+
+const (
+	finite formKind = iota
+	gerund
+	participle
+)
+
+// reading is a place in a verb's conjugation a single word can fill
+type reading struct {
+	kind   formKind
+	tense  conjugate.Tense // Finite forms only
+	person conjugate.Person
+}
+
+// readings lists the one-word forms of a verb: every simple tense but the
+// negative imperative ("no hables"), the gerund and the participle
+var readings = func() []reading {
+	var rs []reading
+	for _, t := range conjugate.Tenses {
+		if t.Compound() || t == conjugate.NegativeImperative {
+			continue
+		}
+		for _, p := range conjugate.Persons {
+			if t.HasPerson(p) {
+				rs = append(rs, reading{tense: t, person: p})
+			}
+		}
+	}
+	return append(rs, reading{kind: gerund}, reading{kind: participle})
+}()
+
+// forms returns the forms of a verb at r. Participles agree like
+// adjectives: hecho, hecha, hechos, hechas.
+func (r reading) forms(inf string) []string {
+	switch r.kind {
+	case gerund:
+		g, err := conjugate.Gerund(inf)
+		if err != nil {
+			return nil
+		}
+		return []string{g}
+	case participle:
+		p, err := conjugate.Participle(inf)
+		if err != nil {
+			return nil
+		}
+		stem := strings.TrimSuffix(p, "o")
+		return []string{p, stem + "a", p + "s", stem + "as"}
+	}
+	c, err := conjugate.Conjugate(inf, r.tense, r.person)
+	if err != nil {
+		return nil
+	}
+	return c.Accepted()
+}
+
+func (r reading) note(inf string) string {
+	switch r.kind {
+	case gerund:
+		return "gerund of " + inf
+	case participle:
+		return "participle of " + inf
+	}
+	return fmt.Sprintf("%s %s of %s", r.person.Pronoun(r.tense), strings.ToLower(r.tense.Label()), inf)
+}
+
+// commandable reports whether pronouns attach to the end of the form:
+// affirmative commands and gerunds
+func (r reading) commandable() bool {
+	return r.kind == gerund || r.tense == conjugate.Imperative
+}
+
+// verbForm is a verb and a reading that produce a word
+type verbForm struct {
+	inf string
+	r   reading
+}
+
+// model is a verb whose forms give the endings of the verbs like it. Its
+// stem is the part those verbs don't share: conducir is condu + cir so
+// that conduzco and conduje keep their -zco and -je.
+type model struct{ inf, stem string }
+
+var models = []model{
+	{"hablar", "habl"}, {"comer", "com"}, {"vivir", "viv"},
+	// Spelling changes
+	{"buscar", "bus"}, {"llegar", "lle"}, {"cruzar", "cru"}, {"averiguar", "averi"},
+	{"coger", "co"}, {"dirigir", "diri"}, {"vencer", "ven"}, {"distinguir", "distin"},
+	// -zc-, -ducir, -uir and vowel stems
+	{"conocer", "cono"}, {"conducir", "condu"}, {"construir", "constru"}, {"leer", "le"},
+}
+
+var (
+	tablesOnce sync.Once
+	// endings maps an ending to the infinitive endings it can replace and
+	// the readings it marks: "amos" → ar → nosotros present, ...
+	endings map[string]map[string][]reading
+	// irregularForms maps the forms of conjugate.Irregulars to their verbs
+	irregularForms map[string][]verbForm
+	// foldedForms is irregularForms keyed without accents, to find the
+	// base of compounds: mantén ends in ten
+	foldedForms map[string][]verbForm
+)
+
+func buildTables() {
+	endings = make(map[string]map[string][]reading)
+	for _, m := range models {
+		suffix := strings.TrimPrefix(m.inf, m.stem)
+		for _, r := range readings {
+			for _, f := range r.forms(m.inf) {
+				end, ok := strings.CutPrefix(f, m.stem)
+				if !ok {
+					continue
+				}
+				if endings[end] == nil {
+					endings[end] = make(map[string][]reading)
+				}
+				endings[end][suffix] = appendReading(endings[end][suffix], r)
+			}
+		}
+	}
+
+	irregularForms = make(map[string][]verbForm)
+	foldedForms = make(map[string][]verbForm)
+	for _, inf := range conjugate.Irregulars() {
+		for _, r := range readings {
+			for _, f := range r.forms(inf) {
+				irregularForms[f] = append(irregularForms[f], verbForm{inf, r})
+				foldedForms[unaccent(f)] = append(foldedForms[unaccent(f)], verbForm{inf, r})
+			}
+		}
+	}
+}
+
+func appendReading(rs []reading, r reading) []reading {
+	for _, x := range rs {
+		if x == r {
+			return rs
+		}
+	}
+	return append(rs, r)
+}
+
+// verbForms finds the verbs and readings that produce w, keeping those ok
+// accepts (all if ok is nil). Irregular verbs and their compounds are
+// tried first; irregular reports whether one matched, in which case the
+// regular endings aren't tried: piensa is pensar, not "piensar".
+func verbForms(w string, ok func(reading) bool) (forms []verbForm, irregular bool) {
+	tablesOnce.Do(buildTables)
+	keep := func(vf verbForm) {
+		if ok != nil && !ok(vf.r) {
+			return
+		}
+		for _, x := range forms {
+			if x.inf == vf.inf {
+				return
+			}
+		}
+		forms = append(forms, vf)
+	}
+
+	for _, vf := range irregularForms[w] {
+		keep(vf)
+	}
+	// Compounds: mantengo is man + tengo, mantén is man + ten
+	for i := range w {
+		if i == 0 {
+			continue
+		}
+		for _, vf := range foldedForms[unaccent(w[i:])] {
+			inf := w[:i] + vf.inf
+			if conjugate.Base(inf) == vf.inf && produces(inf, vf.r, w) {
+				keep(verbForm{inf, vf.r})
+			}
+		}
+	}
+	if len(forms) > 0 {
+		return forms, true
+	}
+
+	// Regular verbs. The longest ending is the likeliest (hablaban is
+	// habl + aban, not hablab + an), then the earliest reading.
+	type candidate struct {
+		verbForm
+		end int
+	}
+	var found []candidate
+	for i := range w {
+		stem := w[:i]
+		if len([]rune(stem)) < 2 {
+			continue
+		}
+		for suffix, rs := range endings[w[i:]] {
+			inf := stem + suffix
+			if !conjugate.IsInfinitive(inf) {
+				continue
+			}
+			for _, r := range rs {
+				if (ok == nil || ok(r)) && produces(inf, r, w) {
+					found = append(found, candidate{verbForm{inf, r}, len(w) - i})
+					break
+				}
+			}
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.end != b.end {
+			return a.end > b.end
+		}
+		if readingRank[a.r] != readingRank[b.r] {
+			return readingRank[a.r] < readingRank[b.r]
+		}
+		return classRank(a.inf) < classRank(b.inf)
+	})
+	for _, c := range found {
+		keep(c.verbForm)
+	}
+	return forms, false
+}
+
+// readingRank orders readings as the readings list does
+var readingRank = func() map[reading]int {
+	m := make(map[reading]int)
+	for i, r := range readings {
+		m[r] = i
+	}
+	return m
+}()
+
+// classRank prefers -ar verbs, the most common, then -er and -ir
+func classRank(inf string) int {
+	return strings.Index("aei", inf[len(inf)-2:len(inf)-1])
+}
+
+// produces reports whether the verb's form at r is w
+func produces(inf string, r reading, w string) bool {
+	for _, f := range r.forms(inf) {
+		if f == w {
+			return true
+		}
+	}
+	return false
+}
+
+// verbAnalyses reads verb forms as analyses. A participle may be a word of
+// its own (enamorado), so its masculine singular comes before the verb.
+func verbAnalyses(forms []verbForm) []Analysis {
+	var out []Analysis
+	for _, vf := range forms {
+		if vf.r.kind == participle {
+			if p, err := conjugate.Participle(vf.inf); err == nil {
+				out = append(out, Analysis{Lemma: p, Kind: KindNominal, Note: vf.r.note(vf.inf)})
+			}
+		}
+		out = append(out, Analysis{Lemma: vf.inf, Kind: KindVerb, Note: vf.r.note(vf.inf)})
+	}
+	return out
+}
+
+// clitics are the object and reflexive pronouns that attach to the end of
+// infinitives, gerunds and affirmative commands, longest first
+var clitics = []string{"los", "las", "les", "nos", "me", "te", "se", "lo", "la", "le", "os"}
+
+// reflexives are the reflexive pronouns by person
+var reflexives = [6]string{"me", "te", "se", "nos", "os", "se"}
+
+// withClitics reads w as a verb with one or two pronouns attached:
+// dímelo, quédate, bailarte, vámonos. The reflexive verb comes first when
+// the pronoun is the command's own (quédate → quedarse). Infinitives and
+// irregular verbs come before regular readings: dímelo is decir + me + lo
+// rather than "dimer" + lo.
+func withClitics(w string) []Analysis {
+	var sure, guessed []Analysis
+	rest := w
+	var attached []string
+	for len(attached) < 2 {
+		c := ""
+		for _, cl := range clitics {
+			if strings.HasSuffix(rest, cl) && len([]rune(rest))-len(cl) >= 2 {
+				c = cl
+				break
+			}
+		}
+		if c == "" {
+			break
+		}
+		rest = strings.TrimSuffix(rest, c)
+		attached = append([]string{c}, attached...)
+		out, certain := encliticBase(rest, attached)
+		if certain {
+			sure = append(sure, out...)
+		} else {
+			guessed = append(guessed, out...)
+		}
+	}
+	return append(sure, guessed...)
+}
+
+// encliticBase reads what is left of a word once pronouns are cut off.
+// certain reports an infinitive or an irregular verb.
+func encliticBase(rest string, attached []string) (out []Analysis, certain bool) {
+	first := attached[0]
+	pronouns := " + " + strings.Join(attached, " + ")
+
+	// The pronouns can move the stress and add an accent (dí + me), and
+	// nos and os take the s of vamos and the d of levantad
+	candidates := []string{rest}
+	if plain := unaccent(rest); plain != rest {
+		candidates = append(candidates, plain)
+	}
+	switch {
+	case (first == "nos" || first == "se") && strings.HasSuffix(unaccent(rest), "mo"):
+		candidates = append(candidates, unaccent(rest)+"s")
+	case first == "os":
+		candidates = append(candidates, unaccent(rest)+"d")
+	}
+
+	for _, cand := range candidates {
+		if conjugate.IsInfinitive(cand) && !strings.HasSuffix(cand, "se") {
+			certain = true
+			out = append(out,
+				Analysis{Lemma: cand, Kind: KindVerb, Note: "infinitive" + pronouns},
+				Analysis{Lemma: cand + "se", Kind: KindVerb, Note: "infinitive" + pronouns},
+			)
+			continue
+		}
+		forms, irregular := verbForms(cand, reading.commandable)
+		certain = certain || irregular
+		for _, vf := range forms {
+			plain := Analysis{Lemma: vf.inf, Kind: KindVerb, Note: vf.r.note(vf.inf) + pronouns}
+			refl := Analysis{Lemma: vf.inf + "se", Kind: KindVerb, Note: vf.r.note(vf.inf+"se") + pronouns}
+			if vf.r.kind == finite && reflexives[vf.r.person] == first {
+				out = append(out, refl, plain)
+			} else {
+				out = append(out, plain, refl)
+			}
+		}
+	}
+	return out, certain
+}
@@ -0,0 +1,105 @@
+package lrc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lrcTime formats milliseconds as mm:ss.xx
+func lrcTime(ms int) string {
+	return fmt.Sprintf("%02d:%02d.%02d", ms/60000, ms/1000%60, ms%1000/10)
+}
+
+// clockTime formats milliseconds as hh:mm:ss followed by sep and the
+// milliseconds: a comma for SRT, a dot for WebVTT
+func clockTime(ms int, sep string) string {
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// FormatLRC writes lyrics as LRC, with word timings in the enhanced
+// format's <mm:ss.xx> tags and an empty line wherever a line ends before
+// the next one starts. Translations are left out.
+func FormatLRC(l *Lyrics) string {
+	var b strings.Builder
+	for _, h := range []struct{ key, value string }{{"ti", l.Title}, {"ar", l.Artist}, {"al", l.Album}} {
+		if h.value != "" {
+			fmt.Fprintf(&b, "[%s:%s]\n", h.key, h.value)
+		}
+	}
+	if l.LengthMs > 0 {
+		fmt.Fprintf(&b, "[length:%02d:%02d]\n", l.LengthMs/60000, l.LengthMs/1000%60)
+	}
+
+	for i, line := range l.Lines {
+		fmt.Fprintf(&b, "[%s]", lrcTime(line.StartMs))
+		if len(line.Words) == 0 {
+			b.WriteString(line.Text)
+		} else {
+			for j, w := range line.Words {
+				if j > 0 {
+					b.WriteString(" ")
+				}
+				fmt.Fprintf(&b, "<%s>%s", lrcTime(w.StartMs), w.Text)
+			}
+			if last := line.Words[len(line.Words)-1]; last.EndMs != line.EndMs {
+				fmt.Fprintf(&b, " <%s>", lrcTime(last.EndMs))
+			}
+		}
+		b.WriteString("\n")
+		if i+1 < len(l.Lines) && line.EndMs < l.Lines[i+1].StartMs {
+			fmt.Fprintf(&b, "[%s]\n", lrcTime(line.EndMs))
+		}
+	}
+	return b.String()
+}
+
+// FormatSRT writes lyrics as SRT subtitles, each translation under its line
+func FormatSRT(l *Lyrics) string {
+	var b strings.Builder
+	for i, line := range l.Lines {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n", i+1, clockTime(line.StartMs, ","), clockTime(line.EndMs, ","), line.Text)
+		if line.Translation != "" {
+			b.WriteString(line.Translation + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// FormatVTT writes lyrics as WebVTT subtitles, each translation under its
+// line. Word timings become cue timestamps, which players use for karaoke
+// style highlighting.
+func FormatVTT(l *Lyrics) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for i, line := range l.Lines {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n", i+1, clockTime(line.StartMs, "."), clockTime(line.EndMs, "."))
+		if len(line.Words) == 0 {
+			b.WriteString(vttEscape(line.Text))
+		} else {
+			for j, w := range line.Words {
+				if j > 0 {
+					b.WriteString(" ")
+				}
+				// Timestamps must fall inside the cue
+				if w.StartMs > line.StartMs && w.StartMs < line.EndMs {
+					fmt.Fprintf(&b, "<%s>", clockTime(w.StartMs, "."))
+				}
+				b.WriteString(vttEscape(w.Text))
+			}
+		}
+		b.WriteString("\n")
+		if line.Translation != "" {
+			b.WriteString(vttEscape(line.Translation) + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// vttEscape escapes the characters WebVTT cue text reserves
+func vttEscape(s string) string {
+	return vttEscaper.Replace(s)
+}
@@ -0,0 +1,229 @@
+// Package lrc reads and writes timed lyrics: LRC, including the enhanced
+// format's word timings and its metadata headers, and SRT and WebVTT
+// subtitles.
+package lrc
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLastLineMs is how long the last line lasts when neither the
+// audio's duration nor a [length:] header says
+const DefaultLastLineMs = 5000
+
+// ErrNoLines is returned when the text has no timed lines
+var ErrNoLines = errors.New("no timed lyrics lines")
+
+// Word is a timed part of a line, usually a word or a syllable
+type Word struct {
+	StartMs int
+	EndMs   int
+	Text    string
+}
+
+// Line is a timed lyrics line
+type Line struct {
+	StartMs     int
+	EndMs       int
+	Text        string
+	Translation string // Not part of LRC; written to SRT and WebVTT
+	Words       []Word // Word timings, if any
+}
+
+// Lyrics are timed lyrics lines with the metadata of their headers
+type Lyrics struct {
+	Title    string
+	Artist   string
+	Album    string
+	OffsetMs int // Already applied to the lines' times
+	LengthMs int
+	Lines    []Line
+}
+
+var (
+	tagRe       = regexp.MustCompile(`^\[([^\]]*)\]`)
+	timeRe      = regexp.MustCompile(`^(\d+):(\d{1,2})(?:[.:](\d{1,3}))?$`)
+	metaRe      = regexp.MustCompile(`^([A-Za-z#]+):(.*)$`)
+	wordTagRe   = regexp.MustCompile(`<(\d+:\d{1,2}(?:[.:]\d{1,3})?)>`)
+	whitespace  = regexp.MustCompile(`\s+`)
+	errNotATime = errors.New("not a timestamp")
+)
+
+// parseTime reads mm:ss, mm:ss.x, mm:ss.xx or mm:ss.xxx as milliseconds
+func parseTime(s string) (int, error) {
+	m := timeRe.FindStringSubmatch(s)
+	if m == nil {
+		return 0, errNotATime
+	}
+	minutes, _ := strconv.Atoi(m[1])
+	seconds, _ := strconv.Atoi(m[2])
+	ms := 0
+	if frac := m[3]; frac != "" {
+		ms, _ = strconv.Atoi(frac)
+		for i := len(frac); i < 3; i++ {
+			ms *= 10 // .5 is 500ms, .05 is 50ms
+		}
+	}
+	return (minutes*60+seconds)*1000 + ms, nil
+}
+
+// Parse reads LRC lyrics. Lines with several timestamps are repeated at
+// each; the [offset:] header shifts every time; a line with no text ends
+// the one before it. durationMs, the audio's length, ends the last line
+// (0 if unknown).
+func Parse(text string, durationMs int) (*Lyrics, error) {
+	lyrics := &Lyrics{}
+	var all []timed
+	for _, raw := range strings.Split(text, "\n") {
+		rest := strings.TrimSpace(raw)
+		var times []int
+		for {
+			m := tagRe.FindStringSubmatch(rest)
+			if m == nil {
+				break
+			}
+			rest = strings.TrimSpace(rest[len(m[0]):])
+			if t, err := parseTime(strings.TrimSpace(m[1])); err == nil {
+				times = append(times, t)
+			} else if meta := metaRe.FindStringSubmatch(m[1]); meta != nil {
+				lyrics.setHeader(strings.ToLower(meta[1]), strings.TrimSpace(meta[2]))
+			}
+		}
+		if len(times) == 0 {
+			continue
+		}
+
+		line := parseWords(rest, times[0])
+		for _, t := range times {
+			if line.Text == "" {
+				all = append(all, timed{start: t})
+				continue
+			}
+			l := *line
+			l.StartMs = t
+			l.Words = nil
+			for _, w := range line.Words {
+				// Word tags are absolute; a repeat of the line moves them
+				w.StartMs += t - times[0]
+				if w.EndMs != 0 {
+					w.EndMs += t - times[0]
+				}
+				l.Words = append(l.Words, w)
+			}
+			all = append(all, timed{start: t, line: &l})
+		}
+	}
+
+	if err := lyrics.build(all, durationMs); err != nil {
+		return nil, err
+	}
+
+	// A positive offset shows the lyrics sooner
+	if lyrics.OffsetMs != 0 {
+		shift := func(t int) int { return max(t-lyrics.OffsetMs, 0) }
+		for i := range lyrics.Lines {
+			l := &lyrics.Lines[i]
+			l.StartMs, l.EndMs = shift(l.StartMs), shift(l.EndMs)
+			for j := range l.Words {
+				l.Words[j].StartMs, l.Words[j].EndMs = shift(l.Words[j].StartMs), shift(l.Words[j].EndMs)
+			}
+		}
+	}
+	return lyrics, nil
+}
+
+// timed is a line at its start time, or the end of the line before it if
+// line is nil
+type timed struct {
+	start int
+	line  *Line
+}
+
+// build sorts the lines and sets when each line and word ends: when the
+// next starts, or for the last line at durationMs, the length header or
+// DefaultLastLineMs later
+func (l *Lyrics) build(all []timed, durationMs int) error {
+	sort.SliceStable(all, func(i, j int) bool { return all[i].start < all[j].start })
+	for i, t := range all {
+		if t.line == nil {
+			continue
+		}
+		line := t.line
+		switch {
+		case i+1 < len(all):
+			line.EndMs = all[i+1].start
+		case durationMs > line.StartMs:
+			line.EndMs = durationMs
+		case l.LengthMs > line.StartMs:
+			line.EndMs = l.LengthMs
+		default:
+			line.EndMs = line.StartMs + DefaultLastLineMs
+		}
+		for j := range line.Words {
+			w := &line.Words[j]
+			if w.EndMs == 0 {
+				w.EndMs = line.EndMs
+				if j+1 < len(line.Words) {
+					w.EndMs = line.Words[j+1].StartMs
+				}
+			}
+		}
+		l.Lines = append(l.Lines, *line)
+	}
+	if len(l.Lines) == 0 {
+		return ErrNoLines
+	}
+	return nil
+}
+
+func (l *Lyrics) setHeader(key, value string) {
+	switch key {
+	case "ti":
+		l.Title = value
+	case "ar":
+		l.Artist = value
+	case "al":
+		l.Album = value
+	case "offset":
+		l.OffsetMs, _ = strconv.Atoi(strings.TrimPrefix(value, "+"))
+	case "length":
+		l.LengthMs, _ = parseTime(value)
+	}
+}
+
+// parseWords reads the text of a line and its <mm:ss.xx> word tags. Text
+// before the first tag starts with the line; a tag with no text after it
+// ends the word before it.
+func parseWords(text string, startMs int) *Line {
+	line := &Line{StartMs: startMs}
+	tags := wordTagRe.FindAllStringSubmatchIndex(text, -1)
+	if len(tags) == 0 {
+		line.Text = whitespace.ReplaceAllString(text, " ")
+		return line
+	}
+
+	var plain strings.Builder
+	add := func(segment string, start int) {
+		plain.WriteString(segment)
+		if w := strings.TrimSpace(segment); w != "" {
+			line.Words = append(line.Words, Word{StartMs: start, Text: whitespace.ReplaceAllString(w, " ")})
+		} else if n := len(line.Words); n > 0 && line.Words[n-1].EndMs == 0 {
+			line.Words[n-1].EndMs = start
+		}
+	}
+	add(text[:tags[0][0]], startMs)
+	for i, tag := range tags {
+		start, _ := parseTime(text[tag[2]:tag[3]])
+		end := len(text)
+		if i+1 < len(tags) {
+			end = tags[i+1][0]
+		}
+		add(text[tag[1]:end], start)
+	}
+	line.Text = strings.TrimSpace(whitespace.ReplaceAllString(plain.String(), " "))
+	return line
+}
@@ -0,0 +1,138 @@
+package lrc
+
+import (
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestParse(t *testing.T) {
+	text := `[ti:Callaíta]
+[ar:Bad Bunny]
+[offset:+500]
+[length:03:00]
+[00:10.50]Ella es callaíta
+[00:15.00][01:15.00]<00:15.00>Tra, <00:15.5>tra, <00:16.250>tra<00:17.00>
+[00:20.00]
+[00:22:00]  Siempre   callaíta
+`
+	lyrics, err := Parse(text, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lyrics.Title != "Callaíta" || lyrics.Artist != "Bad Bunny" || lyrics.OffsetMs != 500 || lyrics.LengthMs != 180000 {
+		t.Errorf("headers: %+v", lyrics)
+	}
+
+	want := []Line{
+		{StartMs: 10000, EndMs: 14500, Text: "Ella es callaíta"},
+		{StartMs: 14500, EndMs: 19500, Text: "Tra, tra, tra", Words: []Word{
+			{14500, 15000, "Tra,"}, {15000, 15750, "tra,"}, {15750, 16500, "tra"},
+		}},
+		{StartMs: 21500, EndMs: 74500, Text: "Siempre callaíta"},
+		// The repeat runs to the length header
+		{StartMs: 74500, EndMs: 179500, Text: "Tra, tra, tra", Words: []Word{
+			{74500, 75000, "Tra,"}, {75000, 75750, "tra,"}, {75750, 76500, "tra"},
+		}},
+	}
+	if !reflect.DeepEqual(lyrics.Lines, want) {
+		t.Errorf("got  %+v\nwant %+v", lyrics.Lines, want)
+	}
+
+	// The audio's duration wins over the header and the default
+	lyrics, _ = Parse("[00:01.00]Uno\n[00:02.00]Dos", 9000)
+	if end := lyrics.Lines[1].EndMs; end != 9000 {
+		t.Errorf("last line ends at %d with a duration, want 9000", end)
+	}
+	lyrics, _ = Parse("[00:01.00]Uno\n[00:02.00]Dos", 0)
+	if end := lyrics.Lines[1].EndMs; end != 2000+DefaultLastLineMs {
+		t.Errorf("last line ends at %d, want %d", end, 2000+DefaultLastLineMs)
+	}
+
+	if _, err := Parse("[ar:Bad Bunny]\nno timestamps", 0); err != ErrNoLines {
+		t.Errorf("got %v, want ErrNoLines", err)
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	lyrics := &Lyrics{Title: "Dakiti", Lines: []Line{
+		{StartMs: 1000, EndMs: 3000, Text: "Baby, ya yo me enteré", Translation: "Baby, I found out", Words: []Word{
+			{1000, 1500, "Baby,"}, {1500, 2000, "ya"}, {2000, 2200, "yo"}, {2200, 2500, "me"}, {2500, 2800, "enteré"},
+		}},
+		{StartMs: 5000, EndMs: 7000, Text: "Se nota cuando me ve"},
+	}}
+
+	parsed, err := Parse(FormatLRC(lyrics), 7000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range parsed.Lines {
+		parsed.Lines[i].Translation = lyrics.Lines[i].Translation
+	}
+	if !reflect.DeepEqual(parsed.Lines, lyrics.Lines) {
+		t.Errorf("LRC round trip:\ngot  %+v\nwant %+v", parsed.Lines, lyrics.Lines)
+	}
+
+	srt := FormatSRT(lyrics)
+	if !strings.HasPrefix(srt, "1\n00:00:01,000 --> 00:00:03,000\nBaby, ya yo me enteré\nBaby, I found out\n\n2\n") {
+		t.Errorf("SRT:\n%s", srt)
+	}
+	vtt := FormatVTT(lyrics)
+	if !strings.Contains(vtt, "00:00:05.000 --> 00:00:07.000\nSe nota cuando me ve\n") ||
+		!strings.Contains(vtt, "Baby, <00:00:01.500>ya <00:00:02.000>yo") {
+		t.Errorf("WebVTT:\n%s", vtt)
+	}
+}
+
+// sylt builds a SYLT frame body timed in milliseconds
+func sylt(enc byte, encode func(string) []byte, entries ...any) []byte {
+	b := append([]byte{enc, 's', 'p', 'a', 2, 1}, encode("")...)
+	for i := 0; i < len(entries); i += 2 {
+		b = append(b, encode(entries[i].(string))...)
+		b = binary.BigEndian.AppendUint32(b, uint32(entries[i+1].(int)))
+	}
+	return b
+}
+
+func TestParseSYLT(t *testing.T) {
+	utf8 := func(s string) []byte { return append([]byte(s), 0) }
+	lyrics, err := ParseSYLT(sylt(3, utf8, "Ella es callaíta", 1000, "Ninguna se le compara", 4000, "", 6000), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Line{
+		{StartMs: 1000, EndMs: 4000, Text: "Ella es callaíta"},
+		{StartMs: 4000, EndMs: 6000, Text: "Ninguna se le compara"},
+	}
+	if !reflect.DeepEqual(lyrics.Lines, want) {
+		t.Errorf("lines:\ngot  %+v\nwant %+v", lyrics.Lines, want)
+	}
+
+	// One entry per syllable, lines starting with a newline, in UTF-16
+	utf16le := func(s string) []byte {
+		b := []byte{0xff, 0xfe}
+		for _, u := range utf16.Encode([]rune(s)) {
+			b = binary.LittleEndian.AppendUint16(b, u)
+		}
+		return append(b, 0, 0)
+	}
+	lyrics, err = ParseSYLT(sylt(1, utf16le, "Te ", 1000, "quie", 1500, "ro", 1800, "\nBebé", 3000), 5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []Line{
+		{StartMs: 1000, EndMs: 3000, Text: "Te quiero", Words: []Word{{1000, 1500, "Te"}, {1500, 1800, "quie"}, {1800, 3000, "ro"}}},
+		{StartMs: 3000, EndMs: 5000, Text: "Bebé"},
+	}
+	if !reflect.DeepEqual(lyrics.Lines, want) {
+		t.Errorf("syllables:\ngot  %+v\nwant %+v", lyrics.Lines, want)
+	}
+
+	frames := sylt(3, utf8, "Hola", 1000)
+	frames[4] = 1 // MPEG frames
+	if _, err := ParseSYLT(frames, 0); err != ErrUnsupportedSYLT {
+		t.Errorf("got %v, want ErrUnsupportedSYLT", err)
+	}
+}
@@ -0,0 +1,154 @@
+package lrc
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrUnsupportedSYLT is returned for SYLT frames timed in MPEG frames
+// rather than milliseconds, or holding something other than lyrics
+var ErrUnsupportedSYLT = errors.New("unsupported SYLT frame")
+
+// SYLT header values
+const (
+	syltMilliseconds = 2
+	syltLyrics       = 1
+)
+
+// ParseSYLT reads the body of an ID3v2 SYLT (synchronised lyrics) frame.
+// Taggers write either one entry per line or one per word or syllable,
+// starting each line with a newline; the latter become word timings.
+// durationMs ends the last line as in Parse.
+func ParseSYLT(frame []byte, durationMs int) (*Lyrics, error) {
+	if len(frame) < 6 {
+		return nil, ErrNoLines
+	}
+	enc, format, content := frame[0], frame[4], frame[5]
+	if format != syltMilliseconds || (content != syltLyrics && content != 0) {
+		return nil, ErrUnsupportedSYLT
+	}
+
+	type entry struct {
+		text  string
+		start int
+	}
+	var entries []entry
+	_, rest := readID3Text(enc, frame[6:]) // Content descriptor
+	for len(rest) > 0 {
+		var text string
+		text, rest = readID3Text(enc, rest)
+		if len(rest) < 4 {
+			break
+		}
+		entries = append(entries, entry{text, int(binary.BigEndian.Uint32(rest))})
+		rest = rest[4:]
+	}
+
+	newLine := func(s string) bool { return strings.HasPrefix(s, "\n") || strings.HasPrefix(s, "\r") }
+	perWord := false
+	for _, e := range entries[min(len(entries), 1):] {
+		perWord = perWord || newLine(e.text)
+	}
+
+	var all []timed
+	var line *Line
+	var text strings.Builder
+	flush := func() {
+		if line != nil {
+			line.Text = strings.TrimSpace(whitespace.ReplaceAllString(text.String(), " "))
+			if len(line.Words) == 1 {
+				line.Words = nil // A line's only word tells nothing more
+			}
+			all = append(all, timed{start: line.StartMs, line: line})
+		}
+		line = nil
+		text.Reset()
+	}
+	for _, e := range entries {
+		if !perWord || newLine(e.text) || line == nil {
+			flush()
+		}
+		word := strings.TrimSpace(e.text)
+		if word == "" {
+			all = append(all, timed{start: e.start}) // Ends the line before
+			continue
+		}
+		if line == nil {
+			line = &Line{StartMs: e.start}
+		}
+		text.WriteString(strings.TrimLeft(e.text, "\r\n"))
+		line.Words = append(line.Words, Word{StartMs: e.start, Text: word})
+	}
+	flush()
+
+	lyrics := &Lyrics{}
+	if err := lyrics.build(all, durationMs); err != nil {
+		return nil, err
+	}
+	return lyrics, nil
+}
+
+// readID3Text reads a string terminated by a null character in an ID3v2
+// text encoding and returns it with the bytes after it
+func readID3Text(enc byte, b []byte) (string, []byte) {
+	switch enc {
+	case 1, 2: // UTF-16 with a byte order mark, UTF-16BE
+		end := len(b)
+		for i := 0; i+1 < len(b); i += 2 {
+			if b[i] == 0 && b[i+1] == 0 {
+				end = i
+				break
+			}
+		}
+		rest := b[min(end+2, len(b)):]
+		data := b[:end]
+		bigEndian := enc == 2
+		if len(data) >= 2 && (data[0] == 0xfe && data[1] == 0xff || data[0] == 0xff && data[1] == 0xfe) {
+			bigEndian = data[0] == 0xfe
+			data = data[2:]
+		}
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			if bigEndian {
+				units[i] = binary.BigEndian.Uint16(data[2*i:])
+			} else {
+				units[i] = binary.LittleEndian.Uint16(data[2*i:])
+			}
+		}
+		return string(utf16.Decode(units)), rest
+	}
+
+	end := len(b)
+	for i, c := range b {
+		if c == 0 {
+			end = i
+			break
+		}
+	}
+	rest := b[min(end+1, len(b)):]
+	if enc == 3 { // UTF-8
+		return string(b[:end]), rest
+	}
+	// ISO-8859-1 maps byte for byte to the first Unicode code points
+	runes := make([]rune, end)
+	for i, c := range b[:end] {
+		runes[i] = rune(c)
+	}
+	return string(runes), rest
+}
+
+// Plain reads lyrics without timestamps as untimed lines, skipping blank
+// lines and section headers such as [Coro]
+func Plain(text string) *Lyrics {
+	lyrics := &Lyrics{}
+	for _, raw := range strings.Split(text, "\n") {
+		line := strings.TrimSpace(whitespace.ReplaceAllString(raw, " "))
+		if line == "" || strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			continue
+		}
+		lyrics.Lines = append(lyrics.Lines, Line{Text: line})
+	}
+	return lyrics
+}
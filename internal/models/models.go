@@ -2,6 +2,9 @@ package models
 
 import (
 	"database/sql"
+	"fmt"
+	"math"
+	"net/url"
 	"time"
 )
 
@@ -36,13 +39,39 @@ type Card struct {
 	Notes           string
 	AudioURL        string
 	FrequencyRank   sql.NullInt64
-	Source          string        // "curriculum" or "song"
-	SourceSongID    sql.NullInt64 // Reference to songs.id if source="song"
+	Source          string        // "curriculum", "song", "song_line" or "anki"
+	SourceSongID    sql.NullInt64 // Reference to songs.id if source is "song" or "song_line"
+	UserID          sql.NullInt64 // Owner for user-added cards, NULL for shared cards
 	CreatedAt       time.Time
 	// Joined data
 	Bridges []Bridge
 }
 
+// AudioClipURL is the URL of a song file from startMs to endMs, as a media
+// fragment: /audio/song.mp3#t=12.5,15
+func AudioClipURL(audioPath string, startMs, endMs int) string {
+	u := url.URL{
+		Path:     "/audio/" + audioPath,
+		Fragment: fmt.Sprintf("t=%g,%g", float64(startMs)/1000, float64(endMs)/1000),
+	}
+	return u.String()
+}
+
+// AudioClip splits a card's AudioURL made by AudioClipURL into the file and
+// the clip's bounds. ok is false for any other URL.
+func (c *Card) AudioClip() (src string, startMs, endMs int, ok bool) {
+	u, err := url.Parse(c.AudioURL)
+	if err != nil {
+		return "", 0, 0, false
+	}
+	var start, end float64
+	if _, err := fmt.Sscanf(u.Fragment, "t=%g,%g", &start, &end); err != nil {
+		return "", 0, 0, false
+	}
+	u.Fragment = ""
+	return u.String(), int(math.Round(start * 1000)), int(math.Round(end * 1000)), true
+}
+
 // Bridge represents a polyglot connection (Hindi/Dutch/English)
 type Bridge struct {
 	ID           int64
@@ -109,6 +138,18 @@ type ReviewLog struct {
 	ReviewDurationMs int
 }
 
+// FSRSParams are a user's FSRS weights fitted from their review history
+type FSRSParams struct {
+	UserID        int64
+	Weights       []float64
+	ReviewCount   int
+	LogLossBefore float64
+	LogLossAfter  float64
+	RMSEBefore    float64
+	RMSEAfter     float64
+	OptimizedAt   time.Time
+}
+
 // DailyLog represents daily activity stats (for heat map)
 type DailyLog struct {
 	ID            int64
@@ -230,13 +271,47 @@ type CalendarData struct {
 	TotalCards      int
 }
 
-// CurriculumJourney tracks the user's 180-day learning journey
+// CurriculumJourney tracks the user's progress through a curriculum
 type CurriculumJourney struct {
-	ID        int64
-	UserID    int64
-	StartDate time.Time
-	IsActive  bool
-	CreatedAt time.Time
+	ID           int64
+	UserID       int64
+	CurriculumID sql.NullInt64 // NULL means the default curriculum
+	StartDate    time.Time
+	IsActive     bool
+	CreatedAt    time.Time
+}
+
+// Curriculum is a plan of phases a journey follows, stored as data so
+// users can pick a built-in plan or define their own
+type Curriculum struct {
+	ID          int64             `json:"-"`
+	Slug        string            `json:"slug,omitempty"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	UserID      sql.NullInt64     `json:"-"` // Owner; NULL for built-in curricula
+	Phases      []CurriculumPhase `json:"phases"`
+}
+
+// TotalDays returns the length of the curriculum (the last phase's end day)
+func (c *Curriculum) TotalDays() int {
+	total := 0
+	for _, p := range c.Phases {
+		if p.EndDay > total {
+			total = p.EndDay
+		}
+	}
+	return total
+}
+
+// PhaseForDay returns the phase covering a day, or the last phase if the
+// day is past the end of the curriculum
+func (c *Curriculum) PhaseForDay(day int) *CurriculumPhase {
+	for i := range c.Phases {
+		if day >= c.Phases[i].StartDay && day <= c.Phases[i].EndDay {
+			return &c.Phases[i]
+		}
+	}
+	return &c.Phases[len(c.Phases)-1]
 }
 
 // LessonSession tracks a daily lesson completion
@@ -254,23 +329,23 @@ type LessonSession struct {
 	CreatedAt       time.Time
 }
 
-// CurriculumPhase defines a phase in the 180-day journey
+// CurriculumPhase defines a phase of a curriculum
 type CurriculumPhase struct {
-	ID              int
-	Name            string
-	Description     string
-	StartDay        int
-	EndDay          int
-	NewCardsPerDay  int
-	TargetIslands   []int64
-	ModeWeights     ModeWeights
+	ID             int         `json:"id"`
+	Name           string      `json:"name"`
+	Description    string      `json:"description"`
+	StartDay       int         `json:"start_day"`
+	EndDay         int         `json:"end_day"`
+	NewCardsPerDay int         `json:"new_cards_per_day"`
+	TargetIslands  []int64     `json:"target_islands"`
+	ModeWeights    ModeWeights `json:"mode_weights"`
 }
 
 // ModeWeights defines the distribution of practice modes
 type ModeWeights struct {
-	Standard int // percentage
-	Reverse  int // percentage
-	Typing   int // percentage
+	Standard int `json:"standard"` // percentage
+	Reverse  int `json:"reverse"`  // percentage
+	Typing   int `json:"typing"`   // percentage
 }
 
 // LessonCard represents a card with its assigned practice mode
@@ -285,26 +360,49 @@ type LessonCard struct {
 // DailyLesson represents the structured lesson for a day
 type DailyLesson struct {
 	DayNumber      int
+	TotalDays      int
 	Phase          *CurriculumPhase
 	Cards          []LessonCard
 	EstimatedMins  int
 	DueReviewCount int
 	NewCardCount   int
-	SuggestedSong  *Song // Song to suggest for full lesson (if any)
+	SuggestedSong  *Song           // Song to suggest for full lesson (if any)
+	Overrides      []PhaseOverride // Phase defaults replaced by user settings
+}
+
+// PhaseOverride records a phase default that a user setting replaced
+type PhaseOverride struct {
+	Setting    string
+	PhaseValue string
+	UserValue  string
+}
+
+// ActiveLesson is a persisted daily lesson that may be partly done
+type ActiveLesson struct {
+	Session  *LessonSession
+	Lesson   *DailyLesson
+	Position int          // Index of the next card to show
+	Results  []CardResult // Answered cards in order (skips excluded)
+	XPEarned int
 }
 
 // JourneyHomeData holds all data for the journey home page
 type JourneyHomeData struct {
-	DayNumber      int
-	TotalDays      int
-	CurrentPhase   *CurriculumPhase
-	TodayCompleted bool
-	TodayStats     string
-	DueCount       int
-	NewCount       int
-	EstimatedMins  int
-	Streak         int
-	TotalXP        int
+	Curriculum      *Curriculum
+	NextCurriculum  *Curriculum // Suggested once the journey is complete
+	JourneyComplete bool        // Past the curriculum's last day (maintenance mode)
+	Message         string
+	DayNumber       int
+	TotalDays       int
+	CurrentPhase    *CurriculumPhase
+	TodayCompleted  bool
+	TodayStats      string
+	DueCount        int
+	NewCount        int
+	EstimatedMins   int
+	Streak          int
+	TotalXP         int
+	IsAdmin         bool // Shows the admin pages, such as AI usage
 }
 
 // CardResult tracks the result of a single card review in a lesson
@@ -338,6 +436,7 @@ func (r Rating) Label() string {
 // LessonSummary holds detailed data for the lesson completion screen
 type LessonSummary struct {
 	DayNumber      int
+	TotalDays      int
 	Phase          *CurriculumPhase
 	TotalCards     int
 	CorrectCount   int
@@ -437,6 +536,7 @@ type Song struct {
 	DurationSeconds int
 	ThumbnailURL    string
 	AudioPath       string // Local path to MP3 file (served at /songs/audio/)
+	LyricsSource    LyricsSource
 	CreatedAt       time.Time
 	// Joined data
 	Lines      []SongLine
@@ -452,6 +552,53 @@ type SongLine struct {
 	EndTimeMs   int
 	SpanishText string
 	EnglishText string
+	CardID      sql.NullInt64 // The line's sentence card
+	// Joined data
+	Words   []SongLineWord   // Words linked to cards, in line order
+	Timings []SongWordTiming // Word timings from enhanced LRC, if any
+}
+
+// SongLineWord links a word of a song line to the card of its dictionary
+// form: quiero to querer
+type SongLineWord struct {
+	ID       int64
+	SongID   int64
+	LineID   int64
+	Position int    // Index among the line's whitespace-separated words
+	Word     string // As cleaned by lemma.Clean
+	Standard string // The standard spelling of a colloquial word, e.g. "para" for "pa", else ""
+	Lemma    string // The card's term
+	CardID   int64
+	// Joined data
+	Translation string
+}
+
+// SongWordTiming is when a word or syllable of a song line is sung
+type SongWordTiming struct {
+	ID          int64
+	LineID      int64
+	Position    int
+	Text        string
+	StartTimeMs int
+	EndTimeMs   int
+}
+
+// SongLinesVersion is a saved state of a song's lines. The latest version
+// is what song_lines holds; undoing an edit restores the one before it.
+type SongLinesVersion struct {
+	ID        int64
+	SongID    int64
+	UserID    sql.NullInt64 // NULL for the lines as imported
+	Note      string
+	Lines     []SongLine // With their timings, but not their linked words
+	CreatedAt time.Time
+}
+
+// SongUnmatchedWord is a word of a song's lyrics that matches no card
+type SongUnmatchedWord struct {
+	SongID      int64
+	Word        string
+	Occurrences int
 }
 
 // SongVocab links song vocabulary to flashcard system
@@ -466,18 +613,11 @@ type SongVocab struct {
 	Card *Card // Linked flashcard if exists
 }
 
-// SongProgress tracks user's progress on a song (FSRS-based)
+// SongProgress tracks user's progress on a song. The embedded CardProgress
+// (without a CardID) is scheduled like a card's, one review per lesson.
 type SongProgress struct {
-	ID                int64
-	UserID            int64
+	CardProgress
 	SongID            int64
-	Stability         float64
-	Difficulty        float64
-	Reps              int
-	Lapses            int
-	State             CardState // reusing CardState
-	Due               sql.NullTime
-	LastReview        sql.NullTime
 	VocabComplete     bool
 	LyricsComplete    bool
 	ListeningComplete bool
@@ -497,6 +637,11 @@ type SongSession struct {
 	BlanksCorrect   int
 	BlanksTotal     int
 	XPEarned        int
+	CurrentPhase    SongPhase
+	CurrentIndex    int
+	LineIDs         []int64 // Lines the lesson studies, nil for all
+	DictationCorrect int    // Words typed right in dictation
+	DictationTotal   int    // Words dictated
 	CompletedAt     sql.NullTime
 	CreatedAt       time.Time
 }
@@ -509,6 +654,7 @@ const (
 	SongModeLyrics    SongMode = "lyrics"
 	SongModeListening SongMode = "listening"
 	SongModeFull      SongMode = "full"
+	SongModeDictation SongMode = "dictation"
 )
 
 // SongPhase enum for lesson phases
@@ -520,6 +666,7 @@ const (
 	SongPhaseLineBreakdown SongPhase = "line_breakdown"
 	SongPhaseFillBlanks    SongPhase = "fill_blanks"
 	SongPhaseFinalListen   SongPhase = "final_listen"
+	SongPhaseDictation     SongPhase = "dictation"
 	SongPhaseComplete      SongPhase = "complete"
 )
 
@@ -527,12 +674,16 @@ const (
 type SongWithProgress struct {
 	Song
 	Progress *SongProgress
+	// Set once the song has been reviewed
+	Retrievability float64        // Chance of recalling it now, 0-1
+	Intervals      map[Rating]int // Days until due for each lesson rating
 }
 
 // SongVocabCard represents a vocab card for song lessons
 type SongVocabCard struct {
 	SongVocab
-	Mode string // standard, reverse
+	Mode   string // standard, reverse
+	Rating Rating // 0 until reviewed
 }
 
 // SongBlank represents a fill-in-the-blank for listening mode
@@ -540,11 +691,22 @@ type SongBlank struct {
 	LineID     int64
 	Line       *SongLine
 	BlankWord  string
-	BlankIndex int // Word position in line
+	BlankIndex int      // Word position in line
+	Choices    []string // Options of a multiple-choice blank, the answer among them; nil to type it
 	UserAnswer string
 	IsCorrect  bool
 }
 
+// SongDictation is a line to type as it plays, with the answer once given
+type SongDictation struct {
+	LineID       int64
+	Line         *SongLine
+	UserAnswer   string
+	WordsCorrect int
+	WordsTotal   int
+	Answered     bool
+}
+
 // SongLesson represents a complete song lesson flow
 type SongLesson struct {
 	Song          *Song
@@ -552,8 +714,11 @@ type SongLesson struct {
 	CurrentPhase  SongPhase
 	CurrentIndex  int // Index within current phase
 	VocabCards    []SongVocabCard
+	Lines         []SongLine // Lines to study, picked by mastery
 	Blanks        []SongBlank
+	Dictations    []SongDictation
 	EstimatedMins int
+	Session       *SongSession // Persisted session backing this lesson
 }
 
 // SongLessonSummary holds completion data
@@ -565,6 +730,8 @@ type SongLessonSummary struct {
 	LinesStudied  int
 	BlanksCorrect int
 	BlanksTotal   int
+	DictationCorrect int // Words
+	DictationTotal   int
 	Accuracy      int
 	XPEarned      int
 	Message       string
@@ -606,6 +773,32 @@ func (s *Song) DifficultyClass() string {
 	}
 }
 
+// LyricsSource is where a song's lyrics came from
+type LyricsSource string
+
+const (
+	LyricsSourceFile   LyricsSource = "lrc_file" // .lrc file next to the audio
+	LyricsSourceSYLT   LyricsSource = "id3_sylt" // Synchronised lyrics in the audio's tags
+	LyricsSourceUSLT   LyricsSource = "id3_uslt" // Unsynchronised lyrics in the audio's tags
+	LyricsSourceLRCLib LyricsSource = "lrclib"   // lrclib.net
+)
+
+// Label describes a lyrics source for display
+func (s LyricsSource) Label() string {
+	switch s {
+	case LyricsSourceFile:
+		return ".lrc file"
+	case LyricsSourceSYLT:
+		return "synced lyrics in the audio file"
+	case LyricsSourceUSLT:
+		return "lyrics in the audio file (untimed)"
+	case LyricsSourceLRCLib:
+		return "lrclib.net"
+	default:
+		return string(s)
+	}
+}
+
 // =============================================
 // PROGRESS OVERVIEW MODELS
 // =============================================
@@ -640,3 +833,126 @@ type ProgressOverviewData struct {
 	TotalXP         int
 	CurrentStreak   int
 }
+
+// =============================================
+// BACKGROUND JOB MODELS
+// =============================================
+
+// JobStatus is the state of a background job
+type JobStatus string
+
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobDone      JobStatus = "done"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// Finished reports whether the job will not run again
+func (s JobStatus) Finished() bool {
+	return s == JobDone || s == JobFailed || s == JobCancelled
+}
+
+// Job is a unit of background work, e.g. generating bridges for one card
+type Job struct {
+	ID          int64
+	BatchID     sql.NullInt64
+	UserID      sql.NullInt64 // NULL when enqueued from the CLI
+	Kind        string
+	Payload     string // JSON arguments for the kind
+	Status      JobStatus
+	Attempts    int
+	MaxAttempts int
+	LastError   string
+	RunAfter    time.Time
+	CreatedAt   time.Time
+	StartedAt   sql.NullTime
+	FinishedAt  sql.NullTime
+}
+
+// JobBatch groups the jobs started together, e.g. "Bridges for 50 cards"
+type JobBatch struct {
+	ID        int64
+	Name      string
+	UserID    sql.NullInt64
+	CreatedAt time.Time
+
+	// Job counts by status
+	Total     int
+	Pending   int
+	Running   int
+	Done      int
+	Failed    int
+	Cancelled int
+}
+
+// Finished reports whether every job in the batch is finished
+func (b *JobBatch) Finished() bool {
+	return b.Pending == 0 && b.Running == 0
+}
+
+// PercentComplete returns the share of finished jobs, 0-100
+func (b *JobBatch) PercentComplete() int {
+	if b.Total == 0 {
+		return 100
+	}
+	return (b.Done + b.Failed + b.Cancelled) * 100 / b.Total
+}
+
+// =============================================
+// AI USAGE MODELS
+// =============================================
+
+// AIUsage is one AI call in the usage ledger
+type AIUsage struct {
+	Feature      string // bridges, mcq, grammar, lyrics_translation, ...
+	Provider     string
+	Model        string
+	InputTokens  int
+	OutputTokens int
+	CostUSD      float64 // Estimated from the model's list price
+	Cached       bool    // Served from the response cache
+}
+
+// AIUsageSummary totals the ledger for one feature
+type AIUsageSummary struct {
+	Feature      string
+	Calls        int
+	CachedCalls  int
+	InputTokens  int
+	OutputTokens int
+	CostUSD      float64
+}
+
+// =============================================
+// CONJUGATION MODELS
+// =============================================
+
+// ConjugationProgress is the FSRS state of one tense of a verb card. The
+// embedded CardProgress is scheduled like a card's.
+type ConjugationProgress struct {
+	CardProgress
+	Tense string // conjugate.Tense, e.g. present_subjunctive
+}
+
+// ConjugationLog is one answered conjugation drill
+type ConjugationLog struct {
+	UserID           int64
+	CardID           int64
+	Tense            string
+	Person           int // 0 yo to 5 ellos
+	Answer           string
+	Expected         string
+	Rating           Rating
+	ReviewDurationMs int
+}
+
+// ConjugationTenseStats summarizes a user's drills of one tense
+type ConjugationTenseStats struct {
+	Tense   string
+	Verbs   int // Verb cards with progress in the tense
+	Due     int
+	Answers int // In the last 30 days
+	Correct int // Answers rated above Again
+}
@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"languagepapi/internal/db"
+)
+
+// SaveAIRejection records an AI response that failed validation
+func SaveAIRejection(task string, attempt int, reason, response string) error {
+	_, err := db.DB.Exec(`
+		INSERT INTO ai_rejections (task, attempt, reason, response)
+		VALUES (?, ?, ?, ?)
+	`, task, attempt, reason, response)
+	return err
+}
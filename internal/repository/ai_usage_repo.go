@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"languagepapi/internal/db"
+	"languagepapi/internal/models"
+)
+
+// GetAICachedResponse returns the cached response for a cache key.
+// Returns sql.ErrNoRows on a miss.
+func GetAICachedResponse(key string) (string, error) {
+	var response string
+	err := db.DB.QueryRow(`SELECT response FROM ai_cache WHERE key = ?`, key).Scan(&response)
+	return response, err
+}
+
+// SaveAICachedResponse stores a response under its cache key
+func SaveAICachedResponse(key, feature, model, response string) error {
+	_, err := db.DB.Exec(`
+		INSERT OR REPLACE INTO ai_cache (key, feature, model, response)
+		VALUES (?, ?, ?, ?)
+	`, key, feature, model, response)
+	return err
+}
+
+// DeleteAICachedResponse drops one cached response
+func DeleteAICachedResponse(key string) error {
+	_, err := db.DB.Exec(`DELETE FROM ai_cache WHERE key = ?`, key)
+	return err
+}
+
+// CountAICachedResponses returns the number of cached responses
+func CountAICachedResponses() (int, error) {
+	var n int
+	err := db.DB.QueryRow(`SELECT COUNT(*) FROM ai_cache`).Scan(&n)
+	return n, err
+}
+
+// ClearAICache drops every cached response
+func ClearAICache() (int64, error) {
+	result, err := db.DB.Exec(`DELETE FROM ai_cache`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// SaveAIUsage appends a call to the usage ledger
+func SaveAIUsage(u *models.AIUsage) error {
+	_, err := db.DB.Exec(`
+		INSERT INTO ai_usage (feature, provider, model, input_tokens, output_tokens, cost_usd, cached)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, u.Feature, u.Provider, u.Model, u.InputTokens, u.OutputTokens, u.CostUSD, u.Cached)
+	return err
+}
+
+// GetAICostThisMonth returns the estimated spend since the start of the
+// month (UTC)
+func GetAICostThisMonth() (float64, error) {
+	var cost float64
+	err := db.DB.QueryRow(`
+		SELECT COALESCE(SUM(cost_usd), 0) FROM ai_usage
+		WHERE created_at >= datetime('now', 'start of month')
+	`).Scan(&cost)
+	return cost, err
+}
+
+// GetAIUsageThisMonth totals the ledger by feature since the start of the
+// month (UTC), most expensive first
+func GetAIUsageThisMonth() ([]models.AIUsageSummary, error) {
+	rows, err := db.DB.Query(`
+		SELECT feature, COUNT(*), COALESCE(SUM(cached), 0),
+		       COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0), COALESCE(SUM(cost_usd), 0)
+		FROM ai_usage
+		WHERE created_at >= datetime('now', 'start of month')
+		GROUP BY feature
+		ORDER BY SUM(cost_usd) DESC, COUNT(*) DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []models.AIUsageSummary
+	for rows.Next() {
+		var s models.AIUsageSummary
+		if err := rows.Scan(&s.Feature, &s.Calls, &s.CachedCalls, &s.InputTokens, &s.OutputTokens, &s.CostUSD); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"database/sql"
+	"fmt"
 	"time"
 
 	"languagepapi/internal/db"
@@ -14,11 +15,11 @@ func GetCard(id int64) (*models.Card, error) {
 	err := db.DB.QueryRow(`
 		SELECT id, island_id, term, translation,
 		       COALESCE(example_sentence, ''), COALESCE(notes, ''), COALESCE(audio_url, ''),
-		       frequency_rank, created_at
+		       frequency_rank, user_id, created_at
 		FROM cards WHERE id = ?
 	`, id).Scan(
 		&card.ID, &card.IslandID, &card.Term, &card.Translation,
-		&card.ExampleSentence, &card.Notes, &card.AudioURL, &card.FrequencyRank, &card.CreatedAt,
+		&card.ExampleSentence, &card.Notes, &card.AudioURL, &card.FrequencyRank, &card.UserID, &card.CreatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -52,15 +53,16 @@ func GetCardWithBridges(id int64) (*models.Card, error) {
 	return card, rows.Err()
 }
 
-// GetCardsByIsland retrieves all cards for an island
-func GetCardsByIsland(islandID int64) ([]models.Card, error) {
+// GetCardsByIsland retrieves the shared cards and the user's own cards for
+// an island
+func GetCardsByIsland(userID, islandID int64) ([]models.Card, error) {
 	rows, err := db.DB.Query(`
 		SELECT id, island_id, term, translation,
 		       COALESCE(example_sentence, ''), COALESCE(notes, ''), COALESCE(audio_url, ''),
-		       frequency_rank, created_at
-		FROM cards WHERE island_id = ?
+		       frequency_rank, user_id, created_at
+		FROM cards WHERE island_id = ? AND `+visibleCard+` AND `+wordCard+`
 		ORDER BY frequency_rank ASC, id ASC
-	`, islandID)
+	`, islandID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -71,7 +73,7 @@ func GetCardsByIsland(islandID int64) ([]models.Card, error) {
 		var c models.Card
 		if err := rows.Scan(
 			&c.ID, &c.IslandID, &c.Term, &c.Translation,
-			&c.ExampleSentence, &c.Notes, &c.AudioURL, &c.FrequencyRank, &c.CreatedAt,
+			&c.ExampleSentence, &c.Notes, &c.AudioURL, &c.FrequencyRank, &c.UserID, &c.CreatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -82,15 +84,19 @@ func GetCardsByIsland(islandID int64) ([]models.Card, error) {
 
 // CreateCard inserts a new card
 func CreateCard(card *models.Card) error {
+	return insertCard(db.DB, card)
+}
+
+func insertCard(q execer, card *models.Card) error {
 	// Default source to "curriculum" if not set
 	source := card.Source
 	if source == "" {
 		source = "curriculum"
 	}
-	result, err := db.DB.Exec(`
-		INSERT INTO cards (island_id, term, translation, example_sentence, notes, audio_url, frequency_rank, source, source_song_id)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, card.IslandID, card.Term, card.Translation, card.ExampleSentence, card.Notes, card.AudioURL, card.FrequencyRank, source, card.SourceSongID)
+	result, err := q.Exec(`
+		INSERT INTO cards (island_id, term, translation, example_sentence, notes, audio_url, frequency_rank, source, source_song_id, user_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, card.IslandID, card.Term, card.Translation, card.ExampleSentence, card.Notes, card.AudioURL, card.FrequencyRank, source, card.SourceSongID, card.UserID)
 	if err != nil {
 		return err
 	}
@@ -100,7 +106,11 @@ func CreateCard(card *models.Card) error {
 
 // CreateBridge inserts a new bridge for a card
 func CreateBridge(bridge *models.Bridge) error {
-	result, err := db.DB.Exec(`
+	return insertBridge(db.DB, bridge)
+}
+
+func insertBridge(q execer, bridge *models.Bridge) error {
+	result, err := q.Exec(`
 		INSERT INTO bridges (card_id, bridge_type, bridge_content, explanation)
 		VALUES (?, ?, ?, ?)
 	`, bridge.CardID, bridge.BridgeType, bridge.BridgeContent, bridge.Explanation)
@@ -119,7 +129,11 @@ func DeleteCard(id int64) error {
 
 // UpdateCard updates an existing card
 func UpdateCard(card *models.Card) error {
-	_, err := db.DB.Exec(`
+	return updateCard(db.DB, card)
+}
+
+func updateCard(q execer, card *models.Card) error {
+	_, err := q.Exec(`
 		UPDATE cards SET
 			island_id = ?,
 			term = ?,
@@ -138,8 +152,19 @@ func DeleteBridgesForCard(cardID int64) error {
 	return err
 }
 
-// SearchCards searches for cards by term or translation
-func SearchCards(query string, islandID int64) ([]models.Card, error) {
+// wordCard holds for every card but a song line's. Song line cards (see
+// song_line_cards_repo.go) are sentences reviewed through songs and daily
+// lessons, so word lists, new-card pools, distractors and batch generation
+// leave them out.
+const wordCard = `COALESCE(source, 'curriculum') != 'song_line'`
+
+// visibleCard holds for the shared cards and the cards of the user given as
+// its one parameter. Other users' cards are private.
+const visibleCard = `(user_id IS NULL OR user_id = ?)`
+
+// SearchCards searches the shared cards and the user's own cards by term
+// or translation
+func SearchCards(userID int64, query string, islandID int64) ([]models.Card, error) {
 	searchPattern := "%" + query + "%"
 	var rows *sql.Rows
 	var err error
@@ -148,22 +173,24 @@ func SearchCards(query string, islandID int64) ([]models.Card, error) {
 		rows, err = db.DB.Query(`
 			SELECT id, island_id, term, translation,
 			       COALESCE(example_sentence, ''), COALESCE(notes, ''), COALESCE(audio_url, ''),
-			       frequency_rank, created_at
+			       frequency_rank, user_id, created_at
 			FROM cards
 			WHERE island_id = ? AND (term LIKE ? OR translation LIKE ? OR example_sentence LIKE ?)
+			  AND `+visibleCard+` AND `+wordCard+`
 			ORDER BY frequency_rank ASC, id ASC
 			LIMIT 100
-		`, islandID, searchPattern, searchPattern, searchPattern)
+		`, islandID, searchPattern, searchPattern, searchPattern, userID)
 	} else {
 		rows, err = db.DB.Query(`
 			SELECT id, island_id, term, translation,
 			       COALESCE(example_sentence, ''), COALESCE(notes, ''), COALESCE(audio_url, ''),
-			       frequency_rank, created_at
+			       frequency_rank, user_id, created_at
 			FROM cards
-			WHERE term LIKE ? OR translation LIKE ? OR example_sentence LIKE ?
+			WHERE (term LIKE ? OR translation LIKE ? OR example_sentence LIKE ?)
+			  AND `+visibleCard+` AND `+wordCard+`
 			ORDER BY frequency_rank ASC, id ASC
 			LIMIT 100
-		`, searchPattern, searchPattern, searchPattern)
+		`, searchPattern, searchPattern, searchPattern, userID)
 	}
 	if err != nil {
 		return nil, err
@@ -175,7 +202,7 @@ func SearchCards(query string, islandID int64) ([]models.Card, error) {
 		var c models.Card
 		if err := rows.Scan(
 			&c.ID, &c.IslandID, &c.Term, &c.Translation,
-			&c.ExampleSentence, &c.Notes, &c.AudioURL, &c.FrequencyRank, &c.CreatedAt,
+			&c.ExampleSentence, &c.Notes, &c.AudioURL, &c.FrequencyRank, &c.UserID, &c.CreatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -206,10 +233,10 @@ func GetBridgesForCard(cardID int64) ([]models.Bridge, error) {
 	return bridges, rows.Err()
 }
 
-// CountCards returns total card count
-func CountCards() (int, error) {
+// CountCards returns the number of shared cards and the user's own cards
+func CountCards(userID int64) (int, error) {
 	var count int
-	err := db.DB.QueryRow(`SELECT COUNT(*) FROM cards`).Scan(&count)
+	err := db.DB.QueryRow(`SELECT COUNT(*) FROM cards WHERE `+visibleCard+` AND `+wordCard, userID).Scan(&count)
 	return count, err
 }
 
@@ -217,7 +244,7 @@ func CountCards() (int, error) {
 func GetRandomTranslations(excludeCardID int64, count int) ([]string, error) {
 	rows, err := db.DB.Query(`
 		SELECT translation FROM cards
-		WHERE id != ?
+		WHERE id != ? AND `+wordCard+`
 		ORDER BY RANDOM()
 		LIMIT ?
 	`, excludeCardID, count)
@@ -237,16 +264,18 @@ func GetRandomTranslations(excludeCardID int64, count int) ([]string, error) {
 	return translations, rows.Err()
 }
 
-// GetAllCards retrieves all cards with pagination
-func GetAllCards(limit, offset int) ([]models.Card, error) {
+// GetAllCards retrieves the shared cards and the user's own cards with
+// pagination
+func GetAllCards(userID int64, limit, offset int) ([]models.Card, error) {
 	rows, err := db.DB.Query(`
 		SELECT id, island_id, term, translation,
 		       COALESCE(example_sentence, ''), COALESCE(notes, ''), COALESCE(audio_url, ''),
-		       frequency_rank, created_at
+		       frequency_rank, user_id, created_at
 		FROM cards
+		WHERE `+visibleCard+` AND `+wordCard+`
 		ORDER BY frequency_rank ASC, id ASC
 		LIMIT ? OFFSET ?
-	`, limit, offset)
+	`, userID, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -257,7 +286,7 @@ func GetAllCards(limit, offset int) ([]models.Card, error) {
 		var c models.Card
 		if err := rows.Scan(
 			&c.ID, &c.IslandID, &c.Term, &c.Translation,
-			&c.ExampleSentence, &c.Notes, &c.AudioURL, &c.FrequencyRank, &c.CreatedAt,
+			&c.ExampleSentence, &c.Notes, &c.AudioURL, &c.FrequencyRank, &c.UserID, &c.CreatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -268,6 +297,12 @@ func GetAllCards(limit, offset int) ([]models.Card, error) {
 
 // GetDueSongVocabCards fetches song vocabulary cards due for review
 func GetDueSongVocabCards(userID int64, limit int) ([]models.CardWithProgress, error) {
+	return getDueCardsFromSource(userID, "song", limit)
+}
+
+// getDueCardsFromSource fetches the cards of one source due for review,
+// longest overdue first
+func getDueCardsFromSource(userID int64, source string, limit int) ([]models.CardWithProgress, error) {
 	now := time.Now().Format("2006-01-02 15:04:05")
 	rows, err := db.DB.Query(`
 		SELECT c.id, c.island_id, c.term, c.translation,
@@ -277,12 +312,12 @@ func GetDueSongVocabCards(userID int64, limit int) ([]models.CardWithProgress, e
 		       p.reps, p.lapses, p.state, p.due, p.last_review
 		FROM cards c
 		JOIN card_progress p ON c.id = p.card_id AND p.user_id = ?
-		WHERE c.source = 'song'
+		WHERE c.source = ?
 		  AND p.state IN ('learning', 'review', 'relearning')
 		  AND substr(p.due, 1, 19) <= ?
 		ORDER BY p.due ASC
 		LIMIT ?
-	`, userID, now, limit)
+	`, userID, source, now, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -376,3 +411,152 @@ func GetSongTitleForCard(cardID int64) (string, error) {
 	}
 	return title, err
 }
+
+// GetCardsForExport retrieves the shared cards and the user's own cards
+// with their bridges, optionally limited to one island
+func GetCardsForExport(userID, islandID int64) ([]models.Card, error) {
+	rows, err := db.DB.Query(`
+		SELECT id, island_id, term, translation,
+		       COALESCE(example_sentence, ''), COALESCE(notes, ''), COALESCE(audio_url, ''),
+		       frequency_rank, COALESCE(source, 'curriculum'), source_song_id, user_id, created_at
+		FROM cards
+		WHERE (user_id IS NULL OR user_id = ?) AND (? = 0 OR island_id = ?) AND `+wordCard+`
+		ORDER BY island_id ASC, frequency_rank ASC, id ASC
+	`, userID, islandID, islandID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cards []models.Card
+	for rows.Next() {
+		var c models.Card
+		if err := rows.Scan(
+			&c.ID, &c.IslandID, &c.Term, &c.Translation,
+			&c.ExampleSentence, &c.Notes, &c.AudioURL, &c.FrequencyRank,
+			&c.Source, &c.SourceSongID, &c.UserID, &c.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		cards = append(cards, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range cards {
+		if cards[i].Bridges, err = GetBridgesForCard(cards[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return cards, nil
+}
+
+// GetCardByTerm finds a shared or user-owned card by term, ignoring case
+func GetCardByTerm(userID int64, term string) (*models.Card, error) {
+	var id int64
+	err := db.DB.QueryRow(`
+		SELECT id FROM cards
+		WHERE term = ? COLLATE NOCASE AND (user_id IS NULL OR user_id = ?) AND `+wordCard+`
+		ORDER BY id LIMIT 1
+	`, term, userID).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	return GetCard(id)
+}
+
+// FilterCards retrieves the shared cards and the user's own cards matching
+// the words page filters, without pagination
+func FilterCards(userID, islandID int64, query string) ([]models.Card, error) {
+	searchPattern := "%" + query + "%"
+	rows, err := db.DB.Query(`
+		SELECT id, island_id, term, translation,
+		       COALESCE(example_sentence, ''), COALESCE(notes, ''), COALESCE(audio_url, ''),
+		       frequency_rank, user_id, created_at
+		FROM cards
+		WHERE (user_id IS NULL OR user_id = ?)
+		  AND (? = 0 OR island_id = ?)
+		  AND (? = '' OR term LIKE ? OR translation LIKE ? OR example_sentence LIKE ?)
+		  AND `+wordCard+`
+		ORDER BY frequency_rank ASC, id ASC
+	`, userID, islandID, islandID, query, searchPattern, searchPattern, searchPattern)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cards []models.Card
+	for rows.Next() {
+		var c models.Card
+		if err := rows.Scan(
+			&c.ID, &c.IslandID, &c.Term, &c.Translation,
+			&c.ExampleSentence, &c.Notes, &c.AudioURL, &c.FrequencyRank, &c.UserID, &c.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		cards = append(cards, c)
+	}
+	return cards, rows.Err()
+}
+
+// UpdateCardFrequencyRank sets or clears a card's frequency rank
+func UpdateCardFrequencyRank(id int64, rank sql.NullInt64) error {
+	return updateCardFrequencyRank(db.DB, id, rank)
+}
+
+func updateCardFrequencyRank(q execer, id int64, rank sql.NullInt64) error {
+	_, err := q.Exec(`UPDATE cards SET frequency_rank = ? WHERE id = ?`, rank, id)
+	return err
+}
+
+// CardsMissing selects what batch generation fills in
+type CardsMissing string
+
+const (
+	MissingBridges   CardsMissing = "bridges"
+	MissingExample   CardsMissing = "example"
+	MissingQuestions CardsMissing = "questions"
+	MissingGrammar   CardsMissing = "grammar"
+)
+
+var cardsMissingConditions = map[CardsMissing]string{
+	MissingBridges:   `NOT EXISTS (SELECT 1 FROM bridges b WHERE b.card_id = c.id)`,
+	MissingExample:   `COALESCE(c.example_sentence, '') = ''`,
+	MissingQuestions: `(SELECT COUNT(DISTINCT q.question_type) FROM questions q WHERE q.card_id = c.id) < 3`,
+	MissingGrammar:   `NOT EXISTS (SELECT 1 FROM card_grammar cg WHERE cg.card_id = c.id)`,
+}
+
+// GetCardIDsMissing returns up to limit of the user's cards that lack what,
+// most frequent first. Shared cards are included if includeShared is set.
+func GetCardIDsMissing(userID int64, includeShared bool, what CardsMissing, limit int) ([]int64, error) {
+	condition, ok := cardsMissingConditions[what]
+	if !ok {
+		return nil, fmt.Errorf("unknown card filter %q", what)
+	}
+	owner := "c.user_id = ?"
+	if includeShared {
+		owner = "(c.user_id IS NULL OR c.user_id = ?)"
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT c.id FROM cards c
+		WHERE `+owner+` AND `+wordCard+` AND `+condition+`
+		ORDER BY c.frequency_rank ASC, c.id
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"time"
+
+	"languagepapi/internal/db"
+	"languagepapi/internal/models"
+)
+
+const conjugationProgressColumns = `
+	id, user_id, card_id, tense, stability, difficulty, elapsed_days, scheduled_days,
+	reps, lapses, state, due, last_review`
+
+func scanConjugationProgress(row interface{ Scan(...any) error }) (*models.ConjugationProgress, error) {
+	p := &models.ConjugationProgress{}
+	err := row.Scan(
+		&p.ID, &p.UserID, &p.CardID, &p.Tense, &p.Stability, &p.Difficulty,
+		&p.ElapsedDays, &p.ScheduledDays, &p.Reps, &p.Lapses,
+		&p.State, &p.Due, &p.LastReview,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetConjugationProgress retrieves a user's progress in one tense of a verb
+// card. Returns sql.ErrNoRows if the pair was never drilled.
+func GetConjugationProgress(userID, cardID int64, tense string) (*models.ConjugationProgress, error) {
+	return scanConjugationProgress(db.DB.QueryRow(`
+		SELECT `+conjugationProgressColumns+`
+		FROM conjugation_progress WHERE user_id = ? AND card_id = ? AND tense = ?
+	`, userID, cardID, tense))
+}
+
+// GetAllConjugationProgress returns every verb and tense the user has drilled
+func GetAllConjugationProgress(userID int64) ([]models.ConjugationProgress, error) {
+	return queryConjugationProgress(`
+		SELECT `+conjugationProgressColumns+`
+		FROM conjugation_progress WHERE user_id = ?
+	`, userID)
+}
+
+// GetDueConjugations returns the verb and tense pairs due for a drill, most
+// overdue first
+func GetDueConjugations(userID int64, limit int) ([]models.ConjugationProgress, error) {
+	now := time.Now().Format("2006-01-02 15:04:05")
+	return queryConjugationProgress(`
+		SELECT `+conjugationProgressColumns+`
+		FROM conjugation_progress
+		WHERE user_id = ? AND substr(due, 1, 19) <= ? AND state IN ('learning', 'review', 'relearning')
+		ORDER BY due ASC
+		LIMIT ?
+	`, userID, now, limit)
+}
+
+func queryConjugationProgress(query string, args ...any) ([]models.ConjugationProgress, error) {
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.ConjugationProgress
+	for rows.Next() {
+		p, err := scanConjugationProgress(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *p)
+	}
+	return list, rows.Err()
+}
+
+// UpsertConjugationProgress creates or updates progress in one tense of a
+// verb card
+func UpsertConjugationProgress(p *models.ConjugationProgress) error {
+	result, err := db.DB.Exec(`
+		INSERT INTO conjugation_progress (user_id, card_id, tense, stability, difficulty, elapsed_days,
+		                                  scheduled_days, reps, lapses, state, due, last_review)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, card_id, tense) DO UPDATE SET
+			stability = excluded.stability,
+			difficulty = excluded.difficulty,
+			elapsed_days = excluded.elapsed_days,
+			scheduled_days = excluded.scheduled_days,
+			reps = excluded.reps,
+			lapses = excluded.lapses,
+			state = excluded.state,
+			due = excluded.due,
+			last_review = excluded.last_review
+	`, p.UserID, p.CardID, p.Tense, p.Stability, p.Difficulty, p.ElapsedDays, p.ScheduledDays,
+		p.Reps, p.Lapses, p.State, p.Due, p.LastReview)
+	if err != nil {
+		return err
+	}
+	if p.ID == 0 {
+		p.ID, _ = result.LastInsertId()
+	}
+	return nil
+}
+
+// CountConjugationsStartedToday counts the verb and tense pairs the user
+// drilled for the first time today
+func CountConjugationsStartedToday(userID int64) (int, error) {
+	var n int
+	err := db.DB.QueryRow(`
+		SELECT COUNT(*) FROM conjugation_progress
+		WHERE user_id = ? AND date(created_at, 'localtime') = date('now', 'localtime')
+	`, userID).Scan(&n)
+	return n, err
+}
+
+// GetStudiedVerbCards returns the cards visible to the user that they have
+// started learning and that translate to an English infinitive ("to eat"),
+// most frequent first. Callers check the term is a Spanish infinitive.
+func GetStudiedVerbCards(userID int64) ([]models.Card, error) {
+	rows, err := db.DB.Query(`
+		SELECT c.id, c.island_id, c.term, c.translation,
+		       COALESCE(c.example_sentence, ''), COALESCE(c.notes, ''), COALESCE(c.audio_url, ''),
+		       c.frequency_rank, c.user_id, c.created_at
+		FROM cards c
+		INNER JOIN card_progress p ON p.card_id = c.id AND p.user_id = ?
+		WHERE (c.user_id IS NULL OR c.user_id = ?)
+		  AND p.state != 'new'
+		  AND LOWER(TRIM(c.translation)) LIKE 'to %'
+		ORDER BY c.frequency_rank IS NULL, c.frequency_rank ASC, c.id ASC
+	`, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cards []models.Card
+	for rows.Next() {
+		var c models.Card
+		if err := rows.Scan(
+			&c.ID, &c.IslandID, &c.Term, &c.Translation,
+			&c.ExampleSentence, &c.Notes, &c.AudioURL, &c.FrequencyRank, &c.UserID, &c.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		cards = append(cards, c)
+	}
+	return cards, rows.Err()
+}
+
+// LogConjugation records an answered conjugation drill
+func LogConjugation(l *models.ConjugationLog) error {
+	_, err := db.DB.Exec(`
+		INSERT INTO conjugation_logs (user_id, card_id, tense, person, answer, expected, rating, review_duration_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, l.UserID, l.CardID, l.Tense, l.Person, l.Answer, l.Expected, l.Rating, l.ReviewDurationMs)
+	return err
+}
+
+// GetConjugationStats summarizes the user's drills per tense: verbs
+// started, pairs due now and answers in the last 30 days
+func GetConjugationStats(userID int64) ([]models.ConjugationTenseStats, error) {
+	now := time.Now().Format("2006-01-02 15:04:05")
+	rows, err := db.DB.Query(`
+		SELECT tense, COUNT(*),
+		       SUM(CASE WHEN substr(due, 1, 19) <= ? AND state != 'new' THEN 1 ELSE 0 END)
+		FROM conjugation_progress WHERE user_id = ?
+		GROUP BY tense
+	`, now, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byTense := make(map[string]*models.ConjugationTenseStats)
+	var stats []models.ConjugationTenseStats
+	for rows.Next() {
+		var s models.ConjugationTenseStats
+		if err := rows.Scan(&s.Tense, &s.Verbs, &s.Due); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range stats {
+		byTense[stats[i].Tense] = &stats[i]
+	}
+
+	logRows, err := db.DB.Query(`
+		SELECT tense, COUNT(*), SUM(CASE WHEN rating > 1 THEN 1 ELSE 0 END)
+		FROM conjugation_logs
+		WHERE user_id = ? AND reviewed_at >= datetime('now', '-30 days')
+		GROUP BY tense
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer logRows.Close()
+	for logRows.Next() {
+		var tense string
+		var answers, correct int
+		if err := logRows.Scan(&tense, &answers, &correct); err != nil {
+			return nil, err
+		}
+		if s, ok := byTense[tense]; ok {
+			s.Answers, s.Correct = answers, correct
+		}
+	}
+	return stats, logRows.Err()
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"languagepapi/internal/db"
+	"languagepapi/internal/models"
+)
+
+// scanCurriculum scans a curricula row and decodes its phases
+func scanCurriculum(row interface{ Scan(...any) error }) (*models.Curriculum, error) {
+	var c models.Curriculum
+	var slug sql.NullString
+	var phasesJSON string
+	if err := row.Scan(&c.ID, &slug, &c.Name, &c.Description, &c.UserID, &phasesJSON); err != nil {
+		return nil, err
+	}
+	c.Slug = slug.String
+	if err := json.Unmarshal([]byte(phasesJSON), &c.Phases); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetCurriculum retrieves a curriculum by ID
+func GetCurriculum(id int64) (*models.Curriculum, error) {
+	return scanCurriculum(db.DB.QueryRow(`
+		SELECT id, slug, name, description, user_id, phases
+		FROM curricula WHERE id = ?
+	`, id))
+}
+
+// GetCurriculumBySlug retrieves a built-in curriculum by its slug
+func GetCurriculumBySlug(slug string) (*models.Curriculum, error) {
+	return scanCurriculum(db.DB.QueryRow(`
+		SELECT id, slug, name, description, user_id, phases
+		FROM curricula WHERE slug = ?
+	`, slug))
+}
+
+// ListCurricula returns the built-in curricula followed by the user's own
+func ListCurricula(userID int64) ([]models.Curriculum, error) {
+	rows, err := db.DB.Query(`
+		SELECT id, slug, name, description, user_id, phases
+		FROM curricula
+		WHERE user_id IS NULL OR user_id = ?
+		ORDER BY user_id IS NOT NULL, id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var curricula []models.Curriculum
+	for rows.Next() {
+		c, err := scanCurriculum(rows)
+		if err != nil {
+			return nil, err
+		}
+		curricula = append(curricula, *c)
+	}
+	return curricula, rows.Err()
+}
+
+// CreateCurriculum stores a user-defined curriculum
+func CreateCurriculum(c *models.Curriculum) (int64, error) {
+	phasesJSON, err := json.Marshal(c.Phases)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := db.DB.Exec(`
+		INSERT INTO curricula (name, description, user_id, phases)
+		VALUES (?, ?, ?, ?)
+	`, c.Name, c.Description, c.UserID, string(phasesJSON))
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
@@ -50,8 +50,12 @@ func UpdateDailyLog(log *models.DailyLog) error {
 
 // IncrementDailyStats increments daily counters
 func IncrementDailyStats(userID int64, xp, reviewed, correct, newCards int) error {
+	return incrementDailyStats(db.DB, userID, xp, reviewed, correct, newCards)
+}
+
+func incrementDailyStats(q execer, userID int64, xp, reviewed, correct, newCards int) error {
 	today := time.Now().Format("2006-01-02")
-	_, err := db.DB.Exec(`
+	_, err := q.Exec(`
 		INSERT INTO daily_logs (user_id, date, xp_earned, cards_reviewed, cards_correct, new_cards_added)
 		VALUES (?, ?, ?, ?, ?, ?)
 		ON CONFLICT(user_id, date) DO UPDATE SET
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"encoding/json"
+
+	"languagepapi/internal/db"
+	"languagepapi/internal/models"
+)
+
+// GetFSRSParams retrieves a user's fitted FSRS weights.
+// Returns sql.ErrNoRows if the optimizer has not been run for the user.
+func GetFSRSParams(userID int64) (*models.FSRSParams, error) {
+	var p models.FSRSParams
+	var weightsJSON string
+	err := db.DB.QueryRow(`
+		SELECT user_id, weights, review_count, log_loss_before, log_loss_after,
+		       rmse_before, rmse_after, optimized_at
+		FROM user_fsrs_params WHERE user_id = ?
+	`, userID).Scan(
+		&p.UserID, &weightsJSON, &p.ReviewCount, &p.LogLossBefore, &p.LogLossAfter,
+		&p.RMSEBefore, &p.RMSEAfter, &p.OptimizedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(weightsJSON), &p.Weights); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// SaveFSRSParams stores a user's fitted FSRS weights, replacing earlier ones
+func SaveFSRSParams(p *models.FSRSParams) error {
+	weightsJSON, err := json.Marshal(p.Weights)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.DB.Exec(`
+		INSERT INTO user_fsrs_params (user_id, weights, review_count, log_loss_before, log_loss_after, rmse_before, rmse_after, optimized_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id) DO UPDATE SET
+			weights = excluded.weights,
+			review_count = excluded.review_count,
+			log_loss_before = excluded.log_loss_before,
+			log_loss_after = excluded.log_loss_after,
+			rmse_before = excluded.rmse_before,
+			rmse_after = excluded.rmse_after,
+			optimized_at = CURRENT_TIMESTAMP
+	`, p.UserID, string(weightsJSON), p.ReviewCount, p.LogLossBefore, p.LogLossAfter, p.RMSEBefore, p.RMSEAfter)
+	return err
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"database/sql"
+
+	"languagepapi/internal/db"
+	"languagepapi/internal/models"
+)
+
+// execer is satisfied by *sql.DB and *sql.Tx, so a write can run on its
+// own or as part of a transaction
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// querier is an execer that can also read, for writes that depend on what
+// is stored
+type querier interface {
+	execer
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// ImportedCard is a new card with the bridges and review history that come
+// with it
+type ImportedCard struct {
+	Card     *models.Card
+	Bridges  []models.Bridge
+	Reviews  []models.ReviewLog
+	Progress *models.CardProgress // nil for cards without a schedule
+}
+
+// CardImport is everything one import writes
+type CardImport struct {
+	Created []ImportedCard
+	Updated []*models.Card // Existing cards to save, frequency rank included
+}
+
+// SaveCardImport writes an import in one transaction, so a failure leaves
+// no partial import behind. IDs are set on the created cards and the rows
+// that belong to them.
+func SaveCardImport(imp *CardImport) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, c := range imp.Created {
+		if err := insertCard(tx, c.Card); err != nil {
+			return err
+		}
+		for i := range c.Bridges {
+			c.Bridges[i].CardID = c.Card.ID
+			if err := insertBridge(tx, &c.Bridges[i]); err != nil {
+				return err
+			}
+		}
+		for i := range c.Reviews {
+			c.Reviews[i].CardID = c.Card.ID
+			if err := insertReviewLog(tx, &c.Reviews[i]); err != nil {
+				return err
+			}
+		}
+		if c.Progress != nil {
+			c.Progress.CardID = c.Card.ID
+			if err := upsertProgress(tx, c.Progress); err != nil {
+				return err
+			}
+		}
+	}
+	for _, card := range imp.Updated {
+		if err := updateCard(tx, card); err != nil {
+			return err
+		}
+		if err := updateCardFrequencyRank(tx, card.ID, card.FrequencyRank); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"languagepapi/internal/db"
+	"languagepapi/internal/models"
+)
+
+const jobColumns = `id, batch_id, user_id, kind, payload, status, attempts, max_attempts,
+	last_error, run_after, created_at, started_at, finished_at`
+
+// scanJob scans a jobs row selected with jobColumns
+func scanJob(row interface{ Scan(...any) error }) (*models.Job, error) {
+	var j models.Job
+	err := row.Scan(&j.ID, &j.BatchID, &j.UserID, &j.Kind, &j.Payload, &j.Status, &j.Attempts, &j.MaxAttempts,
+		&j.LastError, &j.RunAfter, &j.CreatedAt, &j.StartedAt, &j.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// CreateJobBatch creates a named group for jobs started together
+func CreateJobBatch(name string, userID sql.NullInt64) (int64, error) {
+	result, err := db.DB.Exec(`INSERT INTO job_batches (name, user_id) VALUES (?, ?)`, name, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// EnqueueJob inserts a pending job, unless the same user already has an
+// unfinished job of the same kind and payload in the same batch (or also
+// outside any batch), and sets job.ID to whichever it is. Jobs of other
+// users and batches are never reused, so every batch counts all its work.
+// Reports whether a new job was inserted.
+func EnqueueJob(job *models.Job) (bool, error) {
+	err := db.DB.QueryRow(`
+		SELECT id FROM jobs
+		WHERE kind = ? AND payload = ? AND batch_id IS ? AND user_id IS ?
+			AND status IN ('pending', 'running')
+		LIMIT 1
+	`, job.Kind, job.Payload, job.BatchID, job.UserID).Scan(&job.ID)
+	if err == nil {
+		return false, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, err
+	}
+
+	result, err := db.DB.Exec(`
+		INSERT INTO jobs (batch_id, user_id, kind, payload, max_attempts)
+		VALUES (?, ?, ?, ?, ?)
+	`, job.BatchID, job.UserID, job.Kind, job.Payload, job.MaxAttempts)
+	if err != nil {
+		return false, err
+	}
+	job.ID, err = result.LastInsertId()
+	return true, err
+}
+
+// ClaimJob marks the oldest due pending job of one of kinds as running and
+// returns it, only looking at the jobs of batchID if it is not zero.
+// Returns sql.ErrNoRows if there is none.
+func ClaimJob(kinds []string, batchID int64) (*models.Job, error) {
+	if len(kinds) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	args := make([]any, len(kinds), len(kinds)+2)
+	for i, k := range kinds {
+		args[i] = k
+	}
+	args = append(args, batchID, batchID)
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(kinds)), ", ")
+
+	return scanJob(db.DB.QueryRow(`
+		UPDATE jobs
+		SET status = 'running', attempts = attempts + 1, started_at = CURRENT_TIMESTAMP,
+		    heartbeat_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status = 'pending' AND run_after <= CURRENT_TIMESTAMP AND kind IN (`+placeholders+`)
+			  AND (? = 0 OR batch_id = ?)
+			ORDER BY run_after, id
+			LIMIT 1
+		) AND status = 'pending'
+		RETURNING `+jobColumns, args...))
+}
+
+// FinishJob records the outcome of a running job. Jobs cancelled while
+// running keep their cancelled status.
+func FinishJob(id int64, status models.JobStatus, lastError string) error {
+	_, err := db.DB.Exec(`
+		UPDATE jobs SET status = ?, last_error = ?, finished_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status = 'running'
+	`, status, lastError, id)
+	return err
+}
+
+// RetryJobLater puts a running job back in the queue after delay
+func RetryJobLater(id int64, lastError string, delay time.Duration) error {
+	_, err := db.DB.Exec(`
+		UPDATE jobs SET status = 'pending', last_error = ?, run_after = datetime('now', ?)
+		WHERE id = ? AND status = 'running'
+	`, lastError, fmt.Sprintf("+%d seconds", int(delay.Seconds())), id)
+	return err
+}
+
+// RequeueJob returns a running job that was interrupted, not failed, to
+// the queue
+func RequeueJob(id int64) error {
+	_, err := db.DB.Exec(`UPDATE jobs SET status = 'pending' WHERE id = ? AND status = 'running'`, id)
+	return err
+}
+
+// TouchJobs records that the running jobs ids are still being worked on
+func TouchJobs(ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	_, err := db.DB.Exec(`
+		UPDATE jobs SET heartbeat_at = CURRENT_TIMESTAMP
+		WHERE status = 'running' AND id IN (`+placeholders+`)
+	`, args...)
+	return err
+}
+
+// ResetStaleJobs returns running jobs without a heartbeat for staleAfter to
+// the queue: the process running them crashed or was stopped. Jobs other
+// processes are still running keep their heartbeat fresh and stay put.
+func ResetStaleJobs(staleAfter time.Duration) (int64, error) {
+	result, err := db.DB.Exec(`
+		UPDATE jobs SET status = 'pending'
+		WHERE status = 'running'
+		  AND COALESCE(heartbeat_at, started_at, created_at) <= datetime('now', ?)
+	`, fmt.Sprintf("-%d seconds", int(staleAfter.Seconds())))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// CancelJob cancels an unfinished job the user can see
+func CancelJob(id, userID int64) (bool, error) {
+	result, err := db.DB.Exec(`
+		UPDATE jobs SET status = 'cancelled', finished_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status IN ('pending', 'running') AND (user_id IS NULL OR user_id = ?)
+	`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// CancelJobBatch cancels the unfinished jobs of a batch the user can see
+// and returns their IDs
+func CancelJobBatch(batchID, userID int64) ([]int64, error) {
+	rows, err := db.DB.Query(`
+		UPDATE jobs SET status = 'cancelled', finished_at = CURRENT_TIMESTAMP
+		WHERE batch_id = ? AND status IN ('pending', 'running') AND (user_id IS NULL OR user_id = ?)
+		RETURNING id
+	`, batchID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// RetryFailedJobs queues the failed jobs of a batch the user can see again
+func RetryFailedJobs(batchID, userID int64) (int64, error) {
+	result, err := db.DB.Exec(`
+		UPDATE jobs
+		SET status = 'pending', attempts = 0, run_after = CURRENT_TIMESTAMP, finished_at = NULL
+		WHERE batch_id = ? AND status = 'failed' AND (user_id IS NULL OR user_id = ?)
+	`, batchID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetJob retrieves a job by ID
+func GetJob(id int64) (*models.Job, error) {
+	return scanJob(db.DB.QueryRow(`SELECT `+jobColumns+` FROM jobs WHERE id = ?`, id))
+}
+
+// ListRecentJobs returns the user's most recent jobs, including those
+// enqueued from the CLI
+func ListRecentJobs(userID int64, limit int) ([]models.Job, error) {
+	rows, err := db.DB.Query(`
+		SELECT `+jobColumns+` FROM jobs
+		WHERE user_id IS NULL OR user_id = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []models.Job
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *j)
+	}
+	return jobs, rows.Err()
+}
+
+const jobBatchQuery = `
+	SELECT b.id, b.name, b.user_id, b.created_at,
+	       COUNT(j.id),
+	       COALESCE(SUM(j.status = 'pending'), 0),
+	       COALESCE(SUM(j.status = 'running'), 0),
+	       COALESCE(SUM(j.status = 'done'), 0),
+	       COALESCE(SUM(j.status = 'failed'), 0),
+	       COALESCE(SUM(j.status = 'cancelled'), 0)
+	FROM job_batches b
+	LEFT JOIN jobs j ON j.batch_id = b.id`
+
+func scanJobBatch(row interface{ Scan(...any) error }) (*models.JobBatch, error) {
+	var b models.JobBatch
+	err := row.Scan(&b.ID, &b.Name, &b.UserID, &b.CreatedAt,
+		&b.Total, &b.Pending, &b.Running, &b.Done, &b.Failed, &b.Cancelled)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// GetJobBatch retrieves a batch with its job counts
+func GetJobBatch(id int64) (*models.JobBatch, error) {
+	return scanJobBatch(db.DB.QueryRow(jobBatchQuery+`
+		WHERE b.id = ?
+		GROUP BY b.id
+	`, id))
+}
+
+// ListJobBatches returns the user's most recent batches with their job
+// counts, including those started from the CLI
+func ListJobBatches(userID int64, limit int) ([]models.JobBatch, error) {
+	rows, err := db.DB.Query(jobBatchQuery+`
+		WHERE b.user_id IS NULL OR b.user_id = ?
+		GROUP BY b.id
+		ORDER BY b.id DESC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batches []models.JobBatch
+	for rows.Next() {
+		b, err := scanJobBatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, *b)
+	}
+	return batches, rows.Err()
+}
@@ -12,39 +12,42 @@ import (
 func GetJourney(userID int64) (*models.CurriculumJourney, error) {
 	j := &models.CurriculumJourney{}
 	err := db.DB.QueryRow(`
-		SELECT id, user_id, start_date, is_active, created_at
+		SELECT id, user_id, curriculum_id, start_date, is_active, created_at
 		FROM curriculum_journey
 		WHERE user_id = ? AND is_active = 1
-	`, userID).Scan(&j.ID, &j.UserID, &j.StartDate, &j.IsActive, &j.CreatedAt)
+	`, userID).Scan(&j.ID, &j.UserID, &j.CurriculumID, &j.StartDate, &j.IsActive, &j.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
 	return j, nil
 }
 
-// CreateJourney creates a new journey starting today
-func CreateJourney(userID int64) (*models.CurriculumJourney, error) {
+// CreateJourney creates a new journey starting today, replacing any earlier
+// one. A NULL curriculumID follows the default curriculum.
+func CreateJourney(userID int64, curriculumID sql.NullInt64) (*models.CurriculumJourney, error) {
 	now := time.Now()
 	startDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 
 	result, err := db.DB.Exec(`
-		INSERT INTO curriculum_journey (user_id, start_date, is_active)
-		VALUES (?, ?, 1)
+		INSERT INTO curriculum_journey (user_id, curriculum_id, start_date, is_active)
+		VALUES (?, ?, ?, 1)
 		ON CONFLICT(user_id) DO UPDATE SET
+			curriculum_id = excluded.curriculum_id,
 			start_date = excluded.start_date,
 			is_active = 1
-	`, userID, startDate)
+	`, userID, curriculumID, startDate)
 	if err != nil {
 		return nil, err
 	}
 
 	id, _ := result.LastInsertId()
 	return &models.CurriculumJourney{
-		ID:        id,
-		UserID:    userID,
-		StartDate: startDate,
-		IsActive:  true,
-		CreatedAt: now,
+		ID:           id,
+		UserID:       userID,
+		CurriculumID: curriculumID,
+		StartDate:    startDate,
+		IsActive:     true,
+		CreatedAt:    now,
 	}, nil
 }
 
@@ -52,7 +55,7 @@ func CreateJourney(userID int64) (*models.CurriculumJourney, error) {
 func GetOrCreateJourney(userID int64) (*models.CurriculumJourney, error) {
 	journey, err := GetJourney(userID)
 	if err == sql.ErrNoRows {
-		return CreateJourney(userID)
+		return CreateJourney(userID, sql.NullInt64{})
 	}
 	return journey, err
 }
@@ -135,7 +138,8 @@ func IsTodayLessonComplete(userID int64) (bool, *models.LessonSession, error) {
 	return session.CompletedAt.Valid, session, nil
 }
 
-// GetNewCardsFromIslands retrieves new cards from specific islands
+// GetNewCardsFromIslands retrieves new cards from specific islands, out of
+// the shared cards and the user's own
 func GetNewCardsFromIslands(userID int64, islandIDs []int64, limit int) ([]models.CardWithProgress, error) {
 	if len(islandIDs) == 0 {
 		return nil, nil
@@ -149,9 +153,10 @@ func GetNewCardsFromIslands(userID int64, islandIDs []int64, limit int) ([]model
 		FROM cards c
 		LEFT JOIN card_progress p ON c.id = p.card_id AND p.user_id = ?
 		WHERE (p.id IS NULL OR p.state = 'new')
+		  AND (c.user_id IS NULL OR c.user_id = ?) AND ` + wordCard + `
 		  AND c.island_id IN (`
 
-	args := []interface{}{userID}
+	args := []interface{}{userID, userID}
 	for i, id := range islandIDs {
 		if i > 0 {
 			query += ","
@@ -0,0 +1,428 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"languagepapi/internal/db"
+	"languagepapi/internal/models"
+)
+
+// =============================================
+// Daily lesson state
+// =============================================
+
+// SaveLessonCards stores the card order and modes of a daily lesson,
+// replacing any cards previously stored for the session
+func SaveLessonCards(sessionID int64, cards []models.LessonCard) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM lesson_session_cards WHERE session_id = ?`, sessionID); err != nil {
+		return err
+	}
+
+	for i, c := range cards {
+		_, err := tx.Exec(`
+			INSERT INTO lesson_session_cards (session_id, position, card_id, mode, is_new, is_song_vocab, song_title)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, sessionID, i, c.ID, c.Mode, boolToInt(c.IsNew), boolToInt(c.IsSongVocab), c.SongTitle)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadLessonCards loads a persisted daily lesson in order, with the user's
+// current card progress. Fills Lesson.Cards, Position, Results and XPEarned.
+func LoadLessonCards(userID int64, active *models.ActiveLesson) error {
+	rows, err := db.DB.Query(`
+		SELECT lc.mode, lc.is_new, lc.is_song_vocab, COALESCE(lc.song_title, ''),
+		       lc.rating, lc.time_spent_ms, lc.xp_earned, lc.answered_at,
+		       c.id, c.island_id, c.term, c.translation,
+		       COALESCE(c.example_sentence, ''), COALESCE(c.notes, ''), COALESCE(c.audio_url, ''),
+		       c.frequency_rank, COALESCE(c.source, 'curriculum'), c.source_song_id, c.created_at,
+		       p.id, p.user_id, p.card_id, p.stability, p.difficulty, p.elapsed_days, p.scheduled_days,
+		       p.reps, p.lapses, p.state, p.due, p.last_review
+		FROM lesson_session_cards lc
+		JOIN cards c ON c.id = lc.card_id
+		LEFT JOIN card_progress p ON p.card_id = c.id AND p.user_id = ?
+		WHERE lc.session_id = ?
+		ORDER BY lc.position ASC
+	`, userID, active.Session.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	active.Lesson.Cards = nil
+	active.Results = nil
+	active.Position = 0
+	active.XPEarned = 0
+
+	for rows.Next() {
+		var lc models.LessonCard
+		var isNew, isSongVocab int
+		var rating sql.NullInt64
+		var timeSpent, xp int64
+		var answeredAt sql.NullTime
+		var pID, pUserID, pCardID sql.NullInt64
+		var stability, difficulty sql.NullFloat64
+		var elapsed, scheduled, reps, lapses sql.NullInt64
+		var state sql.NullString
+		var due, lastReview sql.NullTime
+
+		if err := rows.Scan(
+			&lc.Mode, &isNew, &isSongVocab, &lc.SongTitle,
+			&rating, &timeSpent, &xp, &answeredAt,
+			&lc.ID, &lc.IslandID, &lc.Term, &lc.Translation,
+			&lc.ExampleSentence, &lc.Notes, &lc.AudioURL,
+			&lc.FrequencyRank, &lc.Source, &lc.SourceSongID, &lc.CreatedAt,
+			&pID, &pUserID, &pCardID, &stability, &difficulty, &elapsed, &scheduled,
+			&reps, &lapses, &state, &due, &lastReview,
+		); err != nil {
+			return err
+		}
+		lc.IsNew = isNew == 1
+		lc.IsSongVocab = isSongVocab == 1
+
+		if pID.Valid {
+			lc.Progress = &models.CardProgress{
+				ID:            pID.Int64,
+				UserID:        pUserID.Int64,
+				CardID:        pCardID.Int64,
+				Stability:     stability.Float64,
+				Difficulty:    difficulty.Float64,
+				ElapsedDays:   int(elapsed.Int64),
+				ScheduledDays: int(scheduled.Int64),
+				Reps:          int(reps.Int64),
+				Lapses:        int(lapses.Int64),
+				State:         models.CardState(state.String),
+				Due:           due,
+				LastReview:    lastReview,
+			}
+		}
+
+		if answeredAt.Valid {
+			active.Position = len(active.Lesson.Cards) + 1
+			if rating.Valid {
+				r := models.Rating(rating.Int64)
+				active.Results = append(active.Results, models.CardResult{
+					CardID:      lc.ID,
+					Term:        lc.Term,
+					Translation: lc.Translation,
+					Rating:      r,
+					TimeSpentMs: timeSpent,
+					Mode:        lc.Mode,
+					WasCorrect:  r >= models.RatingGood,
+					IsNew:       lc.IsNew,
+				})
+				active.XPEarned += int(xp)
+			}
+		}
+
+		active.Lesson.Cards = append(active.Lesson.Cards, lc)
+	}
+	return rows.Err()
+}
+
+// recordLessonCardResult stores the answer for the card at a position; see
+// SaveReview. Positions are indexes into the cards returned by
+// LoadLessonCards.
+func recordLessonCardResult(q execer, sessionID int64, position int, rating models.Rating, timeSpentMs int64, xp int) error {
+	_, err := q.Exec(`
+		UPDATE lesson_session_cards
+		SET rating = ?, time_spent_ms = ?, xp_earned = ?, answered_at = ?
+		WHERE id = (
+			SELECT id FROM lesson_session_cards WHERE session_id = ?
+			ORDER BY position LIMIT 1 OFFSET ?
+		)
+	`, rating, timeSpentMs, xp, time.Now().Format("2006-01-02 15:04:05"), sessionID, position)
+	return err
+}
+
+// SkipLessonCard marks the card at a position as passed without an answer
+func SkipLessonCard(sessionID int64, position int) error {
+	_, err := db.DB.Exec(`
+		UPDATE lesson_session_cards
+		SET answered_at = ?
+		WHERE id = (
+			SELECT id FROM lesson_session_cards WHERE session_id = ?
+			ORDER BY position LIMIT 1 OFFSET ?
+		)
+	`, time.Now().Format("2006-01-02 15:04:05"), sessionID, position)
+	return err
+}
+
+// =============================================
+// Song lesson state
+// =============================================
+
+// GetActiveSongSession returns today's unfinished session for a song, if any
+func GetActiveSongSession(userID, songID int64) (*models.SongSession, error) {
+	today := time.Now().Format("2006-01-02")
+	s := &models.SongSession{}
+	var phase, lineIDs sql.NullString
+	err := db.DB.QueryRow(`
+		SELECT id, user_id, song_id, session_date, mode,
+		       vocab_reviewed, vocab_correct, lines_studied, blanks_correct, blanks_total, xp_earned,
+		       current_phase, COALESCE(current_index, 0), line_ids,
+		       COALESCE(dictation_correct, 0), COALESCE(dictation_total, 0), completed_at, created_at
+		FROM song_sessions
+		WHERE user_id = ? AND song_id = ? AND session_date = ? AND completed_at IS NULL
+		ORDER BY id DESC
+		LIMIT 1
+	`, userID, songID, today).Scan(
+		&s.ID, &s.UserID, &s.SongID, &s.SessionDate, &s.Mode,
+		&s.VocabReviewed, &s.VocabCorrect, &s.LinesStudied, &s.BlanksCorrect, &s.BlanksTotal, &s.XPEarned,
+		&phase, &s.CurrentIndex, &lineIDs,
+		&s.DictationCorrect, &s.DictationTotal, &s.CompletedAt, &s.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.CurrentPhase = models.SongPhase(phase.String)
+	if lineIDs.Valid {
+		if err := json.Unmarshal([]byte(lineIDs.String), &s.LineIDs); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// UpdateSongSessionPosition stores the current phase and index of a song lesson
+func UpdateSongSessionPosition(sessionID int64, phase models.SongPhase, index int) error {
+	_, err := db.DB.Exec(`
+		UPDATE song_sessions SET current_phase = ?, current_index = ? WHERE id = ?
+	`, phase, index, sessionID)
+	return err
+}
+
+// IncrementSongLinesStudied counts one more studied line for a session
+func IncrementSongLinesStudied(sessionID int64) error {
+	_, err := db.DB.Exec(`
+		UPDATE song_sessions SET lines_studied = lines_studied + 1 WHERE id = ?
+	`, sessionID)
+	return err
+}
+
+// SaveSongSessionItems stores the vocab cards and blanks chosen for a song lesson
+func SaveSongSessionItems(sessionID int64, vocab []models.SongVocabCard, blanks []models.SongBlank) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, v := range vocab {
+		_, err := tx.Exec(`
+			INSERT INTO song_session_items (session_id, item_type, position, vocab_id, mode)
+			VALUES (?, 'vocab', ?, ?, ?)
+		`, sessionID, i, v.ID, v.Mode)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, b := range blanks {
+		var choices sql.NullString
+		if len(b.Choices) > 0 {
+			data, err := json.Marshal(b.Choices)
+			if err != nil {
+				return err
+			}
+			choices = sql.NullString{String: string(data), Valid: true}
+		}
+		_, err := tx.Exec(`
+			INSERT INTO song_session_items (session_id, item_type, position, line_id, blank_word, blank_index, choices)
+			VALUES (?, 'blank', ?, ?, ?, ?, ?)
+		`, sessionID, i, b.LineID, b.BlankWord, b.BlankIndex, choices)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadSongSessionItems rebuilds the vocab cards and blanks of a song lesson,
+// resolving them against the song's vocabulary and lines
+func LoadSongSessionItems(sessionID int64, song *models.Song) ([]models.SongVocabCard, []models.SongBlank, error) {
+	rows, err := db.DB.Query(`
+		SELECT item_type, vocab_id, COALESCE(mode, 'standard'), line_id,
+		       COALESCE(blank_word, ''), COALESCE(blank_index, 0), choices,
+		       rating, COALESCE(user_answer, ''), is_correct
+		FROM song_session_items
+		WHERE session_id = ?
+		ORDER BY item_type, position ASC
+	`, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	vocabByID := make(map[int64]models.SongVocab)
+	for _, v := range song.Vocabulary {
+		vocabByID[v.ID] = v
+	}
+	lineByID := make(map[int64]*models.SongLine)
+	for i := range song.Lines {
+		lineByID[song.Lines[i].ID] = &song.Lines[i]
+	}
+
+	var vocab []models.SongVocabCard
+	var blanks []models.SongBlank
+	for rows.Next() {
+		var itemType, mode, blankWord, userAnswer string
+		var vocabID, lineID, rating sql.NullInt64
+		var choices sql.NullString
+		var blankIndex, isCorrect int
+		if err := rows.Scan(
+			&itemType, &vocabID, &mode, &lineID,
+			&blankWord, &blankIndex, &choices,
+			&rating, &userAnswer, &isCorrect,
+		); err != nil {
+			return nil, nil, err
+		}
+
+		switch itemType {
+		case "vocab":
+			v, ok := vocabByID[vocabID.Int64]
+			if !ok {
+				continue
+			}
+			vocab = append(vocab, models.SongVocabCard{
+				SongVocab: v,
+				Mode:      mode,
+				Rating:    models.Rating(rating.Int64),
+			})
+		case "blank":
+			line, ok := lineByID[lineID.Int64]
+			if !ok {
+				continue
+			}
+			blank := models.SongBlank{
+				LineID:     line.ID,
+				Line:       line,
+				BlankWord:  blankWord,
+				BlankIndex: blankIndex,
+				UserAnswer: userAnswer,
+				IsCorrect:  isCorrect == 1,
+			}
+			if choices.Valid {
+				if err := json.Unmarshal([]byte(choices.String), &blank.Choices); err != nil {
+					return nil, nil, err
+				}
+			}
+			blanks = append(blanks, blank)
+		}
+	}
+	return vocab, blanks, rows.Err()
+}
+
+// RecordSongVocabRating stores the rating given to a song vocab card
+func RecordSongVocabRating(sessionID int64, position int, rating models.Rating) error {
+	_, err := db.DB.Exec(`
+		UPDATE song_session_items
+		SET rating = ?, is_correct = ?, answered_at = ?
+		WHERE id = (
+			SELECT id FROM song_session_items WHERE session_id = ? AND item_type = 'vocab'
+			ORDER BY position LIMIT 1 OFFSET ?
+		)
+	`, rating, boolToInt(rating >= models.RatingGood), time.Now().Format("2006-01-02 15:04:05"), sessionID, position)
+	return err
+}
+
+// RecordSongBlankAnswer stores the answer given for a song blank
+func RecordSongBlankAnswer(sessionID int64, position int, answer string, isCorrect bool) error {
+	_, err := db.DB.Exec(`
+		UPDATE song_session_items
+		SET user_answer = ?, is_correct = ?, answered_at = ?
+		WHERE id = (
+			SELECT id FROM song_session_items WHERE session_id = ? AND item_type = 'blank'
+			ORDER BY position LIMIT 1 OFFSET ?
+		)
+	`, answer, boolToInt(isCorrect), time.Now().Format("2006-01-02 15:04:05"), sessionID, position)
+	return err
+}
+
+// SaveSongSessionDictations stores the lines chosen for a dictation lesson
+func SaveSongSessionDictations(sessionID int64, dictations []models.SongDictation) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, d := range dictations {
+		_, err := tx.Exec(`
+			INSERT INTO song_session_dictations (session_id, position, line_id)
+			VALUES (?, ?, ?)
+		`, sessionID, i, d.LineID)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadSongSessionDictations rebuilds the lines of a dictation lesson,
+// resolving them against the song's lines
+func LoadSongSessionDictations(sessionID int64, song *models.Song) ([]models.SongDictation, error) {
+	rows, err := db.DB.Query(`
+		SELECT line_id, COALESCE(user_answer, ''), words_correct, words_total, answered_at IS NOT NULL
+		FROM song_session_dictations
+		WHERE session_id = ?
+		ORDER BY position ASC
+	`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lineByID := make(map[int64]*models.SongLine)
+	for i := range song.Lines {
+		lineByID[song.Lines[i].ID] = &song.Lines[i]
+	}
+
+	var dictations []models.SongDictation
+	for rows.Next() {
+		var d models.SongDictation
+		if err := rows.Scan(&d.LineID, &d.UserAnswer, &d.WordsCorrect, &d.WordsTotal, &d.Answered); err != nil {
+			return nil, err
+		}
+		line, ok := lineByID[d.LineID]
+		if !ok {
+			continue
+		}
+		d.Line = line
+		dictations = append(dictations, d)
+	}
+	return dictations, rows.Err()
+}
+
+// RecordSongDictationAnswer stores what was typed for a dictated line and
+// how many of its words were right
+func RecordSongDictationAnswer(sessionID int64, position int, answer string, wordsCorrect, wordsTotal int) error {
+	_, err := db.DB.Exec(`
+		UPDATE song_session_dictations
+		SET user_answer = ?, words_correct = ?, words_total = ?, answered_at = ?
+		WHERE id = (
+			SELECT id FROM song_session_dictations WHERE session_id = ?
+			ORDER BY position LIMIT 1 OFFSET ?
+		)
+	`, answer, wordsCorrect, wordsTotal, time.Now().Format("2006-01-02 15:04:05"), sessionID, position)
+	return err
+}
+
+// boolToInt converts a bool to SQLite's 0/1 representation
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
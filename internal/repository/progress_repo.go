@@ -28,7 +28,11 @@ func GetProgress(userID, cardID int64) (*models.CardProgress, error) {
 
 // UpsertProgress creates or updates card progress
 func UpsertProgress(p *models.CardProgress) error {
-	result, err := db.DB.Exec(`
+	return upsertProgress(db.DB, p)
+}
+
+func upsertProgress(q execer, p *models.CardProgress) error {
+	result, err := q.Exec(`
 		INSERT INTO card_progress (user_id, card_id, stability, difficulty, elapsed_days, scheduled_days,
 		                           reps, lapses, state, due, last_review)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
@@ -67,6 +71,7 @@ func GetDueCards(userID int64, limit int) ([]models.CardWithProgress, error) {
 		FROM cards c
 		INNER JOIN card_progress p ON c.id = p.card_id
 		WHERE p.user_id = ? AND substr(p.due, 1, 19) <= ? AND p.state IN ('learning', 'review', 'relearning')
+		  AND `+wordCard+`
 		ORDER BY p.due ASC
 		LIMIT ?
 	`, userID, now, limit)
@@ -94,7 +99,8 @@ func GetDueCards(userID int64, limit int) ([]models.CardWithProgress, error) {
 	return cards, rows.Err()
 }
 
-// GetNewCards returns cards that haven't been studied yet
+// GetNewCards returns the shared cards and the user's own cards that
+// haven't been studied yet
 func GetNewCards(userID int64, limit int) ([]models.CardWithProgress, error) {
 	// Get cards with no progress record OR with state='new'
 	rows, err := db.DB.Query(`
@@ -103,10 +109,10 @@ func GetNewCards(userID int64, limit int) ([]models.CardWithProgress, error) {
 		       c.frequency_rank, c.created_at
 		FROM cards c
 		LEFT JOIN card_progress p ON c.id = p.card_id AND p.user_id = ?
-		WHERE p.id IS NULL OR p.state = 'new'
+		WHERE (p.id IS NULL OR p.state = 'new') AND (c.user_id IS NULL OR c.user_id = ?) AND `+wordCard+`
 		ORDER BY RANDOM()
 		LIMIT ?
-	`, userID, limit)
+	`, userID, userID, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -134,34 +140,37 @@ func CountDueCards(userID int64) (int, error) {
 	var count int
 	err := db.DB.QueryRow(`
 		SELECT COUNT(*)
-		FROM card_progress
-		WHERE user_id = ? AND substr(due, 1, 19) <= ? AND state IN ('learning', 'review', 'relearning')
+		FROM card_progress p
+		JOIN cards c ON c.id = p.card_id
+		WHERE p.user_id = ? AND substr(p.due, 1, 19) <= ? AND p.state IN ('learning', 'review', 'relearning')
+		  AND `+wordCard+`
 	`, userID, now).Scan(&count)
 	return count, err
 }
 
-// CountNewCards returns the number of new cards not yet studied
+// CountNewCards returns the number of shared and own cards not yet studied
 func CountNewCards(userID int64) (int, error) {
 	var count int
 	err := db.DB.QueryRow(`
 		SELECT COUNT(*)
 		FROM cards c
 		LEFT JOIN card_progress p ON c.id = p.card_id AND p.user_id = ?
-		WHERE p.id IS NULL OR p.state = 'new'
-	`, userID).Scan(&count)
+		WHERE (p.id IS NULL OR p.state = 'new') AND (c.user_id IS NULL OR c.user_id = ?) AND `+wordCard+`
+	`, userID, userID).Scan(&count)
 	return count, err
 }
 
 // GetCardProgressStats returns stats for a user
 func GetCardProgressStats(userID int64) (total, learned, due, mastered int, err error) {
 	// Total cards
-	if err = db.DB.QueryRow(`SELECT COUNT(*) FROM cards`).Scan(&total); err != nil {
+	if total, err = CountCards(userID); err != nil {
 		return
 	}
 
 	// Learned (has progress, not new)
 	if err = db.DB.QueryRow(`
-		SELECT COUNT(*) FROM card_progress WHERE user_id = ? AND state != 'new'
+		SELECT COUNT(*) FROM card_progress p JOIN cards c ON c.id = p.card_id
+		WHERE p.user_id = ? AND p.state != 'new' AND `+wordCard+`
 	`, userID).Scan(&learned); err != nil {
 		return
 	}
@@ -169,16 +178,17 @@ func GetCardProgressStats(userID int64) (total, learned, due, mastered int, err
 	// Due
 	now := time.Now().Format("2006-01-02 15:04:05")
 	if err = db.DB.QueryRow(`
-		SELECT COUNT(*) FROM card_progress
-		WHERE user_id = ? AND substr(due, 1, 19) <= ? AND state IN ('learning', 'review', 'relearning')
+		SELECT COUNT(*) FROM card_progress p JOIN cards c ON c.id = p.card_id
+		WHERE p.user_id = ? AND substr(p.due, 1, 19) <= ? AND p.state IN ('learning', 'review', 'relearning')
+		  AND `+wordCard+`
 	`, userID, now).Scan(&due); err != nil {
 		return
 	}
 
 	// Mastered (stability > 30 days, high reps)
 	if err = db.DB.QueryRow(`
-		SELECT COUNT(*) FROM card_progress
-		WHERE user_id = ? AND stability > 30 AND reps >= 5
+		SELECT COUNT(*) FROM card_progress p JOIN cards c ON c.id = p.card_id
+		WHERE p.user_id = ? AND p.stability > 30 AND p.reps >= 5 AND `+wordCard+`
 	`, userID).Scan(&mastered); err != nil {
 		return
 	}
@@ -242,7 +252,7 @@ func GetWordsByState(userID int64, state models.CardState, limit int) ([]models.
 		       p.reps, p.lapses, p.state, p.due, p.last_review
 		FROM cards c
 		INNER JOIN card_progress p ON c.id = p.card_id
-		WHERE p.user_id = ? AND p.state = ?
+		WHERE p.user_id = ? AND p.state = ? AND `+wordCard+`
 		ORDER BY p.last_review DESC
 		LIMIT ?
 	`, userID, state, limit)
@@ -280,7 +290,7 @@ func GetRecentlyLearnedWords(userID int64, limit int) ([]models.CardWithProgress
 		       p.reps, p.lapses, p.state, p.due, p.last_review
 		FROM cards c
 		INNER JOIN card_progress p ON c.id = p.card_id
-		WHERE p.user_id = ? AND p.state != 'new'
+		WHERE p.user_id = ? AND p.state != 'new' AND `+wordCard+`
 		ORDER BY p.last_review DESC
 		LIMIT ?
 	`, userID, limit)
@@ -312,8 +322,8 @@ func GetRecentlyLearnedWords(userID int64, limit int) ([]models.CardWithProgress
 func GetProgressOverviewStats(userID int64) (*models.ProgressOverviewStats, error) {
 	stats := &models.ProgressOverviewStats{}
 
-	// Total cards in system
-	db.DB.QueryRow(`SELECT COUNT(*) FROM cards`).Scan(&stats.TotalCards)
+	// Cards the user can study
+	stats.TotalCards, _ = CountCards(userID)
 
 	// Cards by state
 	db.DB.QueryRow(`
@@ -376,3 +386,27 @@ func GetRecentLessonSessions(userID int64, limit int) ([]models.LessonSession, e
 	}
 	return sessions, rows.Err()
 }
+
+// GetMasteredTerms returns the lowercased terms of the cards a user has
+// mastered, by the same measure as GetCardProgressStats
+func GetMasteredTerms(userID int64) (map[string]bool, error) {
+	rows, err := db.DB.Query(`
+		SELECT DISTINCT LOWER(TRIM(c.term)) FROM card_progress p
+		JOIN cards c ON c.id = p.card_id
+		WHERE p.user_id = ? AND p.stability > 30 AND p.reps >= 5
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	terms := make(map[string]bool)
+	for rows.Next() {
+		var term string
+		if err := rows.Scan(&term); err != nil {
+			return nil, err
+		}
+		terms[term] = true
+	}
+	return terms, rows.Err()
+}
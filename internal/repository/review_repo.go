@@ -5,9 +5,71 @@ import (
 	"languagepapi/internal/models"
 )
 
+// LessonAnswer places a review in a lesson: the card at Position of the
+// lesson session
+type LessonAnswer struct {
+	SessionID int64
+	Position  int
+}
+
+// ReviewSave is everything one review writes
+type ReviewSave struct {
+	Progress *models.CardProgress
+	Log      *models.ReviewLog
+	XP       int
+	Correct  bool
+	New      bool
+	Lesson   *LessonAnswer // nil for a review outside a lesson
+}
+
+// SaveReview writes a review in one transaction: the card's new schedule,
+// the review log, the user's XP, streak and daily stats, and the answer in
+// the lesson if it was given in one. A failure leaves none of them behind.
+func SaveReview(rev *ReviewSave) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	userID := rev.Log.UserID
+	if err := upsertProgress(tx, rev.Progress); err != nil {
+		return err
+	}
+	if err := logReview(tx, rev.Log); err != nil {
+		return err
+	}
+	if err := updateUserXP(tx, userID, rev.XP); err != nil {
+		return err
+	}
+	if err := updateStreak(tx, userID); err != nil {
+		return err
+	}
+	correct, newCards := 0, 0
+	if rev.Correct {
+		correct = 1
+	}
+	if rev.New {
+		newCards = 1
+	}
+	if err := incrementDailyStats(tx, userID, rev.XP, 1, correct, newCards); err != nil {
+		return err
+	}
+	if l := rev.Lesson; l != nil {
+		if err := recordLessonCardResult(tx, l.SessionID, l.Position, rev.Log.Rating, int64(rev.Log.ReviewDurationMs), rev.XP); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
 // LogReview records a review event
 func LogReview(log *models.ReviewLog) error {
-	result, err := db.DB.Exec(`
+	return logReview(db.DB, log)
+}
+
+func logReview(q execer, log *models.ReviewLog) error {
+	result, err := q.Exec(`
 		INSERT INTO review_logs (user_id, card_id, rating, elapsed_days, scheduled_days, review_duration_ms)
 		VALUES (?, ?, ?, ?, ?, ?)
 	`, log.UserID, log.CardID, log.Rating, log.ElapsedDays, log.ScheduledDays, log.ReviewDurationMs)
@@ -63,3 +125,47 @@ func GetTodayReviewCount(userID int64) (int, error) {
 	`, userID).Scan(&count)
 	return count, err
 }
+
+// GetReviewLogsForOptimizer retrieves a user's full review history ordered
+// per card by review time, for fitting FSRS weights
+func GetReviewLogsForOptimizer(userID int64) ([]models.ReviewLog, error) {
+	rows, err := db.DB.Query(`
+		SELECT id, user_id, card_id, rating, COALESCE(elapsed_days, 0), COALESCE(scheduled_days, 0),
+		       reviewed_at, COALESCE(review_duration_ms, 0)
+		FROM review_logs
+		WHERE user_id = ?
+		ORDER BY card_id, reviewed_at, id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []models.ReviewLog
+	for rows.Next() {
+		var l models.ReviewLog
+		if err := rows.Scan(
+			&l.ID, &l.UserID, &l.CardID, &l.Rating,
+			&l.ElapsedDays, &l.ScheduledDays, &l.ReviewedAt, &l.ReviewDurationMs,
+		); err != nil {
+			return nil, err
+		}
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
+
+// insertReviewLog records a review that happened at log.ReviewedAt, such
+// as one carried over from another app
+func insertReviewLog(q execer, log *models.ReviewLog) error {
+	result, err := q.Exec(`
+		INSERT INTO review_logs (user_id, card_id, rating, elapsed_days, scheduled_days, reviewed_at, review_duration_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, log.UserID, log.CardID, log.Rating, log.ElapsedDays, log.ScheduledDays,
+		log.ReviewedAt.UTC().Format("2006-01-02 15:04:05"), log.ReviewDurationMs)
+	if err != nil {
+		return err
+	}
+	log.ID, err = result.LastInsertId()
+	return err
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"time"
+
+	"languagepapi/internal/db"
+)
+
+// CreateUserSession stores a login session token for a user
+func CreateUserSession(token string, userID int64, expiresAt time.Time) error {
+	_, err := db.DB.Exec(`
+		INSERT INTO user_sessions (token, user_id, expires_at)
+		VALUES (?, ?, ?)
+	`, token, userID, expiresAt.Format("2006-01-02 15:04:05"))
+	return err
+}
+
+// GetUserIDForSession returns the user owning an unexpired session token
+func GetUserIDForSession(token string) (int64, error) {
+	var userID int64
+	now := time.Now().Format("2006-01-02 15:04:05")
+	err := db.DB.QueryRow(`
+		SELECT user_id FROM user_sessions
+		WHERE token = ? AND substr(expires_at, 1, 19) > ?
+	`, token, now).Scan(&userID)
+	return userID, err
+}
+
+// DeleteUserSession removes a session token (logout)
+func DeleteUserSession(token string) error {
+	_, err := db.DB.Exec(`DELETE FROM user_sessions WHERE token = ?`, token)
+	return err
+}
+
+// DeleteExpiredSessions removes all expired session tokens
+func DeleteExpiredSessions() error {
+	now := time.Now().Format("2006-01-02 15:04:05")
+	_, err := db.DB.Exec(`
+		DELETE FROM user_sessions WHERE substr(expires_at, 1, 19) <= ?
+	`, now)
+	return err
+}
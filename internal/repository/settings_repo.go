@@ -6,14 +6,18 @@ import (
 	"languagepapi/internal/db"
 )
 
-// UserSettings represents user preferences
+// UserSettings represents user preferences. DefaultMode and NewCardsPerDay
+// are optional: "" and 0 mean the user never set them, so the curriculum
+// phase's own apply. Settings saved before they were optional hold the
+// user's explicit values and keep overriding the phase.
 type UserSettings struct {
 	DailyGoal         int    `json:"daily_goal"`
 	EnableTTS         bool   `json:"enable_tts"`
 	ShowBridges       bool   `json:"show_bridges"`
-	DefaultMode       string `json:"default_mode"`
-	NewCardsPerDay    int    `json:"new_cards_per_day"`
+	DefaultMode       string `json:"default_mode"`      // "" keeps the curriculum phase's mode mix
+	NewCardsPerDay    int    `json:"new_cards_per_day"` // 0 keeps the curriculum phase's quota
 	ReviewsPerSession int    `json:"reviews_per_session"`
+	DrillVosotros     bool   `json:"drill_vosotros"` // Ask vosotros forms in conjugation drills
 }
 
 // GetUserSettings retrieves settings for a user
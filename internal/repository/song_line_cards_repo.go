@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"languagepapi/internal/db"
+	"languagepapi/internal/models"
+)
+
+// SetSongLineCard links a song line to its sentence card
+func SetSongLineCard(lineID, cardID int64) error {
+	_, err := db.DB.Exec(`UPDATE song_lines SET card_id = ? WHERE id = ?`, cardID, lineID)
+	return err
+}
+
+// DeleteStaleSongLineCards deletes a song's line cards that no line links to
+// any more, e.g. after lines were merged, with their progress
+func DeleteStaleSongLineCards(songID int64) error {
+	_, err := db.DB.Exec(`
+		DELETE FROM cards
+		WHERE source = 'song_line' AND source_song_id = ?
+		  AND id NOT IN (SELECT card_id FROM song_lines WHERE song_id = ? AND card_id IS NOT NULL)
+	`, songID, songID)
+	return err
+}
+
+// GetDueSongLineCards fetches song line cards due for review
+func GetDueSongLineCards(userID int64, limit int) ([]models.CardWithProgress, error) {
+	return getDueCardsFromSource(userID, "song_line", limit)
+}
+
+// GetSongLineProgress returns the user's progress on the cards of a song's
+// lines, by line id. Lines never reviewed are missing.
+func GetSongLineProgress(userID, songID int64) (map[int64]*models.CardProgress, error) {
+	rows, err := db.DB.Query(`
+		SELECT l.id, p.id, p.user_id, p.card_id, p.stability, p.difficulty, p.elapsed_days,
+		       p.scheduled_days, p.reps, p.lapses, p.state, p.due, p.last_review
+		FROM song_lines l
+		JOIN card_progress p ON p.card_id = l.card_id AND p.user_id = ?
+		WHERE l.song_id = ?
+	`, userID, songID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	progress := make(map[int64]*models.CardProgress)
+	for rows.Next() {
+		var lineID int64
+		var p models.CardProgress
+		if err := rows.Scan(
+			&lineID, &p.ID, &p.UserID, &p.CardID, &p.Stability, &p.Difficulty, &p.ElapsedDays,
+			&p.ScheduledDays, &p.Reps, &p.Lapses, &p.State, &p.Due, &p.LastReview,
+		); err != nil {
+			return nil, err
+		}
+		progress[lineID] = &p
+	}
+	return progress, rows.Err()
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"languagepapi/internal/db"
@@ -14,11 +15,11 @@ func GetSong(id int64) (*models.Song, error) {
 	err := db.DB.QueryRow(`
 		SELECT id, COALESCE(youtube_id, ''), genius_id, title, artist, COALESCE(album, ''),
 		       difficulty, COALESCE(duration_seconds, 0), COALESCE(thumbnail_url, ''),
-		       COALESCE(audio_path, ''), created_at
+		       COALESCE(audio_path, ''), COALESCE(lyrics_source, ''), created_at
 		FROM songs
 		WHERE id = ?
 	`, id).Scan(&s.ID, &s.YouTubeID, &s.GeniusID, &s.Title, &s.Artist, &s.Album,
-		&s.Difficulty, &s.DurationSeconds, &s.ThumbnailURL, &s.AudioPath, &s.CreatedAt)
+		&s.Difficulty, &s.DurationSeconds, &s.ThumbnailURL, &s.AudioPath, &s.LyricsSource, &s.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -31,11 +32,11 @@ func GetSongByYouTubeID(youtubeID string) (*models.Song, error) {
 	err := db.DB.QueryRow(`
 		SELECT id, COALESCE(youtube_id, ''), genius_id, title, artist, COALESCE(album, ''),
 		       difficulty, COALESCE(duration_seconds, 0), COALESCE(thumbnail_url, ''),
-		       COALESCE(audio_path, ''), created_at
+		       COALESCE(audio_path, ''), COALESCE(lyrics_source, ''), created_at
 		FROM songs
 		WHERE youtube_id = ?
 	`, youtubeID).Scan(&s.ID, &s.YouTubeID, &s.GeniusID, &s.Title, &s.Artist, &s.Album,
-		&s.Difficulty, &s.DurationSeconds, &s.ThumbnailURL, &s.AudioPath, &s.CreatedAt)
+		&s.Difficulty, &s.DurationSeconds, &s.ThumbnailURL, &s.AudioPath, &s.LyricsSource, &s.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -56,6 +57,32 @@ func GetSongWithDetails(id int64) (*models.Song, error) {
 	}
 	song.Lines = lines
 
+	// Attach the words linked to cards
+	words, err := GetSongLineWords(id)
+	if err != nil {
+		return nil, err
+	}
+	byLine := make(map[int64][]models.SongLineWord)
+	for _, w := range words {
+		byLine[w.LineID] = append(byLine[w.LineID], w)
+	}
+	for i := range song.Lines {
+		song.Lines[i].Words = byLine[song.Lines[i].ID]
+	}
+
+	// ...and the word timings
+	timings, err := GetSongWordTimings(id)
+	if err != nil {
+		return nil, err
+	}
+	timingsByLine := make(map[int64][]models.SongWordTiming)
+	for _, t := range timings {
+		timingsByLine[t.LineID] = append(timingsByLine[t.LineID], t)
+	}
+	for i := range song.Lines {
+		song.Lines[i].Timings = timingsByLine[song.Lines[i].ID]
+	}
+
 	// Get vocabulary
 	vocab, err := GetSongVocabulary(id, false)
 	if err != nil {
@@ -113,7 +140,7 @@ func GetAllSongs(difficulty int) ([]models.Song, error) {
 func GetSongLines(songID int64) ([]models.SongLine, error) {
 	rows, err := db.DB.Query(`
 		SELECT id, song_id, line_number, start_time_ms, end_time_ms,
-		       spanish_text, english_text
+		       spanish_text, english_text, card_id
 		FROM song_lines
 		WHERE song_id = ?
 		ORDER BY line_number ASC
@@ -127,7 +154,7 @@ func GetSongLines(songID int64) ([]models.SongLine, error) {
 	for rows.Next() {
 		var l models.SongLine
 		if err := rows.Scan(&l.ID, &l.SongID, &l.LineNumber, &l.StartTimeMs,
-			&l.EndTimeMs, &l.SpanishText, &l.EnglishText); err != nil {
+			&l.EndTimeMs, &l.SpanishText, &l.EnglishText, &l.CardID); err != nil {
 			return nil, err
 		}
 		lines = append(lines, l)
@@ -171,14 +198,15 @@ func GetSongProgress(userID, songID int64) (*models.SongProgress, error) {
 	p := &models.SongProgress{}
 	var vocabComplete, lyricsComplete, listeningComplete int
 	err := db.DB.QueryRow(`
-		SELECT id, user_id, song_id, stability, difficulty, reps, lapses,
-		       state, due, last_review, vocab_complete, lyrics_complete,
+		SELECT id, user_id, song_id, stability, difficulty, elapsed_days,
+		       scheduled_days, reps, lapses, state, due, last_review,
+		       vocab_complete, lyrics_complete,
 		       listening_complete, total_listens
 		FROM song_progress
 		WHERE user_id = ? AND song_id = ?
 	`, userID, songID).Scan(
-		&p.ID, &p.UserID, &p.SongID, &p.Stability, &p.Difficulty,
-		&p.Reps, &p.Lapses, &p.State, &p.Due, &p.LastReview,
+		&p.ID, &p.UserID, &p.SongID, &p.Stability, &p.Difficulty, &p.ElapsedDays,
+		&p.ScheduledDays, &p.Reps, &p.Lapses, &p.State, &p.Due, &p.LastReview,
 		&vocabComplete, &lyricsComplete, &listeningComplete, &p.TotalListens,
 	)
 	if err != nil {
@@ -206,13 +234,15 @@ func UpsertSongProgress(p *models.SongProgress) error {
 	}
 
 	_, err := db.DB.Exec(`
-		INSERT INTO song_progress (user_id, song_id, stability, difficulty, reps, lapses,
-		                           state, due, last_review, vocab_complete, lyrics_complete,
-		                           listening_complete, total_listens)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO song_progress (user_id, song_id, stability, difficulty, elapsed_days,
+		                           scheduled_days, reps, lapses, state, due, last_review,
+		                           vocab_complete, lyrics_complete, listening_complete, total_listens)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(user_id, song_id) DO UPDATE SET
 			stability = excluded.stability,
 			difficulty = excluded.difficulty,
+			elapsed_days = excluded.elapsed_days,
+			scheduled_days = excluded.scheduled_days,
 			reps = excluded.reps,
 			lapses = excluded.lapses,
 			state = excluded.state,
@@ -222,7 +252,7 @@ func UpsertSongProgress(p *models.SongProgress) error {
 			lyrics_complete = excluded.lyrics_complete,
 			listening_complete = excluded.listening_complete,
 			total_listens = excluded.total_listens
-	`, p.UserID, p.SongID, p.Stability, p.Difficulty, p.Reps, p.Lapses,
+	`, p.UserID, p.SongID, p.Stability, p.Difficulty, p.ElapsedDays, p.ScheduledDays, p.Reps, p.Lapses,
 		p.State, p.Due, p.LastReview, vocabComplete, lyricsComplete,
 		listeningComplete, p.TotalListens)
 	return err
@@ -235,7 +265,8 @@ func GetDueSongs(userID int64, limit int) ([]models.SongWithProgress, error) {
 		SELECT s.id, COALESCE(s.youtube_id, ''), s.genius_id, s.title, s.artist, COALESCE(s.album, ''),
 		       s.difficulty, COALESCE(s.duration_seconds, 0), COALESCE(s.thumbnail_url, ''),
 		       COALESCE(s.audio_path, ''), s.created_at,
-		       p.id, p.stability, p.difficulty, p.reps, p.lapses, p.state,
+		       p.id, p.stability, p.difficulty, p.elapsed_days, p.scheduled_days,
+		       p.reps, p.lapses, p.state,
 		       p.due, p.last_review, p.vocab_complete, p.lyrics_complete,
 		       p.listening_complete, p.total_listens
 		FROM songs s
@@ -257,7 +288,8 @@ func GetDueSongs(userID int64, limit int) ([]models.SongWithProgress, error) {
 		if err := rows.Scan(
 			&swp.ID, &swp.YouTubeID, &swp.GeniusID, &swp.Title, &swp.Artist, &swp.Album,
 			&swp.Difficulty, &swp.DurationSeconds, &swp.ThumbnailURL, &swp.AudioPath, &swp.CreatedAt,
-			&prog.ID, &prog.Stability, &prog.Difficulty, &prog.Reps, &prog.Lapses,
+			&prog.ID, &prog.Stability, &prog.Difficulty, &prog.ElapsedDays, &prog.ScheduledDays,
+			&prog.Reps, &prog.Lapses,
 			&prog.State, &prog.Due, &prog.LastReview, &vocabComplete, &lyricsComplete,
 			&listeningComplete, &prog.TotalListens,
 		); err != nil {
@@ -280,7 +312,8 @@ func GetSongsWithProgress(userID int64) ([]models.SongWithProgress, error) {
 		SELECT s.id, COALESCE(s.youtube_id, ''), s.genius_id, s.title, s.artist, COALESCE(s.album, ''),
 		       s.difficulty, COALESCE(s.duration_seconds, 0), COALESCE(s.thumbnail_url, ''),
 		       COALESCE(s.audio_path, ''), s.created_at,
-		       p.id, p.stability, p.difficulty, p.reps, p.lapses, p.state,
+		       p.id, p.stability, p.difficulty, p.elapsed_days, p.scheduled_days,
+		       p.reps, p.lapses, p.state,
 		       p.due, p.last_review, p.vocab_complete, p.lyrics_complete,
 		       p.listening_complete, p.total_listens
 		FROM songs s
@@ -297,7 +330,7 @@ func GetSongsWithProgress(userID int64) ([]models.SongWithProgress, error) {
 		var swp models.SongWithProgress
 		var progID sql.NullInt64
 		var stability, difficulty sql.NullFloat64
-		var reps, lapses, totalListens sql.NullInt64
+		var elapsedDays, scheduledDays, reps, lapses, totalListens sql.NullInt64
 		var state sql.NullString
 		var due, lastReview sql.NullTime
 		var vocabComplete, lyricsComplete, listeningComplete sql.NullInt64
@@ -305,7 +338,7 @@ func GetSongsWithProgress(userID int64) ([]models.SongWithProgress, error) {
 		if err := rows.Scan(
 			&swp.ID, &swp.YouTubeID, &swp.GeniusID, &swp.Title, &swp.Artist, &swp.Album,
 			&swp.Difficulty, &swp.DurationSeconds, &swp.ThumbnailURL, &swp.AudioPath, &swp.CreatedAt,
-			&progID, &stability, &difficulty, &reps, &lapses,
+			&progID, &stability, &difficulty, &elapsedDays, &scheduledDays, &reps, &lapses,
 			&state, &due, &lastReview, &vocabComplete, &lyricsComplete,
 			&listeningComplete, &totalListens,
 		); err != nil {
@@ -314,16 +347,20 @@ func GetSongsWithProgress(userID int64) ([]models.SongWithProgress, error) {
 
 		if progID.Valid {
 			swp.Progress = &models.SongProgress{
-				ID:                progID.Int64,
-				UserID:            userID,
+				CardProgress: models.CardProgress{
+					ID:            progID.Int64,
+					UserID:        userID,
+					Stability:     stability.Float64,
+					Difficulty:    difficulty.Float64,
+					ElapsedDays:   int(elapsedDays.Int64),
+					ScheduledDays: int(scheduledDays.Int64),
+					Reps:          int(reps.Int64),
+					Lapses:        int(lapses.Int64),
+					State:         models.CardState(state.String),
+					Due:           due,
+					LastReview:    lastReview,
+				},
 				SongID:            swp.ID,
-				Stability:         stability.Float64,
-				Difficulty:        difficulty.Float64,
-				Reps:              int(reps.Int64),
-				Lapses:            int(lapses.Int64),
-				State:             models.CardState(state.String),
-				Due:               due,
-				LastReview:        lastReview,
 				VocabComplete:     vocabComplete.Int64 == 1,
 				LyricsComplete:    lyricsComplete.Int64 == 1,
 				ListeningComplete: listeningComplete.Int64 == 1,
@@ -338,10 +375,18 @@ func GetSongsWithProgress(userID int64) ([]models.SongWithProgress, error) {
 // CreateSongSession creates a new song lesson session
 func CreateSongSession(s *models.SongSession) error {
 	today := time.Now().Format("2006-01-02")
+	var lineIDs sql.NullString
+	if s.LineIDs != nil {
+		data, err := json.Marshal(s.LineIDs)
+		if err != nil {
+			return err
+		}
+		lineIDs = sql.NullString{String: string(data), Valid: true}
+	}
 	result, err := db.DB.Exec(`
-		INSERT INTO song_sessions (user_id, song_id, session_date, mode)
-		VALUES (?, ?, ?, ?)
-	`, s.UserID, s.SongID, today, s.Mode)
+		INSERT INTO song_sessions (user_id, song_id, session_date, mode, line_ids)
+		VALUES (?, ?, ?, ?, ?)
+	`, s.UserID, s.SongID, today, s.Mode, lineIDs)
 	if err != nil {
 		return err
 	}
@@ -351,7 +396,7 @@ func CreateSongSession(s *models.SongSession) error {
 }
 
 // UpdateSongSession updates session stats
-func UpdateSongSession(sessionID int64, vocabReviewed, vocabCorrect, linesStudied, blanksCorrect, blanksTotal, xp int) error {
+func UpdateSongSession(sessionID int64, vocabReviewed, vocabCorrect, linesStudied, blanksCorrect, blanksTotal, dictationCorrect, dictationTotal, xp int) error {
 	_, err := db.DB.Exec(`
 		UPDATE song_sessions
 		SET vocab_reviewed = vocab_reviewed + ?,
@@ -359,9 +404,11 @@ func UpdateSongSession(sessionID int64, vocabReviewed, vocabCorrect, linesStudie
 		    lines_studied = lines_studied + ?,
 		    blanks_correct = blanks_correct + ?,
 		    blanks_total = blanks_total + ?,
+		    dictation_correct = dictation_correct + ?,
+		    dictation_total = dictation_total + ?,
 		    xp_earned = xp_earned + ?
 		WHERE id = ?
-	`, vocabReviewed, vocabCorrect, linesStudied, blanksCorrect, blanksTotal, xp, sessionID)
+	`, vocabReviewed, vocabCorrect, linesStudied, blanksCorrect, blanksTotal, dictationCorrect, dictationTotal, xp, sessionID)
 	return err
 }
 
@@ -409,7 +456,7 @@ func CreateSong(song *models.Song) error {
 	return nil
 }
 
-// CreateSongLine inserts a song line
+// CreateSongLine inserts a song line and its word timings
 func CreateSongLine(line *models.SongLine) error {
 	result, err := db.DB.Exec(`
 		INSERT INTO song_lines (song_id, line_number, start_time_ms, end_time_ms, spanish_text, english_text)
@@ -420,9 +467,59 @@ func CreateSongLine(line *models.SongLine) error {
 	}
 	id, _ := result.LastInsertId()
 	line.ID = id
+
+	for i := range line.Timings {
+		t := &line.Timings[i]
+		t.LineID = id
+		result, err := db.DB.Exec(`
+			INSERT INTO song_word_timings (line_id, position, text, start_time_ms, end_time_ms)
+			VALUES (?, ?, ?, ?, ?)
+		`, t.LineID, t.Position, t.Text, t.StartTimeMs, t.EndTimeMs)
+		if err != nil {
+			return err
+		}
+		t.ID, _ = result.LastInsertId()
+	}
 	return nil
 }
 
+// GetSongWordTimings retrieves the word timings of a song's lines
+func GetSongWordTimings(songID int64) ([]models.SongWordTiming, error) {
+	rows, err := db.DB.Query(`
+		SELECT t.id, t.line_id, t.position, t.text, t.start_time_ms, t.end_time_ms
+		FROM song_word_timings t
+		JOIN song_lines l ON l.id = t.line_id
+		WHERE l.song_id = ?
+		ORDER BY l.line_number, t.position
+	`, songID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var timings []models.SongWordTiming
+	for rows.Next() {
+		var t models.SongWordTiming
+		if err := rows.Scan(&t.ID, &t.LineID, &t.Position, &t.Text, &t.StartTimeMs, &t.EndTimeMs); err != nil {
+			return nil, err
+		}
+		timings = append(timings, t)
+	}
+	return timings, rows.Err()
+}
+
+// SetSongLyricsSource records where a song's lyrics came from
+func SetSongLyricsSource(songID int64, source models.LyricsSource) error {
+	_, err := db.DB.Exec(`UPDATE songs SET lyrics_source = ? WHERE id = ?`, source, songID)
+	return err
+}
+
+// UpdateSongDuration sets the length of a song's audio
+func UpdateSongDuration(songID int64, seconds int) error {
+	_, err := db.DB.Exec(`UPDATE songs SET duration_seconds = ? WHERE id = ?`, seconds, songID)
+	return err
+}
+
 // CreateSongVocab inserts song vocabulary
 func CreateSongVocab(vocab *models.SongVocab) error {
 	isKey := 0
@@ -447,11 +544,11 @@ func GetOrCreateSongProgress(userID, songID int64) (*models.SongProgress, error)
 	if err == sql.ErrNoRows {
 		// Create initial progress
 		progress = &models.SongProgress{
-			UserID:     userID,
-			SongID:     songID,
-			State:      models.StateNew,
-			Stability:  0,
-			Difficulty: 0,
+			CardProgress: models.CardProgress{
+				UserID: userID,
+				State:  models.StateNew,
+			},
+			SongID: songID,
 		}
 		err = UpsertSongProgress(progress)
 		if err != nil {
@@ -507,11 +604,11 @@ func GetSongByAudioPath(audioPath string) (*models.Song, error) {
 	err := db.DB.QueryRow(`
 		SELECT id, COALESCE(youtube_id, ''), genius_id, title, artist, COALESCE(album, ''),
 		       difficulty, COALESCE(duration_seconds, 0), COALESCE(thumbnail_url, ''),
-		       COALESCE(audio_path, ''), created_at
+		       COALESCE(audio_path, ''), COALESCE(lyrics_source, ''), created_at
 		FROM songs
 		WHERE audio_path = ?
 	`, audioPath).Scan(&s.ID, &s.YouTubeID, &s.GeniusID, &s.Title, &s.Artist, &s.Album,
-		&s.Difficulty, &s.DurationSeconds, &s.ThumbnailURL, &s.AudioPath, &s.CreatedAt)
+		&s.Difficulty, &s.DurationSeconds, &s.ThumbnailURL, &s.AudioPath, &s.LyricsSource, &s.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -542,3 +639,57 @@ func GetUnlinkedSongVocab(songID int64) ([]models.SongVocab, error) {
 	}
 	return vocabs, rows.Err()
 }
+
+// GetSongIDsWithoutLyrics returns the songs that have no lyrics lines yet
+func GetSongIDsWithoutLyrics() ([]int64, error) {
+	return querySongIDs(`
+		SELECT s.id FROM songs s
+		WHERE NOT EXISTS (SELECT 1 FROM song_lines sl WHERE sl.song_id = s.id)
+		ORDER BY s.id
+	`)
+}
+
+// GetSongIDsWithUntranslatedLines returns the songs with lines that have no
+// English translation
+func GetSongIDsWithUntranslatedLines() ([]int64, error) {
+	return querySongIDs(`
+		SELECT DISTINCT sl.song_id FROM song_lines sl
+		WHERE COALESCE(sl.english_text, '') = ''
+		ORDER BY sl.song_id
+	`)
+}
+
+// GetSongIDsWithoutVocab returns the songs that have lyrics but no
+// vocabulary
+func GetSongIDsWithoutVocab() ([]int64, error) {
+	return querySongIDs(`
+		SELECT s.id FROM songs s
+		WHERE EXISTS (SELECT 1 FROM song_lines sl WHERE sl.song_id = s.id)
+		  AND NOT EXISTS (SELECT 1 FROM song_vocabulary sv WHERE sv.song_id = s.id)
+		ORDER BY s.id
+	`)
+}
+
+func querySongIDs(query string) ([]int64, error) {
+	rows, err := db.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// UpdateSongLineTranslation sets the English text of a song line
+func UpdateSongLineTranslation(lineID int64, englishText string) error {
+	_, err := db.DB.Exec(`UPDATE song_lines SET english_text = ? WHERE id = ?`, englishText, lineID)
+	return err
+}
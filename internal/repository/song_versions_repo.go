@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"languagepapi/internal/db"
+	"languagepapi/internal/models"
+)
+
+// versionLine is how a line is stored in song_line_versions.lines
+type versionLine struct {
+	StartMs int                 `json:"start_ms"`
+	EndMs   int                 `json:"end_ms"`
+	Spanish string              `json:"spanish"`
+	English string              `json:"english"`
+	Words   []versionWordTiming `json:"words,omitempty"`
+}
+
+type versionWordTiming struct {
+	Text    string `json:"text"`
+	StartMs int    `json:"start_ms"`
+	EndMs   int    `json:"end_ms"`
+}
+
+func encodeVersionLines(lines []models.SongLine) (string, error) {
+	out := make([]versionLine, len(lines))
+	for i, l := range lines {
+		out[i] = versionLine{StartMs: l.StartTimeMs, EndMs: l.EndTimeMs, Spanish: l.SpanishText, English: l.EnglishText}
+		for _, t := range l.Timings {
+			out[i].Words = append(out[i].Words, versionWordTiming{t.Text, t.StartTimeMs, t.EndTimeMs})
+		}
+	}
+	data, err := json.Marshal(out)
+	return string(data), err
+}
+
+func decodeVersionLines(songID int64, data string) ([]models.SongLine, error) {
+	var stored []versionLine
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		return nil, err
+	}
+	lines := make([]models.SongLine, len(stored))
+	for i, l := range stored {
+		lines[i] = models.SongLine{
+			SongID:      songID,
+			LineNumber:  i + 1,
+			StartTimeMs: l.StartMs,
+			EndTimeMs:   l.EndMs,
+			SpanishText: l.Spanish,
+			EnglishText: l.English,
+		}
+		for j, w := range l.Words {
+			lines[i].Timings = append(lines[i].Timings, models.SongWordTiming{
+				Position: j, Text: w.Text, StartTimeMs: w.StartMs, EndTimeMs: w.EndMs,
+			})
+		}
+	}
+	return lines, nil
+}
+
+// GetSongLinesVersions returns a song's saved line versions, newest first
+func GetSongLinesVersions(songID int64) ([]models.SongLinesVersion, error) {
+	rows, err := db.DB.Query(`
+		SELECT id, song_id, user_id, note, lines, created_at
+		FROM song_line_versions
+		WHERE song_id = ?
+		ORDER BY id DESC
+	`, songID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []models.SongLinesVersion
+	for rows.Next() {
+		var v models.SongLinesVersion
+		var lines string
+		if err := rows.Scan(&v.ID, &v.SongID, &v.UserID, &v.Note, &lines, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		if v.Lines, err = decodeVersionLines(v.SongID, lines); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// SaveSongLinesVersion records v and replaces the song's lines with its
+// lines. If the song has no versions yet, imported (the lines it has now)
+// is recorded first so the edit can be undone.
+func SaveSongLinesVersion(v *models.SongLinesVersion, imported []models.SongLine) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM song_line_versions WHERE song_id = ?`, v.SongID).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		if _, err := insertSongLinesVersion(tx, &models.SongLinesVersion{SongID: v.SongID, Note: "Imported", Lines: imported}); err != nil {
+			return err
+		}
+	}
+	if v.ID, err = insertSongLinesVersion(tx, v); err != nil {
+		return err
+	}
+	if err := replaceSongLines(tx, v.SongID, v.Lines); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UndoSongLinesVersion deletes a song's latest line version and restores
+// the lines of the one before it, returning the undone version.
+// Returns sql.ErrNoRows if there is no edit to undo.
+func UndoSongLinesVersion(songID int64) (*models.SongLinesVersion, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT id, note, lines FROM song_line_versions
+		WHERE song_id = ?
+		ORDER BY id DESC
+		LIMIT 2
+	`, songID)
+	if err != nil {
+		return nil, err
+	}
+	var latest []models.SongLinesVersion
+	for rows.Next() {
+		v := models.SongLinesVersion{SongID: songID}
+		var lines string
+		if err := rows.Scan(&v.ID, &v.Note, &lines); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if v.Lines, err = decodeVersionLines(songID, lines); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		latest = append(latest, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(latest) < 2 {
+		return nil, sql.ErrNoRows
+	}
+
+	if _, err := tx.Exec(`DELETE FROM song_line_versions WHERE id = ?`, latest[0].ID); err != nil {
+		return nil, err
+	}
+	if err := replaceSongLines(tx, songID, latest[1].Lines); err != nil {
+		return nil, err
+	}
+	return &latest[0], tx.Commit()
+}
+
+func insertSongLinesVersion(tx *sql.Tx, v *models.SongLinesVersion) (int64, error) {
+	lines, err := encodeVersionLines(v.Lines)
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec(`
+		INSERT INTO song_line_versions (song_id, user_id, note, lines) VALUES (?, ?, ?, ?)
+	`, v.SongID, v.UserID, v.Note, lines)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// replaceSongLines writes lines over a song's lines. Lines are updated in
+// place by line number, so they keep their ids (and the lesson items that
+// point at them); extra lines are inserted and missing ones deleted. Word
+// timings are rewritten.
+func replaceSongLines(tx *sql.Tx, songID int64, lines []models.SongLine) error {
+	if _, err := tx.Exec(`DELETE FROM song_lines WHERE song_id = ? AND line_number > ?`, songID, len(lines)); err != nil {
+		return err
+	}
+	for i := range lines {
+		l := &lines[i]
+		l.SongID = songID
+		l.LineNumber = i + 1
+		err := tx.QueryRow(`
+			INSERT INTO song_lines (song_id, line_number, start_time_ms, end_time_ms, spanish_text, english_text)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(song_id, line_number) DO UPDATE SET
+				start_time_ms = excluded.start_time_ms,
+				end_time_ms = excluded.end_time_ms,
+				spanish_text = excluded.spanish_text,
+				english_text = excluded.english_text
+			RETURNING id
+		`, songID, l.LineNumber, l.StartTimeMs, l.EndTimeMs, l.SpanishText, l.EnglishText).Scan(&l.ID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`DELETE FROM song_word_timings WHERE line_id = ?`, l.ID); err != nil {
+			return err
+		}
+		for j := range l.Timings {
+			t := &l.Timings[j]
+			t.LineID, t.Position = l.ID, j
+			_, err := tx.Exec(`
+				INSERT INTO song_word_timings (line_id, position, text, start_time_ms, end_time_ms)
+				VALUES (?, ?, ?, ?, ?)
+			`, t.LineID, t.Position, t.Text, t.StartTimeMs, t.EndTimeMs)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"languagepapi/internal/db"
+	"languagepapi/internal/models"
+)
+
+// GetSharedCardTerms maps the lowercased terms of the shared cards to their
+// ids, the oldest card winning a tie
+func GetSharedCardTerms() (map[string]int64, error) {
+	rows, err := db.DB.Query(`
+		SELECT LOWER(TRIM(term)), MIN(id) FROM cards
+		WHERE user_id IS NULL AND ` + wordCard + `
+		GROUP BY LOWER(TRIM(term))
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	terms := make(map[string]int64)
+	for rows.Next() {
+		var term string
+		var id int64
+		if err := rows.Scan(&term, &id); err != nil {
+			return nil, err
+		}
+		terms[term] = id
+	}
+	return terms, rows.Err()
+}
+
+// GetSharedCardsByTerm is GetSharedCardTerms with the cards' translations
+// and frequency ranks
+func GetSharedCardsByTerm() (map[string]*models.Card, error) {
+	rows, err := db.DB.Query(`
+		SELECT LOWER(TRIM(c.term)), c.id, c.term, c.translation, c.frequency_rank
+		FROM cards c
+		JOIN (
+			SELECT MIN(id) AS id FROM cards
+			WHERE user_id IS NULL AND ` + wordCard + `
+			GROUP BY LOWER(TRIM(term))
+		) first ON first.id = c.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cards := make(map[string]*models.Card)
+	for rows.Next() {
+		var key string
+		var c models.Card
+		if err := rows.Scan(&key, &c.ID, &c.Term, &c.Translation, &c.FrequencyRank); err != nil {
+			return nil, err
+		}
+		cards[key] = &c
+	}
+	return cards, rows.Err()
+}
+
+// ReplaceSongWords replaces a song's linked and unmatched words and marks
+// it linked
+func ReplaceSongWords(songID int64, words []models.SongLineWord, unmatched []models.SongUnmatchedWord) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM song_line_words WHERE song_id = ?`, songID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM song_unmatched_words WHERE song_id = ?`, songID); err != nil {
+		return err
+	}
+	for _, w := range words {
+		_, err := tx.Exec(`
+			INSERT INTO song_line_words (song_id, line_id, position, word, standard, lemma, card_id)
+			VALUES (?, ?, ?, ?, NULLIF(?, ''), ?, ?)
+		`, songID, w.LineID, w.Position, w.Word, w.Standard, w.Lemma, w.CardID)
+		if err != nil {
+			return err
+		}
+	}
+	for _, u := range unmatched {
+		_, err := tx.Exec(`
+			INSERT INTO song_unmatched_words (song_id, word, occurrences) VALUES (?, ?, ?)
+		`, songID, u.Word, u.Occurrences)
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE songs SET words_linked_at = CURRENT_TIMESTAMP WHERE id = ?`, songID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SongWordsStale reports whether a song's words were never linked, or
+// shared cards were added since
+func SongWordsStale(songID int64) (bool, error) {
+	var stale bool
+	err := db.DB.QueryRow(`
+		SELECT words_linked_at IS NULL
+		       OR words_linked_at < COALESCE((SELECT MAX(created_at) FROM cards WHERE user_id IS NULL AND `+wordCard+`), '')
+		FROM songs WHERE id = ?
+	`, songID).Scan(&stale)
+	return stale, err
+}
+
+// GetSongLineWords returns a song's linked words with their cards'
+// translations, in line and word order
+func GetSongLineWords(songID int64) ([]models.SongLineWord, error) {
+	rows, err := db.DB.Query(`
+		SELECT w.id, w.song_id, w.line_id, w.position, w.word, COALESCE(w.standard, ''), w.lemma, w.card_id, c.translation
+		FROM song_line_words w
+		INNER JOIN cards c ON c.id = w.card_id
+		WHERE w.song_id = ?
+		ORDER BY w.line_id, w.position
+	`, songID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var words []models.SongLineWord
+	for rows.Next() {
+		var w models.SongLineWord
+		if err := rows.Scan(&w.ID, &w.SongID, &w.LineID, &w.Position, &w.Word, &w.Standard, &w.Lemma, &w.CardID, &w.Translation); err != nil {
+			return nil, err
+		}
+		words = append(words, w)
+	}
+	return words, rows.Err()
+}
+
+// GetSongWordProgress returns a user's progress on the cards linked to a
+// song's words, by card ID. Cards never studied are left out.
+func GetSongWordProgress(userID, songID int64) (map[int64]*models.CardProgress, error) {
+	rows, err := db.DB.Query(`
+		SELECT p.id, p.user_id, p.card_id, p.stability, p.difficulty, p.elapsed_days,
+		       p.scheduled_days, p.reps, p.lapses, p.state, p.due, p.last_review
+		FROM card_progress p
+		WHERE p.user_id = ? AND p.card_id IN (SELECT card_id FROM song_line_words WHERE song_id = ?)
+	`, userID, songID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	progress := make(map[int64]*models.CardProgress)
+	for rows.Next() {
+		var p models.CardProgress
+		if err := rows.Scan(
+			&p.ID, &p.UserID, &p.CardID, &p.Stability, &p.Difficulty, &p.ElapsedDays,
+			&p.ScheduledDays, &p.Reps, &p.Lapses, &p.State, &p.Due, &p.LastReview,
+		); err != nil {
+			return nil, err
+		}
+		progress[p.CardID] = &p
+	}
+	return progress, rows.Err()
+}
+
+// GetSongUnmatchedWords returns the words of a song that match no card,
+// most repeated first
+func GetSongUnmatchedWords(songID int64) ([]models.SongUnmatchedWord, error) {
+	rows, err := db.DB.Query(`
+		SELECT song_id, word, occurrences FROM song_unmatched_words
+		WHERE song_id = ?
+		ORDER BY occurrences DESC, word
+	`, songID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var words []models.SongUnmatchedWord
+	for rows.Next() {
+		var w models.SongUnmatchedWord
+		if err := rows.Scan(&w.SongID, &w.Word, &w.Occurrences); err != nil {
+			return nil, err
+		}
+		words = append(words, w)
+	}
+	return words, rows.Err()
+}
@@ -26,7 +26,11 @@ func GetUser(id int64) (*models.User, error) {
 
 // UpdateUserXP adds XP to a user's total
 func UpdateUserXP(userID int64, xpToAdd int) error {
-	_, err := db.DB.Exec(`
+	return updateUserXP(db.DB, userID, xpToAdd)
+}
+
+func updateUserXP(q execer, userID int64, xpToAdd int) error {
+	_, err := q.Exec(`
 		UPDATE users SET total_xp = total_xp + ? WHERE id = ?
 	`, xpToAdd, userID)
 	return err
@@ -34,13 +38,17 @@ func UpdateUserXP(userID int64, xpToAdd int) error {
 
 // UpdateStreak updates the user's streak based on activity
 func UpdateStreak(userID int64) error {
+	return updateStreak(db.DB, userID)
+}
+
+func updateStreak(q querier, userID int64) error {
 	today := time.Now().Format("2006-01-02")
 	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
 
 	// Get current streak info
 	var lastActive sql.NullString
 	var currentStreak, longestStreak int
-	err := db.DB.QueryRow(`
+	err := q.QueryRow(`
 		SELECT last_active_date, current_streak, longest_streak
 		FROM users WHERE id = ?
 	`, userID).Scan(&lastActive, &currentStreak, &longestStreak)
@@ -69,7 +77,7 @@ func UpdateStreak(userID int64) error {
 		newLongest = newStreak
 	}
 
-	_, err = db.DB.Exec(`
+	_, err = q.Exec(`
 		UPDATE users
 		SET current_streak = ?, longest_streak = ?, last_active_date = ?
 		WHERE id = ?
@@ -96,3 +104,50 @@ func GetStreakInfo(userID int64) (*models.StreakInfo, error) {
 	}
 	return info, nil
 }
+
+// GetUserByUsername retrieves a user by username
+func GetUserByUsername(username string) (*models.User, error) {
+	var id int64
+	err := db.DB.QueryRow(`SELECT id FROM users WHERE username = ?`, username).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	return GetUser(id)
+}
+
+// GetPasswordHash returns the stored password hash for a username.
+// The hash is empty for legacy accounts that never set a password.
+func GetPasswordHash(username string) (int64, string, error) {
+	var id int64
+	var hash sql.NullString
+	err := db.DB.QueryRow(`
+		SELECT id, password_hash FROM users WHERE username = ?
+	`, username).Scan(&id, &hash)
+	if err != nil {
+		return 0, "", err
+	}
+	return id, hash.String, nil
+}
+
+// CreateUser inserts a new user with a password hash
+func CreateUser(username, passwordHash string) (*models.User, error) {
+	result, err := db.DB.Exec(`
+		INSERT INTO users (username, password_hash) VALUES (?, ?)
+	`, username, passwordHash)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return GetUser(id)
+}
+
+// SetPasswordHash updates a user's password hash
+func SetPasswordHash(userID int64, passwordHash string) error {
+	_, err := db.DB.Exec(`
+		UPDATE users SET password_hash = ? WHERE id = ?
+	`, passwordHash, userID)
+	return err
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+
+	"languagepapi/internal/bridge"
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+)
+
+// aiStore keeps what the bridge package records about AI calls in the
+// database
+type aiStore struct{}
+
+// InitAI connects AI calls to the database: rejected responses, the
+// response cache, the usage ledger and the monthly budget. Call it once the
+// database is open; until then calls go straight to the provider and
+// rejections are only logged.
+func InitAI() {
+	bridge.SetRejectionStore(aiStore{})
+	bridge.SetUsageStore(aiStore{})
+}
+
+// SaveRejection implements bridge.RejectionStore
+func (aiStore) SaveRejection(r bridge.Rejection) error {
+	return repository.SaveAIRejection(r.Task, r.Attempt, r.Reason, r.Response)
+}
+
+// CachedResponse implements bridge.UsageStore
+func (aiStore) CachedResponse(key string) (string, bool, error) {
+	text, err := repository.GetAICachedResponse(key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return text, true, nil
+}
+
+// SaveCachedResponse implements bridge.UsageStore
+func (aiStore) SaveCachedResponse(key, feature, model, text string) error {
+	return repository.SaveAICachedResponse(key, feature, model, text)
+}
+
+// DeleteCachedResponse implements bridge.UsageStore
+func (aiStore) DeleteCachedResponse(key string) error {
+	return repository.DeleteAICachedResponse(key)
+}
+
+// SaveUsage implements bridge.UsageStore
+func (aiStore) SaveUsage(u *models.AIUsage) error {
+	return repository.SaveAIUsage(u)
+}
+
+// CostThisMonth implements bridge.UsageStore
+func (aiStore) CostThisMonth() (float64, error) {
+	return repository.GetAICostThisMonth()
+}
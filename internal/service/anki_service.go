@@ -0,0 +1,440 @@
+package service
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"languagepapi/internal/anki"
+	"languagepapi/internal/audio"
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+)
+
+// ankiFields are the fields of the note type written by Export, in order
+var ankiFields = []string{
+	"Term", "Translation", "Example", "Notes",
+	"Hindi bridge", "Dutch bridge", "English bridge",
+	"Song line", "Audio",
+}
+
+// ankiBridgeFields maps bridge types to their note fields
+var ankiBridgeFields = map[models.BridgeType]string{
+	models.BridgeHindiPhonetic:  "Hindi bridge",
+	models.BridgeDutchSyntax:    "Dutch bridge",
+	models.BridgeEnglishCognate: "English bridge",
+}
+
+// Field names recognised when importing notes from other decks
+var (
+	ankiTermFields        = []string{"Term", "Front", "Spanish", "Word", "Expression", "Vocab"}
+	ankiTranslationFields = []string{"Translation", "Back", "English", "Meaning", "Definition"}
+	ankiExampleFields     = []string{"Example", "Sentence", "Example sentence", "Song line"}
+	ankiNotesFields       = []string{"Notes", "Extra", "Note"}
+)
+
+const ankiFront = `<div class="term">{{Term}}</div>
+{{Audio}}`
+
+const ankiBack = `{{FrontSide}}
+<hr id="answer">
+<div class="translation">{{Translation}}</div>
+{{#Example}}<div class="example">{{Example}}</div>{{/Example}}
+{{#Song line}}<div class="song-line">♪ {{Song line}}</div>{{/Song line}}
+{{#Hindi bridge}}<div class="bridge"><b>Hindi:</b> {{Hindi bridge}}</div>{{/Hindi bridge}}
+{{#Dutch bridge}}<div class="bridge"><b>Dutch:</b> {{Dutch bridge}}</div>{{/Dutch bridge}}
+{{#English bridge}}<div class="bridge"><b>English:</b> {{English bridge}}</div>{{/English bridge}}
+{{#Notes}}<div class="notes">{{Notes}}</div>{{/Notes}}`
+
+const ankiCSS = `.card { font-family: sans-serif; font-size: 22px; text-align: center; }
+.term { font-size: 34px; font-weight: bold; }
+.example, .song-line { font-style: italic; margin-top: 12px; }
+.bridge, .notes { font-size: 16px; margin-top: 8px; color: #555; }`
+
+// AnkiImportResult summarises an .apkg import
+type AnkiImportResult struct {
+	Imported    int // Cards created
+	Duplicates  int // Notes skipped because the term already exists
+	Skipped     int // Notes without a usable term and translation
+	WithHistory int // Imported cards that got scheduling state from the revlog
+	Reviews     int // Review log entries imported
+}
+
+// AnkiService moves cards between this app and Anki
+type AnkiService struct{}
+
+// NewAnkiService creates a new Anki service
+func NewAnkiService() *AnkiService {
+	return &AnkiService{}
+}
+
+// Export builds an .apkg with the cards visible to the user (one island,
+// or all when islandID is 0), their bridges, FSRS state and review history.
+// Song vocabulary gets the lyric line it appears in, with an audio clip cut
+// from the song when the MP3 is under songsPath.
+func (s *AnkiService) Export(userID, islandID int64, songsPath string) ([]byte, error) {
+	cards, err := repository.GetCardsForExport(userID, islandID)
+	if err != nil {
+		return nil, err
+	}
+
+	islands, err := repository.GetAllIslands()
+	if err != nil {
+		return nil, err
+	}
+	islandNames := make(map[int64]string, len(islands))
+	for _, island := range islands {
+		islandNames[island.ID] = island.Name
+	}
+
+	logs, err := repository.GetReviewLogsForOptimizer(userID)
+	if err != nil {
+		return nil, err
+	}
+	logsByCard := make(map[int64][]models.ReviewLog)
+	for _, l := range logs {
+		logsByCard[l.CardID] = append(logsByCard[l.CardID], l)
+	}
+
+	deck := &anki.Deck{
+		Name: "languagepapi",
+		Model: anki.Model{
+			Name:   "languagepapi",
+			Fields: ankiFields,
+			Front:  ankiFront,
+			Back:   ankiBack,
+			CSS:    ankiCSS,
+		},
+		Media: make(map[string][]byte),
+	}
+	if islandID > 0 {
+		deck.Name += "::" + islandNames[islandID]
+	}
+
+	clips := newSongClips(songsPath)
+	for _, card := range cards {
+		fields := map[string]string{
+			"Term":        card.Term,
+			"Translation": card.Translation,
+			"Example":     card.ExampleSentence,
+			"Notes":       card.Notes,
+		}
+		for _, b := range card.Bridges {
+			if name, ok := ankiBridgeFields[b.BridgeType]; ok {
+				fields[name] = b.BridgeContent
+			}
+		}
+
+		tags := []string{"languagepapi"}
+		if card.IslandID.Valid && islandNames[card.IslandID.Int64] != "" {
+			tags = append(tags, ankiTag(islandNames[card.IslandID.Int64]))
+		}
+		if card.Source == "song" && card.SourceSongID.Valid {
+			tags = append(tags, "song")
+			if line, filename, data := clips.find(card.SourceSongID.Int64, card.Term); line != nil {
+				fields["Song line"] = line.SpanishText
+				if data != nil {
+					deck.Media[filename] = data
+					fields["Audio"] = anki.Sound(filename)
+				}
+			}
+		}
+
+		note := anki.Note{
+			GUID:    fmt.Sprintf("languagepapi-%d", card.ID),
+			Fields:  make([]string, len(ankiFields)),
+			Tags:    tags,
+			Reviews: ankiReviews(logsByCard[card.ID]),
+		}
+		for i, name := range ankiFields {
+			note.Fields[i] = fields[name]
+		}
+
+		progress, err := repository.GetProgress(userID, card.ID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		note.Schedule = ankiSchedule(progress)
+
+		deck.Notes = append(deck.Notes, note)
+	}
+
+	var buf bytes.Buffer
+	if err := anki.Write(&buf, deck); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Import creates cards on the target island from the notes of an .apkg.
+// Terms the user can already see are skipped. Notes whose card has been
+// reviewed in Anki also get card progress and review logs. Everything is
+// saved in one transaction, so a failed import adds nothing.
+func (s *AnkiService) Import(userID, islandID int64, r io.ReaderAt, size int64) (*AnkiImportResult, error) {
+	notes, err := anki.Read(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AnkiImportResult{}
+	imp := &repository.CardImport{}
+	seen := make(map[string]bool)
+	for i := range notes {
+		n := &notes[i]
+		term := anki.StripHTML(n.Field(ankiTermFields...))
+		translation := anki.StripHTML(n.Field(ankiTranslationFields...))
+		// Fall back to field order for note types with unfamiliar names
+		if term == "" && len(n.Fields) > 0 {
+			term = anki.StripHTML(n.Fields[0])
+		}
+		if translation == "" && len(n.Fields) > 1 {
+			translation = anki.StripHTML(n.Fields[1])
+		}
+		if term == "" || translation == "" {
+			result.Skipped++
+			continue
+		}
+
+		key := strings.ToLower(term)
+		if seen[key] {
+			result.Duplicates++
+			continue
+		}
+		seen[key] = true
+		if _, err := repository.GetCardByTerm(userID, term); err == nil {
+			result.Duplicates++
+			continue
+		} else if err != sql.ErrNoRows {
+			return nil, err
+		}
+
+		imported := repository.ImportedCard{
+			Card: &models.Card{
+				IslandID:        sql.NullInt64{Int64: islandID, Valid: true},
+				Term:            term,
+				Translation:     translation,
+				ExampleSentence: anki.StripHTML(n.Field(ankiExampleFields...)),
+				Notes:           anki.StripHTML(n.Field(ankiNotesFields...)),
+				Source:          "anki",
+				UserID:          sql.NullInt64{Int64: userID, Valid: true},
+			},
+			Reviews: importReviews(userID, n.Reviews),
+		}
+		for bridgeType, field := range ankiBridgeFields {
+			if content := anki.StripHTML(n.Field(field)); content != "" {
+				imported.Bridges = append(imported.Bridges, models.Bridge{BridgeType: bridgeType, BridgeContent: content})
+			}
+		}
+		if len(imported.Reviews) > 0 && n.Schedule != nil {
+			imported.Progress = importProgress(userID, n.Schedule, imported.Reviews)
+			result.WithHistory++
+		}
+		result.Imported++
+		result.Reviews += len(imported.Reviews)
+		imp.Created = append(imp.Created, imported)
+	}
+
+	if err := repository.SaveCardImport(imp); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ankiSchedule converts card progress to an Anki schedule (nil for new cards)
+func ankiSchedule(p *models.CardProgress) *anki.Schedule {
+	if p == nil || p.State == models.StateNew {
+		return nil
+	}
+	s := &anki.Schedule{
+		Type:       anki.CardLearning,
+		Interval:   p.ScheduledDays,
+		Reps:       p.Reps,
+		Lapses:     p.Lapses,
+		Stability:  p.Stability,
+		Difficulty: p.Difficulty,
+	}
+	switch p.State {
+	case models.StateReview:
+		s.Type = anki.CardReview
+	case models.StateRelearning:
+		s.Type = anki.CardRelearning
+	}
+	if p.Due.Valid {
+		s.Due = p.Due.Time
+	} else {
+		s.Due = time.Now()
+	}
+	return s
+}
+
+// ankiReviews converts review logs (oldest first) to revlog entries
+func ankiReviews(logs []models.ReviewLog) []anki.Review {
+	reviews := make([]anki.Review, len(logs))
+	for i, l := range logs {
+		kind := anki.ReviewReview
+		lastInterval := 0
+		if i == 0 {
+			kind = anki.ReviewLearn
+		} else {
+			lastInterval = logs[i-1].ScheduledDays
+			if logs[i-1].Rating == models.RatingAgain {
+				kind = anki.ReviewRelearn
+			}
+		}
+		reviews[i] = anki.Review{
+			Time:         l.ReviewedAt,
+			Ease:         int(l.Rating),
+			Interval:     l.ScheduledDays,
+			LastInterval: lastInterval,
+			DurationMs:   l.ReviewDurationMs,
+			Kind:         kind,
+		}
+	}
+	return reviews
+}
+
+// importReviews converts a note's graded reviews to review logs, skipping
+// manual reschedules
+func importReviews(userID int64, reviews []anki.Review) []models.ReviewLog {
+	var logs []models.ReviewLog
+	var prev time.Time
+	for _, r := range reviews {
+		if r.Ease < 1 || r.Ease > 4 || r.Kind == anki.ReviewManual {
+			continue
+		}
+		log := models.ReviewLog{
+			UserID:           userID,
+			Rating:           models.Rating(r.Ease),
+			ScheduledDays:    r.Interval,
+			ReviewedAt:       r.Time,
+			ReviewDurationMs: r.DurationMs,
+		}
+		if !prev.IsZero() {
+			log.ElapsedDays = calendarDaysBetween(prev, r.Time)
+		}
+		logs = append(logs, log)
+		prev = r.Time
+	}
+	return logs
+}
+
+// importProgress builds card progress from an Anki schedule. Cards reviewed
+// before FSRS was enabled in Anki have no memory state, so stability is
+// taken from the interval and difficulty from the ease factor.
+func importProgress(userID int64, s *anki.Schedule, logs []models.ReviewLog) *models.CardProgress {
+	last := logs[len(logs)-1]
+	p := &models.CardProgress{
+		UserID:        userID,
+		Stability:     s.Stability,
+		Difficulty:    s.Difficulty,
+		ElapsedDays:   last.ElapsedDays,
+		ScheduledDays: s.Interval,
+		Reps:          s.Reps,
+		Lapses:        s.Lapses,
+		State:         models.StateLearning,
+		Due:           sql.NullTime{Time: s.Due, Valid: true},
+		LastReview:    sql.NullTime{Time: last.ReviewedAt, Valid: true},
+	}
+	switch s.Type {
+	case anki.CardReview:
+		p.State = models.StateReview
+	case anki.CardRelearning:
+		p.State = models.StateRelearning
+	}
+
+	if p.Stability == 0 {
+		p.Stability = math.Max(float64(s.Interval), 1)
+		factor := s.Factor
+		if factor == 0 {
+			factor = 2500
+		}
+		// Ease 130% (hardest) .. 350% maps to difficulty 10 .. 1
+		p.Difficulty = math.Min(math.Max(10-float64(factor-1300)*9/2200, 1), 10)
+	}
+	return p
+}
+
+// calendarDaysBetween counts UTC calendar days from a to b
+func calendarDaysBetween(a, b time.Time) int {
+	a, b = a.UTC(), b.UTC()
+	da := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	db := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(db.Sub(da).Hours() / 24)
+}
+
+// ankiTag turns a name into a single Anki tag
+func ankiTag(name string) string {
+	return strings.Join(strings.Fields(name), "_")
+}
+
+// songClips finds the lyric line a song word appears in and cuts its audio,
+// caching lines and audio per song
+type songClips struct {
+	songsPath string
+	lines     map[int64][]models.SongLine
+	audio     map[int64][]byte
+}
+
+func newSongClips(songsPath string) *songClips {
+	return &songClips{
+		songsPath: songsPath,
+		lines:     make(map[int64][]models.SongLine),
+		audio:     make(map[int64][]byte),
+	}
+}
+
+// find returns the first line of the song containing term, and an MP3 clip
+// of it with a media filename when the audio is available
+func (c *songClips) find(songID int64, term string) (*models.SongLine, string, []byte) {
+	lines, ok := c.lines[songID]
+	if !ok {
+		lines, _ = repository.GetSongLines(songID)
+		c.lines[songID] = lines
+	}
+
+	needle := strings.ToLower(term)
+	for i := range lines {
+		line := &lines[i]
+		if !strings.Contains(strings.ToLower(line.SpanishText), needle) {
+			continue
+		}
+		if line.EndTimeMs <= line.StartTimeMs {
+			return line, "", nil
+		}
+		data := c.songAudio(songID)
+		if data == nil {
+			return line, "", nil
+		}
+		clip, err := audio.CutMP3(data, line.StartTimeMs, line.EndTimeMs)
+		if err != nil {
+			return line, "", nil
+		}
+		return line, fmt.Sprintf("languagepapi-song%d-line%d.mp3", songID, line.LineNumber), clip
+	}
+	return nil, "", nil
+}
+
+// songAudio loads a song's MP3, or returns nil if it has none
+func (c *songClips) songAudio(songID int64) []byte {
+	if data, ok := c.audio[songID]; ok {
+		return data
+	}
+	c.audio[songID] = nil
+
+	song, err := repository.GetSong(songID)
+	if err != nil || !strings.EqualFold(filepath.Ext(song.AudioPath), ".mp3") {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(c.songsPath, song.AudioPath))
+	if err != nil {
+		return nil
+	}
+	c.audio[songID] = data
+	return data
+}
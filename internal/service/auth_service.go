@@ -0,0 +1,165 @@
+package service
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+)
+
+// SessionDuration is how long a login session stays valid
+const SessionDuration = 30 * 24 * time.Hour
+
+// MinPasswordLength is the shortest password accepted at registration
+const MinPasswordLength = 8
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrUsernameTaken      = errors.New("username is already taken")
+)
+
+// AuthService handles registration, login and session lookup
+type AuthService struct{}
+
+// NewAuthService creates a new auth service
+func NewAuthService() *AuthService {
+	return &AuthService{}
+}
+
+// Register creates a new account with a hashed password. A username in
+// use, even by an account without a password like the seeded one, is
+// taken: such accounts get theirs with the set-password command.
+func (s *AuthService) Register(username, password string) (*models.User, error) {
+	username = strings.TrimSpace(username)
+	if len(username) < 3 || len(username) > 32 {
+		return nil, fmt.Errorf("username must be 3-32 characters")
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, err = repository.GetPasswordHash(username)
+	switch {
+	case err == sql.ErrNoRows:
+		return repository.CreateUser(username, hash)
+	case err != nil:
+		return nil, err
+	}
+	return nil, ErrUsernameTaken
+}
+
+// SetPassword sets or replaces the password of an existing account. It is
+// how an account without one, like the seeded user, gets one.
+func (s *AuthService) SetPassword(username, password string) (*models.User, error) {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	id, _, err := repository.GetPasswordHash(strings.TrimSpace(username))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no user %q", username)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := repository.SetPasswordHash(id, hash); err != nil {
+		return nil, err
+	}
+	return repository.GetUser(id)
+}
+
+// hashPassword checks a new password's length and hashes it with bcrypt
+func hashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// Login verifies credentials and opens a new session, returning its token
+func (s *AuthService) Login(username, password string) (string, *models.User, error) {
+	id, hash, err := repository.GetPasswordHash(strings.TrimSpace(username))
+	if err == sql.ErrNoRows || (err == nil && hash == "") {
+		return "", nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return "", nil, ErrInvalidCredentials
+	}
+
+	token, err := newSessionToken()
+	if err != nil {
+		return "", nil, err
+	}
+	if err := repository.CreateUserSession(token, id, time.Now().Add(SessionDuration)); err != nil {
+		return "", nil, err
+	}
+
+	// Opportunistic cleanup of stale sessions
+	repository.DeleteExpiredSessions()
+
+	user, err := repository.GetUser(id)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, user, nil
+}
+
+// Logout ends a session
+func (s *AuthService) Logout(token string) error {
+	return repository.DeleteUserSession(token)
+}
+
+// UserIDForToken resolves a session token to its user ID
+func (s *AuthService) UserIDForToken(token string) (int64, error) {
+	if token == "" {
+		return 0, ErrInvalidCredentials
+	}
+	userID, err := repository.GetUserIDForSession(token)
+	if err == sql.ErrNoRows {
+		return 0, ErrInvalidCredentials
+	}
+	return userID, err
+}
+
+// IsAdmin reports whether the user is listed in ADMIN_USERS, a
+// comma-separated list of usernames. Admins see the AI usage of every
+// account, can clear the shared AI response cache, edit the songs' lines
+// and run batches over the shared cards.
+func (s *AuthService) IsAdmin(userID int64) (bool, error) {
+	user, err := repository.GetUser(userID)
+	if err != nil {
+		return false, err
+	}
+	for _, name := range strings.Split(os.Getenv("ADMIN_USERS"), ",") {
+		if strings.TrimSpace(name) == user.Username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// newSessionToken returns a random 256-bit hex token
+func newSessionToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"languagepapi/internal/conjugate"
+	"languagepapi/internal/grading"
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+)
+
+// newConjugationsPerDay caps the verb and tense pairs introduced each day
+const newConjugationsPerDay = 10
+
+// DrillTenses are the tenses drilled, in the order each verb meets them. A
+// verb moves on to its next tense once the previous one has graduated to
+// review. The past anterior and future subjunctives are literary and left
+// out.
+var DrillTenses = []conjugate.Tense{
+	conjugate.Present,
+	conjugate.Preterite,
+	conjugate.Imperfect,
+	conjugate.Future,
+	conjugate.PresentPerfect,
+	conjugate.Imperative,
+	conjugate.PresentSubjunctive,
+	conjugate.Conditional,
+	conjugate.NegativeImperative,
+	conjugate.ImperfectSubjunctive,
+	conjugate.Pluperfect,
+	conjugate.FuturePerfect,
+	conjugate.ConditionalPerfect,
+	conjugate.PresentPerfectSubjunctive,
+	conjugate.PluperfectSubjunctive,
+}
+
+// ConjugationDrill asks for one person of one tense of a verb card
+type ConjugationDrill struct {
+	Card     *models.Card
+	Tense    conjugate.Tense
+	Person   conjugate.Person
+	Expected conjugate.Conjugation
+	IsNew    bool // First drill of this verb in this tense
+}
+
+// Pronoun is the subject the drill asks for, e.g. "nosotros" or "usted"
+func (d *ConjugationDrill) Pronoun() string {
+	return d.Person.Pronoun(d.Tense)
+}
+
+// ConjugationResult is a graded drill answer
+type ConjugationResult struct {
+	Drill  *ConjugationDrill
+	Grade  grading.Result
+	Rating models.Rating
+	// Mixup says which other form of the verb was typed, e.g. "hablaban is
+	// the ellos/ellas/ustedes imperfect"
+	Mixup   string
+	Table   []conjugate.Conjugation // The whole tense, for reference
+	NextDue time.Time
+}
+
+// ConjugationOverview summarizes the drills for the drills page
+type ConjugationOverview struct {
+	Verbs   int // Studied verb cards the drills can use
+	Due     int
+	NewLeft int // New verb and tense pairs left today
+	Tenses  []models.ConjugationTenseStats
+}
+
+// ConjugationService runs the conjugation drills. Each verb card and tense
+// is scheduled with FSRS on its own; each drill asks a random person.
+type ConjugationService struct {
+	reviews *ReviewService
+}
+
+// NewConjugationService creates a conjugation drill service
+func NewConjugationService() *ConjugationService {
+	return &ConjugationService{reviews: NewReviewService()}
+}
+
+// Next picks the next drill: the most overdue verb and tense, else a new
+// pair while today's allowance lasts. Returns nil when there is nothing to
+// drill.
+func (s *ConjugationService) Next(userID int64) (*ConjugationDrill, error) {
+	due, err := repository.GetDueConjugations(userID, 20)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range due {
+		card, err := repository.GetCard(p.CardID)
+		if err != nil {
+			return nil, err
+		}
+		// The card may have been edited into something else since
+		if conjugate.IsInfinitive(card.Term) {
+			return s.drill(userID, card, conjugate.Tense(p.Tense), false)
+		}
+	}
+
+	started, err := repository.CountConjugationsStartedToday(userID)
+	if err != nil || started >= newConjugationsPerDay {
+		return nil, err
+	}
+	card, tense, err := s.nextNew(userID)
+	if err != nil || card == nil {
+		return nil, err
+	}
+	return s.drill(userID, card, tense, true)
+}
+
+// nextNew finds the most frequent studied verb with a tense ready to start
+func (s *ConjugationService) nextNew(userID int64) (*models.Card, conjugate.Tense, error) {
+	verbs, err := s.verbs(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	progress, err := repository.GetAllConjugationProgress(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	states := make(map[int64]map[string]models.CardState)
+	for _, p := range progress {
+		if states[p.CardID] == nil {
+			states[p.CardID] = make(map[string]models.CardState)
+		}
+		states[p.CardID][p.Tense] = p.State
+	}
+
+	for i := range verbs {
+		for j, t := range DrillTenses {
+			if _, started := states[verbs[i].ID][string(t)]; started {
+				continue
+			}
+			if j == 0 || states[verbs[i].ID][string(DrillTenses[j-1])] == models.StateReview {
+				return &verbs[i], t, nil
+			}
+			break
+		}
+	}
+	return nil, "", nil
+}
+
+// verbs returns the user's studied cards whose term is a Spanish infinitive
+func (s *ConjugationService) verbs(userID int64) ([]models.Card, error) {
+	cards, err := repository.GetStudiedVerbCards(userID)
+	if err != nil {
+		return nil, err
+	}
+	verbs := cards[:0]
+	for _, c := range cards {
+		if conjugate.IsInfinitive(c.Term) {
+			verbs = append(verbs, c)
+		}
+	}
+	return verbs, nil
+}
+
+// drill asks for a random person of the tense. Vosotros, only used in
+// Spain, is left out unless the user's settings include it.
+func (s *ConjugationService) drill(userID int64, card *models.Card, tense conjugate.Tense, isNew bool) (*ConjugationDrill, error) {
+	settings, _ := LoadUserSettings(userID)
+	var persons []conjugate.Person
+	for _, p := range conjugate.Persons {
+		if tense.HasPerson(p) && (p != conjugate.Vosotros || settings.DrillVosotros) {
+			persons = append(persons, p)
+		}
+	}
+	return s.Drill(card, tense, persons[rand.Intn(len(persons))], isNew)
+}
+
+// Drill builds the drill for a person and tense of a verb card
+func (s *ConjugationService) Drill(card *models.Card, tense conjugate.Tense, person conjugate.Person, isNew bool) (*ConjugationDrill, error) {
+	expected, err := conjugate.Conjugate(card.Term, tense, person)
+	if err != nil {
+		return nil, fmt.Errorf("conjugating %q: %w", card.Term, err)
+	}
+	return &ConjugationDrill{Card: card, Tense: tense, Person: person, Expected: expected, IsNew: isNew}, nil
+}
+
+// Answer grades a drill answer, explains a mix-up with another form of the
+// verb, and schedules and logs the verb and tense
+func (s *ConjugationService) Answer(userID int64, d *ConjugationDrill, answer string, elapsed time.Duration) (*ConjugationResult, error) {
+	typed := stripSubject(answer, d.Person)
+	result := &ConjugationResult{
+		Drill: d,
+		Grade: grading.Grade(typed, strings.Join(d.Expected.Accepted(), "; ")),
+	}
+	result.Rating = grading.Rate(result.Grade, elapsed)
+
+	if result.Grade.Verdict != grading.Exact {
+		// Typing a real form of the verb is a mix-up, not a typo
+		if mixup := describeMixup(d, typed); mixup != "" {
+			result.Mixup = mixup
+			result.Grade.Verdict = grading.Wrong
+			result.Rating = models.RatingAgain
+		}
+	}
+	if result.Grade.Verdict == grading.Accent && result.Rating > models.RatingHard {
+		// Accents tell verb forms apart: hablo, habló
+		result.Rating = models.RatingHard
+	}
+
+	now := time.Now()
+	progress, err := repository.GetConjugationProgress(userID, d.Card.ID, string(d.Tense))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		progress = &models.ConjugationProgress{
+			CardProgress: models.CardProgress{
+				UserID: userID,
+				CardID: d.Card.ID,
+				State:  models.StateNew,
+				Due:    sql.NullTime{Time: now, Valid: true},
+			},
+			Tense: string(d.Tense),
+		}
+	case err != nil:
+		return nil, err
+	}
+	isNew := progress.State == models.StateNew
+
+	next := &models.ConjugationProgress{
+		CardProgress: *s.reviews.fsrsFor(userID).ScheduleReview(&progress.CardProgress, result.Rating, now),
+		Tense:        progress.Tense,
+	}
+	if err := repository.UpsertConjugationProgress(next); err != nil {
+		return nil, err
+	}
+	result.NextDue = next.Due.Time
+
+	err = repository.LogConjugation(&models.ConjugationLog{
+		UserID:           userID,
+		CardID:           d.Card.ID,
+		Tense:            string(d.Tense),
+		Person:           int(d.Person),
+		Answer:           answer,
+		Expected:         d.Expected.Form,
+		Rating:           result.Rating,
+		ReviewDurationMs: int(elapsed.Milliseconds()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := repository.UpdateUserXP(userID, CalculateReviewXP(result.Rating, isNew, 0)); err != nil {
+		return nil, err
+	}
+	if err := repository.UpdateStreak(userID); err != nil {
+		return nil, err
+	}
+
+	result.Table, err = conjugate.Table(d.Card.Term, d.Tense)
+	return result, err
+}
+
+// Overview counts the verbs, due drills and new pairs left today, with
+// per-tense stats in drill order
+func (s *ConjugationService) Overview(userID int64) (*ConjugationOverview, error) {
+	verbs, err := s.verbs(userID)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := repository.GetConjugationStats(userID)
+	if err != nil {
+		return nil, err
+	}
+	started, err := repository.CountConjugationsStartedToday(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &ConjugationOverview{Verbs: len(verbs), NewLeft: max(newConjugationsPerDay-started, 0)}
+	for _, t := range DrillTenses {
+		for _, st := range stats {
+			if st.Tense == string(t) {
+				o.Tenses = append(o.Tenses, st)
+				o.Due += st.Due
+			}
+		}
+	}
+	return o, nil
+}
+
+// subjects are the pronouns an answer may start with, by person
+var subjects = [6][]string{
+	{"yo"},
+	{"tú", "tu", "vos"},
+	{"él", "ella", "usted", "ud"},
+	{"nosotros", "nosotras"},
+	{"vosotros", "vosotras"},
+	{"ellos", "ellas", "ustedes", "uds"},
+}
+
+// stripSubject drops a subject pronoun typed before the verb: "yo hablo"
+func stripSubject(answer string, p conjugate.Person) string {
+	words := strings.Fields(answer)
+	if len(words) < 2 {
+		return answer
+	}
+	first := strings.ToLower(strings.TrimSuffix(words[0], "."))
+	for _, s := range subjects[p] {
+		if first == s {
+			return strings.Join(words[1:], " ")
+		}
+	}
+	return answer
+}
+
+// describeMixup says where the typed form sits in the verb's conjugation if
+// it is a form other than the one asked for, or returns ""
+func describeMixup(d *ConjugationDrill, typed string) string {
+	var places []string
+	for _, c := range conjugate.Lookup(d.Card.Term, typed) {
+		if c.Tense == d.Tense && c.Person == d.Person {
+			return ""
+		}
+		places = append(places, fmt.Sprintf("the %s %s", c.Person.Pronoun(c.Tense), strings.ToLower(c.Tense.Label())))
+	}
+	switch len(places) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf("%s is %s", strings.TrimSpace(typed), places[0])
+	default:
+		return fmt.Sprintf("%s is %s and %s", strings.TrimSpace(typed), places[0], places[1])
+	}
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+)
+
+// DefaultCurriculumSlug is the curriculum followed by journeys that never picked one
+const DefaultCurriculumSlug = "sprint-14"
+
+// MaxCurriculumDays caps user-defined curricula at about a year
+const MaxCurriculumDays = 365
+
+// CurriculumService handles choosing and defining curricula
+type CurriculumService struct{}
+
+// NewCurriculumService creates a new curriculum service
+func NewCurriculumService() *CurriculumService {
+	return &CurriculumService{}
+}
+
+// ListCurricula returns the built-in curricula and the user's own
+func (s *CurriculumService) ListCurricula(userID int64) ([]models.Curriculum, error) {
+	return repository.ListCurricula(userID)
+}
+
+// GetCurriculum returns a curriculum by ID
+func (s *CurriculumService) GetCurriculum(id int64) (*models.Curriculum, error) {
+	return repository.GetCurriculum(id)
+}
+
+// CurrentCurriculum returns the curriculum the user's journey follows
+func (s *CurriculumService) CurrentCurriculum(userID int64) (*models.Curriculum, error) {
+	journey, err := repository.GetOrCreateJourney(userID)
+	if err != nil {
+		return nil, err
+	}
+	return journeyCurriculum(journey)
+}
+
+// StartCurriculum restarts the user's journey from day 1 on a curriculum
+func (s *CurriculumService) StartCurriculum(userID int64, c *models.Curriculum) error {
+	_, err := repository.CreateJourney(userID, sql.NullInt64{Int64: c.ID, Valid: true})
+	return err
+}
+
+// CreateCurriculum parses a JSON curriculum definition and stores it for the user
+func (s *CurriculumService) CreateCurriculum(userID int64, data []byte) (*models.Curriculum, error) {
+	var c models.Curriculum
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid curriculum JSON: %w", err)
+	}
+	c.Slug = ""
+	c.UserID = sql.NullInt64{Int64: userID, Valid: true}
+
+	if err := ValidateCurriculum(&c); err != nil {
+		return nil, err
+	}
+	if err := validateIslands(&c); err != nil {
+		return nil, err
+	}
+
+	id, err := repository.CreateCurriculum(&c)
+	if err != nil {
+		return nil, err
+	}
+	c.ID = id
+	return &c, nil
+}
+
+// ExportCurriculum returns a curriculum as indented JSON, the format
+// accepted by CreateCurriculum
+func (s *CurriculumService) ExportCurriculum(c *models.Curriculum) string {
+	data, _ := json.MarshalIndent(c, "", "  ")
+	return string(data)
+}
+
+// ValidateCurriculum checks that phases cover consecutive days from day 1
+// and have usable quotas and mode weights
+func ValidateCurriculum(c *models.Curriculum) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return fmt.Errorf("curriculum needs a name")
+	}
+	if len(c.Phases) == 0 {
+		return fmt.Errorf("curriculum needs at least one phase")
+	}
+
+	nextDay := 1
+	for i := range c.Phases {
+		p := &c.Phases[i]
+		p.ID = i + 1
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("Phase %d", p.ID)
+		}
+		if p.StartDay != nextDay {
+			return fmt.Errorf("phase %d must start on day %d", p.ID, nextDay)
+		}
+		if p.EndDay < p.StartDay {
+			return fmt.Errorf("phase %d ends before it starts", p.ID)
+		}
+		if p.NewCardsPerDay < 0 || p.NewCardsPerDay > 200 {
+			return fmt.Errorf("phase %d: new_cards_per_day must be 0-200", p.ID)
+		}
+		if len(p.TargetIslands) == 0 {
+			return fmt.Errorf("phase %d needs at least one target island", p.ID)
+		}
+		w := p.ModeWeights
+		if w.Standard < 0 || w.Reverse < 0 || w.Typing < 0 || w.Standard+w.Reverse+w.Typing == 0 {
+			return fmt.Errorf("phase %d: mode weights must be non-negative and not all zero", p.ID)
+		}
+		nextDay = p.EndDay + 1
+	}
+
+	if c.TotalDays() > MaxCurriculumDays {
+		return fmt.Errorf("curriculum can be at most %d days", MaxCurriculumDays)
+	}
+	return nil
+}
+
+// validateIslands checks that every target island exists
+func validateIslands(c *models.Curriculum) error {
+	islands, err := repository.GetAllIslands()
+	if err != nil {
+		return err
+	}
+	known := make(map[int64]bool, len(islands))
+	for _, island := range islands {
+		known[island.ID] = true
+	}
+
+	for _, p := range c.Phases {
+		for _, id := range p.TargetIslands {
+			if !known[id] {
+				return fmt.Errorf("phase %d: unknown island %d", p.ID, id)
+			}
+		}
+	}
+	return nil
+}
+
+// journeyCurriculum loads the curriculum a journey follows
+func journeyCurriculum(journey *models.CurriculumJourney) (*models.Curriculum, error) {
+	if journey.CurriculumID.Valid {
+		c, err := repository.GetCurriculum(journey.CurriculumID.Int64)
+		if err != sql.ErrNoRows {
+			return c, err
+		}
+	}
+	return repository.GetCurriculumBySlug(DefaultCurriculumSlug)
+}
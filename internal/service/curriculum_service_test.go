@@ -0,0 +1,65 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"languagepapi/internal/models"
+)
+
+func TestValidateCurriculum(t *testing.T) {
+	phase := func(start, end int) models.CurriculumPhase {
+		return models.CurriculumPhase{StartDay: start, EndDay: end, NewCardsPerDay: 10,
+			TargetIslands: []int64{1}, ModeWeights: models.ModeWeights{Standard: 100}}
+	}
+	tests := []struct {
+		name    string
+		c       models.Curriculum
+		wantErr string // Substring of the error, "" for valid
+	}{
+		{"valid", models.Curriculum{Name: " Mine ", Phases: []models.CurriculumPhase{phase(1, 7), phase(8, 30)}}, ""},
+		{"no name", models.Curriculum{Name: "  ", Phases: []models.CurriculumPhase{phase(1, 7)}}, "needs a name"},
+		{"no phases", models.Curriculum{Name: "Mine"}, "at least one phase"},
+		{"late start", models.Curriculum{Name: "Mine", Phases: []models.CurriculumPhase{phase(2, 7)}}, "start on day 1"},
+		{"gap", models.Curriculum{Name: "Mine", Phases: []models.CurriculumPhase{phase(1, 7), phase(9, 14)}}, "phase 2 must start on day 8"},
+		{"overlap", models.Curriculum{Name: "Mine", Phases: []models.CurriculumPhase{phase(1, 7), phase(7, 14)}}, "phase 2 must start on day 8"},
+		{"ends before start", models.Curriculum{Name: "Mine", Phases: []models.CurriculumPhase{phase(1, 0)}}, "ends before it starts"},
+		{"too long", models.Curriculum{Name: "Mine", Phases: []models.CurriculumPhase{phase(1, MaxCurriculumDays+1)}}, "at most"},
+	}
+	for _, tt := range tests {
+		err := ValidateCurriculum(&tt.c)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: ValidateCurriculum() error = %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: ValidateCurriculum() error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+
+	bad := []struct {
+		name   string
+		change func(*models.CurriculumPhase)
+	}{
+		{"negative quota", func(p *models.CurriculumPhase) { p.NewCardsPerDay = -1 }},
+		{"huge quota", func(p *models.CurriculumPhase) { p.NewCardsPerDay = 201 }},
+		{"no islands", func(p *models.CurriculumPhase) { p.TargetIslands = nil }},
+		{"zero weights", func(p *models.CurriculumPhase) { p.ModeWeights = models.ModeWeights{} }},
+		{"negative weight", func(p *models.CurriculumPhase) { p.ModeWeights.Reverse = -10 }},
+	}
+	for _, tt := range bad {
+		p := phase(1, 7)
+		tt.change(&p)
+		if err := ValidateCurriculum(&models.Curriculum{Name: "Mine", Phases: []models.CurriculumPhase{p}}); err == nil {
+			t.Errorf("%s: ValidateCurriculum() succeeded, want an error", tt.name)
+		}
+	}
+
+	// Valid curricula are normalised: trimmed name, numbered and named phases
+	c := models.Curriculum{Name: " Mine ", Phases: []models.CurriculumPhase{phase(1, 7), phase(8, 30)}}
+	if err := ValidateCurriculum(&c); err != nil {
+		t.Fatalf("ValidateCurriculum() error = %v", err)
+	}
+	if c.Name != "Mine" || c.Phases[1].ID != 2 || c.Phases[1].Name != "Phase 2" {
+		t.Errorf("normalised curriculum = %q, phase %d %q", c.Name, c.Phases[1].ID, c.Phases[1].Name)
+	}
+}
@@ -8,7 +8,7 @@ import (
 	"languagepapi/internal/repository"
 )
 
-const DefaultDailyGoal = 20 // cards per day
+const DefaultDailyGoal = 20 // cards per day, unless set in user settings
 
 // CalculateLevel returns level based on XP (level = floor(sqrt(XP / 100)))
 func CalculateLevel(xp int) int {
@@ -54,7 +54,12 @@ func GetGamificationStats(userID int64) (*models.GamificationStats, error) {
 	if todayStats != nil {
 		dailyProgress = todayStats.CardsReviewed
 	}
-	dailyPercent := math.Min(float64(dailyProgress)/float64(DefaultDailyGoal)*100, 100)
+	settings, _ := LoadUserSettings(userID)
+	dailyGoal := settings.DailyGoal
+	if dailyGoal < 1 {
+		dailyGoal = DefaultDailyGoal
+	}
+	dailyPercent := math.Min(float64(dailyProgress)/float64(dailyGoal)*100, 100)
 
 	// Get achievements
 	achievements, _ := repository.GetUserAchievements(userID)
@@ -73,7 +78,7 @@ func GetGamificationStats(userID int64) (*models.GamificationStats, error) {
 		CurrentStreak:  user.CurrentStreak,
 		LongestStreak:  user.LongestStreak,
 		IsActiveToday:  isActiveToday,
-		DailyGoal:      DefaultDailyGoal,
+		DailyGoal:      dailyGoal,
 		DailyProgress:  dailyProgress,
 		DailyPercent:   dailyPercent,
 		Achievements:   achievements,
@@ -212,7 +217,7 @@ func GetCalendarData(userID int64) (*models.CalendarData, error) {
 	}
 
 	totalReviews, _ := repository.GetTotalReviews(userID)
-	totalCards, _ := repository.CountCards()
+	totalCards, _ := repository.CountCards(userID)
 	user, _ := repository.GetUser(userID)
 
 	totalXP := 0
@@ -40,11 +40,10 @@ func (s *GrammarService) GetGrammarForCard(ctx context.Context, card *models.Car
 
 // GenerateGrammarForCard generates grammar explanation using AI
 func (s *GrammarService) GenerateGrammarForCard(ctx context.Context, card *models.Card) (*models.GrammarTip, error) {
-	gemini, err := bridge.NewGeminiService(ctx)
+	llm, err := bridge.NewLLM(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create AI service: %w", err)
 	}
-	defer gemini.Close()
 
 	prompt := fmt.Sprintf(`Analyze the Spanish word and explain the relevant grammar concept.
 
@@ -69,30 +68,16 @@ Return ONLY valid JSON:
 Keep explanation under 150 words. Include 2-3 practical examples.`,
 		card.Term, card.Translation, card.ExampleSentence)
 
-	response, err := gemini.GenerateContent(ctx, prompt)
+	result, err := bridge.Structured[grammarResponse]{
+		Task:     "grammar",
+		Prompt:   prompt,
+		Schema:   grammarSchema,
+		Validate: validateGrammar,
+	}.Generate(ctx, llm)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate grammar: %w", err)
 	}
 
-	// Parse response
-	text := strings.TrimSpace(response)
-	text = strings.TrimPrefix(text, "```json")
-	text = strings.TrimPrefix(text, "```")
-	text = strings.TrimSuffix(text, "```")
-	text = strings.TrimSpace(text)
-
-	var result struct {
-		RuleKey     string                   `json:"rule_key"`
-		Title       string                   `json:"title"`
-		Explanation string                   `json:"explanation"`
-		Examples    []models.GrammarExample  `json:"examples"`
-		Difficulty  int                      `json:"difficulty"`
-	}
-
-	if err := json.Unmarshal([]byte(text), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse grammar response: %w", err)
-	}
-
 	// Save to database
 	examplesJSON, _ := json.Marshal(result.Examples)
 	ruleID, err := repository.SaveGrammarRule(
@@ -117,6 +102,43 @@ Keep explanation under 150 words. Include 2-3 practical examples.`,
 	}, nil
 }
 
+type grammarResponse struct {
+	RuleKey     string                  `json:"rule_key"`
+	Title       string                  `json:"title"`
+	Explanation string                  `json:"explanation"`
+	Examples    []models.GrammarExample `json:"examples"`
+	Difficulty  int                     `json:"difficulty"`
+}
+
+var grammarSchema = bridge.ObjectSchema(map[string]bridge.Schema{
+	"rule_key":    bridge.StringSchema(60),
+	"title":       bridge.StringSchema(0),
+	"explanation": bridge.StringSchema(0),
+	"examples": bridge.ArraySchema(bridge.ObjectSchema(map[string]bridge.Schema{
+		"spanish": bridge.StringSchema(0),
+		"english": bridge.StringSchema(0),
+	}), 1, 5),
+	"difficulty": bridge.IntegerSchema(1, 5),
+})
+
+// validateGrammar requires a snake_case rule key, an explanation and at
+// least one example
+func validateGrammar(g *grammarResponse) error {
+	if g.RuleKey == "" {
+		return fmt.Errorf("rule_key is empty")
+	}
+	if strings.Trim(g.RuleKey, "abcdefghijklmnopqrstuvwxyz0123456789_") != "" {
+		return fmt.Errorf("rule_key %q is not snake_case", g.RuleKey)
+	}
+	if strings.TrimSpace(g.Explanation) == "" {
+		return fmt.Errorf("explanation is empty")
+	}
+	if len(g.Examples) == 0 {
+		return fmt.Errorf("no examples")
+	}
+	return nil
+}
+
 // GetPreLessonTips analyzes cards in a lesson and returns relevant grammar tips
 func (s *GrammarService) GetPreLessonTips(ctx context.Context, cards []models.LessonCard) ([]models.GrammarTip, error) {
 	var tips []models.GrammarTip
@@ -0,0 +1,518 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"languagepapi/internal/bridge"
+	"languagepapi/internal/jobs"
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+)
+
+// Job kinds
+const (
+	JobBridges         = "bridges"
+	JobExample         = "example"
+	JobQuestion        = "question"
+	JobGrammar         = "grammar"
+	JobLyrics          = "lyrics"
+	JobTranslateLyrics = "translate_lyrics"
+	JobSongVocab       = "song_vocab"
+)
+
+// Rate limits shared by the job kinds that call the same provider
+const (
+	limitLLM    = "llm"
+	limitLRCLib = "lrclib"
+)
+
+// defaultLLMRates are the LLM requests per minute allowed by default for
+// each provider; JOBS_LLM_RPM overrides them. Gemini's free tier allows 15.
+var defaultLLMRates = map[string]int{
+	bridge.ProviderGemini: 15,
+	bridge.ProviderOpenAI: 60,
+	bridge.ProviderFake:   0,
+}
+
+const (
+	defaultJobWorkers = 2
+	defaultLRCLibRate = 30
+	defaultBatchLimit = 100
+)
+
+// ErrNothingToDo is returned when a batch would have no jobs
+var ErrNothingToDo = errors.New("nothing to do")
+
+// jobPayload holds the arguments of every job kind
+type jobPayload struct {
+	CardID   int64               `json:"card_id,omitempty"`
+	SongID   int64               `json:"song_id,omitempty"`
+	Question models.QuestionType `json:"question,omitempty"`
+	Replace  bool                `json:"replace,omitempty"` // Bridges: delete the existing ones
+}
+
+// BatchKind is a batch of work users can start from the jobs page
+type BatchKind struct {
+	Key   string
+	Label string
+}
+
+// BatchKinds lists the batches in the order the jobs page offers them
+var BatchKinds = []BatchKind{
+	{"bridges", "Memory bridges for words without any"},
+	{"examples", "Example sentences for words without one"},
+	{"questions", "Practice questions for words"},
+	{"grammar", "Grammar notes for words"},
+	{"lyrics", "Fetch missing song lyrics"},
+	{"translations", "Translate untranslated song lines"},
+	{"song_vocab", "Extract vocabulary for songs without any"},
+}
+
+// cardBatches maps the one-job-per-card batches to their job kind and the
+// cards they select
+var cardBatches = map[string]struct {
+	kind    string
+	missing repository.CardsMissing
+}{
+	"bridges":  {JobBridges, repository.MissingBridges},
+	"examples": {JobExample, repository.MissingExample},
+	"grammar":  {JobGrammar, repository.MissingGrammar},
+}
+
+// JobService enqueues background work and runs it on a jobs.Queue
+type JobService struct {
+	queue     *jobs.Queue
+	auth      *AuthService
+	questions *QuestionService
+	grammar   *GrammarService
+}
+
+// NewJobService creates a job service with every job kind registered. Jobs
+// only run once Start or RunBatch is called.
+func NewJobService() *JobService {
+	s := &JobService{
+		queue:     jobs.New(),
+		auth:      NewAuthService(),
+		questions: NewQuestionService(),
+		grammar:   NewGrammarService(),
+	}
+	s.queue.Register(JobBridges, jobs.Kind{Handler: llmJob(s.runBridges), Limit: limitLLM})
+	s.queue.Register(JobExample, jobs.Kind{Handler: llmJob(s.runExample), Limit: limitLLM})
+	s.queue.Register(JobQuestion, jobs.Kind{Handler: llmJob(s.runQuestion), Limit: limitLLM})
+	s.queue.Register(JobGrammar, jobs.Kind{Handler: llmJob(s.runGrammar), Limit: limitLLM})
+	s.queue.Register(JobLyrics, jobs.Kind{Handler: s.runLyrics, Limit: limitLRCLib})
+	s.queue.Register(JobTranslateLyrics, jobs.Kind{Handler: llmJob(s.runTranslateLyrics), Limit: limitLLM})
+	s.queue.Register(JobSongVocab, jobs.Kind{Handler: llmJob(s.runSongVocab), Limit: limitLLM})
+	return s
+}
+
+// Start requeues jobs interrupted by the last shutdown and starts the
+// workers. Jobs another process is running, such as a CLI batch, are left
+// alone while their heartbeat is fresh. JOBS_WORKERS, JOBS_LLM_RPM and
+// JOBS_LRCLIB_RPM are read here, so call it after .env is loaded.
+func (s *JobService) Start(ctx context.Context) {
+	if n, err := repository.ResetStaleJobs(jobs.StaleAfter); err != nil {
+		log.Printf("Failed to requeue interrupted jobs: %v", err)
+	} else if n > 0 {
+		log.Printf("Requeued %d interrupted jobs", n)
+	}
+	s.start(ctx)
+}
+
+func (s *JobService) start(ctx context.Context) {
+	provider := bridge.ConfigFromEnv().Provider
+	if provider == "" {
+		provider = bridge.ProviderGemini
+	}
+	llmRate, ok := defaultLLMRates[provider]
+	if !ok {
+		llmRate = defaultLLMRates[bridge.ProviderGemini]
+	}
+
+	s.queue.SetRate(limitLLM, envInt("JOBS_LLM_RPM", llmRate))
+	s.queue.SetRate(limitLRCLib, envInt("JOBS_LRCLIB_RPM", defaultLRCLibRate))
+	s.queue.Start(ctx, envInt("JOBS_WORKERS", defaultJobWorkers))
+}
+
+// RunBatch runs the batch's jobs in this process until it is finished or
+// ctx is cancelled, calling progress whenever its counts change. Jobs of
+// other batches are left to the server, and the batch's jobs still running
+// when it returns go back in the queue. Used by the CLI tools.
+func (s *JobService) RunBatch(ctx context.Context, batchID int64, progress func(*models.JobBatch)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer s.queue.Wait()
+	defer cancel()
+	s.queue.OnlyBatch(batchID)
+	s.start(ctx)
+
+	var last models.JobBatch
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		batch, err := repository.GetJobBatch(batchID)
+		if err != nil {
+			return err
+		}
+		if *batch != last {
+			progress(batch)
+			last = *batch
+		}
+		if batch.Finished() {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// EnqueueBridges queues bridge generation for a card, replacing its
+// existing bridges if replace is set
+func (s *JobService) EnqueueBridges(userID, cardID int64, replace bool) (int64, error) {
+	return s.enqueue(userID, 0, JobBridges, jobPayload{CardID: cardID, Replace: replace})
+}
+
+// EnqueueLyrics queues fetching and then translating a song's lyrics and
+// extracting its vocabulary
+func (s *JobService) EnqueueLyrics(userID, songID int64) (int64, error) {
+	return s.enqueue(userID, 0, JobLyrics, jobPayload{SongID: songID})
+}
+
+// Cancel cancels a job the user can see
+func (s *JobService) Cancel(userID, jobID int64) error {
+	ok, err := repository.CancelJob(jobID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("job %d is not running or queued", jobID)
+	}
+	s.queue.Cancel(jobID)
+	return nil
+}
+
+// CancelBatch cancels the unfinished jobs of a batch
+func (s *JobService) CancelBatch(userID, batchID int64) error {
+	ids, err := repository.CancelJobBatch(batchID, userID)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		s.queue.Cancel(id)
+	}
+	return nil
+}
+
+// RetryBatch queues the failed jobs of a batch again
+func (s *JobService) RetryBatch(userID, batchID int64) error {
+	if _, err := repository.RetryFailedJobs(batchID, userID); err != nil {
+		return err
+	}
+	s.queue.Wake()
+	return nil
+}
+
+// StartBatch queues one of BatchKinds for up to limit items (0 for the
+// default). A zero userID starts it on behalf of the CLI. Card batches cover
+// the user's own cards, and the shared ones too for the CLI and admins.
+// Returns ErrNothingToDo if no item needs the work.
+func (s *JobService) StartBatch(userID int64, key string, limit int) (*models.JobBatch, error) {
+	if limit <= 0 {
+		limit = defaultBatchLimit
+	}
+	shared, err := s.mayEditShared(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var kind, noun string
+	var payloads []jobPayload
+	switch key {
+	case "bridges", "examples", "grammar":
+		batch := cardBatches[key]
+		kind = batch.kind
+		ids, err := repository.GetCardIDsMissing(userID, shared, batch.missing, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			payloads = append(payloads, jobPayload{CardID: id})
+		}
+		noun = "words"
+	case "questions":
+		kind = JobQuestion
+		ids, err := repository.GetCardIDsMissing(userID, shared, repository.MissingQuestions, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			for _, q := range []models.QuestionType{models.QuestionMCQ, models.QuestionFillBlank, models.QuestionSentenceBuild} {
+				payloads = append(payloads, jobPayload{CardID: id, Question: q})
+			}
+		}
+		noun = "questions"
+	case "lyrics", "translations", "song_vocab":
+		kind = JobLyrics
+		lookup := repository.GetSongIDsWithoutLyrics
+		switch key {
+		case "translations":
+			kind = JobTranslateLyrics
+			lookup = repository.GetSongIDsWithUntranslatedLines
+		case "song_vocab":
+			kind = JobSongVocab
+			lookup = repository.GetSongIDsWithoutVocab
+		}
+		ids, err := lookup()
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids[:min(len(ids), limit)] {
+			payloads = append(payloads, jobPayload{SongID: id})
+		}
+		noun = "songs"
+	default:
+		return nil, fmt.Errorf("unknown batch %q", key)
+	}
+	if len(payloads) == 0 {
+		return nil, ErrNothingToDo
+	}
+
+	label := key
+	for _, b := range BatchKinds {
+		if b.Key == key {
+			label = b.Label
+		}
+	}
+	if len(payloads) == 1 {
+		noun = strings.TrimSuffix(noun, "s")
+	}
+	name := fmt.Sprintf("%s (%d %s)", label, len(payloads), noun)
+	batchID, err := repository.CreateJobBatch(name, nullUserID(userID))
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range payloads {
+		if _, err := s.enqueue(userID, batchID, kind, p); err != nil {
+			return nil, err
+		}
+	}
+	return repository.GetJobBatch(batchID)
+}
+
+func (s *JobService) enqueue(userID, batchID int64, kind string, payload jobPayload) (int64, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	job := &models.Job{
+		UserID:  nullUserID(userID),
+		Kind:    kind,
+		Payload: string(data),
+	}
+	if batchID != 0 {
+		job.BatchID = sql.NullInt64{Int64: batchID, Valid: true}
+	}
+	return s.queue.Enqueue(job)
+}
+
+// mayEditShared reports whether work started by the user may change shared
+// cards. The CLI (a zero userID) and admins may.
+func (s *JobService) mayEditShared(userID int64) (bool, error) {
+	if userID == 0 {
+		return true, nil
+	}
+	return s.auth.IsAdmin(userID)
+}
+
+func nullUserID(userID int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: userID, Valid: userID != 0}
+}
+
+// Job handlers
+
+func decodePayload(job *models.Job) (jobPayload, error) {
+	var p jobPayload
+	if err := json.Unmarshal([]byte(job.Payload), &p); err != nil {
+		return p, jobs.Permanent(fmt.Errorf("invalid payload: %w", err))
+	}
+	return p, nil
+}
+
+// jobCard loads the card a job works on; a deleted card fails it for good
+func jobCard(job *models.Job) (*models.Card, jobPayload, error) {
+	p, err := decodePayload(job)
+	if err != nil {
+		return nil, p, err
+	}
+	card, err := repository.GetCard(p.CardID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, p, jobs.Permanent(fmt.Errorf("card %d not found", p.CardID))
+	}
+	return card, p, err
+}
+
+// editableJobCard loads the card a job changes. A shared card fails the job
+// for good unless whoever queued it may edit shared cards.
+func (s *JobService) editableJobCard(job *models.Job) (*models.Card, jobPayload, error) {
+	card, p, err := jobCard(job)
+	if err != nil || card.UserID.Valid {
+		return card, p, err
+	}
+	ok, err := s.mayEditShared(job.UserID.Int64)
+	if err != nil {
+		return nil, p, err
+	}
+	if !ok {
+		return nil, p, jobs.Permanent(fmt.Errorf("card %d is shared", card.ID))
+	}
+	return card, p, nil
+}
+
+// newAI creates the bridge service; a misconfigured provider won't fix
+// itself on retry
+func newAI(ctx context.Context) (*bridge.Service, error) {
+	ai, err := bridge.NewService(ctx)
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+	return ai, nil
+}
+
+// llmJob fails a job for good when the monthly AI budget blocks it;
+// retrying before the month is out would only be blocked again
+func llmJob(h jobs.Handler) jobs.Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		err := h(ctx, job)
+		if errors.Is(err, bridge.ErrBudgetExceeded) {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+}
+
+func (s *JobService) runBridges(ctx context.Context, job *models.Job) error {
+	card, p, err := s.editableJobCard(job)
+	if err != nil {
+		return err
+	}
+	ai, err := newAI(ctx)
+	if err != nil {
+		return err
+	}
+	return ai.GenerateAndSaveBridges(ctx, card.ID, p.Replace)
+}
+
+func (s *JobService) runExample(ctx context.Context, job *models.Job) error {
+	card, _, err := s.editableJobCard(job)
+	if err != nil || card.ExampleSentence != "" {
+		return err
+	}
+	ai, err := newAI(ctx)
+	if err != nil {
+		return err
+	}
+
+	example, err := ai.GenerateExampleSentence(ctx, card.Term, card.Translation)
+	if err != nil {
+		return err
+	}
+	card.ExampleSentence = strings.TrimSpace(example)
+	return repository.UpdateCard(card)
+}
+
+func (s *JobService) runQuestion(ctx context.Context, job *models.Job) error {
+	card, p, err := jobCard(job)
+	if err != nil {
+		return err
+	}
+
+	switch p.Question {
+	case models.QuestionMCQ:
+		_, err = s.questions.GetOrGenerateMCQ(ctx, card)
+	case models.QuestionFillBlank:
+		_, err = s.questions.GetOrGenerateFillBlank(ctx, card)
+	case models.QuestionSentenceBuild:
+		_, err = s.questions.GetOrGenerateSentenceBuild(ctx, card)
+	default:
+		err = jobs.Permanent(fmt.Errorf("unknown question type %q", p.Question))
+	}
+	return err
+}
+
+func (s *JobService) runGrammar(ctx context.Context, job *models.Job) error {
+	card, _, err := jobCard(job)
+	if err != nil {
+		return err
+	}
+	_, err = s.grammar.GetGrammarForCard(ctx, card)
+	return err
+}
+
+// runLyrics fetches a song's lyrics and queues their translation in the
+// same batch
+func (s *JobService) runLyrics(ctx context.Context, job *models.Job) error {
+	p, err := decodePayload(job)
+	if err != nil {
+		return err
+	}
+
+	err = NewLyricsService().FetchAndStoreLyrics(p.SongID)
+	switch {
+	case errors.Is(err, ErrHasLyrics):
+		// Fetched by an earlier job; still make sure it gets translated
+	case errors.Is(err, ErrLyricsNotFound), errors.Is(err, sql.ErrNoRows):
+		return jobs.Permanent(err)
+	case err != nil:
+		return err
+	}
+
+	_, err = s.enqueue(job.UserID.Int64, job.BatchID.Int64, JobTranslateLyrics, jobPayload{SongID: p.SongID})
+	return err
+}
+
+// runTranslateLyrics translates a song's lines and queues extracting its
+// vocabulary in the same batch
+func (s *JobService) runTranslateLyrics(ctx context.Context, job *models.Job) error {
+	p, err := decodePayload(job)
+	if err != nil {
+		return err
+	}
+	if _, err := NewLyricsService().TranslateSongLines(ctx, p.SongID); err != nil {
+		return err
+	}
+
+	_, err = s.enqueue(job.UserID.Int64, job.BatchID.Int64, JobSongVocab, jobPayload{SongID: p.SongID})
+	return err
+}
+
+func (s *JobService) runSongVocab(ctx context.Context, job *models.Job) error {
+	p, err := decodePayload(job)
+	if err != nil {
+		return err
+	}
+	_, err = NewSongVocabService().Extract(ctx, job.UserID.Int64, p.SongID)
+	if errors.Is(err, ErrNoLyrics) || errors.Is(err, sql.ErrNoRows) {
+		return jobs.Permanent(err)
+	}
+	return err
+}
+
+// envInt reads a non-negative integer from the environment
+func envInt(key string, defaultVal int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil && n >= 0 {
+		return n
+	}
+	return defaultVal
+}
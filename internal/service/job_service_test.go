@@ -0,0 +1,40 @@
+package service
+
+import (
+	"testing"
+
+	"languagepapi/internal/repository"
+)
+
+func TestCardBatchesSkipSharedCardsForUsers(t *testing.T) {
+	openServiceTestDB(t)
+	t.Setenv("ADMIN_USERS", "admin")
+	alice, err := repository.CreateUser("alice", "")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	admin, err := repository.CreateUser("admin", "")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	createServiceTestCard(t, "trasto", "junk", alice.ID)
+	createServiceTestCard(t, "cachivache", "gadget", admin.ID)
+	createServiceTestCard(t, "zarandaja", "trifle", 0)
+
+	s := NewJobService()
+	batch, err := s.StartBatch(alice.ID, "examples", 0)
+	if err != nil {
+		t.Fatalf("StartBatch(alice) error = %v", err)
+	}
+	if batch.Total != 1 {
+		t.Errorf("alice's batch has %d jobs, want 1 for her own card", batch.Total)
+	}
+
+	batch, err = s.StartBatch(admin.ID, "examples", 0)
+	if err != nil {
+		t.Fatalf("StartBatch(admin) error = %v", err)
+	}
+	if batch.Total != 2 {
+		t.Errorf("admin's batch has %d jobs, want 2 for their own and the shared card", batch.Total)
+	}
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"database/sql"
 	"fmt"
 	"math/rand"
 	"time"
@@ -9,30 +10,6 @@ import (
 	"languagepapi/internal/repository"
 )
 
-// CurriculumPhases defines the 14-day intensive sprint (~70 new words/day)
-var CurriculumPhases = []models.CurriculumPhase{
-	{
-		ID:             1,
-		Name:           "Sprint Week 1",
-		Description:    "Core 500 most frequent words",
-		StartDay:       1,
-		EndDay:         7,
-		NewCardsPerDay: 72,
-		TargetIslands:  []int64{1, 2, 3}, // Core Essentials, Common Words, Expanding
-		ModeWeights:    models.ModeWeights{Standard: 60, Reverse: 30, Typing: 10},
-	},
-	{
-		ID:             2,
-		Name:           "Sprint Week 2",
-		Description:    "Advanced 500 words + Review",
-		StartDay:       8,
-		EndDay:         14,
-		NewCardsPerDay: 72,
-		TargetIslands:  []int64{4, 5, 6, 7, 8, 9}, // All remaining islands
-		ModeWeights:    models.ModeWeights{Standard: 50, Reverse: 35, Typing: 15},
-	},
-}
-
 // LessonService handles the daily lesson flow
 type LessonService struct {
 	reviewService *ReviewService
@@ -45,44 +22,39 @@ func NewLessonService() *LessonService {
 	}
 }
 
-// GetPhaseForDay returns the curriculum phase for a given day number
-func GetPhaseForDay(day int) *models.CurriculumPhase {
-	for i := range CurriculumPhases {
-		if day >= CurriculumPhases[i].StartDay && day <= CurriculumPhases[i].EndDay {
-			return &CurriculumPhases[i]
-		}
-	}
-	// Default to last phase if beyond 14 days
-	return &CurriculumPhases[len(CurriculumPhases)-1]
-}
+// MaintenanceNewCardsPerDay is the new-card quota once a curriculum is
+// finished. The new-cards setting can lower it but not raise it.
+const MaintenanceNewCardsPerDay = 5
+
+// maintenancePhaseID is the ID of the phase after the curriculum; curriculum
+// phases are numbered from 1
+const maintenancePhaseID = 0
 
-// CalculateDayNumber calculates which day of the journey we're on
+// CalculateDayNumber calculates which day of the journey we're on.
+// Days past the curriculum's end mean the journey is complete.
 func CalculateDayNumber(startDate time.Time) int {
 	now := time.Now()
 	// Normalize to start of day
 	startDay := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, startDate.Location())
 	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 
-	days := int(today.Sub(startDay).Hours() / 24) + 1
+	days := int(today.Sub(startDay).Hours()/24) + 1
 	if days < 1 {
 		days = 1
 	}
-	if days > 14 {
-		days = 14
-	}
 	return days
 }
 
 // BuildDailyLesson creates today's lesson with smart card selection and mode assignment
 func (s *LessonService) BuildDailyLesson(userID int64) (*models.DailyLesson, error) {
 	// Get or create journey
-	journey, err := repository.GetOrCreateJourney(userID)
+	journey, curriculum, err := s.journeyAndCurriculum(userID)
 	if err != nil {
 		return nil, err
 	}
 
 	dayNumber := CalculateDayNumber(journey.StartDate)
-	phase := GetPhaseForDay(dayNumber)
+	phase, overrides := applySettingsToPhase(userID, phaseForDay(curriculum, dayNumber))
 
 	// Get all due reviews (mandatory) - curriculum cards only
 	dueCards, err := repository.GetDueCards(userID, 100)
@@ -96,6 +68,12 @@ func (s *LessonService) BuildDailyLesson(userID int64) (*models.DailyLesson, err
 		songVocabDue = nil // Continue without song vocab if error
 	}
 
+	// Get due song lines (max 5 per day); new ones are met in song lessons
+	songLinesDue, err := repository.GetDueSongLineCards(userID, 5)
+	if err != nil {
+		songLinesDue = nil
+	}
+
 	// Get new song vocab cards from songs in progress (max 2 per day)
 	songsInProgress, _ := repository.GetUserSongsInProgress(userID)
 	songVocabNew, err := repository.GetNewSongVocabCards(userID, songsInProgress, 2)
@@ -117,6 +95,9 @@ func (s *LessonService) BuildDailyLesson(userID int64) (*models.DailyLesson, err
 	for _, c := range songVocabDue {
 		allDueCards = append(allDueCards, c)
 	}
+	for _, c := range songLinesDue {
+		allDueCards = append(allDueCards, c)
+	}
 	allNewCards := newCards
 	for _, c := range songVocabNew {
 		allNewCards = append(allNewCards, c)
@@ -125,13 +106,14 @@ func (s *LessonService) BuildDailyLesson(userID int64) (*models.DailyLesson, err
 	// Build lesson with interleaving
 	lessonCards := s.interleaveLessonCards(allDueCards, allNewCards, phase)
 
-	// Mark song vocab cards and fetch song titles
+	// Mark song vocab cards and fetch song titles, for lines too
 	for i := range lessonCards {
-		if lessonCards[i].Source == "song" {
-			lessonCards[i].IsSongVocab = true
-			if title, err := repository.GetSongTitleForCard(lessonCards[i].ID); err == nil {
-				lessonCards[i].SongTitle = title
-			}
+		if lessonCards[i].Source != "song" && lessonCards[i].Source != "song_line" {
+			continue
+		}
+		lessonCards[i].IsSongVocab = lessonCards[i].Source == "song"
+		if title, err := repository.GetSongTitleForCard(lessonCards[i].ID); err == nil {
+			lessonCards[i].SongTitle = title
 		}
 	}
 
@@ -151,25 +133,137 @@ func (s *LessonService) BuildDailyLesson(userID int64) (*models.DailyLesson, err
 
 	return &models.DailyLesson{
 		DayNumber:      dayNumber,
+		TotalDays:      curriculum.TotalDays(),
 		Phase:          phase,
 		Cards:          lessonCards,
 		EstimatedMins:  estimatedMins,
-		DueReviewCount: len(dueCards) + len(songVocabDue),
+		DueReviewCount: len(dueCards) + len(songVocabDue) + len(songLinesDue),
 		NewCardCount:   len(newCards) + len(songVocabNew),
+		Overrides:      overrides,
 	}, nil
 }
 
+// StartOrResumeLesson returns today's persisted lesson, building and storing
+// it when none was started today. A lesson already worked through is
+// returned as is, for its summary: its session and card results are today's
+// record and are never rebuilt. Extra practice goes through /practice.
+func (s *LessonService) StartOrResumeLesson(userID int64) (*models.ActiveLesson, error) {
+	active, err := s.GetActiveLesson(userID)
+	if err == nil {
+		return active, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	lesson, err := s.BuildDailyLesson(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(lesson.Cards) == 0 {
+		return &models.ActiveLesson{Lesson: lesson}, nil
+	}
+
+	if _, err := repository.CreateLessonSession(userID, lesson.DayNumber, lesson.Phase.ID); err != nil {
+		return nil, err
+	}
+	session, err := repository.GetTodayLessonSession(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := repository.SaveLessonCards(session.ID, lesson.Cards); err != nil {
+		return nil, err
+	}
+	return &models.ActiveLesson{Session: session, Lesson: lesson}, nil
+}
+
+// GetActiveLesson loads today's persisted lesson with its position and results.
+// Returns sql.ErrNoRows if no lesson was started today.
+func (s *LessonService) GetActiveLesson(userID int64) (*models.ActiveLesson, error) {
+	session, err := repository.GetTodayLessonSession(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, curriculum, err := s.journeyAndCurriculum(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	phase, overrides := applySettingsToPhase(userID, phaseForDay(curriculum, session.DayNumber))
+	active := &models.ActiveLesson{
+		Session: session,
+		Lesson: &models.DailyLesson{
+			DayNumber: session.DayNumber,
+			TotalDays: curriculum.TotalDays(),
+			Phase:     phase,
+			Overrides: overrides,
+		},
+	}
+	if err := repository.LoadLessonCards(userID, active); err != nil {
+		return nil, err
+	}
+	return active, nil
+}
+
+// phaseForDay returns the curriculum phase for a day, or the maintenance
+// phase once the curriculum is finished
+func phaseForDay(c *models.Curriculum, day int) *models.CurriculumPhase {
+	if day <= c.TotalDays() {
+		return c.PhaseForDay(day)
+	}
+
+	// Maintenance: reviews plus a trickle of new cards from every island
+	// the curriculum covered, so leftovers still get introduced
+	last := c.Phases[len(c.Phases)-1]
+	seen := make(map[int64]bool)
+	var islands []int64
+	for _, p := range c.Phases {
+		for _, id := range p.TargetIslands {
+			if !seen[id] {
+				seen[id] = true
+				islands = append(islands, id)
+			}
+		}
+	}
+
+	return &models.CurriculumPhase{
+		ID:             maintenancePhaseID,
+		Name:           "Maintenance",
+		Description:    "Journey complete: daily reviews and a few new words",
+		StartDay:       c.TotalDays() + 1,
+		EndDay:         day,
+		NewCardsPerDay: MaintenanceNewCardsPerDay,
+		TargetIslands:  islands,
+		ModeWeights:    last.ModeWeights,
+	}
+}
+
+// journeyAndCurriculum loads the user's journey (creating it on first use)
+// and the curriculum it follows
+func (s *LessonService) journeyAndCurriculum(userID int64) (*models.CurriculumJourney, *models.Curriculum, error) {
+	journey, err := repository.GetOrCreateJourney(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	curriculum, err := journeyCurriculum(journey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load curriculum: %w", err)
+	}
+	return journey, curriculum, nil
+}
+
 // calculateNewCardsForToday adjusts new card count based on review load
-// For intensive sprint: stay aggressive, only minor reductions for extreme loads
+// Stay close to the phase quota, only minor reductions for extreme loads
 func (s *LessonService) calculateNewCardsForToday(phase *models.CurriculumPhase, dueCount int) int {
 	base := phase.NewCardsPerDay
 
 	// Only reduce new cards if review load is extreme
 	if dueCount > 150 {
-		return max(50, base-20) // Still learn 50+ new words
+		return base * 2 / 3 // Still learn two thirds of the quota
 	}
 	if dueCount > 100 {
-		return max(60, base-10) // Still learn 60+ new words
+		return base * 5 / 6
 	}
 
 	return base
@@ -230,6 +324,14 @@ func (s *LessonService) selectModeForCard(
 	phase *models.CurriculumPhase,
 	modeHistory []string,
 ) string {
+	// Song lines are heard and recalled whole, sometimes from the translation
+	if card.Source == "song_line" {
+		if card.Translation != "" && rand.Intn(100) < 30 {
+			return "lyric_reverse"
+		}
+		return "lyric"
+	}
+
 	// Rule 1: New cards can be standard or mcq (50/50)
 	if card.Progress == nil || card.Progress.State == models.StateNew {
 		if rand.Intn(100) < 50 {
@@ -347,13 +449,14 @@ func (s *LessonService) weightedRandomExcluding(exclude string, weights models.M
 // GetJourneyHomeData builds the data for the journey home page
 func (s *LessonService) GetJourneyHomeData(userID int64) (*models.JourneyHomeData, error) {
 	// Get or create journey
-	journey, err := repository.GetOrCreateJourney(userID)
+	journey, curriculum, err := s.journeyAndCurriculum(userID)
 	if err != nil {
 		return nil, err
 	}
 
 	dayNumber := CalculateDayNumber(journey.StartDate)
-	phase := GetPhaseForDay(dayNumber)
+	phase := phaseForDay(curriculum, dayNumber)
+	journeyComplete := dayNumber > curriculum.TotalDays()
 
 	// Check if today's lesson is complete
 	completed, session, err := repository.IsTodayLessonComplete(userID)
@@ -362,8 +465,8 @@ func (s *LessonService) GetJourneyHomeData(userID int64) (*models.JourneyHomeDat
 	}
 
 	todayStats := ""
+	accuracy := 0
 	if completed && session != nil {
-		accuracy := 0
 		if session.CardsReviewed > 0 {
 			accuracy = session.CardsCorrect * 100 / session.CardsReviewed
 		}
@@ -391,20 +494,44 @@ func (s *LessonService) GetJourneyHomeData(userID int64) (*models.JourneyHomeDat
 		totalXP = user.TotalXP
 	}
 
+	// Offer the next plan once this one is finished
+	var next *models.Curriculum
+	if journeyComplete {
+		next = s.nextCurriculum(userID, curriculum)
+	}
+
 	return &models.JourneyHomeData{
-		DayNumber:      dayNumber,
-		TotalDays:      14,
-		CurrentPhase:   phase,
-		TodayCompleted: completed,
-		TodayStats:     todayStats,
-		DueCount:       dueCount,
-		NewCount:       newCount,
-		EstimatedMins:  estimatedMins,
-		Streak:         streak,
-		TotalXP:        totalXP,
+		Curriculum:      curriculum,
+		NextCurriculum:  next,
+		JourneyComplete: journeyComplete,
+		Message:         GetMotivationalMessage(dayNumber, curriculum.TotalDays(), accuracy, completed),
+		DayNumber:       dayNumber,
+		TotalDays:       curriculum.TotalDays(),
+		CurrentPhase:    phase,
+		TodayCompleted:  completed,
+		TodayStats:      todayStats,
+		DueCount:        dueCount,
+		NewCount:        newCount,
+		EstimatedMins:   estimatedMins,
+		Streak:          streak,
+		TotalXP:         totalXP,
 	}, nil
 }
 
+// nextCurriculum returns the curriculum listed after the current one, if any
+func (s *LessonService) nextCurriculum(userID int64, current *models.Curriculum) *models.Curriculum {
+	curricula, err := repository.ListCurricula(userID)
+	if err != nil {
+		return nil
+	}
+	for i := range curricula {
+		if curricula[i].ID == current.ID && i+1 < len(curricula) {
+			return &curricula[i+1]
+		}
+	}
+	return nil
+}
+
 func formatStats(reviewed int, accuracy int) string {
 	if reviewed == 0 {
 		return "No cards reviewed"
@@ -413,7 +540,20 @@ func formatStats(reviewed int, accuracy int) string {
 }
 
 // GetMotivationalMessage returns a message based on performance
-func GetMotivationalMessage(dayNumber int, accuracy int, isComplete bool) string {
+func GetMotivationalMessage(dayNumber, totalDays int, accuracy int, isComplete bool) string {
+	// Journey finished: maintenance mode
+	if dayNumber > totalDays {
+		if !isComplete {
+			return "Journey complete! A few minutes of reviews keeps every word fresh."
+		}
+		maintenance := []string{
+			"Words maintained. See you tomorrow!",
+			"Staying sharp after the finish line!",
+			"Reviews done. Your vocabulary thanks you.",
+		}
+		return maintenance[rand.Intn(len(maintenance))]
+	}
+
 	if !isComplete {
 		return "Your daily lesson awaits!"
 	}
@@ -446,26 +586,17 @@ func GetMotivationalMessage(dayNumber int, accuracy int, isComplete bool) string
 		}
 	}
 
-	// Milestone messages for 14-day sprint
+	// Milestone messages along the curriculum
 	switch {
 	case dayNumber == 1:
-		return "Day 1! Let's crush 1000 words in 2 weeks."
-	case dayNumber == 3:
-		return "Day 3! You've already learned ~200 words!"
-	case dayNumber == 7:
-		return "Week 1 complete! ~500 words down, 500 to go!"
-	case dayNumber == 10:
-		return "Day 10! Over 700 words learned. The finish line is close!"
-	case dayNumber == 14:
-		return "YOU DID IT! 1000 words in 14 days. Absolute legend."
+		return fmt.Sprintf("Day 1 of %d! Let's get going.", totalDays)
+	case dayNumber == totalDays:
+		return fmt.Sprintf("YOU DID IT! %d days complete. Absolute legend.", totalDays)
+	case totalDays >= 4 && dayNumber == totalDays/2:
+		return "Halfway there! The finish line is in sight."
+	case dayNumber%7 == 0:
+		return fmt.Sprintf("Week %d complete! Keep the momentum going.", dayNumber/7)
 	}
 
 	return messages[rand.Intn(len(messages))]
 }
-
-func max(a, b int) int {
-	if a > b {
-		return a
-	}
-	return b
-}
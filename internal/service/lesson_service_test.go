@@ -0,0 +1,47 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"languagepapi/internal/models"
+)
+
+func TestPhaseForDay(t *testing.T) {
+	c := &models.Curriculum{Phases: []models.CurriculumPhase{
+		{ID: 1, Name: "Basics", StartDay: 1, EndDay: 7, NewCardsPerDay: 10, TargetIslands: []int64{1, 2},
+			ModeWeights: models.ModeWeights{Standard: 100}},
+		{ID: 2, Name: "Growth", StartDay: 8, EndDay: 14, NewCardsPerDay: 15, TargetIslands: []int64{2, 3},
+			ModeWeights: models.ModeWeights{Standard: 50, Reverse: 30, Typing: 20}},
+	}}
+
+	tests := []struct {
+		day       int
+		wantID    int
+		wantName  string
+		wantQuota int
+	}{
+		{1, 1, "Basics", 10},
+		{7, 1, "Basics", 10},
+		{8, 2, "Growth", 15},
+		{14, 2, "Growth", 15},
+		{15, maintenancePhaseID, "Maintenance", MaintenanceNewCardsPerDay},
+		{400, maintenancePhaseID, "Maintenance", MaintenanceNewCardsPerDay},
+	}
+	for _, tt := range tests {
+		p := phaseForDay(c, tt.day)
+		if p.ID != tt.wantID || p.Name != tt.wantName || p.NewCardsPerDay != tt.wantQuota {
+			t.Errorf("phaseForDay(%d) = phase %d %q with %d new cards, want phase %d %q with %d",
+				tt.day, p.ID, p.Name, p.NewCardsPerDay, tt.wantID, tt.wantName, tt.wantQuota)
+		}
+	}
+
+	// Maintenance covers every island once and keeps the last phase's mix
+	p := phaseForDay(c, 20)
+	if want := []int64{1, 2, 3}; !slices.Equal(p.TargetIslands, want) {
+		t.Errorf("maintenance islands = %v, want %v", p.TargetIslands, want)
+	}
+	if p.ModeWeights != c.Phases[1].ModeWeights || p.StartDay != 15 || p.EndDay != 20 {
+		t.Errorf("maintenance phase = %+v, want days 15-20 with the last phase's weights", p)
+	}
+}
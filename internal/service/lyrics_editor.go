@@ -0,0 +1,260 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+)
+
+// Errors from editing a song's lines
+var (
+	ErrInvalidEdit   = errors.New("invalid lyrics edit")
+	ErrNothingToUndo = errors.New("no lyrics edit to undo")
+)
+
+// LineEditOp is the kind of a LineEdit
+type LineEditOp string
+
+const (
+	EditOffset      LineEditOp = "offset"      // Shift Line, or every line if 0, by Ms
+	EditTime        LineEditOp = "time"        // Set Line's StartMs and EndMs
+	EditText        LineEditOp = "text"        // Set Line's Spanish text to Text
+	EditTranslation LineEditOp = "translation" // Set Line's English text to Text
+	EditSplit       LineEditOp = "split"       // Start a new line at Line's word Word, at Ms if set
+	EditMerge       LineEditOp = "merge"       // Join Line and the line after it
+)
+
+// LineEdit is one change to a song's lines. Lines are numbered from 1 and
+// words, separated by spaces, from 0.
+type LineEdit struct {
+	Op      LineEditOp
+	Line    int
+	Ms      int
+	StartMs int
+	EndMs   int
+	Word    int
+	Text    string
+}
+
+// ApplyLineEdits applies edits in order to a copy of lines, numbering the
+// result from 1. It also returns what each edit that changed something
+// did, e.g. "Split line 4".
+func ApplyLineEdits(lines []models.SongLine, edits []LineEdit) ([]models.SongLine, []string, error) {
+	out := make([]models.SongLine, len(lines))
+	for i, l := range lines {
+		out[i] = l
+		out[i].Words = nil // Positions change; relinked after saving
+		out[i].Timings = append([]models.SongWordTiming(nil), l.Timings...)
+	}
+
+	var done []string
+	for _, e := range edits {
+		if e.Line < 0 || e.Line > len(out) || (e.Line == 0 && e.Op != EditOffset) {
+			return nil, nil, fmt.Errorf("%w: no line %d", ErrInvalidEdit, e.Line)
+		}
+		i := e.Line - 1
+		var note string
+
+		switch e.Op {
+		case EditOffset:
+			if e.Ms == 0 {
+				continue
+			}
+			if e.Line == 0 {
+				for j := range out {
+					shiftLine(&out[j], e.Ms)
+				}
+				note = fmt.Sprintf("Shifted all lines by %+d ms", e.Ms)
+			} else {
+				shiftLine(&out[i], e.Ms)
+				note = fmt.Sprintf("Shifted line %d by %+d ms", e.Line, e.Ms)
+			}
+
+		case EditTime:
+			if e.StartMs == out[i].StartTimeMs && e.EndMs == out[i].EndTimeMs {
+				continue
+			}
+			if e.StartMs < 0 || e.EndMs < e.StartMs {
+				return nil, nil, fmt.Errorf("%w: line %d ends before it starts", ErrInvalidEdit, e.Line)
+			}
+			retimeLine(&out[i], e.StartMs, e.EndMs)
+			note = fmt.Sprintf("Retimed line %d", e.Line)
+
+		case EditText:
+			text := strings.Join(strings.Fields(e.Text), " ")
+			if text == out[i].SpanishText {
+				continue
+			}
+			if text == "" {
+				return nil, nil, fmt.Errorf("%w: line %d has no text", ErrInvalidEdit, e.Line)
+			}
+			out[i].SpanishText = text
+			out[i].Timings = nil // They no longer match the words
+			note = fmt.Sprintf("Edited line %d", e.Line)
+
+		case EditTranslation:
+			text := strings.TrimSpace(e.Text)
+			if text == out[i].EnglishText {
+				continue
+			}
+			out[i].EnglishText = text
+			note = fmt.Sprintf("Translated line %d", e.Line)
+
+		case EditSplit:
+			second, err := splitLine(&out[i], e.Word, e.Ms)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: line %d: %v", ErrInvalidEdit, e.Line, err)
+			}
+			out = append(out[:i+1], append([]models.SongLine{second}, out[i+1:]...)...)
+			note = fmt.Sprintf("Split line %d", e.Line)
+
+		case EditMerge:
+			if i+1 >= len(out) {
+				return nil, nil, fmt.Errorf("%w: line %d is the last line", ErrInvalidEdit, e.Line)
+			}
+			mergeLines(&out[i], &out[i+1])
+			out = append(out[:i+1], out[i+2:]...)
+			note = fmt.Sprintf("Merged lines %d and %d", e.Line, e.Line+1)
+
+		default:
+			return nil, nil, fmt.Errorf("%w: unknown op %q", ErrInvalidEdit, e.Op)
+		}
+		done = append(done, note)
+	}
+
+	for i := range out {
+		out[i].LineNumber = i + 1
+	}
+	return out, done, nil
+}
+
+// shiftLine moves a line and its word timings by ms, not before 0
+func shiftLine(l *models.SongLine, ms int) {
+	shift := func(t int) int { return max(t+ms, 0) }
+	l.StartTimeMs, l.EndTimeMs = shift(l.StartTimeMs), shift(l.EndTimeMs)
+	for i := range l.Timings {
+		l.Timings[i].StartTimeMs, l.Timings[i].EndTimeMs = shift(l.Timings[i].StartTimeMs), shift(l.Timings[i].EndTimeMs)
+	}
+}
+
+// retimeLine sets when a line starts and ends. Its word timings move with
+// its start; words that would start after its end are dropped.
+func retimeLine(l *models.SongLine, startMs, endMs int) {
+	shiftLine(l, startMs-l.StartTimeMs)
+	l.StartTimeMs, l.EndTimeMs = startMs, endMs
+	var kept []models.SongWordTiming
+	for _, t := range l.Timings {
+		if t.StartTimeMs >= endMs {
+			break
+		}
+		t.EndTimeMs = min(t.EndTimeMs, endMs)
+		kept = append(kept, t)
+	}
+	l.Timings = kept
+}
+
+// splitLine cuts l before its word at index word and returns the second
+// part. The cut falls at atMs if it is inside the line, else where the word
+// is timed to start, else in proportion to the text on each side. The
+// translation stays with the first part.
+func splitLine(l *models.SongLine, word, atMs int) (models.SongLine, error) {
+	words := strings.Fields(l.SpanishText)
+	if word < 1 || word >= len(words) {
+		return models.SongLine{}, fmt.Errorf("can't split before word %d of %d", word, len(words))
+	}
+	first, rest := strings.Join(words[:word], " "), strings.Join(words[word:], " ")
+
+	// Word timings split with the words when there is one per word
+	timed := len(l.Timings) == len(words)
+	if atMs <= l.StartTimeMs || atMs >= l.EndTimeMs {
+		switch {
+		case timed:
+			atMs = l.Timings[word].StartTimeMs
+		default:
+			total := utf8.RuneCountInString(first) + utf8.RuneCountInString(rest)
+			atMs = l.StartTimeMs + (l.EndTimeMs-l.StartTimeMs)*utf8.RuneCountInString(first)/total
+		}
+	}
+
+	second := models.SongLine{SongID: l.SongID, StartTimeMs: atMs, EndTimeMs: l.EndTimeMs, SpanishText: rest}
+	if timed {
+		second.Timings = append(second.Timings, l.Timings[word:]...)
+		l.Timings = l.Timings[:word]
+		l.Timings[word-1].EndTimeMs = min(l.Timings[word-1].EndTimeMs, atMs)
+	} else {
+		l.Timings = nil
+	}
+	l.SpanishText, l.EndTimeMs = first, atMs
+	return second, nil
+}
+
+// mergeLines appends next to l
+func mergeLines(l, next *models.SongLine) {
+	l.SpanishText = strings.TrimSpace(l.SpanishText + " " + next.SpanishText)
+	l.EnglishText = strings.TrimSpace(l.EnglishText + " " + next.EnglishText)
+	l.EndTimeMs = max(l.EndTimeMs, next.EndTimeMs)
+	l.Timings = append(l.Timings, next.Timings...)
+}
+
+// EditSongLines applies edits to a song's lines and saves the result as a
+// new version, relinking the lines' words. Edits that change nothing save
+// nothing.
+func (s *LyricsService) EditSongLines(userID, songID int64, edits []LineEdit) error {
+	song, err := repository.GetSongWithDetails(songID)
+	if err != nil {
+		return err
+	}
+	lines, done, err := ApplyLineEdits(song.Lines, edits)
+	if err != nil {
+		return err
+	}
+	if len(done) == 0 {
+		return nil
+	}
+
+	for i := 1; i < len(done); i++ {
+		done[i] = strings.ToLower(done[i][:1]) + done[i][1:]
+	}
+	note := strings.Join(done, ", ")
+	if len(done) > 3 {
+		note = fmt.Sprintf("%s, %s and %d more edits", done[0], done[1], len(done)-2)
+	}
+	version := &models.SongLinesVersion{
+		SongID: songID,
+		UserID: sql.NullInt64{Int64: userID, Valid: userID > 0},
+		Note:   note,
+		Lines:  lines,
+	}
+	if err := repository.SaveSongLinesVersion(version, song.Lines); err != nil {
+		return err
+	}
+	return relinkSongLines(songID)
+}
+
+// UndoSongLines restores a song's lines to before their latest edit and
+// returns the undone version
+func (s *LyricsService) UndoSongLines(songID int64) (*models.SongLinesVersion, error) {
+	version, err := repository.UndoSongLinesVersion(songID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNothingToUndo
+	}
+	if err != nil {
+		return nil, err
+	}
+	return version, relinkSongLines(songID)
+}
+
+// relinkSongLines brings a song's word links and line cards up to date
+// with its edited lines. Lines keep their ids, so their cards keep their
+// progress.
+func relinkSongLines(songID int64) error {
+	if err := LinkSongWords(songID); err != nil {
+		return err
+	}
+	return EnsureSongLineCards(songID)
+}
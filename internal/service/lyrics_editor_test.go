@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"languagepapi/internal/models"
+)
+
+func TestApplyLineEdits(t *testing.T) {
+	timing := func(text string, start, end int) models.SongWordTiming {
+		return models.SongWordTiming{Text: text, StartTimeMs: start, EndTimeMs: end}
+	}
+	lines := []models.SongLine{
+		{LineNumber: 1, StartTimeMs: 1000, EndTimeMs: 4000, SpanishText: "Ella es callaíta", EnglishText: "She is quiet",
+			Timings: []models.SongWordTiming{timing("Ella", 1000, 1500), timing("es", 1500, 2000), timing("callaíta", 2000, 4000)}},
+		{LineNumber: 2, StartTimeMs: 4000, EndTimeMs: 8000, SpanishText: "pero pa' montar es una fiera"},
+		{LineNumber: 3, StartTimeMs: 8000, EndTimeMs: 9000, SpanishText: "Ninguna se le compara", EnglishText: "No one compares"},
+	}
+
+	got, done, err := ApplyLineEdits(lines, []LineEdit{
+		{Op: EditOffset, Ms: -500},
+		{Op: EditSplit, Line: 1, Word: 2},
+		{Op: EditSplit, Line: 3, Word: 3, Ms: 5500},
+		{Op: EditMerge, Line: 4},
+		{Op: EditTranslation, Line: 2, Text: " quiet "},
+		{Op: EditTime, Line: 2, StartMs: 1600, EndMs: 3000},
+		{Op: EditText, Line: 3, Text: "pero pa' montar"}, // Unchanged
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.SongLine{
+		{LineNumber: 1, StartTimeMs: 500, EndTimeMs: 1500, SpanishText: "Ella es", EnglishText: "She is quiet",
+			Timings: []models.SongWordTiming{timing("Ella", 500, 1000), timing("es", 1000, 1500)}},
+		// Its timing moves with the line and is cut at its new end
+		{LineNumber: 2, StartTimeMs: 1600, EndTimeMs: 3000, SpanishText: "callaíta", EnglishText: "quiet",
+			Timings: []models.SongWordTiming{timing("callaíta", 1600, 3000)}},
+		{LineNumber: 3, StartTimeMs: 3500, EndTimeMs: 5500, SpanishText: "pero pa' montar"},
+		{LineNumber: 4, StartTimeMs: 5500, EndTimeMs: 8500, SpanishText: "es una fiera Ninguna se le compara", EnglishText: "No one compares"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+	if len(done) != 6 || done[0] != "Shifted all lines by -500 ms" || done[1] != "Split line 1" {
+		t.Errorf("done: %q", done)
+	}
+	if lines[0].StartTimeMs != 1000 || len(lines[0].Timings) != 3 || lines[0].Timings[0].StartTimeMs != 1000 {
+		t.Errorf("the original lines were changed: %+v", lines[0])
+	}
+
+	// Untimed lines split in proportion to their text
+	got, _, _ = ApplyLineEdits([]models.SongLine{{StartTimeMs: 0, EndTimeMs: 3000, SpanishText: "ab cdef"}},
+		[]LineEdit{{Op: EditSplit, Line: 1, Word: 1}})
+	if got[0].EndTimeMs != 1000 || got[1].StartTimeMs != 1000 {
+		t.Errorf("proportional split at %d", got[0].EndTimeMs)
+	}
+
+	for _, e := range []LineEdit{
+		{Op: EditMerge, Line: 3},
+		{Op: EditSplit, Line: 1, Word: 3},
+		{Op: EditTime, Line: 1, StartMs: 2000, EndMs: 1000},
+		{Op: EditText, Line: 5, Text: "hola"},
+		{Op: "rename", Line: 1},
+	} {
+		if _, _, err := ApplyLineEdits(lines, []LineEdit{e}); !errors.Is(err, ErrInvalidEdit) {
+			t.Errorf("%+v: got %v, want ErrInvalidEdit", e, err)
+		}
+	}
+}
@@ -3,23 +3,24 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"regexp"
 	"strconv"
 	"strings"
 
 	"languagepapi/internal/bridge"
+	"languagepapi/internal/lrc"
 	"languagepapi/internal/models"
 	"languagepapi/internal/repository"
 )
 
-// LyricsService handles fetching and parsing synced lyrics
+// LyricsService finds, stores and translates song lyrics
 type LyricsService struct {
-	httpClient    *http.Client
-	geminiService *bridge.GeminiService
+	httpClient *http.Client
+	llm        bridge.LLM
 }
 
 // LRCLibResponse represents the response from lrclib.net API
@@ -44,10 +45,10 @@ type LyricLine struct {
 
 // NewLyricsService creates a new lyrics service
 func NewLyricsService() *LyricsService {
-	gemini, _ := bridge.NewGeminiService(context.Background()) // May be nil if no API key
+	llm, _ := bridge.NewLLM(context.Background()) // nil if the provider is unavailable, e.g. no API key
 	return &LyricsService{
-		httpClient:    &http.Client{},
-		geminiService: gemini,
+		httpClient: &http.Client{},
+		llm:        llm,
 	}
 }
 
@@ -72,7 +73,7 @@ func (s *LyricsService) FetchLyrics(artist, track, album string, durationSec int
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("lyrics not found for %s - %s", artist, track)
+		return nil, fmt.Errorf("%w for %s - %s", ErrLyricsNotFound, artist, track)
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -88,69 +89,10 @@ func (s *LyricsService) FetchLyrics(artist, track, album string, durationSec int
 	return &result, nil
 }
 
-// ParseSyncedLyrics parses LRC format lyrics into structured lines
-// Format: [MM:SS.MS] lyric text
-func (s *LyricsService) ParseSyncedLyrics(syncedLyrics string) ([]LyricLine, error) {
-	if syncedLyrics == "" {
-		return nil, fmt.Errorf("no synced lyrics provided")
-	}
-
-	// Regex to match [MM:SS.MS] or [MM:SS:MS] format
-	lineRegex := regexp.MustCompile(`\[(\d{2}):(\d{2})[.:](\d{2,3})\]\s*(.*)`)
-
-	lines := strings.Split(syncedLyrics, "\n")
-	var result []LyricLine
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		matches := lineRegex.FindStringSubmatch(line)
-		if len(matches) != 5 {
-			continue
-		}
-
-		minutes, _ := strconv.Atoi(matches[1])
-		seconds, _ := strconv.Atoi(matches[2])
-		ms := matches[3]
-		// Handle both .XX and .XXX formats
-		milliseconds, _ := strconv.Atoi(ms)
-		if len(ms) == 2 {
-			milliseconds *= 10 // Convert centiseconds to milliseconds
-		}
-
-		text := strings.TrimSpace(matches[4])
-		if text == "" {
-			continue // Skip empty lines
-		}
-
-		startTimeMs := (minutes*60+seconds)*1000 + milliseconds
-
-		result = append(result, LyricLine{
-			StartTimeMs: startTimeMs,
-			Text:        text,
-		})
-	}
-
-	// Calculate end times (each line ends when the next one starts)
-	for i := 0; i < len(result); i++ {
-		if i < len(result)-1 {
-			result[i].EndTimeMs = result[i+1].StartTimeMs
-		} else {
-			// Last line: add 5 seconds as default duration
-			result[i].EndTimeMs = result[i].StartTimeMs + 5000
-		}
-	}
-
-	return result, nil
-}
-
-// TranslateLyrics translates Spanish lyrics to English using Gemini
-func (s *LyricsService) TranslateLyrics(lines []LyricLine) ([]string, error) {
-	if s.geminiService == nil {
-		// Return empty translations if no Gemini service
+// TranslateLyrics translates Spanish lyrics to English using the LLM
+func (s *LyricsService) TranslateLyrics(ctx context.Context, lines []LyricLine) ([]string, error) {
+	if s.llm == nil {
+		// Return empty translations if no LLM is available
 		result := make([]string, len(lines))
 		for i := range result {
 			result[i] = ""
@@ -158,58 +100,55 @@ func (s *LyricsService) TranslateLyrics(lines []LyricLine) ([]string, error) {
 		return result, nil
 	}
 
-	// Build a batch translation prompt
+	// Build a batch translation prompt with numbered lines so the model
+	// keeps one translation per line
 	var spanishLines []string
-	for _, line := range lines {
-		spanishLines = append(spanishLines, line.Text)
+	for i, line := range lines {
+		spanishLines = append(spanishLines, fmt.Sprintf("%d. %s", i+1, line.Text))
 	}
 
 	prompt := fmt.Sprintf(`Translate these Spanish song lyrics to English.
-Return ONLY a JSON array of strings with the translations in the same order.
+Return ONLY valid JSON with exactly one translation per line, in the same order and without the line numbers.
 Keep translations natural and conversational.
 
-Spanish lines:
+Spanish lines (%d):
 %s
 
-Return format: ["translation1", "translation2", ...]`, strings.Join(spanishLines, "\n"))
-
-	response, err := s.geminiService.GenerateContent(context.Background(), prompt)
+Return format: {"translations": ["translation1", "translation2", ...]}`, len(lines), strings.Join(spanishLines, "\n"))
+
+	result, err := bridge.Structured[lyricsTranslation]{
+		Task:   "lyrics_translation",
+		Prompt: prompt,
+		Schema: bridge.ObjectSchema(map[string]bridge.Schema{
+			"translations": bridge.ArraySchema(bridge.StringSchema(0), len(lines), len(lines)),
+		}),
+		Validate: func(t *lyricsTranslation) error {
+			if len(t.Translations) != len(lines) {
+				return fmt.Errorf("got %d translations for %d lines", len(t.Translations), len(lines))
+			}
+			return nil
+		},
+	}.Generate(ctx, s.llm)
 	if err != nil {
 		return nil, fmt.Errorf("translation failed: %w", err)
 	}
 
-	// Parse JSON array from response
-	// Find JSON array in response
-	start := strings.Index(response, "[")
-	end := strings.LastIndex(response, "]")
-	if start == -1 || end == -1 || end <= start {
-		return nil, fmt.Errorf("invalid translation response format")
-	}
-
-	jsonStr := response[start : end+1]
-	var translations []string
-	if err := json.Unmarshal([]byte(jsonStr), &translations); err != nil {
-		return nil, fmt.Errorf("failed to parse translations: %w", err)
-	}
-
-	// Ensure we have the right number of translations
-	if len(translations) != len(lines) {
-		// Pad or truncate
-		result := make([]string, len(lines))
-		for i := range result {
-			if i < len(translations) {
-				result[i] = translations[i]
-			} else {
-				result[i] = ""
-			}
-		}
-		return result, nil
-	}
+	return result.Translations, nil
+}
 
-	return translations, nil
+type lyricsTranslation struct {
+	Translations []string `json:"translations"`
 }
 
-// FetchAndStoreLyrics fetches lyrics from lrclib and stores them in the database
+// Errors from FetchAndStoreLyrics that fetching again won't fix
+var (
+	ErrHasLyrics      = errors.New("song already has lyrics")
+	ErrLyricsNotFound = errors.New("lyrics not found") // By any of the lyrics sources
+)
+
+// FetchAndStoreLyrics finds a song's lyrics (see FindLyrics), stores them
+// in the database untranslated and records their source; see
+// TranslateSongLines
 func (s *LyricsService) FetchAndStoreLyrics(songID int64) error {
 	// Get song details
 	song, err := repository.GetSong(songID)
@@ -220,86 +159,136 @@ func (s *LyricsService) FetchAndStoreLyrics(songID int64) error {
 	// Check if lyrics already exist
 	existingLines, _ := repository.GetSongLines(songID)
 	if len(existingLines) > 0 {
-		return fmt.Errorf("song already has %d lyrics lines", len(existingLines))
-	}
-
-	// Fetch from lrclib
-	lrcResp, err := s.FetchLyrics(song.Artist, song.Title, song.Album, song.DurationSeconds)
-	if err != nil {
-		return err
+		return fmt.Errorf("%w (%d lines)", ErrHasLyrics, len(existingLines))
 	}
 
-	if lrcResp.SyncedLyrics == "" {
-		return fmt.Errorf("no synced lyrics available")
-	}
-
-	// Parse lyrics
-	lines, err := s.ParseSyncedLyrics(lrcResp.SyncedLyrics)
+	lyrics, source, err := s.FindLyrics(song)
 	if err != nil {
 		return err
 	}
 
-	if len(lines) == 0 {
-		return fmt.Errorf("no lyrics lines parsed")
-	}
-
-	// Translate
-	translations, err := s.TranslateLyrics(lines)
-	if err != nil {
-		// Continue without translations if it fails
-		translations = make([]string, len(lines))
+	// Keep the length the lyrics give for songs without one
+	if song.DurationSeconds == 0 && lyrics.LengthMs > 0 {
+		if err := repository.UpdateSongDuration(songID, lyrics.LengthMs/1000); err != nil {
+			return err
+		}
 	}
 
 	// Store in database
-	for i, line := range lines {
-		englishText := ""
-		if i < len(translations) {
-			englishText = translations[i]
-		}
-
+	for i, line := range lyrics.Lines {
 		songLine := &models.SongLine{
 			SongID:      songID,
 			LineNumber:  i + 1,
-			StartTimeMs: line.StartTimeMs,
-			EndTimeMs:   line.EndTimeMs,
+			StartTimeMs: line.StartMs,
+			EndTimeMs:   line.EndMs,
 			SpanishText: line.Text,
-			EnglishText: englishText,
+		}
+		for j, w := range line.Words {
+			songLine.Timings = append(songLine.Timings, models.SongWordTiming{
+				Position:    j,
+				Text:        w.Text,
+				StartTimeMs: w.StartMs,
+				EndTimeMs:   w.EndMs,
+			})
 		}
 
 		if err := repository.CreateSongLine(songLine); err != nil {
 			return fmt.Errorf("failed to store line %d: %w", i+1, err)
 		}
 	}
+	if err := repository.SetSongLyricsSource(songID, source); err != nil {
+		return err
+	}
 
-	return nil
+	return LinkSongWords(songID)
 }
 
-// FetchLyricsForAllSongs fetches lyrics for all songs that don't have them
-func (s *LyricsService) FetchLyricsForAllSongs() (int, int, error) {
-	songs, err := repository.GetAllSongs(0)
+// TranslateSongLines translates a song's lines that have no English text
+// yet and returns how many were translated. Cancelling ctx stops the LLM
+// request.
+func (s *LyricsService) TranslateSongLines(ctx context.Context, songID int64) (int, error) {
+	lines, err := repository.GetSongLines(songID)
 	if err != nil {
-		return 0, 0, err
+		return 0, err
 	}
 
-	success := 0
-	failed := 0
+	var untranslated []models.SongLine
+	var lyricLines []LyricLine
+	for _, line := range lines {
+		if line.EnglishText == "" {
+			untranslated = append(untranslated, line)
+			lyricLines = append(lyricLines, LyricLine{StartTimeMs: line.StartTimeMs, EndTimeMs: line.EndTimeMs, Text: line.SpanishText})
+		}
+	}
+	if len(untranslated) == 0 {
+		return 0, nil
+	}
+	if s.llm == nil {
+		return 0, fmt.Errorf("translation failed: no LLM available")
+	}
 
-	for _, song := range songs {
-		// Check if already has lyrics
-		lines, _ := repository.GetSongLines(song.ID)
-		if len(lines) > 0 {
-			continue
+	translations, err := s.TranslateLyrics(ctx, lyricLines)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, line := range untranslated {
+		if err := repository.UpdateSongLineTranslation(line.ID, translations[i]); err != nil {
+			return i, fmt.Errorf("failed to store translation of line %d: %w", line.LineNumber, err)
 		}
+	}
+	return len(untranslated), nil
+}
+
+// Lyrics export formats
+const (
+	FormatLRC = "lrc"
+	FormatSRT = "srt"
+	FormatVTT = "vtt"
+)
+
+// ErrUnknownFormat is returned for an export format other than FormatLRC,
+// FormatSRT and FormatVTT
+var ErrUnknownFormat = errors.New("unknown lyrics format")
 
-		err := s.FetchAndStoreLyrics(song.ID)
-		if err != nil {
-			fmt.Printf("Failed to fetch lyrics for %s: %v\n", song.Title, err)
-			failed++
-		} else {
-			fmt.Printf("Fetched lyrics for %s\n", song.Title)
-			success++
+// ExportSongLyrics writes a song's timed lyrics in one of the export
+// formats. SRT and WebVTT put each line's translation under it; LRC keeps
+// the word timings.
+func ExportSongLyrics(songID int64, format string) (string, error) {
+	song, err := repository.GetSongWithDetails(songID)
+	if err != nil {
+		return "", err
+	}
+	if len(song.Lines) == 0 {
+		return "", ErrNoLyrics
+	}
+
+	lyrics := &lrc.Lyrics{
+		Title:    song.Title,
+		Artist:   song.Artist,
+		Album:    song.Album,
+		LengthMs: song.DurationSeconds * 1000,
+	}
+	for _, line := range song.Lines {
+		l := lrc.Line{
+			StartMs:     line.StartTimeMs,
+			EndMs:       line.EndTimeMs,
+			Text:        line.SpanishText,
+			Translation: line.EnglishText,
 		}
+		for _, t := range line.Timings {
+			l.Words = append(l.Words, lrc.Word{StartMs: t.StartTimeMs, EndMs: t.EndTimeMs, Text: t.Text})
+		}
+		lyrics.Lines = append(lyrics.Lines, l)
 	}
 
-	return success, failed, nil
+	switch format {
+	case FormatLRC:
+		return lrc.FormatLRC(lyrics), nil
+	case FormatSRT:
+		return lrc.FormatSRT(lyrics), nil
+	case FormatVTT:
+		return lrc.FormatVTT(lyrics), nil
+	}
+	return "", ErrUnknownFormat
 }
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dhowden/tag"
+
+	"languagepapi/internal/lrc"
+	"languagepapi/internal/models"
+)
+
+// SongsPath is the directory of the songs' audio files (set from main.go)
+var SongsPath = "./songs"
+
+// LyricsProvider is a source of song lyrics
+type LyricsProvider interface {
+	Source() models.LyricsSource
+	// Lyrics returns the song's lyrics, or ErrLyricsNotFound. Untimed
+	// lyrics have lines that start and end at 0.
+	Lyrics(song *models.Song) (*lrc.Lyrics, error)
+}
+
+// lyricsProviders lists the lyrics sources in the order they are tried:
+// the song's own files first, so lyrics work offline, then lrclib.net
+// unless LYRICS_OFFLINE is set
+func (s *LyricsService) lyricsProviders() []LyricsProvider {
+	providers := []LyricsProvider{
+		sidecarLyrics{},
+		embeddedLyrics{synced: true},
+		embeddedLyrics{synced: false},
+	}
+	if os.Getenv("LYRICS_OFFLINE") == "" {
+		providers = append(providers, lrclibLyrics{s})
+	}
+	return providers
+}
+
+// FindLyrics returns a song's lyrics from the first source that has them.
+// A source that fails is skipped; its error is returned if no later
+// source has the lyrics.
+func (s *LyricsService) FindLyrics(song *models.Song) (*lrc.Lyrics, models.LyricsSource, error) {
+	var firstErr error
+	for _, p := range s.lyricsProviders() {
+		lyrics, err := p.Lyrics(song)
+		if err == nil {
+			return lyrics, p.Source(), nil
+		}
+		if !errors.Is(err, ErrLyricsNotFound) && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", p.Source(), err)
+		}
+	}
+	if firstErr != nil {
+		return nil, "", firstErr
+	}
+	return nil, "", ErrLyricsNotFound
+}
+
+// sidecarLyrics reads the .lrc file next to a song's audio: Song.mp3 and
+// Song.lrc
+type sidecarLyrics struct{}
+
+func (sidecarLyrics) Source() models.LyricsSource { return models.LyricsSourceFile }
+
+func (sidecarLyrics) Lyrics(song *models.Song) (*lrc.Lyrics, error) {
+	if song.AudioPath == "" {
+		return nil, ErrLyricsNotFound
+	}
+	base := strings.TrimSuffix(filepath.Join(SongsPath, song.AudioPath), filepath.Ext(song.AudioPath))
+	for _, ext := range []string{".lrc", ".LRC"} {
+		data, err := os.ReadFile(base + ext)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		lyrics, err := lrc.Parse(string(data), song.DurationSeconds*1000)
+		if errors.Is(err, lrc.ErrNoLines) {
+			return nil, ErrLyricsNotFound
+		}
+		return lyrics, err
+	}
+	return nil, ErrLyricsNotFound
+}
+
+// embeddedLyrics reads the lyrics in a song's audio tags: synchronised
+// lyrics from ID3 SYLT frames, or unsynchronised ones from USLT frames,
+// which sometimes hold LRC
+type embeddedLyrics struct {
+	synced bool
+}
+
+func (p embeddedLyrics) Source() models.LyricsSource {
+	if p.synced {
+		return models.LyricsSourceSYLT
+	}
+	return models.LyricsSourceUSLT
+}
+
+func (p embeddedLyrics) Lyrics(song *models.Song) (*lrc.Lyrics, error) {
+	if song.AudioPath == "" {
+		return nil, ErrLyricsNotFound
+	}
+	f, err := os.Open(filepath.Join(SongsPath, song.AudioPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrLyricsNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := tag.ReadFrom(f)
+	if errors.Is(err, tag.ErrNoTagsFound) {
+		return nil, ErrLyricsNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	durationMs := song.DurationSeconds * 1000
+
+	if !p.synced {
+		text := strings.TrimSpace(m.Lyrics())
+		if text == "" {
+			return nil, ErrLyricsNotFound
+		}
+		if lyrics, err := lrc.Parse(text, durationMs); err == nil {
+			return lyrics, nil
+		}
+		if lyrics := lrc.Plain(text); len(lyrics.Lines) > 0 {
+			return lyrics, nil
+		}
+		return nil, ErrLyricsNotFound
+	}
+
+	// SYLT frames are kept raw, named SYLT, SYLT_1, ... when there are
+	// several (one per language)
+	var names []string
+	for name := range m.Raw() {
+		if name == "SYLT" || strings.HasPrefix(name, "SYLT_") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		frame, ok := m.Raw()[name].([]byte)
+		if !ok {
+			continue
+		}
+		if lyrics, err := lrc.ParseSYLT(frame, durationMs); err == nil {
+			return lyrics, nil
+		}
+	}
+	return nil, ErrLyricsNotFound
+}
+
+// lrclibLyrics fetches synced lyrics from lrclib.net
+type lrclibLyrics struct {
+	s *LyricsService
+}
+
+func (lrclibLyrics) Source() models.LyricsSource { return models.LyricsSourceLRCLib }
+
+func (p lrclibLyrics) Lyrics(song *models.Song) (*lrc.Lyrics, error) {
+	resp, err := p.s.FetchLyrics(song.Artist, song.Title, song.Album, song.DurationSeconds)
+	if err != nil {
+		return nil, err
+	}
+	if resp.SyncedLyrics == "" {
+		return nil, ErrLyricsNotFound
+	}
+
+	durationMs := song.DurationSeconds * 1000
+	if durationMs == 0 {
+		durationMs = int(resp.Duration * 1000)
+	}
+	lyrics, err := lrc.Parse(resp.SyncedLyrics, durationMs)
+	if errors.Is(err, lrc.ErrNoLines) {
+		return nil, ErrLyricsNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lyrics.LengthMs == 0 {
+		lyrics.LengthMs = int(resp.Duration * 1000)
+	}
+	return lyrics, nil
+}
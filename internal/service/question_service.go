@@ -4,10 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"math/rand"
 	"strings"
-	"time"
 
 	"languagepapi/internal/bridge"
 	"languagepapi/internal/models"
@@ -48,12 +46,11 @@ func (s *QuestionService) GetOrGenerateMCQ(ctx context.Context, card *models.Car
 
 // GenerateMCQ generates a multiple choice question using AI
 func (s *QuestionService) GenerateMCQ(ctx context.Context, card *models.Card) (*models.MCQData, error) {
-	gemini, err := bridge.NewGeminiService(ctx)
+	llm, err := bridge.NewLLM(ctx)
 	if err != nil {
 		// Fallback to simple MCQ without AI
 		return s.generateSimpleMCQ(card), nil
 	}
-	defer gemini.Close()
 
 	prompt := fmt.Sprintf(`Generate a multiple choice question for the Spanish vocabulary word.
 
@@ -72,29 +69,50 @@ Return ONLY valid JSON in this exact format:
 Keep options concise (1-4 words each). Make distractors plausible but clearly wrong.`,
 		card.Term, card.Translation, card.ExampleSentence)
 
-	response, err := gemini.GenerateContent(ctx, prompt)
+	mcq, err := bridge.Structured[models.MCQData]{
+		Task:     "mcq",
+		Prompt:   prompt,
+		Schema:   mcqSchema,
+		Validate: validateMCQ,
+	}.Generate(ctx, llm)
 	if err != nil {
 		return s.generateSimpleMCQ(card), nil
 	}
 
-	// Parse response
-	text := strings.TrimSpace(response)
-	text = strings.TrimPrefix(text, "```json")
-	text = strings.TrimPrefix(text, "```")
-	text = strings.TrimSuffix(text, "```")
-	text = strings.TrimSpace(text)
+	return mcq, nil
+}
 
-	var mcq models.MCQData
-	if err := json.Unmarshal([]byte(text), &mcq); err != nil {
-		return s.generateSimpleMCQ(card), nil
+var mcqSchema = bridge.ObjectSchema(map[string]bridge.Schema{
+	"stem":          bridge.StringSchema(0),
+	"options":       bridge.ArraySchema(bridge.StringSchema(0), 4, 4),
+	"correct_index": bridge.IntegerSchema(0, 3),
+	"explanation":   bridge.StringSchema(0),
+})
+
+// validateMCQ requires a stem and 4 distinct options with the correct
+// index among them
+func validateMCQ(mcq *models.MCQData) error {
+	if strings.TrimSpace(mcq.Stem) == "" {
+		return fmt.Errorf("stem is empty")
 	}
-
-	// Validate
-	if len(mcq.Options) != 4 || mcq.CorrectIndex < 0 || mcq.CorrectIndex > 3 {
-		return s.generateSimpleMCQ(card), nil
+	if len(mcq.Options) != 4 {
+		return fmt.Errorf("got %d options, want 4", len(mcq.Options))
 	}
-
-	return &mcq, nil
+	if mcq.CorrectIndex < 0 || mcq.CorrectIndex > 3 {
+		return fmt.Errorf("correct_index %d is out of range 0-3", mcq.CorrectIndex)
+	}
+	seen := make(map[string]bool)
+	for i, option := range mcq.Options {
+		key := strings.ToLower(strings.TrimSpace(option))
+		if key == "" {
+			return fmt.Errorf("option %d is empty", i)
+		}
+		if seen[key] {
+			return fmt.Errorf("option %q appears twice", option)
+		}
+		seen[key] = true
+	}
+	return nil
 }
 
 // generateSimpleMCQ creates a basic MCQ without AI
@@ -148,11 +166,10 @@ func (s *QuestionService) GetOrGenerateFillBlank(ctx context.Context, card *mode
 
 // GenerateFillBlank generates a fill-in-the-blank question using AI
 func (s *QuestionService) GenerateFillBlank(ctx context.Context, card *models.Card) (*models.FillBlankData, error) {
-	gemini, err := bridge.NewGeminiService(ctx)
+	llm, err := bridge.NewLLM(ctx)
 	if err != nil {
 		return s.generateSimpleFillBlank(card), nil
 	}
-	defer gemini.Close()
 
 	prompt := fmt.Sprintf(`Create a fill-in-the-blank exercise for the Spanish word.
 
@@ -169,30 +186,43 @@ Return ONLY valid JSON:
 {"sentence": "Yo ____ español.", "blank_position": 1, "answer": "hablo", "hint": "to speak (yo form)", "context": "I speak Spanish."}`,
 		card.Term, card.Translation)
 
-	response, err := gemini.GenerateContent(ctx, prompt)
+	fb, err := bridge.Structured[models.FillBlankData]{
+		Task:     "fill_blank",
+		Prompt:   prompt,
+		Schema:   fillBlankSchema,
+		Validate: validateFillBlank,
+	}.Generate(ctx, llm)
 	if err != nil {
 		return s.generateSimpleFillBlank(card), nil
 	}
 
-	text := strings.TrimSpace(response)
-	text = strings.TrimPrefix(text, "```json")
-	text = strings.TrimPrefix(text, "```")
-	text = strings.TrimSuffix(text, "```")
-	text = strings.TrimSpace(text)
+	return fb, nil
+}
 
-	var fb models.FillBlankData
-	if err := json.Unmarshal([]byte(text), &fb); err != nil {
-		return s.generateSimpleFillBlank(card), nil
+var fillBlankSchema = bridge.ObjectSchema(map[string]bridge.Schema{
+	"sentence":       bridge.StringSchema(0),
+	"blank_position": bridge.IntegerSchema(0, 50),
+	"answer":         bridge.StringSchema(0),
+	"hint":           bridge.StringSchema(0),
+	"context":        bridge.StringSchema(0),
+})
+
+// validateFillBlank requires exactly one blank and an answer for it
+func validateFillBlank(fb *models.FillBlankData) error {
+	if n := strings.Count(fb.Sentence, "____"); n != 1 {
+		return fmt.Errorf("sentence has %d blanks (____), want 1", n)
 	}
-
-	return &fb, nil
+	if strings.TrimSpace(fb.Answer) == "" {
+		return fmt.Errorf("answer is empty")
+	}
+	return nil
 }
 
 // generateSimpleFillBlank creates a basic fill-blank without AI
 func (s *QuestionService) generateSimpleFillBlank(card *models.Card) *models.FillBlankData {
 	sentence := card.ExampleSentence
 	if sentence == "" {
-		sentence = fmt.Sprintf("____ es una palabra importante.", card.Term)
+		sentence = "____ es una palabra importante."
 	} else {
 		sentence = strings.Replace(sentence, card.Term, "____", 1)
 	}
@@ -229,11 +259,10 @@ func (s *QuestionService) GetOrGenerateSentenceBuild(ctx context.Context, card *
 
 // GenerateSentenceBuild generates a sentence building question using AI
 func (s *QuestionService) GenerateSentenceBuild(ctx context.Context, card *models.Card) (*models.SentenceBuildData, error) {
-	gemini, err := bridge.NewGeminiService(ctx)
+	llm, err := bridge.NewLLM(ctx)
 	if err != nil {
 		return s.generateSimpleSentenceBuild(card), nil
 	}
-	defer gemini.Close()
 
 	prompt := fmt.Sprintf(`Create a sentence building exercise for the Spanish word.
 
@@ -247,26 +276,50 @@ Requirements:
 - Optional hint to help
 
 Return ONLY valid JSON:
-{"target_sentence": "El gato negro duerme.", "word_bank": ["duerme", "El", "negro", "gato"], "translation": "The black cat sleeps.", "hint": "Start with the article"}`,
+{"target_sentence": "El gato negro duerme.", "word_bank": ["duerme.", "El", "negro", "gato"], "translation": "The black cat sleeps.", "hint": "Start with the article"}`,
 		card.Term, card.Translation)
 
-	response, err := gemini.GenerateContent(ctx, prompt)
+	sb, err := bridge.Structured[models.SentenceBuildData]{
+		Task:     "sentence_build",
+		Prompt:   prompt,
+		Schema:   sentenceBuildSchema,
+		Validate: validateSentenceBuild,
+	}.Generate(ctx, llm)
 	if err != nil {
 		return s.generateSimpleSentenceBuild(card), nil
 	}
 
-	text := strings.TrimSpace(response)
-	text = strings.TrimPrefix(text, "```json")
-	text = strings.TrimPrefix(text, "```")
-	text = strings.TrimSuffix(text, "```")
-	text = strings.TrimSpace(text)
+	return sb, nil
+}
 
-	var sb models.SentenceBuildData
-	if err := json.Unmarshal([]byte(text), &sb); err != nil {
-		return s.generateSimpleSentenceBuild(card), nil
+var sentenceBuildSchema = bridge.ObjectSchema(map[string]bridge.Schema{
+	"target_sentence": bridge.StringSchema(0),
+	"word_bank":       bridge.ArraySchema(bridge.StringSchema(0), 2, 0),
+	"translation":     bridge.StringSchema(0),
+	"hint":            bridge.StringSchema(0),
+})
+
+// validateSentenceBuild requires the word bank to be exactly the words of
+// the target sentence
+func validateSentenceBuild(sb *models.SentenceBuildData) error {
+	words := strings.Fields(sb.TargetSentence)
+	if len(words) < 2 {
+		return fmt.Errorf("target sentence has %d words, want at least 2", len(words))
 	}
-
-	return &sb, nil
+	if len(sb.WordBank) != len(words) {
+		return fmt.Errorf("word bank has %d words, target sentence has %d", len(sb.WordBank), len(words))
+	}
+	counts := make(map[string]int)
+	for _, w := range words {
+		counts[w]++
+	}
+	for _, w := range sb.WordBank {
+		if counts[w] == 0 {
+			return fmt.Errorf("word bank entry %q is not in the target sentence", w)
+		}
+		counts[w]--
+	}
+	return nil
 }
 
 // generateSimpleSentenceBuild creates a basic sentence-build without AI
@@ -292,36 +345,3 @@ func (s *QuestionService) generateSimpleSentenceBuild(card *models.Card) *models
 		Hint:           "Arrange the words to form a sentence",
 	}
 }
-
-// GenerateQuestionsForCard generates all question types for a card (background task)
-func (s *QuestionService) GenerateQuestionsForCard(cardID int64) {
-	go func() {
-		ctx := context.Background()
-
-		card, err := repository.GetCard(cardID)
-		if err != nil {
-			log.Printf("Failed to get card %d for question generation: %v", cardID, err)
-			return
-		}
-
-		// Generate MCQ
-		time.Sleep(2 * time.Second) // Rate limiting
-		if _, err := s.GetOrGenerateMCQ(ctx, card); err != nil {
-			log.Printf("Failed to generate MCQ for card %d: %v", cardID, err)
-		}
-
-		// Generate Fill-blank
-		time.Sleep(2 * time.Second)
-		if _, err := s.GetOrGenerateFillBlank(ctx, card); err != nil {
-			log.Printf("Failed to generate fill-blank for card %d: %v", cardID, err)
-		}
-
-		// Generate Sentence-build
-		time.Sleep(2 * time.Second)
-		if _, err := s.GetOrGenerateSentenceBuild(ctx, card); err != nil {
-			log.Printf("Failed to generate sentence-build for card %d: %v", cardID, err)
-		}
-
-		log.Printf("Generated questions for card %d", cardID)
-	}()
-}
@@ -11,7 +11,7 @@ import (
 
 // ReviewService handles the review flow
 type ReviewService struct {
-	fsrs *fsrs.Service
+	fsrs *fsrs.Service // Default weights, for users without fitted ones
 }
 
 // NewReviewService creates a new review service
@@ -21,6 +21,44 @@ func NewReviewService() *ReviewService {
 	}
 }
 
+// fsrsFor returns an FSRS service using the user's fitted weights, if any
+func (s *ReviewService) fsrsFor(userID int64) *fsrs.Service {
+	params, err := repository.GetFSRSParams(userID)
+	if err != nil {
+		return s.fsrs
+	}
+	return fsrs.NewServiceWithWeights(params.Weights)
+}
+
+// OptimizeFSRS fits FSRS weights to the user's review history and stores
+// them; later reviews are scheduled with the fitted weights
+func (s *ReviewService) OptimizeFSRS(userID int64) (*models.FSRSParams, error) {
+	logs, err := repository.GetReviewLogsForOptimizer(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := fsrs.Optimize(logs)
+	if err != nil {
+		return nil, err
+	}
+
+	params := &models.FSRSParams{
+		UserID:        userID,
+		Weights:       result.Weights,
+		ReviewCount:   result.ReviewCount,
+		LogLossBefore: result.LogLossBefore,
+		LogLossAfter:  result.LogLossAfter,
+		RMSEBefore:    result.RMSEBefore,
+		RMSEAfter:     result.RMSEAfter,
+		OptimizedAt:   time.Now(),
+	}
+	if err := repository.SaveFSRSParams(params); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
 // StartSession creates a new review session with due and new cards
 func (s *ReviewService) StartSession(userID int64, maxCards int) (*models.ReviewSession, error) {
 	session := &models.ReviewSession{
@@ -48,6 +86,36 @@ func (s *ReviewService) StartSession(userID int64, maxCards int) (*models.Review
 	return session, nil
 }
 
+// ReviewCard schedules and logs one review of a card outside a review
+// session, like a song line studied in a song lesson
+func (s *ReviewService) ReviewCard(userID, cardID int64, rating models.Rating) (*models.CardProgress, error) {
+	now := time.Now()
+	progress, err := repository.GetProgress(userID, cardID)
+	if err == sql.ErrNoRows {
+		progress = &models.CardProgress{
+			UserID: userID,
+			CardID: cardID,
+			State:  models.StateNew,
+			Due:    sql.NullTime{Time: now, Valid: true},
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	next := s.fsrsFor(userID).ScheduleReview(progress, rating, now)
+	if err := repository.UpsertProgress(next); err != nil {
+		return nil, err
+	}
+	err = repository.LogReview(&models.ReviewLog{
+		UserID:        userID,
+		CardID:        cardID,
+		Rating:        rating,
+		ElapsedDays:   progress.ElapsedDays,
+		ScheduledDays: next.ScheduledDays,
+	})
+	return next, err
+}
+
 // GetNextCard returns the next card to review in the session
 func (s *ReviewService) GetNextCard(session *models.ReviewSession) (*models.CardWithProgress, bool) {
 	if session.CurrentIndex >= len(session.Cards) {
@@ -58,8 +126,8 @@ func (s *ReviewService) GetNextCard(session *models.ReviewSession) (*models.Card
 }
 
 // GetSchedulingPreview returns preview for all rating options
-func (s *ReviewService) GetSchedulingPreview(card *models.CardWithProgress) map[models.Rating]fsrs.SchedulingPreview {
-	return s.fsrs.GetSchedulingPreview(card.Progress, time.Now())
+func (s *ReviewService) GetSchedulingPreview(userID int64, card *models.CardWithProgress) map[models.Rating]fsrs.SchedulingPreview {
+	return s.fsrsFor(userID).GetSchedulingPreview(card.Progress, time.Now())
 }
 
 // SubmitReview processes a review and updates the session
@@ -68,6 +136,29 @@ func (s *ReviewService) SubmitReview(
 	cardID int64,
 	rating models.Rating,
 	durationMs int,
+) (*ReviewResult, error) {
+	return s.submitReview(session, cardID, rating, durationMs, nil)
+}
+
+// SubmitLessonReview processes a review of the card at a position of a
+// lesson, recording the answer in the lesson together with the review
+func (s *ReviewService) SubmitLessonReview(
+	session *models.ReviewSession,
+	cardID int64,
+	rating models.Rating,
+	durationMs int,
+	lessonSessionID int64,
+	position int,
+) (*ReviewResult, error) {
+	return s.submitReview(session, cardID, rating, durationMs, &repository.LessonAnswer{SessionID: lessonSessionID, Position: position})
+}
+
+func (s *ReviewService) submitReview(
+	session *models.ReviewSession,
+	cardID int64,
+	rating models.Rating,
+	durationMs int,
+	lesson *repository.LessonAnswer,
 ) (*ReviewResult, error) {
 	now := time.Now()
 	userID := session.UserID
@@ -98,12 +189,7 @@ func (s *ReviewService) SubmitReview(
 	}
 
 	// Calculate new schedule using FSRS
-	newProgress := s.fsrs.ScheduleReview(card.Progress, rating, now)
-
-	// Save progress
-	if err := repository.UpsertProgress(newProgress); err != nil {
-		return nil, err
-	}
+	newProgress := s.fsrsFor(userID).ScheduleReview(card.Progress, rating, now)
 
 	// Log the review
 	reviewLog := &models.ReviewLog{
@@ -114,33 +200,20 @@ func (s *ReviewService) SubmitReview(
 		ScheduledDays:    newProgress.ScheduledDays,
 		ReviewDurationMs: durationMs,
 	}
-	if err := repository.LogReview(reviewLog); err != nil {
-		return nil, err
-	}
 
 	// Calculate XP
 	xp := CalculateReviewXP(rating, isNew, 0) // TODO: pass actual streak
 
-	// Update user XP
-	if err := repository.UpdateUserXP(userID, xp); err != nil {
-		return nil, err
-	}
-
-	// Update streak
-	if err := repository.UpdateStreak(userID); err != nil {
-		return nil, err
-	}
-
-	// Update daily stats
-	correct := 0
-	if rating >= models.RatingGood {
-		correct = 1
-	}
-	newCardCount := 0
-	if isNew {
-		newCardCount = 1
-	}
-	if err := repository.IncrementDailyStats(userID, xp, 1, correct, newCardCount); err != nil {
+	// Save the schedule, log, XP, streak and daily stats together
+	err := repository.SaveReview(&repository.ReviewSave{
+		Progress: newProgress,
+		Log:      reviewLog,
+		XP:       xp,
+		Correct:  rating >= models.RatingGood,
+		New:      isNew,
+		Lesson:   lesson,
+	})
+	if err != nil {
 		return nil, err
 	}
 
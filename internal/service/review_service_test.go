@@ -1,9 +1,11 @@
 package service
 
 import (
+	"database/sql"
 	"testing"
 
 	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
 )
 
 func TestCalculateReviewXP(t *testing.T) {
@@ -112,3 +114,90 @@ func TestFormatInterval(t *testing.T) {
 		})
 	}
 }
+
+func TestNewCardPoolsSkipOtherUsersCards(t *testing.T) {
+	openServiceTestDB(t)
+	alice, err := repository.CreateUser("alice", "")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	bob, err := repository.CreateUser("bob", "")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	alicesCard := createServiceTestCard(t, "trasto", "junk", alice.ID)
+	bobsCard := createServiceTestCard(t, "cachivache", "gadget", bob.ID)
+	line := &models.Card{IslandID: sql.NullInt64{Int64: 1, Valid: true}, Term: "Quiero bailar contigo",
+		Translation: "I want to dance with you", Source: "song_line"}
+	if err := repository.CreateCard(line); err != nil {
+		t.Fatalf("create card: %v", err)
+	}
+
+	session, err := NewReviewService().StartSession(bob.ID, 100000)
+	if err != nil {
+		t.Fatalf("StartSession() error = %v", err)
+	}
+	fromIslands, err := repository.GetNewCardsFromIslands(bob.ID, []int64{1}, 100000)
+	if err != nil {
+		t.Fatalf("GetNewCardsFromIslands() error = %v", err)
+	}
+	for name, cards := range map[string][]models.CardWithProgress{"session": session.Cards, "island pool": fromIslands} {
+		ids := make(map[int64]bool)
+		for _, c := range cards {
+			ids[c.ID] = true
+		}
+		if ids[alicesCard] || ids[line.ID] || !ids[bobsCard] {
+			t.Errorf("%s: has alice's card %v, song line %v, bob's card %v; want only bob's",
+				name, ids[alicesCard], ids[line.ID], ids[bobsCard])
+		}
+	}
+
+	newCount, err := repository.CountNewCards(bob.ID)
+	if err != nil {
+		t.Fatalf("CountNewCards() error = %v", err)
+	}
+	total, _, _, _, err := repository.GetCardProgressStats(bob.ID)
+	if err != nil {
+		t.Fatalf("GetCardProgressStats() error = %v", err)
+	}
+	if newCount != len(session.Cards) || total != len(session.Cards) {
+		t.Errorf("CountNewCards() = %d, total = %d, want the %d cards bob can study", newCount, total, len(session.Cards))
+	}
+}
+
+func TestSubmitLessonReviewRecordsTheAnswer(t *testing.T) {
+	openServiceTestDB(t)
+	user, err := repository.CreateUser("alice", "")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	lessons := NewLessonService()
+	active, err := lessons.StartOrResumeLesson(user.ID)
+	if err != nil {
+		t.Fatalf("StartOrResumeLesson() error = %v", err)
+	}
+	if len(active.Lesson.Cards) == 0 {
+		t.Fatal("the seeded curriculum gave an empty lesson")
+	}
+
+	card := active.Lesson.Cards[0]
+	session := &models.ReviewSession{UserID: user.ID, Cards: []models.CardWithProgress{card.CardWithProgress}}
+	result, err := NewReviewService().SubmitLessonReview(session, card.ID, models.RatingGood, 1500, active.Session.ID, 0)
+	if err != nil {
+		t.Fatalf("SubmitLessonReview() error = %v", err)
+	}
+
+	active, err = lessons.GetActiveLesson(user.ID)
+	if err != nil {
+		t.Fatalf("GetActiveLesson() error = %v", err)
+	}
+	if active.Position != 1 || len(active.Results) != 1 {
+		t.Fatalf("lesson at position %d with %d results, want 1 and 1", active.Position, len(active.Results))
+	}
+	if got := active.Results[0]; got.Rating != models.RatingGood || got.TimeSpentMs != 1500 || active.XPEarned != result.XPEarned {
+		t.Errorf("lesson answer = rating %d, %d ms, %d XP; want %d, 1500 ms, %d XP", got.Rating, got.TimeSpentMs, active.XPEarned, models.RatingGood, result.XPEarned)
+	}
+	if _, err := repository.GetProgress(user.ID, card.ID); err != nil {
+		t.Errorf("no progress saved with the lesson answer: %v", err)
+	}
+}
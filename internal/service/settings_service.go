@@ -0,0 +1,121 @@
+package service
+
+import (
+	"fmt"
+
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+)
+
+// preferredModeShare is the share of weighted mode picks (percent) given to
+// the user's default mode when it overrides the phase mix
+const preferredModeShare = 60
+
+// DefaultUserSettings returns the settings used until a user saves their own
+func DefaultUserSettings() *repository.UserSettings {
+	return &repository.UserSettings{
+		DailyGoal:         DefaultDailyGoal,
+		EnableTTS:         true,
+		ShowBridges:       true,
+		DefaultMode:       "",
+		NewCardsPerDay:    0,
+		ReviewsPerSession: 20,
+	}
+}
+
+// LoadUserSettings returns the user's saved settings. If none were saved,
+// the defaults are returned and saved is false.
+func LoadUserSettings(userID int64) (settings *repository.UserSettings, saved bool) {
+	settings, err := repository.GetUserSettings(userID)
+	if err != nil {
+		return DefaultUserSettings(), false
+	}
+	return settings, true
+}
+
+// applySettingsToPhase returns a copy of phase with the user's saved settings
+// applied, along with the phase defaults they override
+func applySettingsToPhase(userID int64, phase *models.CurriculumPhase) (*models.CurriculumPhase, []models.PhaseOverride) {
+	settings, saved := LoadUserSettings(userID)
+	if !saved {
+		return phase, nil
+	}
+	return applySettings(phase, settings)
+}
+
+// applySettings applies the settings the user set: a zero new-card quota or
+// no default mode keeps the phase's own. In the maintenance phase the
+// quota is a ceiling, so the setting can slow the trickle of new cards but
+// not bring back a curriculum pace.
+func applySettings(phase *models.CurriculumPhase, settings *repository.UserSettings) (*models.CurriculumPhase, []models.PhaseOverride) {
+	effective := *phase
+	var overrides []models.PhaseOverride
+
+	quota := settings.NewCardsPerDay
+	if phase.ID == maintenancePhaseID {
+		quota = min(quota, phase.NewCardsPerDay)
+	}
+	if quota > 0 && quota != phase.NewCardsPerDay {
+		effective.NewCardsPerDay = quota
+		overrides = append(overrides, models.PhaseOverride{
+			Setting:    "New cards/day",
+			PhaseValue: fmt.Sprintf("%d", phase.NewCardsPerDay),
+			UserValue:  fmt.Sprintf("%d", quota),
+		})
+	}
+
+	weights := biasModeWeights(phase.ModeWeights, settings.DefaultMode)
+	if weights != phase.ModeWeights {
+		effective.ModeWeights = weights
+		overrides = append(overrides, models.PhaseOverride{
+			Setting:    "Mode mix (std/rev/typ)",
+			PhaseValue: formatModeWeights(phase.ModeWeights),
+			UserValue:  formatModeWeights(weights),
+		})
+	}
+
+	return &effective, overrides
+}
+
+// biasModeWeights gives the preferred mode at least preferredModeShare of the
+// total weight, scaling the other modes down proportionally
+func biasModeWeights(weights models.ModeWeights, preferred string) models.ModeWeights {
+	total := weights.Standard + weights.Reverse + weights.Typing
+	share := total * preferredModeShare / 100
+
+	var current *int
+	switch preferred {
+	case "standard":
+		current = &weights.Standard
+	case "reverse":
+		current = &weights.Reverse
+	case "typing":
+		current = &weights.Typing
+	default:
+		return weights
+	}
+	if *current >= share {
+		return weights
+	}
+
+	rest := total - *current
+	scale := func(w int) int { return w * (total - share) / rest }
+	biased := models.ModeWeights{
+		Standard: scale(weights.Standard),
+		Reverse:  scale(weights.Reverse),
+		Typing:   scale(weights.Typing),
+	}
+	switch preferred {
+	case "standard":
+		biased.Standard = share
+	case "reverse":
+		biased.Reverse = share
+	case "typing":
+		biased.Typing = share
+	}
+	return biased
+}
+
+func formatModeWeights(w models.ModeWeights) string {
+	return fmt.Sprintf("%d/%d/%d", w.Standard, w.Reverse, w.Typing)
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"testing"
+
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+)
+
+func TestBiasModeWeights(t *testing.T) {
+	mix := models.ModeWeights{Standard: 50, Reverse: 30, Typing: 20}
+	tests := []struct {
+		name      string
+		weights   models.ModeWeights
+		preferred string
+		want      models.ModeWeights
+	}{
+		{"no preference", mix, "", mix},
+		{"unknown mode", mix, "dictation", mix},
+		{"already dominant", mix, "standard", models.ModeWeights{Standard: 60, Reverse: 24, Typing: 16}},
+		{"typing", mix, "typing", models.ModeWeights{Standard: 25, Reverse: 15, Typing: 60}},
+		{"reverse", mix, "reverse", models.ModeWeights{Standard: 28, Reverse: 60, Typing: 11}},
+		{"share reached", models.ModeWeights{Standard: 20, Reverse: 10, Typing: 70}, "typing",
+			models.ModeWeights{Standard: 20, Reverse: 10, Typing: 70}},
+		{"only other modes", models.ModeWeights{Standard: 100}, "typing", models.ModeWeights{Standard: 40, Typing: 60}},
+	}
+	for _, tt := range tests {
+		if got := biasModeWeights(tt.weights, tt.preferred); got != tt.want {
+			t.Errorf("%s: biasModeWeights(%+v, %q) = %+v, want %+v", tt.name, tt.weights, tt.preferred, got, tt.want)
+		}
+	}
+}
+
+func TestApplySettingsNewCardQuota(t *testing.T) {
+	phase := &models.CurriculumPhase{ID: 2, NewCardsPerDay: 15}
+	maintenance := &models.CurriculumPhase{ID: maintenancePhaseID, NewCardsPerDay: MaintenanceNewCardsPerDay}
+
+	tests := []struct {
+		name    string
+		phase   *models.CurriculumPhase
+		setting int
+		want    int
+	}{
+		{"unset follows the phase", phase, 0, 15},
+		{"setting raises a phase", phase, 25, 25},
+		{"setting lowers a phase", phase, 5, 5},
+		{"unset in maintenance", maintenance, 0, MaintenanceNewCardsPerDay},
+		{"setting can't raise maintenance", maintenance, 20, MaintenanceNewCardsPerDay},
+		{"setting lowers maintenance", maintenance, 2, 2},
+	}
+	for _, tt := range tests {
+		got, overrides := applySettings(tt.phase, &repository.UserSettings{NewCardsPerDay: tt.setting})
+		if got.NewCardsPerDay != tt.want {
+			t.Errorf("%s: new cards/day = %d, want %d", tt.name, got.NewCardsPerDay, tt.want)
+		}
+		if overridden := len(overrides) > 0; overridden != (tt.want != tt.phase.NewCardsPerDay) {
+			t.Errorf("%s: overrides = %+v", tt.name, overrides)
+		}
+	}
+}
@@ -0,0 +1,445 @@
+package service
+
+import (
+	"math/rand"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"languagepapi/internal/conjugate"
+	"languagepapi/internal/dialect"
+	"languagepapi/internal/grading"
+	"languagepapi/internal/lemma"
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+)
+
+// functionWords are the articles, pronouns, prepositions and conjunctions
+// that fill lyrics but carry little of a song's meaning. They are never
+// blanked.
+var functionWords = func() map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.Fields(`
+		el la lo los las un una uno unos unas al del
+		yo tú tu vos él ella ello nosotros nosotras vosotros vosotras ellos ellas usted ustedes
+		me te se nos os le les mí ti sí conmigo contigo consigo
+		mi mis tus su sus nuestro nuestra nuestros nuestras
+		este esta esto estos estas ese esa eso esos esas aquel aquella aquello
+		que qué quien quién quienes cual cuál cuales
+		a ante bajo con contra de desde en entre hacia hasta para por según sin sobre tras
+		y e ni o u pero sino si porque pues como cuando donde
+		no ya muy más tan
+	`) {
+		words[w] = true
+	}
+	return words
+}()
+
+// isFunctionWord reports whether a cleaned word is a function word, clipped
+// ones like pa' included
+func isFunctionWord(word string) bool {
+	if functionWords[word] {
+		return true
+	}
+	for _, r := range dialect.Standard(word) {
+		if r.Rule.Certain() && functionWords[r.Standard] {
+			return true
+		}
+	}
+	return false
+}
+
+// commonVerbs give multiple-choice blanks of verbs their distractors when
+// the song has too few verbs of its own
+var commonVerbs = []string{
+	"ser", "estar", "tener", "hacer", "ir", "poder", "querer", "decir", "ver", "dar",
+	"saber", "venir", "salir", "poner", "sentir", "llegar", "pasar", "bailar", "hablar", "vivir",
+}
+
+// blankLevel is how many blanks a song lesson has and how hard they are
+type blankLevel struct {
+	count   int  // Blanks per lesson
+	choices int  // Options of a multiple-choice blank, 0 when all are typed
+	mixed   bool // Every other blank typed
+}
+
+// knownSongStability is the stability, in days, past which a song is known
+// well enough that its blanks are all typed
+const knownSongStability = 21
+
+// songBlankLevel scales a lesson's blanks to how well the song is known: a
+// song still being learned gets a few multiple-choice blanks, a song in
+// review a mix, and a song known for weeks only blanks to type
+func songBlankLevel(p *models.SongProgress) blankLevel {
+	switch {
+	case p == nil || p.Reps == 0 || p.State == models.StateNew ||
+		p.State == models.StateLearning || p.State == models.StateRelearning:
+		return blankLevel{count: 6, choices: 3}
+	case p.Stability < knownSongStability:
+		return blankLevel{count: 8, choices: 4, mixed: true}
+	default:
+		return blankLevel{count: 10}
+	}
+}
+
+// buildFillBlanks picks the words of the lesson's lines to blank out, the
+// learner's weakest first, as many and as hard as their hold on the song
+// calls for
+func (s *SongService) buildFillBlanks(userID int64, song *models.Song, progress *models.SongProgress, lines []models.SongLine) []models.SongBlank {
+	if len(lines) == 0 {
+		return nil
+	}
+	level := songBlankLevel(progress)
+
+	// Without progress the blanks just don't favour weak words
+	wordProgress, _ := repository.GetSongWordProgress(userID, song.ID)
+	scheduler := s.reviews.fsrsFor(userID)
+	now := time.Now()
+	recall := func(p *models.CardProgress) float64 { return scheduler.Retrievability(p, now) }
+	blanks := pickBlanks(song, lines, wordProgress, recall, now, level.count)
+	if level.choices == 0 {
+		return blanks
+	}
+
+	verbs, words := blankPools(song)
+	var deck []string
+	if terms, err := repository.GetSharedCardTerms(); err == nil {
+		for t := range terms {
+			if isPlainWord(t) && !conjugate.IsInfinitive(t) {
+				deck = append(deck, t)
+			}
+		}
+	}
+	for i := range blanks {
+		if level.mixed && i%2 == 1 {
+			continue
+		}
+		blanks[i].Choices = blankChoices(&blanks[i], verbs, words, deck, level.choices)
+	}
+	return blanks
+}
+
+// pickBlanks picks up to count words of the lines to blank out, each word
+// once, by blankWeight. Lines of under three words give too little to go
+// on, and function words too little to practise.
+func pickBlanks(song *models.Song, lines []models.SongLine, progress map[int64]*models.CardProgress,
+	recall func(*models.CardProgress) float64, now time.Time, count int) []models.SongBlank {
+	// Inflected forms count as vocab: "quiero" is if "querer" is
+	vocabWords := make(map[string]bool)
+	vocabCards := make(map[int64]bool)
+	for _, v := range song.Vocabulary {
+		vocabWords[strings.ToLower(v.Word)] = true
+		if v.CardID.Valid {
+			vocabCards[v.CardID.Int64] = true
+		}
+	}
+	isVocab := func(l string) bool { return vocabWords[l] }
+
+	type candidate struct {
+		blank  models.SongBlank
+		key    string // The word's lemma, to blank it once
+		weight float64
+	}
+	var candidates []candidate
+	for i := range lines {
+		line := &lines[i]
+		if len(strings.Fields(line.SpanishText)) < 3 {
+			continue
+		}
+		for _, tok := range lemma.Tokenize(line.SpanishText) {
+			if isFunctionWord(tok.Word) {
+				continue
+			}
+			c := candidate{
+				blank: models.SongBlank{LineID: line.ID, Line: line, BlankWord: tok.Word, BlankIndex: tok.Index},
+				key:   tok.Word,
+			}
+			w := lineWord(line, tok.Index)
+			vocab := w != nil && vocabCards[w.CardID]
+			if a, _, ok := dialect.Match(tok.Word, isVocab); ok {
+				vocab, c.key = true, a.Lemma
+			}
+			var p *models.CardProgress
+			if w != nil {
+				c.key, p = w.Lemma, progress[w.CardID]
+			} else if !vocab && (len([]rune(tok.Word)) < 3 || (tok.Index > 0 && capitalized(tok.Text))) {
+				continue // Too short to be worth it, or a name
+			}
+			c.weight = blankWeight(p, w != nil, vocab, now, recall)
+			candidates = append(candidates, c)
+		}
+	}
+
+	// Words of equal weight come in random order
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].weight > candidates[j].weight })
+
+	var blanks []models.SongBlank
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if len(blanks) == count {
+			break
+		}
+		if !seen[c.key] {
+			seen[c.key] = true
+			blanks = append(blanks, c.blank)
+		}
+	}
+	rand.Shuffle(len(blanks), func(i, j int) {
+		blanks[i], blanks[j] = blanks[j], blanks[i]
+	})
+	return blanks
+}
+
+// blankWeight is how much the learner needs to practise a word of the
+// lyrics: most for a word whose card is due or still being learned, then
+// for one never studied, then by how likely it is forgotten now. Song
+// vocabulary counts a little extra; words with no card come last.
+func blankWeight(p *models.CardProgress, linked, vocab bool, now time.Time, recall func(*models.CardProgress) float64) float64 {
+	var weight float64
+	switch {
+	case !linked:
+	case p == nil || p.State == models.StateNew:
+		weight = 2
+	case p.State != models.StateReview || (p.Due.Valid && !p.Due.Time.After(now)):
+		weight = 3 + (1 - recall(p))
+	default:
+		weight = 1 + (1 - recall(p))
+	}
+	if vocab {
+		weight += 0.5
+	}
+	return weight
+}
+
+// lineWord is the linked word of a line at a position, or nil
+func lineWord(line *models.SongLine, position int) *models.SongLineWord {
+	if line == nil {
+		return nil
+	}
+	for i := range line.Words {
+		if line.Words[i].Position == position {
+			return &line.Words[i]
+		}
+	}
+	return nil
+}
+
+// blankVerb returns the verb a blanked word is a form of and its places in
+// the verb's conjugation, or "" if it is no conjugated verb
+func blankVerb(blank *models.SongBlank) (string, []conjugate.Cell) {
+	form := blank.BlankWord
+	if w := lineWord(blank.Line, blank.BlankIndex); w != nil {
+		if w.Standard != "" {
+			form = w.Standard
+		}
+		return w.Lemma, conjugate.Lookup(w.Lemma, form)
+	}
+	for _, a := range lemma.Analyze(form) {
+		if a.Kind != lemma.KindVerb {
+			continue
+		}
+		if cells := conjugate.Lookup(a.Lemma, form); len(cells) > 0 {
+			return a.Lemma, cells
+		}
+	}
+	return "", nil
+}
+
+// blankPools collects the song's words that may stand in for a blanked one:
+// the verbs it conjugates, then common ones, and its other content words
+func blankPools(song *models.Song) (verbs, words []string) {
+	seenWord, seenVerb := make(map[string]bool), make(map[string]bool)
+	for i := range song.Lines {
+		line := &song.Lines[i]
+		for _, tok := range lemma.Tokenize(line.SpanishText) {
+			if seenWord[tok.Word] || isFunctionWord(tok.Word) || len([]rune(tok.Word)) < 3 ||
+				(tok.Index > 0 && capitalized(tok.Text)) {
+				continue
+			}
+			seenWord[tok.Word] = true
+			if inf, cells := blankVerb(&models.SongBlank{Line: line, BlankWord: tok.Word, BlankIndex: tok.Index}); len(cells) > 0 {
+				if !seenVerb[inf] {
+					seenVerb[inf] = true
+					verbs = append(verbs, inf)
+				}
+				continue
+			}
+			words = append(words, tok.Word)
+		}
+	}
+	for _, v := range commonVerbs {
+		if !seenVerb[v] {
+			seenVerb[v] = true
+			verbs = append(verbs, v)
+		}
+	}
+	return verbs, words
+}
+
+// blankChoices makes the options of a multiple-choice blank: the word and
+// n-1 distractors of the same part of speech, shuffled. A conjugated verb
+// gets other verbs in the same tense and person, an infinitive other
+// infinitives, and any other word others alike in number and ending, the
+// song's own words before the deck's. It is nil if too few are found.
+func blankChoices(blank *models.SongBlank, verbs, songWords, deckWords []string, n int) []string {
+	answer, form := blank.BlankWord, blank.BlankWord
+	if std := BlankStandard(blank); std != "" {
+		form = std
+	}
+	inf, cells := blankVerb(blank)
+
+	taken := map[string]bool{answer: true, form: true}
+	var distractors []string
+	add := func(w string) {
+		if len(distractors) < n-1 && !taken[w] && isPlainWord(w) && !grading.Grade(w, answer).Correct() {
+			taken[w] = true
+			distractors = append(distractors, w)
+		}
+	}
+
+	switch {
+	case len(cells) > 0:
+		for _, v := range verbs {
+			if v == inf {
+				continue
+			}
+			if c, err := conjugate.Conjugate(v, cells[0].Tense, cells[0].Person); err == nil {
+				add(c.Form)
+			}
+		}
+	case conjugate.IsInfinitive(form) && slices.Contains(verbs, form):
+		for _, v := range verbs {
+			add(v)
+		}
+	default:
+		// Agreeing in number counts most, then in the final vowel
+		// (gender, mostly), then being from the song
+		plural := strings.HasSuffix(form, "s")
+		last := lastRune(strings.TrimSuffix(form, "s"))
+		type scored struct {
+			word  string
+			score int
+		}
+		var pool []scored
+		for i, w := range append(slices.Clip(songWords), deckWords...) {
+			if isFunctionWord(w) || slices.Contains(verbs, w) {
+				continue
+			}
+			score := 0
+			if strings.HasSuffix(w, "s") == plural {
+				score += 4
+			}
+			if lastRune(strings.TrimSuffix(w, "s")) == last {
+				score += 2
+			}
+			if i < len(songWords) {
+				score++
+			}
+			pool = append(pool, scored{w, score})
+		}
+		rand.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+		sort.SliceStable(pool, func(i, j int) bool { return pool[i].score > pool[j].score })
+		for _, s := range pool {
+			add(s.word)
+		}
+	}
+
+	if len(distractors) < n-1 {
+		return nil
+	}
+	choices := append([]string{answer}, distractors...)
+	rand.Shuffle(len(choices), func(i, j int) { choices[i], choices[j] = choices[j], choices[i] })
+	return choices
+}
+
+// isPlainWord reports whether s is a single lowercase word, fit to offer as
+// a choice
+func isPlainWord(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) || unicode.IsUpper(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// lastRune is the last letter of s, or 0 for ""
+func lastRune(s string) rune {
+	r := []rune(s)
+	if len(r) == 0 {
+		return 0
+	}
+	return r[len(r)-1]
+}
+
+// CheckBlankAnswer checks if the user's answer is correct. A typed answer
+// is forgiven accents and punctuation, and the lyric's colloquial spelling
+// and the standard one are each accepted for the other; a chosen one must
+// be the word. Typos are not forgiven: one letter off in a blank is often
+// another word ("pero" for "perro", "hablo" for "hablé").
+func (s *SongService) CheckBlankAnswer(blank *models.SongBlank, answer string) bool {
+	if len(blank.Choices) > 0 {
+		return grading.Normalize(answer) == grading.Normalize(blank.BlankWord)
+	}
+	switch grading.GradeWord(answer, blank.BlankWord, BlankStandard(blank)).Verdict {
+	case grading.Exact, grading.Accent:
+		return true
+	}
+	return false
+}
+
+// BlankStandard is the standard spelling of a blanked colloquial word, e.g.
+// "para" for "pa'", or "" when the word is written the standard way
+func BlankStandard(blank *models.SongBlank) string {
+	return lineStandards(blank.Line)[blank.BlankIndex]
+}
+
+// AnswerBlank checks the answer to the lesson's current blank and reviews
+// the card of its line by it, unless an earlier blank already did, and the
+// card of the blanked word too when that is due
+func (s *SongService) AnswerBlank(userID int64, lesson *models.SongLesson, answer string) error {
+	blank := &lesson.Blanks[lesson.CurrentIndex]
+	blank.UserAnswer = answer
+	blank.IsCorrect = s.CheckBlankAnswer(blank, answer)
+	rating := models.RatingAgain
+	if blank.IsCorrect {
+		rating = models.RatingGood
+	}
+	if !blankLineAnswered(lesson.Blanks[:lesson.CurrentIndex], blank.LineID) {
+		if err := s.ReviewSongLine(userID, blank.Line, rating); err != nil {
+			return err
+		}
+	}
+
+	w := lineWord(blank.Line, blank.BlankIndex)
+	if w == nil {
+		return nil
+	}
+	p, err := repository.GetProgress(userID, w.CardID)
+	if err == nil && p.State != models.StateNew && p.Due.Valid && !p.Due.Time.After(time.Now()) {
+		if _, err := s.reviews.ReviewCard(userID, w.CardID, rating); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blankLineAnswered reports whether one of the answered blanks is on a
+// line. A line's card is reviewed by the first of its blanks only, so a
+// lesson counts each line once.
+func blankLineAnswered(answered []models.SongBlank, lineID int64) bool {
+	for _, b := range answered {
+		if b.LineID == lineID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+	"time"
+
+	"languagepapi/internal/models"
+)
+
+func TestPickBlanks(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	song := &models.Song{}
+	lines := []models.SongLine{
+		{ID: 1, SpanishText: "Quiero bailar contigo en la playa", Words: []models.SongLineWord{
+			{Position: 0, Word: "quiero", Lemma: "querer", CardID: 10},
+			{Position: 1, Word: "bailar", Lemma: "bailar", CardID: 11},
+			{Position: 5, Word: "playa", Lemma: "playa", CardID: 12},
+		}},
+		{ID: 2, SpanishText: "Y quieres bailar toda la noche", Words: []models.SongLineWord{
+			{Position: 1, Word: "quieres", Lemma: "querer", CardID: 10},
+			{Position: 2, Word: "bailar", Lemma: "bailar", CardID: 11},
+			{Position: 5, Word: "noche", Lemma: "noche", CardID: 13},
+		}},
+	}
+	due := func(state models.CardState, at time.Time) *models.CardProgress {
+		return &models.CardProgress{State: state, Due: sql.NullTime{Time: at, Valid: true}}
+	}
+	progress := map[int64]*models.CardProgress{
+		10: due(models.StateReview, now.Add(-time.Hour)),   // querer is due
+		11: due(models.StateReview, now.AddDate(0, 0, 20)), // bailar is well known
+		13: due(models.StateReview, now.AddDate(0, 0, 2)),  // noche is fading
+		// playa was never studied
+	}
+	recall := func(p *models.CardProgress) float64 {
+		if p.CardID == 13 {
+			return 0.6
+		}
+		return 0.95
+	}
+	for i := range progress {
+		progress[i].CardID = i
+	}
+
+	got := pickBlanks(song, lines, progress, recall, now, 3)
+	var words []string
+	for _, b := range got {
+		words = append(words, b.BlankWord)
+	}
+	slices.Sort(words)
+	if len(words) != 3 || !(words[0] == "noche" && words[1] == "playa" && (words[2] == "quiero" || words[2] == "quieres")) {
+		t.Errorf("picked %v, want one form of querer, playa and noche", words)
+	}
+
+	// Every word once, and no function words
+	got = pickBlanks(song, lines, progress, recall, now, 20)
+	seen := make(map[string]bool)
+	for _, b := range got {
+		key := b.BlankWord
+		if w := lineWord(b.Line, b.BlankIndex); w != nil {
+			key = w.Lemma
+		}
+		if seen[key] || functionWords[b.BlankWord] {
+			t.Errorf("blanked %q twice or as a function word: %v", b.BlankWord, got)
+		}
+		seen[key] = true
+	}
+}
+
+func TestBlankChoices(t *testing.T) {
+	line := &models.SongLine{SpanishText: "Quiero las estrellas", Words: []models.SongLineWord{
+		{Position: 0, Word: "quiero", Lemma: "querer", CardID: 1},
+		{Position: 2, Word: "estrellas", Lemma: "estrella", CardID: 2},
+	}}
+
+	verb := &models.SongBlank{Line: line, BlankWord: "quiero", BlankIndex: 0}
+	got := blankChoices(verb, []string{"querer", "tener", "poder"}, nil, nil, 3)
+	slices.Sort(got)
+	if !slices.Equal(got, []string{"puedo", "quiero", "tengo"}) {
+		t.Errorf("choices for quiero = %v, want yo present forms", got)
+	}
+
+	noun := &models.SongBlank{Line: line, BlankWord: "estrellas", BlankIndex: 2}
+	got = blankChoices(noun, []string{"querer"}, []string{"luna", "playas", "noche"}, []string{"casas", "perro"}, 3)
+	slices.Sort(got)
+	if !slices.Equal(got, []string{"casas", "estrellas", "playas"}) {
+		t.Errorf("choices for estrellas = %v, want plurals in -as", got)
+	}
+
+	if got := blankChoices(noun, nil, []string{"luna"}, nil, 4); got != nil {
+		t.Errorf("choices with too few distractors = %v, want none", got)
+	}
+}
+
+func TestCheckBlankAnswer(t *testing.T) {
+	s := &SongService{}
+	tests := []struct {
+		answer, word string
+		want         bool
+	}{
+		{"perro", "perro", true},
+		{"hable", "hablé", true},
+		{"para", "pa'", true},
+		{"pero", "perro", false},
+		{"quiere", "quieres", false},
+		{"hablo", "hablé", false},
+	}
+	for _, tt := range tests {
+		blank := &models.SongBlank{Line: &models.SongLine{}, BlankWord: tt.word}
+		if got := s.CheckBlankAnswer(blank, tt.answer); got != tt.want {
+			t.Errorf("CheckBlankAnswer(%q) for %q = %v, want %v", tt.answer, tt.word, got, tt.want)
+		}
+	}
+}
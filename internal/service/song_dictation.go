@@ -0,0 +1,61 @@
+package service
+
+import (
+	"languagepapi/internal/grading"
+	"languagepapi/internal/models"
+)
+
+// buildDictations picks up to count of the lesson's lines to type as they
+// play, in song order. Lines need audio and a time span to be dictated.
+func buildDictations(song *models.Song, lines []models.SongLine, count int) []models.SongDictation {
+	if song.AudioPath == "" {
+		return nil
+	}
+	var dictations []models.SongDictation
+	for i := range lines {
+		line := &lines[i]
+		if line.EndTimeMs <= line.StartTimeMs || grading.Normalize(line.SpanishText) == "" {
+			continue
+		}
+		dictations = append(dictations, models.SongDictation{LineID: line.ID, Line: line})
+		if len(dictations) == count {
+			break
+		}
+	}
+	return dictations
+}
+
+// GradeDictation grades a typed line word by word against the lyrics,
+// forgiving accents, small typos and colloquial spellings like "pa'"
+func GradeDictation(line *models.SongLine, answer string) grading.LineResult {
+	return grading.GradeLine(answer, line.SpanishText, lineStandards(line))
+}
+
+// lineStandards maps the positions of a line's colloquial words to their
+// standard spellings, as found when its words were linked to cards
+func lineStandards(line *models.SongLine) map[int]string {
+	if line == nil {
+		return nil
+	}
+	var standards map[int]string
+	for _, w := range line.Words {
+		if w.Standard == "" {
+			continue
+		}
+		if standards == nil {
+			standards = make(map[int]string)
+		}
+		standards[w.Position] = w.Standard
+	}
+	return standards
+}
+
+// AnswerDictation grades the answer to a dictated line and reviews the
+// line's card by how much of it was right
+func (s *SongService) AnswerDictation(userID int64, d *models.SongDictation, answer string) error {
+	result := GradeDictation(d.Line, answer)
+	d.UserAnswer = answer
+	d.WordsCorrect, d.WordsTotal = result.Correct, result.Total
+	d.Answered = true
+	return s.ReviewSongLine(userID, d.Line, SongLessonRating(result.Correct, result.Total))
+}
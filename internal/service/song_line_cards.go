@@ -0,0 +1,95 @@
+package service
+
+import (
+	"database/sql"
+	"time"
+
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+)
+
+// EnsureSongLineCards gives each of a song's lines a sentence card, keeping
+// the cards' text and audio clip in step with the lines, and deletes the
+// cards of lines that are gone. The cards let lines be reviewed on their own
+// in the daily lesson.
+func EnsureSongLineCards(songID int64) error {
+	song, err := repository.GetSong(songID)
+	if err != nil {
+		return err
+	}
+	lines, err := repository.GetSongLines(songID)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		card := songLineCard(song, &line)
+		if !line.CardID.Valid {
+			if err := repository.CreateCard(card); err != nil {
+				return err
+			}
+			if err := repository.SetSongLineCard(line.ID, card.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		existing, err := repository.GetCard(line.CardID.Int64)
+		if err != nil {
+			return err
+		}
+		if existing.Term == card.Term && existing.Translation == card.Translation &&
+			existing.Notes == card.Notes && existing.AudioURL == card.AudioURL {
+			continue
+		}
+		existing.Term, existing.Translation = card.Term, card.Translation
+		existing.Notes, existing.AudioURL = card.Notes, card.AudioURL
+		if err := repository.UpdateCard(existing); err != nil {
+			return err
+		}
+	}
+
+	return repository.DeleteStaleSongLineCards(songID)
+}
+
+// songLineCard is the sentence card for a line of a song
+func songLineCard(song *models.Song, line *models.SongLine) *models.Card {
+	card := &models.Card{
+		Term:         line.SpanishText,
+		Translation:  line.EnglishText,
+		Source:       "song_line",
+		SourceSongID: sql.NullInt64{Int64: song.ID, Valid: true},
+		Notes:        "From song: " + song.Title,
+	}
+	if song.AudioPath != "" && line.EndTimeMs > line.StartTimeMs {
+		card.AudioURL = models.AudioClipURL(song.AudioPath, line.StartTimeMs, line.EndTimeMs)
+	}
+	return card
+}
+
+// lessonLines picks the lines a song lesson studies: those never studied,
+// still being learned or due. A song whose lines are all known and not due
+// is studied whole.
+func lessonLines(lines []models.SongLine, progress map[int64]*models.CardProgress, now time.Time) []models.SongLine {
+	var picked []models.SongLine
+	for _, line := range lines {
+		p, ok := progress[line.ID]
+		switch {
+		case !ok, p.State != models.StateReview, !p.Due.Valid, !p.Due.Time.After(now):
+			picked = append(picked, line)
+		}
+	}
+	if len(picked) == 0 {
+		return lines
+	}
+	return picked
+}
+
+// ReviewSongLine records a review of a line's sentence card
+func (s *SongService) ReviewSongLine(userID int64, line *models.SongLine, rating models.Rating) error {
+	if line == nil || !line.CardID.Valid {
+		return nil
+	}
+	_, err := s.reviews.ReviewCard(userID, line.CardID.Int64, rating)
+	return err
+}
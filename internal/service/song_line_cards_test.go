@@ -0,0 +1,54 @@
+package service
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"languagepapi/internal/models"
+)
+
+func TestLessonLines(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	lines := []models.SongLine{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}
+	progress := func(state models.CardState, due time.Time) *models.CardProgress {
+		return &models.CardProgress{State: state, Due: sql.NullTime{Time: due, Valid: true}}
+	}
+
+	got := lessonLines(lines, map[int64]*models.CardProgress{
+		// 1 was never studied
+		2: progress(models.StateReview, now.AddDate(0, 0, 3)),
+		3: progress(models.StateReview, now.Add(-time.Hour)),
+		4: progress(models.StateLearning, now.Add(time.Hour)),
+	}, now)
+	var ids []int64
+	for _, l := range got {
+		ids = append(ids, l.ID)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 3 || ids[2] != 4 {
+		t.Errorf("picked lines %v, want [1 3 4]", ids)
+	}
+
+	known := make(map[int64]*models.CardProgress)
+	for _, l := range lines {
+		known[l.ID] = progress(models.StateReview, now.AddDate(0, 0, 3))
+	}
+	if got := lessonLines(lines, known, now); len(got) != len(lines) {
+		t.Errorf("a song with every line known studies %d lines, want all %d", len(got), len(lines))
+	}
+}
+
+func TestSongLineCardClip(t *testing.T) {
+	song := &models.Song{ID: 7, Title: "Callaíta", AudioPath: "bad bunny/callaíta.mp3"}
+	card := songLineCard(song, &models.SongLine{StartTimeMs: 12345, EndTimeMs: 15000, SpanishText: "Ella es callaíta"})
+
+	src, start, end, ok := card.AudioClip()
+	if !ok || src != "/audio/bad%20bunny/calla%C3%ADta.mp3" || start != 12345 || end != 15000 {
+		t.Errorf("clip of %q = %q %d %d %v", card.AudioURL, src, start, end, ok)
+	}
+
+	card = songLineCard(&models.Song{ID: 7}, &models.SongLine{StartTimeMs: 0, EndTimeMs: 1000})
+	if _, _, _, ok := card.AudioClip(); ok || card.AudioURL != "" {
+		t.Errorf("a song without audio has clip %q", card.AudioURL)
+	}
+}
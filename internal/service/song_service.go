@@ -6,16 +6,20 @@ import (
 	"strings"
 	"time"
 
+	"languagepapi/internal/dialect"
+	"languagepapi/internal/lemma"
 	"languagepapi/internal/models"
 	"languagepapi/internal/repository"
 )
 
 // SongService handles song lesson logic
-type SongService struct{}
+type SongService struct {
+	reviews *ReviewService // For the user's FSRS weights
+}
 
 // NewSongService creates a new song service
 func NewSongService() *SongService {
-	return &SongService{}
+	return &SongService{reviews: NewReviewService()}
 }
 
 // GetSongHomeData builds data for the song lessons browse page
@@ -38,6 +42,21 @@ func (s *SongService) GetSongHomeData(userID int64) (*models.SongHomeData, error
 		learned = 0
 	}
 
+	// Recall and next intervals for studied songs, as for cards
+	scheduler := s.reviews.fsrsFor(userID)
+	now := time.Now()
+	for i := range songs {
+		p := songs[i].Progress
+		if p == nil || p.State == models.StateNew {
+			continue
+		}
+		songs[i].Retrievability = scheduler.Retrievability(&p.CardProgress, now)
+		songs[i].Intervals = make(map[models.Rating]int)
+		for rating, preview := range scheduler.GetSchedulingPreview(&p.CardProgress, now) {
+			songs[i].Intervals[rating] = preview.Interval
+		}
+	}
+
 	return &models.SongHomeData{
 		AvailableSongs:    songs,
 		DueSongs:          dueSongs,
@@ -47,17 +66,25 @@ func (s *SongService) GetSongHomeData(userID int64) (*models.SongHomeData, error
 
 // BuildSongLesson creates a song lesson for a specific mode
 func (s *SongService) BuildSongLesson(userID, songID int64, mode models.SongMode) (*models.SongLesson, error) {
-	// Get song with all details
-	song, err := repository.GetSongWithDetails(songID)
-	if err != nil {
-		return nil, err
-	}
-
 	// Ensure song vocab is linked to flashcards for daily lesson integration
 	if err := EnsureSongVocabCards(userID, songID); err != nil {
 		// Log but don't fail - song lesson can proceed without daily integration
 		_ = err
 	}
+	// Same for the lyrics' words; blanks fall back to vocab words without
+	if err := EnsureSongWords(songID); err != nil {
+		_ = err
+	}
+	// And its lines to sentence cards, whose mastery picks the lines below
+	if err := EnsureSongLineCards(songID); err != nil {
+		_ = err
+	}
+
+	// Get song with all details
+	song, err := repository.GetSongWithDetails(songID)
+	if err != nil {
+		return nil, err
+	}
 
 	// Get or create progress
 	progress, err := repository.GetOrCreateSongProgress(userID, songID)
@@ -65,30 +92,38 @@ func (s *SongService) BuildSongLesson(userID, songID int64, mode models.SongMode
 		return nil, err
 	}
 
-	// Build vocab cards (key vocabulary only)
-	vocabCards := s.buildVocabCards(song)
+	// Build vocab cards from the key vocabulary the user hasn't mastered
+	mastered, err := repository.GetMasteredTerms(userID)
+	if err != nil {
+		return nil, err
+	}
+	vocabCards := s.buildVocabCards(song, mastered)
 
-	// Build fill-in-the-blanks
-	blanks := s.buildFillBlanks(song, 8)
+	// Study the lines not yet known or due again
+	lineProgress, err := repository.GetSongLineProgress(userID, songID)
+	if err != nil {
+		return nil, err
+	}
+	lines := lessonLines(song.Lines, lineProgress, time.Now())
 
-	// Determine starting phase based on mode
-	var startPhase models.SongPhase
-	switch mode {
-	case models.SongModeVocab:
-		startPhase = models.SongPhaseVocabPreview
-	case models.SongModeLyrics:
-		startPhase = models.SongPhaseLineBreakdown
-	case models.SongModeListening:
-		startPhase = models.SongPhaseFillBlanks
-	default: // full
-		startPhase = models.SongPhaseVocabPreview
+	// Build fill-in-the-blanks, or for dictation the lines to type as they play
+	var blanks []models.SongBlank
+	var dictations []models.SongDictation
+	if mode == models.SongModeDictation {
+		dictations = buildDictations(song, lines, 10)
+	} else {
+		blanks = s.buildFillBlanks(userID, song, progress, lines)
 	}
 
+	// Determine starting phase based on mode
+	startPhase := startPhaseForMode(mode)
+
 	// Estimate time
 	estimatedMins := 3 // base time for video
 	estimatedMins += len(vocabCards) / 2
-	estimatedMins += len(song.Lines) / 4
+	estimatedMins += len(lines) / 4
 	estimatedMins += len(blanks) / 2
+	estimatedMins += len(dictations) / 2
 
 	return &models.SongLesson{
 		Song:          song,
@@ -96,132 +131,195 @@ func (s *SongService) BuildSongLesson(userID, songID int64, mode models.SongMode
 		CurrentPhase:  startPhase,
 		CurrentIndex:  0,
 		VocabCards:    vocabCards,
+		Lines:         lines,
 		Blanks:        blanks,
+		Dictations:    dictations,
 		EstimatedMins: estimatedMins,
 	}, nil
 }
 
-// buildVocabCards creates vocab flashcards from song vocabulary
-func (s *SongService) buildVocabCards(song *models.Song) []models.SongVocabCard {
-	var cards []models.SongVocabCard
+// startPhaseForMode returns the first phase of a song lesson in a mode
+func startPhaseForMode(mode models.SongMode) models.SongPhase {
+	switch mode {
+	case models.SongModeVocab:
+		return models.SongPhaseVocabPreview
+	case models.SongModeLyrics:
+		return models.SongPhaseLineBreakdown
+	case models.SongModeListening:
+		return models.SongPhaseFillBlanks
+	case models.SongModeDictation:
+		return models.SongPhaseDictation
+	default: // full
+		return models.SongPhaseVocabPreview
+	}
+}
 
-	for _, v := range song.Vocabulary {
-		if !v.IsKeyVocab {
-			continue
-		}
-		mode := "standard"
-		if rand.Intn(100) < 30 {
-			mode = "reverse"
+// StartOrResumeSongLesson resumes today's unfinished lesson for a song in the
+// same mode, or builds a new lesson and stores it in a new session
+func (s *SongService) StartOrResumeSongLesson(userID, songID int64, mode models.SongMode) (*models.SongLesson, error) {
+	session, err := repository.GetActiveSongSession(userID, songID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	// Sessions without a stored phase predate lesson persistence
+	if session != nil && session.Mode == mode && session.CurrentPhase != "" {
+		return s.loadSongLesson(userID, session)
+	}
+
+	lesson, err := s.BuildSongLesson(userID, songID, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	session = &models.SongSession{
+		UserID:       userID,
+		SongID:       songID,
+		Mode:         mode,
+		CurrentPhase: lesson.CurrentPhase,
+	}
+	if len(lesson.Lines) < len(lesson.Song.Lines) {
+		for _, line := range lesson.Lines {
+			session.LineIDs = append(session.LineIDs, line.ID)
 		}
-		cards = append(cards, models.SongVocabCard{
-			SongVocab: v,
-			Mode:      mode,
-		})
+	}
+	if err := repository.CreateSongSession(session); err != nil {
+		return nil, err
+	}
+	if err := repository.SaveSongSessionItems(session.ID, lesson.VocabCards, lesson.Blanks); err != nil {
+		return nil, err
+	}
+	if err := repository.SaveSongSessionDictations(session.ID, lesson.Dictations); err != nil {
+		return nil, err
+	}
+	if err := repository.UpdateSongSessionPosition(session.ID, lesson.CurrentPhase, 0); err != nil {
+		return nil, err
 	}
 
-	return cards
+	lesson.Session = session
+	return lesson, nil
 }
 
-// buildFillBlanks generates fill-in-the-blank questions from song lines
-func (s *SongService) buildFillBlanks(song *models.Song, count int) []models.SongBlank {
-	if len(song.Lines) == 0 {
-		return nil
+// GetActiveSongLesson loads today's unfinished lesson for a song.
+// Returns sql.ErrNoRows if there is none.
+func (s *SongService) GetActiveSongLesson(userID, songID int64) (*models.SongLesson, error) {
+	session, err := repository.GetActiveSongSession(userID, songID)
+	if err != nil {
+		return nil, err
 	}
-
-	// Build a set of vocabulary words for blanking
-	vocabWords := make(map[string]bool)
-	for _, v := range song.Vocabulary {
-		vocabWords[strings.ToLower(v.Word)] = true
+	if session.CurrentPhase == "" {
+		return nil, sql.ErrNoRows
 	}
+	return s.loadSongLesson(userID, session)
+}
 
-	var candidates []models.SongBlank
-
-	for i := range song.Lines {
-		line := &song.Lines[i]
-		words := strings.Fields(line.SpanishText)
-		if len(words) < 3 {
-			continue
-		}
+// loadSongLesson rebuilds a song lesson from its persisted session
+func (s *SongService) loadSongLesson(userID int64, session *models.SongSession) (*models.SongLesson, error) {
+	song, err := repository.GetSongWithDetails(session.SongID)
+	if err != nil {
+		return nil, err
+	}
 
-		// Find words that are in vocabulary
-		for idx, word := range words {
-			cleanWord := strings.ToLower(strings.Trim(word, ".,!?¿¡"))
-			if vocabWords[cleanWord] {
-				candidates = append(candidates, models.SongBlank{
-					LineID:     line.ID,
-					Line:       line,
-					BlankWord:  cleanWord,
-					BlankIndex: idx,
-				})
-			}
-		}
+	progress, err := repository.GetOrCreateSongProgress(userID, session.SongID)
+	if err != nil {
+		return nil, err
 	}
 
-	// If not enough vocab words, pick random words
-	if len(candidates) < count {
-		for i := range song.Lines {
-			if len(candidates) >= count*2 {
-				break
-			}
-			line := &song.Lines[i]
-			words := strings.Fields(line.SpanishText)
-			if len(words) < 3 {
-				continue
-			}
-			// Pick a random word (not first or last)
-			idx := rand.Intn(len(words)-2) + 1
-			word := strings.ToLower(strings.Trim(words[idx], ".,!?¿¡"))
-			if len(word) >= 3 { // Skip very short words
-				candidates = append(candidates, models.SongBlank{
-					LineID:     line.ID,
-					Line:       line,
-					BlankWord:  word,
-					BlankIndex: idx,
-				})
-			}
-		}
+	vocabCards, blanks, err := repository.LoadSongSessionItems(session.ID, song)
+	if err != nil {
+		return nil, err
+	}
+	dictations, err := repository.LoadSongSessionDictations(session.ID, song)
+	if err != nil {
+		return nil, err
 	}
 
-	// Shuffle and limit
-	rand.Shuffle(len(candidates), func(i, j int) {
-		candidates[i], candidates[j] = candidates[j], candidates[i]
-	})
+	return &models.SongLesson{
+		Song:         song,
+		Progress:     progress,
+		CurrentPhase: session.CurrentPhase,
+		CurrentIndex: session.CurrentIndex,
+		VocabCards:   vocabCards,
+		Lines:        sessionLines(song.Lines, session.LineIDs),
+		Blanks:       blanks,
+		Dictations:   dictations,
+		Session:      session,
+	}, nil
+}
 
-	if len(candidates) > count {
-		candidates = candidates[:count]
+// sessionLines returns the lines of a session's song it studies, all of
+// them if ids is nil. Lines deleted since are left out.
+func sessionLines(lines []models.SongLine, ids []int64) []models.SongLine {
+	if ids == nil {
+		return lines
+	}
+	byID := make(map[int64]models.SongLine, len(lines))
+	for _, line := range lines {
+		byID[line.ID] = line
+	}
+	var picked []models.SongLine
+	for _, id := range ids {
+		if line, ok := byID[id]; ok {
+			picked = append(picked, line)
+		}
 	}
+	return picked
+}
 
-	return candidates
+// SaveSongLessonPosition persists the current phase and index of a lesson
+func (s *SongService) SaveSongLessonPosition(lesson *models.SongLesson) error {
+	return repository.UpdateSongSessionPosition(lesson.Session.ID, lesson.CurrentPhase, lesson.CurrentIndex)
 }
 
-// CheckBlankAnswer checks if the user's answer is correct
-func (s *SongService) CheckBlankAnswer(blank *models.SongBlank, answer string) bool {
-	answer = strings.ToLower(strings.TrimSpace(answer))
-	correct := strings.ToLower(blank.BlankWord)
+// buildVocabCards creates vocab flashcards from song vocabulary. The
+// vocabulary is shared by every learner, so words this learner has
+// mastered are left out here, and the song's other words take their place
+// as key vocab.
+func (s *SongService) buildVocabCards(song *models.Song, mastered map[string]bool) []models.SongVocabCard {
+	var cards []models.SongVocabCard
 
-	// Exact match
-	if answer == correct {
-		return true
+	for _, v := range lessonVocab(song.Vocabulary, mastered) {
+		mode := "standard"
+		if rand.Intn(100) < 30 {
+			mode = "reverse"
+		}
+		cards = append(cards, models.SongVocabCard{
+			SongVocab: v,
+			Mode:      mode,
+		})
 	}
 
-	// Lenient match - remove accents for comparison
-	answerClean := removeAccents(answer)
-	correctClean := removeAccents(correct)
-
-	return answerClean == correctClean
+	return cards
 }
 
-// removeAccents removes Spanish accents for lenient matching
-func removeAccents(s string) string {
-	replacer := strings.NewReplacer(
-		"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u",
-		"ü", "u", "ñ", "n",
-	)
-	return replacer.Replace(s)
+// lessonVocab picks as many words as the song has key vocab: the key words
+// not in mastered, then the other words not in mastered
+func lessonVocab(vocab []models.SongVocab, mastered map[string]bool) []models.SongVocab {
+	var key, rest []models.SongVocab
+	for _, v := range vocab {
+		switch {
+		case v.IsKeyVocab && !mastered[strings.ToLower(v.Word)]:
+			key = append(key, v)
+		case !v.IsKeyVocab && !mastered[strings.ToLower(v.Word)]:
+			rest = append(rest, v)
+		}
+	}
+	want := 0
+	for _, v := range vocab {
+		if v.IsKeyVocab {
+			want++
+		}
+	}
+	for _, v := range rest {
+		if len(key) >= want {
+			break
+		}
+		key = append(key, v)
+	}
+	return key
 }
 
 // CalculateSongXP calculates XP for song lesson completion
-func CalculateSongXP(mode models.SongMode, vocabCorrect, vocabTotal, blanksCorrect, blanksTotal int) int {
+func CalculateSongXP(mode models.SongMode, vocabCorrect, vocabTotal, blanksCorrect, blanksTotal, dictationCorrect, dictationTotal int) int {
 	xp := 0
 
 	// Base XP for completing a song lesson
@@ -230,7 +328,7 @@ func CalculateSongXP(mode models.SongMode, vocabCorrect, vocabTotal, blanksCorre
 		xp = 10
 	case models.SongModeLyrics:
 		xp = 15
-	case models.SongModeListening:
+	case models.SongModeListening, models.SongModeDictation:
 		xp = 20
 	case models.SongModeFull:
 		xp = 30
@@ -248,28 +346,47 @@ func CalculateSongXP(mode models.SongMode, vocabCorrect, vocabTotal, blanksCorre
 		xp += int(accuracy * 15)
 	}
 
+	// Dictation bonus, by words typed right
+	if dictationTotal > 0 {
+		accuracy := float64(dictationCorrect) / float64(dictationTotal)
+		xp += int(accuracy * 20)
+	}
+
 	return xp
 }
 
-// UpdateSongProgressAfterLesson updates song progress after completing a lesson
+// SongLessonRating maps a lesson's accuracy to the rating it gives the
+// song: under 60% is Again, under 80% Hard, under 95% Good, else Easy.
+// A lesson with nothing graded, like listening, counts as Good.
+func SongLessonRating(correct, total int) models.Rating {
+	if total <= 0 {
+		return models.RatingGood
+	}
+	accuracy := float64(correct) / float64(total)
+	switch {
+	case accuracy < 0.6:
+		return models.RatingAgain
+	case accuracy < 0.8:
+		return models.RatingHard
+	case accuracy < 0.95:
+		return models.RatingGood
+	default:
+		return models.RatingEasy
+	}
+}
+
+// UpdateSongProgressAfterLesson updates song progress after completing a
+// lesson, scheduling the song's next review with FSRS
 func (s *SongService) UpdateSongProgressAfterLesson(
 	userID, songID int64,
 	mode models.SongMode,
-	vocabCorrect, vocabTotal, blanksCorrect, blanksTotal int,
+	vocabCorrect, vocabTotal, blanksCorrect, blanksTotal, dictationCorrect, dictationTotal int,
 ) error {
 	progress, err := repository.GetOrCreateSongProgress(userID, songID)
 	if err != nil {
 		return err
 	}
 
-	// Calculate overall performance
-	totalItems := vocabTotal + blanksTotal
-	totalCorrect := vocabCorrect + blanksCorrect
-	accuracy := 0.0
-	if totalItems > 0 {
-		accuracy = float64(totalCorrect) / float64(totalItems)
-	}
-
 	// Update completion flags based on mode
 	switch mode {
 	case models.SongModeVocab:
@@ -284,33 +401,9 @@ func (s *SongService) UpdateSongProgressAfterLesson(
 		progress.ListeningComplete = true
 	}
 
-	// Update FSRS-like progress
-	progress.Reps++
-	progress.LastReview = toNullTime(time.Now())
-
-	// Simple stability update based on accuracy
-	if accuracy >= 0.8 {
-		progress.Stability = progress.Stability*1.5 + 1
-		progress.State = models.StateReview
-	} else if accuracy >= 0.6 {
-		progress.Stability = progress.Stability*1.2 + 0.5
-		progress.State = models.StateLearning
-	} else {
-		progress.Stability = progress.Stability * 0.8
-		progress.Lapses++
-		progress.State = models.StateRelearning
-	}
-
-	// Schedule next review
-	daysUntilReview := int(progress.Stability)
-	if daysUntilReview < 1 {
-		daysUntilReview = 1
-	}
-	if daysUntilReview > 30 {
-		daysUntilReview = 30
-	}
-	nextDue := time.Now().AddDate(0, 0, daysUntilReview)
-	progress.Due = toNullTime(nextDue)
+	// The lesson is one review of the song
+	rating := SongLessonRating(vocabCorrect+blanksCorrect+dictationCorrect, vocabTotal+blanksTotal+dictationTotal)
+	progress.CardProgress = *s.reviews.fsrsFor(userID).ScheduleReview(&progress.CardProgress, rating, time.Now())
 
 	return repository.UpsertSongProgress(progress)
 }
@@ -342,11 +435,6 @@ func RenderBlankLine(spanishText string, blankIndex int) string {
 	return strings.Join(words, " ")
 }
 
-// toNullTime converts a time to sql.NullTime
-func toNullTime(t time.Time) sql.NullTime {
-	return sql.NullTime{Time: t, Valid: true}
-}
-
 // GetNextPhase returns the next phase in the song lesson flow
 func GetNextPhase(current models.SongPhase, mode models.SongMode) models.SongPhase {
 	switch current {
@@ -371,7 +459,7 @@ func GetNextPhase(current models.SongPhase, mode models.SongMode) models.SongPha
 		}
 		return models.SongPhaseFinalListen
 
-	case models.SongPhaseFinalListen:
+	case models.SongPhaseFinalListen, models.SongPhaseDictation:
 		return models.SongPhaseComplete
 
 	default:
@@ -394,14 +482,18 @@ func ShouldSkipPhase(phase models.SongPhase, mode models.SongMode) bool {
 	case models.SongModeListening:
 		// Only fill blanks
 		return phase != models.SongPhaseFillBlanks && phase != models.SongPhaseComplete
+	case models.SongModeDictation:
+		// Only dictation
+		return phase != models.SongPhaseDictation && phase != models.SongPhaseComplete
 	default:
 		// Full mode - no skipping
 		return false
 	}
 }
 
-// EnsureSongVocabCards creates flashcards for song vocabulary that don't have cards yet
-// This allows song vocab to be mixed into the daily lesson flow
+// EnsureSongVocabCards links song vocabulary to the shared card of its
+// dictionary form, creating cards for the words the deck lacks. This allows
+// song vocab to be mixed into the daily lesson flow.
 func EnsureSongVocabCards(userID, songID int64) error {
 	// Get song for title
 	song, err := repository.GetSong(songID)
@@ -411,12 +503,25 @@ func EnsureSongVocabCards(userID, songID int64) error {
 
 	// Get vocabulary without linked cards
 	unlinked, err := repository.GetUnlinkedSongVocab(songID)
+	if err != nil || len(unlinked) == 0 {
+		return err
+	}
+
+	terms, err := repository.GetSharedCardTerms()
 	if err != nil {
 		return err
 	}
+	known := func(l string) bool { return terms[l] != 0 }
 
-	// Create a card for each unlinked vocab
 	for _, v := range unlinked {
+		// "enamorada" goes to the card "enamorado", "enamorao" too
+		if a, _, ok := dialect.Match(v.Word, known); ok {
+			if err := repository.LinkSongVocabToCard(v.ID, terms[a.Lemma]); err != nil {
+				return err
+			}
+			continue
+		}
+
 		card := &models.Card{
 			Term:         v.Word,
 			Translation:  v.Translation,
@@ -428,6 +533,7 @@ func EnsureSongVocabCards(userID, songID int64) error {
 		if err := repository.CreateCard(card); err != nil {
 			return err
 		}
+		terms[strings.ToLower(card.Term)] = card.ID
 
 		// Link the vocab entry to the new card
 		if err := repository.LinkSongVocabToCard(v.ID, card.ID); err != nil {
@@ -437,3 +543,67 @@ func EnsureSongVocabCards(userID, songID int64) error {
 
 	return nil
 }
+
+// LinkSongWords links each word of a song's lines to the shared card of its
+// dictionary form (quiero → querer, bailando → bailar) and records the
+// words that match no card
+func LinkSongWords(songID int64) error {
+	lines, err := repository.GetSongLines(songID)
+	if err != nil {
+		return err
+	}
+	terms, err := repository.GetSharedCardTerms()
+	if err != nil {
+		return err
+	}
+	known := func(l string) bool { return terms[l] != 0 }
+
+	type match struct {
+		lemma    string
+		standard string
+		ok       bool
+	}
+	matches := make(map[string]match) // Lyrics repeat themselves
+	var words []models.SongLineWord
+	var unmatched []models.SongUnmatchedWord
+	unmatchedIdx := make(map[string]int)
+	for _, line := range lines {
+		for _, tok := range lemma.Tokenize(line.SpanishText) {
+			m, seen := matches[tok.Word]
+			if !seen {
+				a, standard, ok := dialect.Match(tok.Word, known)
+				m = match{a.Lemma, standard, ok}
+				matches[tok.Word] = m
+			}
+			if m.ok {
+				words = append(words, models.SongLineWord{
+					SongID:   songID,
+					LineID:   line.ID,
+					Position: tok.Index,
+					Word:     tok.Word,
+					Standard: m.standard,
+					Lemma:    m.lemma,
+					CardID:   terms[m.lemma],
+				})
+				continue
+			}
+			if i, ok := unmatchedIdx[tok.Word]; ok {
+				unmatched[i].Occurrences++
+				continue
+			}
+			unmatchedIdx[tok.Word] = len(unmatched)
+			unmatched = append(unmatched, models.SongUnmatchedWord{SongID: songID, Word: tok.Word, Occurrences: 1})
+		}
+	}
+	return repository.ReplaceSongWords(songID, words, unmatched)
+}
+
+// EnsureSongWords links a song's words unless they are linked already and
+// no shared card was added since
+func EnsureSongWords(songID int64) error {
+	stale, err := repository.SongWordsStale(songID)
+	if err != nil || !stale {
+		return err
+	}
+	return LinkSongWords(songID)
+}
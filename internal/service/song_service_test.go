@@ -0,0 +1,33 @@
+package service
+
+import (
+	"testing"
+
+	"languagepapi/internal/models"
+)
+
+func TestSongLessonRating(t *testing.T) {
+	tests := []struct {
+		correct, total int
+		want           models.Rating
+	}{
+		{0, 0, models.RatingGood}, // Nothing graded, e.g. listening
+		{0, -1, models.RatingGood},
+		{0, 10, models.RatingAgain},
+		{59, 100, models.RatingAgain},
+		{60, 100, models.RatingHard},
+		{3, 5, models.RatingHard},
+		{79, 100, models.RatingHard},
+		{80, 100, models.RatingGood},
+		{4, 5, models.RatingGood},
+		{94, 100, models.RatingGood},
+		{95, 100, models.RatingEasy},
+		{19, 20, models.RatingEasy},
+		{10, 10, models.RatingEasy},
+	}
+	for _, tt := range tests {
+		if got := SongLessonRating(tt.correct, tt.total); got != tt.want {
+			t.Errorf("SongLessonRating(%d, %d) = %d, want %d", tt.correct, tt.total, got, tt.want)
+		}
+	}
+}
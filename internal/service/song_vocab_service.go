@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"languagepapi/internal/bridge"
+	"languagepapi/internal/dialect"
+	"languagepapi/internal/lemma"
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+)
+
+const (
+	maxSongVocab = 12 // Vocabulary entries per song, as in the seeded songs
+	keySongVocab = 6  // The highest ranked entries are key vocab
+	// commonRank is the frequency rank of the commonest words, which are too
+	// basic to be a song's vocabulary (ser, la, que)
+	commonRank = 100
+)
+
+// ErrNoLyrics is returned when extracting the vocabulary of a song without
+// lyrics
+var ErrNoLyrics = errors.New("song has no lyrics")
+
+// SongVocabService extracts a song's vocabulary from its lyrics
+type SongVocabService struct {
+	llm bridge.LLM
+}
+
+// NewSongVocabService creates a song vocab service. Words the deck lacks
+// are translated with the LLM and left out if none is available.
+func NewSongVocabService() *SongVocabService {
+	llm, _ := bridge.NewLLM(context.Background()) // nil if the provider is unavailable
+	return &SongVocabService{llm: llm}
+}
+
+// vocabCandidate is a word of a song that could be part of its vocabulary
+type vocabCandidate struct {
+	word        string       // Dictionary form
+	form        string       // First form seen in the lyrics
+	line        string       // First line it appears in
+	count       int          // Occurrences in the song
+	card        *models.Card // Shared card of the word, if any
+	translation string
+	score       float64
+}
+
+// Extract adds up to maxSongVocab words of a song's lyrics to its
+// vocabulary, linking them to cards or creating cards, and returns how
+// many were added. Words are ranked by how rare they are in the deck's
+// frequency list and how often the song repeats them; the commonest words
+// are left out. The vocabulary is shared by every learner, so words a
+// learner has mastered are left out of their lessons instead (see
+// lessonVocab).
+func (s *SongVocabService) Extract(ctx context.Context, userID, songID int64) (int, error) {
+	song, err := repository.GetSong(songID)
+	if err != nil {
+		return 0, err
+	}
+	lines, err := repository.GetSongLines(songID)
+	if err != nil {
+		return 0, err
+	}
+	if len(lines) == 0 {
+		return 0, ErrNoLyrics
+	}
+
+	existing, err := repository.GetSongVocabulary(songID, false)
+	if err != nil {
+		return 0, err
+	}
+	limit := maxSongVocab - len(existing)
+	if limit <= 0 {
+		return 0, nil
+	}
+	cards, err := repository.GetSharedCardsByTerm()
+	if err != nil {
+		return 0, err
+	}
+
+	skip := make(map[string]bool)
+	for _, v := range existing {
+		skip[strings.ToLower(v.Word)] = true
+	}
+	candidates := songVocabCandidates(lines, cards, skip)
+
+	// Look at twice as many words as needed: the LLM leaves out names and
+	// interjections
+	pool := candidates[:min(len(candidates), 2*limit)]
+	if err := s.translateCandidates(ctx, song, pool); err != nil {
+		return 0, err
+	}
+
+	added := 0
+	for _, c := range pool {
+		if added == limit {
+			break
+		}
+		if c.translation == "" || skip[c.word] {
+			continue
+		}
+		skip[c.word] = true // The LLM may give two forms the same lemma
+
+		vocab := &models.SongVocab{
+			SongID:      songID,
+			Word:        c.word,
+			Translation: c.translation,
+			IsKeyVocab:  len(existing)+added < keySongVocab,
+		}
+		if c.card != nil {
+			vocab.CardID = sql.NullInt64{Int64: c.card.ID, Valid: true}
+		}
+		if err := repository.CreateSongVocab(vocab); err != nil {
+			return added, err
+		}
+		added++
+	}
+
+	// Cards for the words the deck lacks
+	if err := EnsureSongVocabCards(userID, songID); err != nil {
+		return added, err
+	}
+	return added, nil
+}
+
+// songVocabCandidates collects the words of the lyrics by dictionary form,
+// best first. Words in skip, the commonest words, and words outside the
+// deck that are too short or capitalized mid-line (names) are left out.
+func songVocabCandidates(lines []models.SongLine, cards map[string]*models.Card, skip map[string]bool) []*vocabCandidate {
+	known := func(l string) bool { return cards[l] != nil }
+	byWord := make(map[string]*vocabCandidate)
+	var candidates []*vocabCandidate
+	for _, line := range lines {
+		for _, tok := range lemma.Tokenize(line.SpanishText) {
+			word := tok.Word
+			var card *models.Card
+			if a, _, ok := dialect.Match(tok.Word, known); ok {
+				word, card = a.Lemma, cards[a.Lemma]
+				if card.FrequencyRank.Valid && card.FrequencyRank.Int64 <= commonRank {
+					continue
+				}
+			} else if len([]rune(word)) < 3 || (tok.Index > 0 && capitalized(tok.Text)) {
+				continue
+			}
+			if skip[word] {
+				continue
+			}
+
+			if c, ok := byWord[word]; ok {
+				c.count++
+				continue
+			}
+			c := &vocabCandidate{word: word, form: tok.Word, line: line.SpanishText, count: 1, card: card}
+			if card != nil {
+				c.translation = card.Translation
+			}
+			byWord[word] = c
+			candidates = append(candidates, c)
+		}
+	}
+
+	// Rarity is the frequency rank relative to the rarest ranked card;
+	// words without a rank are rarer still. Repetition adds a log bonus.
+	var maxRank int64 = 1
+	for _, card := range cards {
+		if card.FrequencyRank.Valid && card.FrequencyRank.Int64 > maxRank {
+			maxRank = card.FrequencyRank.Int64
+		}
+	}
+	for _, c := range candidates {
+		rarity := 1.0
+		if c.card != nil && c.card.FrequencyRank.Valid {
+			rarity = float64(c.card.FrequencyRank.Int64) / float64(maxRank)
+		}
+		c.score = rarity * (1 + math.Log(float64(c.count)))
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	return candidates
+}
+
+// capitalized reports whether the first letter of s is upper case
+func capitalized(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			return unicode.IsUpper(r)
+		}
+	}
+	return false
+}
+
+type songWordTranslations struct {
+	Words []struct {
+		Word        string `json:"word"`
+		Lemma       string `json:"lemma"`
+		Translation string `json:"translation"`
+	} `json:"words"`
+}
+
+// translateCandidates translates the candidates the deck lacks, giving them
+// their dictionary form. Those the LLM leaves out keep no translation.
+func (s *SongVocabService) translateCandidates(ctx context.Context, song *models.Song, candidates []*vocabCandidate) error {
+	byForm := make(map[string]*vocabCandidate)
+	var list []string
+	for _, c := range candidates {
+		if c.card == nil {
+			byForm[c.form] = c
+			list = append(list, fmt.Sprintf("- %s: %q", c.form, c.line))
+		}
+	}
+	if len(list) == 0 || s.llm == nil {
+		return nil
+	}
+
+	prompt := fmt.Sprintf(`Translate these Spanish words from the lyrics of "%s" by %s. Each comes with a line it appears in.
+Give each word's dictionary form (infinitive for verbs, masculine singular for nouns and adjectives) and a short English translation of that form, as a flashcard would.
+Leave out names, interjections, onomatopoeia and words that are not Spanish.
+
+Words:
+%s
+
+Return ONLY valid JSON: {"words": [{"word": "word as given", "lemma": "dictionary form", "translation": "English"}, ...]}`,
+		song.Title, song.Artist, strings.Join(list, "\n"))
+
+	result, err := bridge.Structured[songWordTranslations]{
+		Task:   "song_vocab",
+		Prompt: prompt,
+		Schema: bridge.ObjectSchema(map[string]bridge.Schema{
+			"words": bridge.ArraySchema(bridge.ObjectSchema(map[string]bridge.Schema{
+				"word":        bridge.StringSchema(0),
+				"lemma":       bridge.StringSchema(0),
+				"translation": bridge.StringSchema(100),
+			}), 0, len(list)),
+		}),
+		Validate: func(t *songWordTranslations) error {
+			for _, w := range t.Words {
+				if byForm[lemma.Clean(w.Word)] == nil {
+					return fmt.Errorf("%q is not one of the words", w.Word)
+				}
+				if strings.TrimSpace(w.Lemma) == "" || strings.TrimSpace(w.Translation) == "" {
+					return fmt.Errorf("%q has no dictionary form or translation", w.Word)
+				}
+			}
+			return nil
+		},
+	}.Generate(ctx, s.llm)
+	if err != nil {
+		return fmt.Errorf("translating song vocab failed: %w", err)
+	}
+
+	for _, w := range result.Words {
+		c := byForm[lemma.Clean(w.Word)]
+		c.word = strings.ToLower(strings.TrimSpace(w.Lemma))
+		c.translation = strings.TrimSpace(w.Translation)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"database/sql"
+	"testing"
+
+	"languagepapi/internal/models"
+)
+
+func TestSongVocabCandidates(t *testing.T) {
+	card := func(id int64, term string, rank int64) *models.Card {
+		return &models.Card{ID: id, Term: term, Translation: "to " + term, FrequencyRank: sql.NullInt64{Int64: rank, Valid: rank > 0}}
+	}
+	cards := map[string]*models.Card{
+		"ser":    card(1, "ser", 8),
+		"luna":   card(2, "luna", 234),
+		"querer": card(3, "querer", 120),
+		"noche":  card(4, "noche", 186),
+		"bailar": card(5, "bailar", 0),
+	}
+	lines := []models.SongLine{
+		{SpanishText: "Eres la luna de la noche"},
+		{SpanishText: "Te quiero, te quiero, Marisol"},
+		{SpanishText: "Bailando contigo, mi fiera"},
+		{SpanishText: "Quiero la noche entera"},
+	}
+
+	got := songVocabCandidates(lines, cards, map[string]bool{"noche": true})
+	var words []string
+	for _, c := range got {
+		words = append(words, c.word)
+	}
+	// querer is repeated enough to beat the rarest words; ties keep the
+	// lyrics' order. Common words (ser), skipped words (noche), short
+	// unknown words (la, de, te, mi) and names (Marisol) are left out.
+	want := []string{"querer", "luna", "bailar", "contigo", "fiera", "entera"}
+	if len(words) != len(want) {
+		t.Fatalf("got %v, want %v", words, want)
+	}
+	for i := range want {
+		if words[i] != want[i] {
+			t.Fatalf("got %v, want %v", words, want)
+		}
+	}
+
+	if q := got[0]; q.count != 3 || q.card != cards["querer"] || q.translation != "to querer" {
+		t.Errorf("querer: count %d, card %v, translation %q", q.count, q.card, q.translation)
+	}
+	if f := got[4]; f.card != nil || f.translation != "" || f.line != "Bailando contigo, mi fiera" {
+		t.Errorf("fiera: card %v, translation %q, line %q", f.card, f.translation, f.line)
+	}
+}
+
+func TestLessonVocab(t *testing.T) {
+	vocab := []models.SongVocab{
+		{Word: "luna", IsKeyVocab: true},
+		{Word: "Querer", IsKeyVocab: true},
+		{Word: "fiera", IsKeyVocab: true},
+		{Word: "noche"},
+		{Word: "entera"},
+	}
+	words := func(vs []models.SongVocab) []string {
+		var out []string
+		for _, v := range vs {
+			out = append(out, v.Word)
+		}
+		return out
+	}
+
+	if got := words(lessonVocab(vocab, nil)); len(got) != 3 || got[0] != "luna" || got[1] != "Querer" || got[2] != "fiera" {
+		t.Errorf("nothing mastered: got %v, want the key words", got)
+	}
+	// Mastered words give way to the song's other words, key words first
+	got := words(lessonVocab(vocab, map[string]bool{"querer": true, "noche": true}))
+	if len(got) != 3 || got[0] != "luna" || got[1] != "fiera" || got[2] != "entera" {
+		t.Errorf("querer and noche mastered: got %v, want [luna fiera entera]", got)
+	}
+}
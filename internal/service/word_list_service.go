@@ -0,0 +1,463 @@
+package service
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+)
+
+// WordColumn is a card field that can be read from or written to a word list
+type WordColumn string
+
+const (
+	ColumnTerm          WordColumn = "term"
+	ColumnTranslation   WordColumn = "translation"
+	ColumnExample       WordColumn = "example"
+	ColumnNotes         WordColumn = "notes"
+	ColumnIsland        WordColumn = "island"
+	ColumnFrequencyRank WordColumn = "frequency_rank"
+)
+
+// WordColumns lists the columns in export order, which is also the order
+// assumed for files without a header row
+var WordColumns = []WordColumn{
+	ColumnTerm, ColumnTranslation, ColumnExample, ColumnNotes, ColumnIsland, ColumnFrequencyRank,
+}
+
+// wordColumnAliases are header names recognised for each column
+var wordColumnAliases = map[WordColumn][]string{
+	ColumnTerm:          {"term", "word", "spanish", "front"},
+	ColumnTranslation:   {"translation", "english", "meaning", "back"},
+	ColumnExample:       {"example", "example_sentence", "sentence"},
+	ColumnNotes:         {"notes", "note"},
+	ColumnIsland:        {"island", "island_id"},
+	ColumnFrequencyRank: {"frequency_rank", "rank", "frequency"},
+}
+
+// DuplicateMode decides what happens to rows whose term already exists
+type DuplicateMode string
+
+const (
+	DuplicateSkip      DuplicateMode = "skip"      // Leave the existing card alone
+	DuplicateMerge     DuplicateMode = "merge"     // Fill in fields the existing card is missing
+	DuplicateOverwrite DuplicateMode = "overwrite" // Replace fields with the imported values
+)
+
+// maxImportErrors caps the row errors reported back
+const maxImportErrors = 20
+
+// WordImportOptions configures a word list import
+type WordImportOptions struct {
+	// Mapping picks the source column for each field: a header name or a
+	// 1-based column number. Unmapped fields are matched by header name.
+	Mapping         map[WordColumn]string
+	Duplicates      DuplicateMode
+	DefaultIslandID int64 // Used when a row has no island
+	Delimiter       rune  // 0 to detect comma, semicolon or tab
+}
+
+// WordImportResult summarises a word list import
+type WordImportResult struct {
+	Created     int
+	Merged      int
+	Overwritten int
+	Skipped     int      // Duplicates left alone
+	Failed      int      // Rows with errors
+	Errors      []string // First few row errors
+}
+
+// WordListService imports and exports word lists as CSV or TSV
+type WordListService struct{}
+
+// NewWordListService creates a new word list service
+func NewWordListService() *WordListService {
+	return &WordListService{}
+}
+
+// ParseColumnMapping parses a mapping like "term=Spanish,translation=2"
+func ParseColumnMapping(spec string) (map[WordColumn]string, error) {
+	mapping := make(map[WordColumn]string)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field, source, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("mapping %q must look like field=column", part)
+		}
+		column, known := wordColumnForName(field)
+		if !known {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		mapping[column] = strings.TrimSpace(source)
+	}
+	return mapping, nil
+}
+
+// wordColumnForName finds a column by its name or one of its aliases
+func wordColumnForName(name string) (WordColumn, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, c := range WordColumns {
+		for _, alias := range wordColumnAliases[c] {
+			if alias == name {
+				return c, true
+			}
+		}
+	}
+	return "", false
+}
+
+// ParseDuplicateMode validates a duplicate mode, defaulting to skip
+func ParseDuplicateMode(s string) (DuplicateMode, error) {
+	switch mode := DuplicateMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "":
+		return DuplicateSkip, nil
+	case DuplicateSkip, DuplicateMerge, DuplicateOverwrite:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("duplicates must be skip, merge or overwrite")
+	}
+}
+
+// Import adds the rows of a CSV/TSV word list as cards owned by the user
+// (shared cards when userID is 0). Rows whose term the user can already see
+// are handled according to opts.Duplicates; merging and overwriting only
+// change the user's own cards. Cards are saved in one transaction, so a
+// failed import changes nothing.
+func (s *WordListService) Import(userID int64, data []byte, opts WordImportOptions) (*WordImportResult, error) {
+	records, err := readWordRecords(data, opts.Delimiter)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("the file is empty")
+	}
+
+	columns, hasHeader, err := resolveWordColumns(records[0], opts.Mapping)
+	if err != nil {
+		return nil, err
+	}
+	if hasHeader {
+		records = records[1:]
+	}
+
+	islands, err := repository.GetAllIslands()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &WordImportResult{}
+	imp := &wordListImport{cards: make(map[string]*models.Card)}
+	firstRow := 1
+	if hasHeader {
+		firstRow = 2
+	}
+	for i, record := range records {
+		if isBlankRecord(record) {
+			continue
+		}
+		if err := s.importRow(userID, record, columns, islands, opts, imp, result); err != nil {
+			result.Failed++
+			if len(result.Errors) < maxImportErrors {
+				result.Errors = append(result.Errors, fmt.Sprintf("row %d: %v", firstRow+i, err))
+			}
+		}
+	}
+	if err := repository.SaveCardImport(&imp.CardImport); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// wordListImport collects the cards an import creates and updates, by
+// lowercased term, so a later row with the same term changes the card an
+// earlier row added or updated
+type wordListImport struct {
+	repository.CardImport
+	cards map[string]*models.Card
+}
+
+// importRow creates, merges or overwrites the card for one row
+func (s *WordListService) importRow(userID int64, record []string, columns map[WordColumn]int, islands []models.Island, opts WordImportOptions, imp *wordListImport, result *WordImportResult) error {
+	value := func(c WordColumn) string {
+		if i, ok := columns[c]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	term := value(ColumnTerm)
+	if term == "" {
+		return fmt.Errorf("missing term")
+	}
+	translation := value(ColumnTranslation)
+
+	islandID, err := parseIsland(value(ColumnIsland), islands)
+	if err != nil {
+		return err
+	}
+	var rank sql.NullInt64
+	if r := value(ColumnFrequencyRank); r != "" {
+		n, err := strconv.ParseInt(r, 10, 64)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid frequency rank %q", r)
+		}
+		rank = sql.NullInt64{Int64: n, Valid: true}
+	}
+
+	key := strings.ToLower(term)
+	existing, pending := imp.cards[key]
+	if !pending {
+		existing, err = repository.GetCardByTerm(userID, term)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+	}
+
+	if existing == nil {
+		if translation == "" {
+			return fmt.Errorf("missing translation for %q", term)
+		}
+		if islandID == 0 {
+			islandID = opts.DefaultIslandID
+		}
+		card := &models.Card{
+			Term:            term,
+			Translation:     translation,
+			ExampleSentence: value(ColumnExample),
+			Notes:           value(ColumnNotes),
+			FrequencyRank:   rank,
+		}
+		if islandID > 0 {
+			card.IslandID = sql.NullInt64{Int64: islandID, Valid: true}
+		}
+		if userID > 0 {
+			card.UserID = sql.NullInt64{Int64: userID, Valid: true}
+		}
+		imp.Created = append(imp.Created, repository.ImportedCard{Card: card})
+		imp.cards[key] = card
+		result.Created++
+		return nil
+	}
+
+	overwrite := opts.Duplicates == DuplicateOverwrite
+	if opts.Duplicates != DuplicateMerge && !overwrite {
+		result.Skipped++
+		return nil
+	}
+	// Only the importer's own cards change; shared cards are everyone's
+	if existing.UserID != (sql.NullInt64{Int64: userID, Valid: userID > 0}) {
+		return fmt.Errorf("%q is a shared card, which imports can't change", term)
+	}
+
+	// Merge only fills blanks; overwrite replaces with any non-empty value
+	set := func(field *string, v string) {
+		if v != "" && (overwrite || *field == "") {
+			*field = v
+		}
+	}
+	set(&existing.Translation, translation)
+	set(&existing.ExampleSentence, value(ColumnExample))
+	set(&existing.Notes, value(ColumnNotes))
+	if islandID > 0 && (overwrite || !existing.IslandID.Valid) {
+		existing.IslandID = sql.NullInt64{Int64: islandID, Valid: true}
+	}
+	if rank.Valid && (overwrite || !existing.FrequencyRank.Valid) {
+		existing.FrequencyRank = rank
+	}
+	if !pending {
+		imp.Updated = append(imp.Updated, existing)
+		imp.cards[key] = existing
+	}
+
+	if overwrite {
+		result.Overwritten++
+	} else {
+		result.Merged++
+	}
+	return nil
+}
+
+// Export writes cards as CSV (or TSV when delimiter is a tab) with a header
+// row in WordColumns order; islands are written by name
+func (s *WordListService) Export(cards []models.Card, delimiter rune) ([]byte, error) {
+	islands, err := repository.GetAllIslands()
+	if err != nil {
+		return nil, err
+	}
+	islandNames := make(map[int64]string, len(islands))
+	for _, island := range islands {
+		islandNames[island.ID] = island.Name
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = delimiter
+
+	header := make([]string, len(WordColumns))
+	for i, c := range WordColumns {
+		header[i] = string(c)
+	}
+	w.Write(header)
+
+	for _, card := range cards {
+		island, rank := "", ""
+		if card.IslandID.Valid {
+			island = islandNames[card.IslandID.Int64]
+		}
+		if card.FrequencyRank.Valid {
+			rank = strconv.FormatInt(card.FrequencyRank.Int64, 10)
+		}
+		w.Write([]string{card.Term, card.Translation, card.ExampleSentence, card.Notes, island, rank})
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// readWordRecords parses CSV/TSV data, detecting the delimiter from the
+// first line when none is given
+func readWordRecords(data []byte, delimiter rune) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if delimiter == 0 {
+		delimiter = detectDelimiter(data)
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = delimiter
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not read the file: %w", err)
+	}
+	return records, nil
+}
+
+// detectDelimiter picks whichever of tab, semicolon and comma is most
+// common on the first line
+func detectDelimiter(data []byte) rune {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	best, bestCount := ',', bytes.Count(line, []byte(","))
+	for _, d := range []rune{'\t', ';'} {
+		if n := bytes.Count(line, []byte(string(d))); n > bestCount {
+			best, bestCount = d, n
+		}
+	}
+	return best
+}
+
+// resolveWordColumns maps fields to column indexes. The first row is a
+// header when it names a known column or one referenced by the mapping;
+// without a header, unmapped fields follow WordColumns order.
+func resolveWordColumns(first []string, mapping map[WordColumn]string) (map[WordColumn]int, bool, error) {
+	headerIndex := make(map[string]int, len(first))
+	for i, name := range first {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if _, dup := headerIndex[key]; !dup {
+			headerIndex[key] = i
+		}
+	}
+
+	hasHeader := false
+	for _, aliases := range wordColumnAliases {
+		for _, alias := range aliases {
+			if _, ok := headerIndex[alias]; ok {
+				hasHeader = true
+			}
+		}
+	}
+	for _, source := range mapping {
+		if _, ok := headerIndex[strings.ToLower(source)]; ok && source != "" && !isColumnNumber(source) {
+			hasHeader = true
+		}
+	}
+
+	columns := make(map[WordColumn]int)
+	for _, c := range WordColumns {
+		source := mapping[c]
+		switch {
+		case source == "":
+			if !hasHeader {
+				if i := columnPosition(c); i < len(first) {
+					columns[c] = i
+				}
+				continue
+			}
+			for _, alias := range wordColumnAliases[c] {
+				if i, ok := headerIndex[alias]; ok {
+					columns[c] = i
+					break
+				}
+			}
+		case isColumnNumber(source):
+			n, _ := strconv.Atoi(source)
+			if n < 1 || n > len(first) {
+				return nil, false, fmt.Errorf("%s: column %d is out of range (the file has %d)", c, n, len(first))
+			}
+			columns[c] = n - 1
+		default:
+			i, ok := headerIndex[strings.ToLower(source)]
+			if !ok {
+				return nil, false, fmt.Errorf("%s: no column named %q", c, source)
+			}
+			columns[c] = i
+		}
+	}
+
+	if _, ok := columns[ColumnTerm]; !ok {
+		return nil, false, fmt.Errorf("no term column found; map one explicitly")
+	}
+	return columns, hasHeader, nil
+}
+
+func columnPosition(c WordColumn) int {
+	for i, col := range WordColumns {
+		if col == c {
+			return i
+		}
+	}
+	return -1
+}
+
+func isColumnNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseIsland accepts an island ID or name; empty means none
+func parseIsland(v string, islands []models.Island) (int64, error) {
+	if v == "" {
+		return 0, nil
+	}
+	if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+		for _, island := range islands {
+			if island.ID == id {
+				return id, nil
+			}
+		}
+		return 0, fmt.Errorf("unknown island %d", id)
+	}
+	for _, island := range islands {
+		if strings.EqualFold(island.Name, v) {
+			return island.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown island %q", v)
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"database/sql"
+	"maps"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"languagepapi/internal/db"
+	"languagepapi/internal/models"
+	"languagepapi/internal/repository"
+)
+
+func TestDetectDelimiter(t *testing.T) {
+	tests := []struct {
+		data string
+		want rune
+	}{
+		{"term,translation\nperro,dog", ','},
+		{"term;translation;notes\nperro;dog;", ';'},
+		{"term\ttranslation\nperro\tdog", '\t'},
+		{"perro", ','},                          // Nothing to count: comma
+		{"term;translation\nuno,dos,tres", ';'}, // Only the first line counts
+	}
+	for _, tt := range tests {
+		if got := detectDelimiter([]byte(tt.data)); got != tt.want {
+			t.Errorf("detectDelimiter(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestParseColumnMapping(t *testing.T) {
+	got, err := ParseColumnMapping(" term=Spanish, english=2 ,,")
+	if err != nil {
+		t.Fatalf("ParseColumnMapping() error = %v", err)
+	}
+	want := map[WordColumn]string{ColumnTerm: "Spanish", ColumnTranslation: "2"}
+	if !maps.Equal(got, want) {
+		t.Errorf("ParseColumnMapping() = %v, want %v", got, want)
+	}
+
+	for _, spec := range []string{"term", "colour=3"} {
+		if _, err := ParseColumnMapping(spec); err == nil {
+			t.Errorf("ParseColumnMapping(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestResolveWordColumns(t *testing.T) {
+	tests := []struct {
+		name       string
+		first      []string
+		mapping    map[WordColumn]string
+		want       map[WordColumn]int
+		wantHeader bool
+		wantErr    bool
+	}{
+		{
+			name:       "header aliases",
+			first:      []string{"Notes", "Spanish", "English"},
+			want:       map[WordColumn]int{ColumnTerm: 1, ColumnTranslation: 2, ColumnNotes: 0},
+			wantHeader: true,
+		},
+		{
+			name:  "no header follows export order",
+			first: []string{"perro", "dog", "El perro ladra"},
+			want:  map[WordColumn]int{ColumnTerm: 0, ColumnTranslation: 1, ColumnExample: 2},
+		},
+		{
+			name:       "mapped header name",
+			first:      []string{"Palabra", "Meaning"},
+			mapping:    map[WordColumn]string{ColumnTerm: "palabra"},
+			want:       map[WordColumn]int{ColumnTerm: 0, ColumnTranslation: 1},
+			wantHeader: true,
+		},
+		{
+			name:    "mapped column number",
+			first:   []string{"dog", "perro"},
+			mapping: map[WordColumn]string{ColumnTerm: "2", ColumnTranslation: "1"},
+			want:    map[WordColumn]int{ColumnTerm: 1, ColumnTranslation: 0},
+		},
+		{
+			name:    "column number out of range",
+			first:   []string{"perro", "dog"},
+			mapping: map[WordColumn]string{ColumnTerm: "3"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown header name",
+			first:   []string{"term", "translation"},
+			mapping: map[WordColumn]string{ColumnNotes: "comments"},
+			wantErr: true,
+		},
+		{
+			name:    "no term column",
+			first:   []string{"translation", "notes"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		got, header, err := resolveWordColumns(tt.first, tt.mapping)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: resolveWordColumns() = %v, want an error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: resolveWordColumns() error = %v", tt.name, err)
+			continue
+		}
+		if !maps.Equal(got, tt.want) || header != tt.wantHeader {
+			t.Errorf("%s: resolveWordColumns() = %v, header %v; want %v, header %v", tt.name, got, header, tt.want, tt.wantHeader)
+		}
+	}
+}
+
+func TestWordListImportDuplicates(t *testing.T) {
+	// Terms the seeded curriculum doesn't have
+	const list = "term,translation,notes\ntrasto,clutter,useless\nTrasto,rubbish,\ncachivache,gadget,\nzarandaja,trifle,\n"
+
+	tests := []struct {
+		mode    DuplicateMode
+		want    WordImportResult
+		wantOwn models.Card
+	}{
+		{DuplicateSkip, WordImportResult{Created: 1, Skipped: 3}, models.Card{Translation: "junk", Notes: ""}},
+		{DuplicateMerge, WordImportResult{Created: 1, Merged: 2, Failed: 1}, models.Card{Translation: "junk", Notes: "useless"}},
+		{DuplicateOverwrite, WordImportResult{Created: 1, Overwritten: 2, Failed: 1}, models.Card{Translation: "rubbish", Notes: "useless"}},
+	}
+	for _, tt := range tests {
+		openServiceTestDB(t)
+		user, err := repository.CreateUser("alice", "")
+		if err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		own := createServiceTestCard(t, "trasto", "junk", user.ID)
+		shared := createServiceTestCard(t, "cachivache", "thingamajig", 0)
+
+		result, err := NewWordListService().Import(user.ID, []byte(list), WordImportOptions{Duplicates: tt.mode, DefaultIslandID: 1})
+		if err != nil {
+			t.Fatalf("%s: Import() error = %v", tt.mode, err)
+		}
+		result.Errors = nil
+		if !reflect.DeepEqual(*result, tt.want) {
+			t.Errorf("%s: Import() = %+v, want %+v", tt.mode, *result, tt.want)
+		}
+
+		got, err := repository.GetCard(own)
+		if err != nil {
+			t.Fatalf("get card: %v", err)
+		}
+		if got.Translation != tt.wantOwn.Translation || got.Notes != tt.wantOwn.Notes {
+			t.Errorf("%s: own card = %q / %q, want %q / %q", tt.mode, got.Translation, got.Notes, tt.wantOwn.Translation, tt.wantOwn.Notes)
+		}
+		if got, _ := repository.GetCard(shared); got.Translation != "thingamajig" {
+			t.Errorf("%s: shared card translation = %q, want it unchanged", tt.mode, got.Translation)
+		}
+		if _, err := repository.GetCardByTerm(user.ID, "zarandaja"); err != nil {
+			t.Errorf("%s: new card not created: %v", tt.mode, err)
+		}
+	}
+}
+
+// openServiceTestDB points the repository at a fresh database for one test
+func openServiceTestDB(t *testing.T) {
+	t.Helper()
+	if err := db.Init(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+}
+
+// createServiceTestCard adds a card owned by ownerID, or a shared card for 0
+func createServiceTestCard(t *testing.T, term, translation string, ownerID int64) int64 {
+	t.Helper()
+	card := &models.Card{
+		IslandID:    sql.NullInt64{Int64: 1, Valid: true},
+		Term:        term,
+		Translation: translation,
+		UserID:      sql.NullInt64{Int64: ownerID, Valid: ownerID != 0},
+	}
+	if err := repository.CreateCard(card); err != nil {
+		t.Fatalf("create card %s: %v", term, err)
+	}
+	return card.ID
+}
@@ -5,25 +5,24 @@ package main
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"log"
-	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 	_ "modernc.org/sqlite"
-	"google.golang.org/genai"
+
+	"languagepapi/internal/bridge"
+	"languagepapi/internal/service"
 )
 
 func main() {
 	_ = godotenv.Load()
 	
-	ctx := context.Background()
-	client, err := genai.NewClient(ctx, nil)
-	if err != nil {
-		log.Fatal("Failed to create Gemini client:", err)
+	if _, err := bridge.NewLLM(context.Background()); err != nil {
+		log.Fatal("Failed to create LLM:", err)
 	}
+	lyrics := service.NewLyricsService()
 	
 	db, err := sql.Open("sqlite", "languagepapi.db")
 	if err != nil {
@@ -75,44 +74,19 @@ func main() {
 			continue
 		}
 		
-		// Build translation prompt
-		var spanishLines []string
+		// Translate with the same validated request the server uses
+		var lyricLines []service.LyricLine
 		for _, l := range lines {
-			spanishLines = append(spanishLines, l.text)
+			lyricLines = append(lyricLines, service.LyricLine{Text: l.text})
 		}
-		
-		prompt := fmt.Sprintf(`Translate these Spanish song lyrics to English.
-Return ONLY a JSON array of strings with the translations in the same order.
-Keep translations natural and conversational.
 
-Spanish lines:
-%s
-
-Return format: ["translation1", "translation2", ...]`, strings.Join(spanishLines, "\n"))
-
-		result, err := client.Models.GenerateContent(ctx, "gemini-2.5-flash", genai.Text(prompt), nil)
+		translations, err := lyrics.TranslateLyrics(context.Background(), lyricLines)
 		if err != nil {
 			fmt.Printf("ERROR: %v\n", err)
 			time.Sleep(2 * time.Second)
 			continue
 		}
 		
-		response := result.Text()
-		
-		// Parse JSON
-		start := strings.Index(response, "[")
-		end := strings.LastIndex(response, "]")
-		if start == -1 || end == -1 {
-			fmt.Println("bad response format")
-			continue
-		}
-		
-		var translations []string
-		if err := json.Unmarshal([]byte(response[start:end+1]), &translations); err != nil {
-			fmt.Printf("parse error: %v\n", err)
-			continue
-		}
-		
 		// Update database
 		updated := 0
 		for i, l := range lines {